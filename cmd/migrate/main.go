@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/app/db"
+	"github.com/Point-AI/backend/internal/app/migration"
+	"github.com/Point-AI/backend/internal/messenger/infrastructure/client"
+	"log"
+)
+
+// one-shot migrations, each of them is safe to run again:
+// splits the messages embedded in chats into their own collection and
+// registers the webhooks of telegram bots that were connected before webhooks had a secret
+func main() {
+	cfg := config.Load()
+	mongodb := db.ConnectToDB(cfg)
+	db.EnsureIndexes(cfg, mongodb)
+
+	migrated, err := migration.SplitChatMessages(cfg, mongodb)
+	if err != nil {
+		log.Fatalf("migration stopped after %d chats: %v", migrated, err)
+	}
+
+	log.Printf("migrated %d chats", migrated)
+
+	registered, err := migration.RegisterTelegramWebhookSecrets(cfg, mongodb, client.NewTelegramBotClientManagerImpl(cfg).RegisterNewBot)
+	if err != nil {
+		log.Fatalf("migration stopped after %d telegram bots: %v", registered, err)
+	}
+
+	log.Printf("registered %d telegram bots", registered)
+}
@@ -10,6 +10,7 @@ import (
 func main() {
 	cfg := config.Load()
 	mongodb := db.ConnectToDB(cfg)
+	db.EnsureIndexes(cfg, mongodb)
 	str := storage.ConnectToStorage(cfg)
 
 	server.RunHTTPServer(cfg, mongodb, str)
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type EnvMode string
 
 const (
@@ -16,30 +18,55 @@ type Config struct {
 	Website      Website
 	MinIo        MinIo
 	Integrations Integrations
+	SLA          SLA
+	Throttle     Throttle
+	Outbox       Outbox
+	Websocket    Websocket
 }
 
 type (
 	MongoDB struct {
-		Host                string `env:"DB_HOST"`
-		Port                string `env:"DB_PORT"`
-		User                string `env:"DB_USER"`
-		Password            string `env:"DB_PASSWORD"`
-		Database            string `env:"DB_NAME"`
-		UserCollection      string `env:"DB_USER_COLLECTION"`
-		WorkspaceCollection string `env:"DB_WORKSPACE_COLLECTION"`
-		HelpDeskCollection  string `env:"DB_HELPDESK_COLLECTION"`
-		ChatCollection      string `env:"DB_CHAT_COLLECTION"`
-		TeamCollection      string `env:"DB_TEAM_COLLECTION"`
+		Host                      string `env:"DB_HOST"`
+		Port                      string `env:"DB_PORT"`
+		User                      string `env:"DB_USER"`
+		Password                  string `env:"DB_PASSWORD"`
+		Database                  string `env:"DB_NAME"`
+		UserCollection            string `env:"DB_USER_COLLECTION"`
+		WorkspaceCollection       string `env:"DB_WORKSPACE_COLLECTION"`
+		HelpDeskCollection        string `env:"DB_HELPDESK_COLLECTION"`
+		ChatCollection            string `env:"DB_CHAT_COLLECTION"`
+		MessageCollection         string `env:"DB_MESSAGE_COLLECTION" envDefault:"messages"`
+		TeamCollection            string `env:"DB_TEAM_COLLECTION"`
+		SLAPolicyCollection       string `env:"DB_SLA_POLICY_COLLECTION" envDefault:"sla_policies"`
+		SavedReplyCollection      string `env:"DB_SAVED_REPLY_COLLECTION" envDefault:"saved_replies"`
+		AuditLogCollection        string `env:"DB_AUDIT_LOG_COLLECTION" envDefault:"audit_logs"`
+		RefreshTokenCollection    string `env:"DB_REFRESH_TOKEN_COLLECTION" envDefault:"refresh_tokens"`
+		RevokedTokenCollection    string `env:"DB_REVOKED_TOKEN_COLLECTION" envDefault:"revoked_tokens"`
+		SessionCollection         string `env:"DB_SESSION_COLLECTION" envDefault:"sessions"`
+		LoginAttemptCollection    string `env:"DB_LOGIN_ATTEMPT_COLLECTION" envDefault:"login_attempts"`
+		OutboxCollection          string `env:"DB_OUTBOX_COLLECTION" envDefault:"email_outbox"`
+		BroadcastCollection       string `env:"DB_BROADCAST_COLLECTION" envDefault:"ws_broadcasts"`
+		EventSequenceCollection   string `env:"DB_EVENT_SEQUENCE_COLLECTION" envDefault:"ws_sequences"`
+		EventCursorCollection     string `env:"DB_EVENT_CURSOR_COLLECTION" envDefault:"ws_cursors"`
+		PresenceCollection        string `env:"DB_PRESENCE_COLLECTION" envDefault:"presence"`
+		PresenceSessionCollection string `env:"DB_PRESENCE_SESSION_COLLECTION" envDefault:"presence_sessions"`
 	}
 
 	Server struct {
 		Environment EnvMode `env:"SERVER_ENVIRONMENT" envDefault:"dev"`
 		Port        string  `env:"SERVER_PORT"`
+		// TrustedProxies are the CIDR ranges of the proxies in front of the server. X-Forwarded-For is only
+		// believed when the request comes from one of them, without any the peer address is the client.
+		TrustedProxies []string `env:"SERVER_TRUSTED_PROXIES" envSeparator:","`
 	}
 
 	Auth struct {
 		JWTSecretKey                string `env:"JWT_SECRET_KEY"`
 		IntegrationsServerSecretKey string `env:"INTEGRATIONS_SERVER_SECRET_KEY"`
+		TOTPIssuer                  string `env:"TOTP_ISSUER" envDefault:"PointAI"`
+
+		ConfirmTokenTTL       time.Duration `env:"CONFIRM_TOKEN_TTL" envDefault:"24h"`
+		ConfirmResendCooldown time.Duration `env:"CONFIRM_RESEND_COOLDOWN" envDefault:"1m"`
 	}
 
 	Email struct {
@@ -47,6 +74,8 @@ type (
 		SMTPPassword string `env:"SMTP_PASSWORD"`
 		SMTPHost     string `env:"SMTP_HOST"`
 		SMTPPort     string `env:"SMTP_PORT"`
+		FromAddress  string `env:"SMTP_FROM"`
+		FromName     string `env:"SMTP_FROM_NAME" envDefault:"PointAI"`
 	}
 
 	OAuth2 struct {
@@ -79,7 +108,54 @@ type (
 
 	Integrations struct {
 		TelegramBaseURL string `env:"TELEGRAM_BASE_URL"`
-		MetaBaseURL     string `env:"META_BASE_URL"`
-		WhatsappBaseURL string `env:"WHATSAPP_BASE_URL"`
+		MetaBaseURL     string `env:"META_BASE_URL" envDefault:"https://graph.facebook.com/v19.0"`
+		WhatsappBaseURL string `env:"WHATSAPP_BASE_URL" envDefault:"https://graph.facebook.com/v19.0"`
+
+		WhatsappVerifyToken string `env:"WHATSAPP_VERIFY_TOKEN"`
+		MetaVerifyToken     string `env:"META_VERIFY_TOKEN"`
+	}
+
+	SLA struct {
+		CheckInterval time.Duration `env:"SLA_CHECK_INTERVAL" envDefault:"1m"`
+	}
+
+	// Throttle slows down repeated failures on the sign in and password recovery endpoints.
+	// Store is "memory" for a single instance or "mongo" to share the counters between instances.
+	Throttle struct {
+		Store           string        `env:"THROTTLE_STORE" envDefault:"memory"`
+		AccountLimit    int           `env:"THROTTLE_ACCOUNT_LIMIT" envDefault:"5"`
+		IPLimit         int           `env:"THROTTLE_IP_LIMIT" envDefault:"20"`
+		BaseDelay       time.Duration `env:"THROTTLE_BASE_DELAY" envDefault:"1s"`
+		MaxDelay        time.Duration `env:"THROTTLE_MAX_DELAY" envDefault:"15m"`
+		LockoutDuration time.Duration `env:"THROTTLE_LOCKOUT_DURATION" envDefault:"15m"`
+		Window          time.Duration `env:"THROTTLE_WINDOW" envDefault:"1h"`
+	}
+
+	// Outbox queues outgoing email. A failed send is retried after BaseDelay, doubling up to MaxDelay,
+	// and the email is marked failed after MaxAttempts.
+	Outbox struct {
+		PollInterval  time.Duration `env:"OUTBOX_POLL_INTERVAL" envDefault:"5s"`
+		MaxAttempts   int           `env:"OUTBOX_MAX_ATTEMPTS" envDefault:"8"`
+		BaseDelay     time.Duration `env:"OUTBOX_BASE_DELAY" envDefault:"30s"`
+		MaxDelay      time.Duration `env:"OUTBOX_MAX_DELAY" envDefault:"1h"`
+		LockTimeout   time.Duration `env:"OUTBOX_LOCK_TIMEOUT" envDefault:"2m"`
+		SentRetention time.Duration `env:"OUTBOX_SENT_RETENTION" envDefault:"720h"`
+	}
+
+	// Websocket fans events out to the sockets of every instance. Bus is "mongo" to share them through a change
+	// stream, which needs a replica set, or "memory" when a single instance holds all the sockets.
+	// Events are kept for BroadcastRetention, a client that reconnects within it gets the ones it missed.
+	// A client is pinged every PingInterval and dropped when it stays silent for PongTimeout,
+	// or when SendQueueSize messages pile up waiting for it.
+	// An agent without activity for IdleTimeout shows as idle, presence is refreshed every PresenceInterval.
+	Websocket struct {
+		Bus                string        `env:"WS_BUS" envDefault:"mongo"`
+		BroadcastRetention time.Duration `env:"WS_BROADCAST_RETENTION" envDefault:"10m"`
+		SendQueueSize      int           `env:"WS_SEND_QUEUE_SIZE" envDefault:"64"`
+		PingInterval       time.Duration `env:"WS_PING_INTERVAL" envDefault:"25s"`
+		PongTimeout        time.Duration `env:"WS_PONG_TIMEOUT" envDefault:"60s"`
+		WriteTimeout       time.Duration `env:"WS_WRITE_TIMEOUT" envDefault:"10s"`
+		IdleTimeout        time.Duration `env:"WS_IDLE_TIMEOUT" envDefault:"5m"`
+		PresenceInterval   time.Duration `env:"WS_PRESENCE_INTERVAL" envDefault:"30s"`
 	}
 )
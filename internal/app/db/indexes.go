@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Point-AI/backend/config"
+)
+
+func EnsureIndexes(cfg *config.Config, db *mongo.Database) {
+	_, err := db.Collection(cfg.MongoDB.MessageCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "chat_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "ticket_id", Value: 1}}},
+		{Keys: bson.D{{Key: "message_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "message", Value: "text"}}, Options: options.Index().SetDefaultLanguage("none")},
+		{
+			Keys:    bson.D{{Key: "source", Value: 1}, {Key: "account_id", Value: 1}, {Key: "chat_id", Value: 1}, {Key: "external_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"source": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = db.Collection(cfg.MongoDB.ChatCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "name", Value: "text"},
+				{Key: "company", Value: "text"},
+				{Key: "client_email", Value: "text"},
+				{Key: "client_phone", Value: "text"},
+				{Key: "notes.text", Value: "text"},
+				{Key: "tickets.notes.text", Value: "text"},
+			},
+			// customers write in several languages, so stemming is left off
+			Options: options.Index().SetDefaultLanguage("none").SetWeights(bson.M{"name": 5, "company": 3, "client_email": 3, "client_phone": 3}),
+		},
+		{Keys: bson.D{{Key: "tickets.sla_status", Value: 1}}},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = db.Collection(cfg.MongoDB.SLAPolicyCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}}},
+		{Keys: bson.D{{Key: "policy_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = db.Collection(cfg.MongoDB.SavedReplyCollection).Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "workspace_id", Value: 1}, {Key: "team_id", Value: 1}, {Key: "shortcut", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = db.Collection(cfg.MongoDB.AuditLogCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "action", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// expired tokens are dropped by mongo, a revoked token entry is only needed until the token would have expired
+	_, err = db.Collection(cfg.MongoDB.RefreshTokenCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = db.Collection(cfg.MongoDB.RevokedTokenCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_id", Value: 1}}},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "revoked_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = db.Collection(cfg.MongoDB.SessionCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "session_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = db.Collection(cfg.MongoDB.LoginAttemptCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// sent emails expire after the retention period, failed ones stay until someone looks at them
+	_, err = db.Collection(cfg.MongoDB.OutboxCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "template", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// broadcasts are kept only as long as a reconnecting client may ask for them again
+	_, err = db.Collection(cfg.MongoDB.BroadcastCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "sequence", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = db.Collection(cfg.MongoDB.EventCursorCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = db.Collection(cfg.MongoDB.PresenceSessionCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "instance_id", Value: 1}, {Key: "workspace_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = db.Collection(cfg.MongoDB.PresenceCollection).Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,74 @@
+package migration
+
+import (
+	"context"
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strconv"
+)
+
+// legacyChat is a chat document from before messages had their own collection
+type legacyChat struct {
+	Id          primitive.ObjectID `bson:"_id"`
+	WorkspaceId primitive.ObjectID `bson:"workspace_id"`
+	ChatId      string             `bson:"chat_id"`
+	Tickets     []struct {
+		TicketId string           `bson:"ticket_id"`
+		Messages []entity.Message `bson:"messages"`
+	} `bson:"tickets"`
+}
+
+// SplitChatMessages moves the messages embedded in chat tickets into the messages collection and
+// returns the number of migrated chats. Messages are upserted by message id, so it is safe to run again.
+func SplitChatMessages(cfg *config.Config, db *mongo.Database) (int, error) {
+	ctx := context.Background()
+	chats := db.Collection(cfg.MongoDB.ChatCollection)
+	messages := db.Collection(cfg.MongoDB.MessageCollection)
+
+	cursor, err := chats.Find(ctx, bson.M{"tickets.messages": bson.M{"$exists": true}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var migrated int
+	for cursor.Next(ctx) {
+		var chat legacyChat
+		if err = cursor.Decode(&chat); err != nil {
+			return migrated, err
+		}
+
+		var writes []mongo.WriteModel
+		set, unset := bson.M{}, bson.M{}
+		for i, ticket := range chat.Tickets {
+			for _, message := range ticket.Messages {
+				message.Id = primitive.NilObjectID
+				message.WorkspaceId, message.ChatId, message.TicketId = chat.WorkspaceId, chat.ChatId, ticket.TicketId
+				writes = append(writes, mongo.NewReplaceOneModel().
+					SetFilter(bson.M{"message_id": message.MessageId}).
+					SetReplacement(message).
+					SetUpsert(true))
+			}
+
+			set["tickets."+strconv.Itoa(i)+".message_count"] = len(ticket.Messages)
+			unset["tickets."+strconv.Itoa(i)+".messages"] = ""
+		}
+
+		if len(writes) > 0 {
+			if _, err = messages.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+				return migrated, err
+			}
+		}
+
+		if _, err = chats.UpdateOne(ctx, bson.M{"_id": chat.Id}, bson.M{"$set": set, "$unset": unset}); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	return migrated, cursor.Err()
+}
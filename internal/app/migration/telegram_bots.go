@@ -0,0 +1,63 @@
+package migration
+
+import (
+	"context"
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	"github.com/Point-AI/backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RegisterTelegramWebhookSecrets gives the active bots registered before webhooks carried a secret one,
+// and returns the number of bots it registered again. The webhook is set before the secret is saved,
+// so a bot that failed is picked up again on the next run.
+func RegisterTelegramWebhookSecrets(cfg *config.Config, db *mongo.Database, register func(botToken, webhookSecret string) error) (int, error) {
+	ctx := context.Background()
+	workspaces := db.Collection(cfg.MongoDB.WorkspaceCollection)
+
+	cursor, err := workspaces.Find(ctx, bson.M{
+		"integrations.telegram_bot": bson.M{
+			"$elemMatch": bson.M{
+				"webhook_secret": bson.M{"$in": bson.A{nil, ""}},
+				"is_active":      true,
+			},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var registered int
+	for cursor.Next(ctx) {
+		var workspace entity.Workspace
+		if err = cursor.Decode(&workspace); err != nil {
+			return registered, err
+		}
+
+		for _, bot := range workspace.Integrations.TelegramBot {
+			if !bot.IsActive || bot.WebhookSecret != "" {
+				continue
+			}
+
+			webhookSecret, err := utils.GenerateToken()
+			if err != nil {
+				return registered, err
+			}
+			if err = register(bot.BotToken, webhookSecret); err != nil {
+				return registered, err
+			}
+
+			if _, err = workspaces.UpdateOne(ctx,
+				bson.M{"_id": workspace.Id, "integrations.telegram_bot.bot_token": bot.BotToken},
+				bson.M{"$set": bson.M{"integrations.telegram_bot.$.webhook_secret": webhookSecret}},
+			); err != nil {
+				return registered, err
+			}
+			registered++
+		}
+	}
+
+	return registered, cursor.Err()
+}
@@ -7,12 +7,15 @@ import (
 	messengerDelivery "github.com/Point-AI/backend/internal/messenger/delivery"
 	systemDelivery "github.com/Point-AI/backend/internal/system/delivery"
 	authDelivery "github.com/Point-AI/backend/internal/user/delivery"
+	userRepository "github.com/Point-AI/backend/internal/user/infrastructure/repository"
+	"github.com/Point-AI/backend/mailer"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/minio/minio-go/v7"
 	"github.com/sirupsen/logrus"
 	echoSwagger "github.com/swaggo/echo-swagger"
 	"go.mongodb.org/mongo-driver/mongo"
+	"net"
 	"os"
 	"sync"
 )
@@ -32,6 +35,8 @@ import (
 // @BasePath /
 func RunHTTPServer(cfg *config.Config, db *mongo.Database, str *minio.Client) {
 	e := echo.New()
+	// throttling, sessions and audit logs record c.RealIP, which must not trust headers a client can set
+	e.IPExtractor = newIPExtractor(cfg.Server.TrustedProxies)
 
 	logger := logrus.New()
 	logger.Out = os.Stdout
@@ -43,14 +48,34 @@ func RunHTTPServer(cfg *config.Config, db *mongo.Database, str *minio.Client) {
 	e.Use(middleware.CORS())
 
 	repoMu, storageMu := new(sync.RWMutex), new(sync.RWMutex)
+	// revoked tokens live in the user module, every module checks access tokens against them
+	denylist := userRepository.NewUserRepositoryImpl(db, cfg, repoMu)
+	// the user and system modules only queue email, a single worker delivers it
+	go mailer.NewOutboxWorker(db, cfg, mailer.NewSMTPClient(cfg.Email)).Run()
 
-	authDelivery.RegisterAuthRoutes(e, cfg, db, str, repoMu, storageMu)
-	systemDelivery.RegisterSystemRoutes(e, cfg, db, str, repoMu, storageMu)
+	authDelivery.RegisterAuthRoutes(e, cfg, db, str, repoMu, storageMu, denylist)
+	systemDelivery.RegisterSystemRoutes(e, cfg, db, str, repoMu, storageMu, denylist)
 	apiDelivery.RegisterAPIRoutes(e, cfg, db)
-	messengerDelivery.RegisterMessengerRoutes(e, cfg, db, repoMu)
+	messengerDelivery.RegisterMessengerRoutes(e, cfg, db, repoMu, denylist)
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
 	if err := e.Start(cfg.Server.Port); err != nil {
 		panic(err)
 	}
 }
+
+func newIPExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range trustedProxies {
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			panic(err)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPExtractorIgnoresForwardedHeadersWithoutTrustedProxies(t *testing.T) {
+	request := httptest.NewRequest("POST", "/user/signin", nil)
+	request.RemoteAddr = "203.0.113.7:51234"
+	request.Header.Set("X-Forwarded-For", "198.51.100.1")
+	request.Header.Set("X-Real-IP", "198.51.100.2")
+
+	if ip := newIPExtractor(nil)(request); ip != "203.0.113.7" {
+		t.Fatalf("expected the peer address, got %s", ip)
+	}
+}
+
+func TestIPExtractorTrustsConfiguredProxies(t *testing.T) {
+	extractIP := newIPExtractor([]string{"10.0.0.0/8"})
+
+	request := httptest.NewRequest("POST", "/user/signin", nil)
+	request.RemoteAddr = "10.1.2.3:51234"
+	request.Header.Set("X-Forwarded-For", "198.51.100.1, 10.4.5.6")
+	if ip := extractIP(request); ip != "198.51.100.1" {
+		t.Fatalf("expected the client behind the proxies, got %s", ip)
+	}
+
+	request.RemoteAddr = "192.168.1.2:51234"
+	if ip := extractIP(request); ip != "192.168.1.2" {
+		t.Fatalf("expected an untrusted peer to be taken as the client, got %s", ip)
+	}
+}
@@ -3,22 +3,26 @@ package controller
 import (
 	"github.com/Point-AI/backend/config"
 	"github.com/Point-AI/backend/internal/messenger/delivery/model"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
 	_interface "github.com/Point-AI/backend/internal/messenger/domain/interface"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
 	"net/http"
 )
 
 type MessengerController struct {
 	messengerService _interface.MessengerService
 	websocketService _interface.WebsocketService
+	slaService       _interface.SLAService
 	config           *config.Config
 }
 
-func NewMessengerController(cfg *config.Config, messengerService _interface.MessengerService, websocketService _interface.WebsocketService) *MessengerController {
+func NewMessengerController(cfg *config.Config, messengerService _interface.MessengerService, websocketService _interface.WebsocketService, slaService _interface.SLAService) *MessengerController {
 	return &MessengerController{
 		messengerService: messengerService,
 		websocketService: websocketService,
+		slaService:       slaService,
 		config:           cfg,
 	}
 }
@@ -29,6 +33,7 @@ func NewMessengerController(cfg *config.Config, messengerService _interface.Mess
 // @Produce json
 // @Param id path string true "Workspace ID"
 // @Param userId path string true "User ID"
+// @Param last_event_id query string false "Id of the last event the client handled, the events after it are replayed"
 // @Success 200 {object} model.SuccessResponse "Connection upgraded successfully"
 // @Failure 400 {object} model.ErrorResponse "Bad request, user not valid in workspace"
 // @Failure 500 {object} model.ErrorResponse "Internal server error, failed to upgrade connection"
@@ -44,6 +49,16 @@ func (mc *MessengerController) ChatWSHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "connection upgraded successfully"})
 }
 
+// GetWebsocketMetrics reports the websocket connections of this instance.
+// @Summary Returns websocket delivery metrics of the instance.
+// @Tags Messenger
+// @Produce json
+// @Success 200 {object} model.WebsocketMetricsResponse "Websocket metrics"
+// @Router /messenger/ws/metrics [get]
+func (mc *MessengerController) GetWebsocketMetrics(c echo.Context) error {
+	return c.JSON(http.StatusOK, mc.websocketService.GetMetrics())
+}
+
 // ReassignTicketToTeam reassigns a support ticket to a different team.
 // @Summary Reassigns a support ticket to a team.
 // @Tags Messenger
@@ -62,7 +77,7 @@ func (mc *MessengerController) ReassignTicketToTeam(c echo.Context) error {
 	}
 
 	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
-	if err := mc.messengerService.ReassignTicketToTeam(userId, request.ChatId, request.TicketId, request.WorkspaceId, request.TeamName); err != nil {
+	if err := mc.messengerService.ReassignTicketToTeam(userId, request.ChatId, request.TicketId, request.WorkspaceId, request.TeamName, auditMeta(c)); err != nil {
 		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 	}
 
@@ -86,7 +101,7 @@ func (mc *MessengerController) ReassignTicketToMember(c echo.Context) error {
 	}
 
 	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
-	if err := mc.messengerService.ReassignTicketToUser(userId, request.ChatId, request.TicketId, request.WorkspaceId, request.Email); err != nil {
+	if err := mc.messengerService.ReassignTicketToUser(userId, request.ChatId, request.TicketId, request.WorkspaceId, request.Email, auditMeta(c)); err != nil {
 		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 	}
 
@@ -134,7 +149,7 @@ func (mc *MessengerController) ChangeTicketStatus(c echo.Context) error {
 	}
 
 	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
-	if err := mc.messengerService.UpdateTicketStatus(userId, request.TicketId, request.WorkspaceId, request.Status); err != nil {
+	if err := mc.messengerService.UpdateTicketStatus(userId, request.TicketId, request.WorkspaceId, request.Status, auditMeta(c)); err != nil {
 		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 	}
 
@@ -145,6 +160,56 @@ func (mc *MessengerController) SendOk(c echo.Context) error {
 	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "okay"})
 }
 
+// SendMessage sends a reply or a note to a chat in a workspace.
+// @Summary Sends a reply or a note to a chat
+// @Tags Messenger
+// @Accept json
+// @Produce json
+// @Param request body model.MessageRequest true "details"
+// @Success 201 {object} model.SendMessageResponse "Message saved, status failed means the channel did not accept the reply"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to send the message"
+// @Router /messenger/message [post]
+func (mc *MessengerController) SendMessage(c echo.Context) error {
+	var request model.MessageRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
+	}
+
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	response, err := mc.messengerService.HandleMessage(userId, request.WorkspaceId, request.TicketId, request.ChatId, request.Type, request.Message)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+// ResendMessage delivers a reply whose delivery failed once more.
+// @Summary Resends a failed reply
+// @Tags Messenger
+// @Accept json
+// @Produce json
+// @Param request body model.MessageRequest true "details"
+// @Success 200 {object} model.SendMessageResponse "Delivery status after the new attempt"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to resend the message"
+// @Router /messenger/message/resend [post]
+func (mc *MessengerController) ResendMessage(c echo.Context) error {
+	var request model.MessageRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
+	}
+
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	response, err := mc.messengerService.ResendMessage(userId, request.WorkspaceId, request.ChatId, request.MessageId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
 // DeleteMessage removes a message from a chat in a workspace.
 // @Summary Removes a message from a chat
 // @Tags Messenger
@@ -162,7 +227,7 @@ func (mc *MessengerController) DeleteMessage(c echo.Context) error {
 	}
 
 	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
-	if err := mc.messengerService.DeleteMessage(userId, request.Type, request.WorkspaceId, request.TicketId, request.MessageId, request.ChatId); err != nil {
+	if err := mc.messengerService.DeleteMessage(userId, request.Type, request.WorkspaceId, request.TicketId, request.MessageId, request.ChatId, auditMeta(c)); err != nil {
 		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 	}
 
@@ -239,6 +304,19 @@ func (mc *MessengerController) GetChat(c echo.Context) error {
 	return c.JSON(http.StatusOK, chat)
 }
 
+// GetMessages returns a page of a chat's messages and ticket notes, newest first.
+// @Summary Returns the message history of a chat.
+// @Tags Messenger
+// @Produce json
+// @Param id path string true "Workspace ID"
+// @Param chat_id path string true "Chat ID"
+// @Param cursor query string false "Cursor returned by a previous page"
+// @Param limit query int false "Page size, 30 by default and at most 100"
+// @Param direction query string false "before (older entries, default) or after (newer entries)"
+// @Success 200 {object} model.MessagesResponse "Page of messages"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /messenger/chats/message/{id}/{chat_id} [get]
 func (mc *MessengerController) GetMessages(c echo.Context) error {
 	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
 	var request model.GetMessagesRequest
@@ -246,7 +324,7 @@ func (mc *MessengerController) GetMessages(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
 	}
 
-	messages, err := mc.messengerService.GetMessages(userId, request.WorkspaceId, request.ChatId, request.LastMessageDate)
+	messages, err := mc.messengerService.GetMessages(userId, request.WorkspaceId, request.ChatId, request.Cursor, request.Limit, request.Direction)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 	}
@@ -254,6 +332,39 @@ func (mc *MessengerController) GetMessages(c echo.Context) error {
 	return c.JSON(http.StatusOK, messages)
 }
 
+// Search looks up chats, messages and notes of a workspace by text.
+// @Summary Searches chats, messages and notes of a workspace.
+// @Tags Messenger
+// @Produce json
+// @Param id path string true "Workspace ID"
+// @Param q query string true "Search query"
+// @Param source query string false "Chat source"
+// @Param tag query string false "Chat tag"
+// @Param status query string false "Ticket status"
+// @Param assignee query string false "Assignee email"
+// @Param from query string false "Start date, YYYY-MM-DD or RFC3339"
+// @Param to query string false "End date, YYYY-MM-DD or RFC3339"
+// @Param page query int false "Page number, starting from 1"
+// @Param limit query int false "Page size, 20 by default and at most 50"
+// @Success 200 {object} model.SearchResponse "Search results"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /messenger/search/{id} [get]
+func (mc *MessengerController) Search(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	var request model.SearchRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
+	}
+
+	results, err := mc.messengerService.Search(userId, request)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, results)
+}
+
 func (mc *MessengerController) GetAllTags(c echo.Context) error {
 	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
 	workspaceId := c.Param("id")
@@ -266,13 +377,322 @@ func (mc *MessengerController) GetAllTags(c echo.Context) error {
 	return c.JSON(http.StatusOK, tags)
 }
 
-//func (mc *MessengerController) HandleTelegramMessage(c echo.Context) error {
-//	workspaceId := c.Param("id")
-//
-//	chats, err := mc.messengerService.GetAllChats(userId, workspaceId)
-//	if err != nil {
-//		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
-//	}
-//
-//	return c.JSON(http.StatusOK, chats)
-//}
+// GetPresence lists the presence of the workspace members.
+// @Summary Returns the presence of the workspace members.
+// @Tags Messenger
+// @Produce json
+// @Param id path string true "Workspace ID"
+// @Success 200 {array} model.PresenceResponse "Members that have been connected, anyone missing is offline"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /messenger/presence/{id} [get]
+func (mc *MessengerController) GetPresence(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	workspaceId := c.Param("id")
+
+	presences, err := mc.messengerService.GetPresence(userId, workspaceId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, presences)
+}
+
+// RegisterTelegramBot connects a Telegram bot to a workspace.
+// @Summary Connects a Telegram bot to a workspace.
+// @Tags Messenger
+// @Accept json
+// @Produce json
+// @Param request body model.RegisterBotRequest true "details"
+// @Success 201 {object} model.SuccessResponse "Bot registered successfully"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to register the bot"
+// @Router /messenger/telegram/bot [post]
+func (mc *MessengerController) RegisterTelegramBot(c echo.Context) error {
+	var request model.RegisterBotRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
+	}
+
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	if err := mc.messengerService.RegisterTelegramBot(userId, request.WorkspaceId, request.BotToken); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, model.SuccessResponse{Message: "bot registered successfully"})
+}
+
+// HandleTelegramBotMessage receives updates pushed by Telegram to a bot webhook.
+// @Summary Receives Telegram bot updates.
+// @Tags Messenger
+// @Accept json
+// @Produce json
+// @Param X-Telegram-Bot-Api-Secret-Token header string true "Secret the bot's webhook was registered with"
+// @Success 200 {object} model.SuccessResponse "Update handled successfully"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to handle the update"
+// @Router /telegram/messages/webhook [post]
+func (mc *MessengerController) HandleTelegramBotMessage(c echo.Context) error {
+	payload, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
+	}
+
+	if err = mc.messengerService.HandleTelegramBotWebhook(payload, c.Request().Header.Get("X-Telegram-Bot-Api-Secret-Token")); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "update handled successfully"})
+}
+
+// HandleTelegramAccountMessage receives messages forwarded by the integrations server for a Telegram account.
+// @Summary Receives Telegram account messages.
+// @Tags Messenger
+// @Accept json
+// @Produce json
+// @Param id path string true "Workspace ID"
+// @Success 200 {object} model.SuccessResponse "Update handled successfully"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to handle the update"
+// @Router /telegram/account/webhook/{id} [post]
+func (mc *MessengerController) HandleTelegramAccountMessage(c echo.Context) error {
+	return mc.handleChannelWebhook(c, entity.SourceTelegram)
+}
+
+// RegisterWhatsApp connects a WhatsApp Business phone number to a workspace.
+// @Summary Connects a WhatsApp Business phone number to a workspace.
+// @Tags Messenger
+// @Accept json
+// @Produce json
+// @Param request body model.RegisterWhatsAppRequest true "details"
+// @Success 201 {object} model.SuccessResponse "Phone number registered successfully"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to register the phone number"
+// @Router /messenger/whatsapp [post]
+func (mc *MessengerController) RegisterWhatsApp(c echo.Context) error {
+	var request model.RegisterWhatsAppRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
+	}
+
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	if err := mc.messengerService.RegisterWhatsApp(userId, request.WorkspaceId, request.PhoneNumberId, request.AccessToken); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, model.SuccessResponse{Message: "phone number registered successfully"})
+}
+
+// SendWhatsAppTemplate sends an approved template message to a WhatsApp chat.
+// @Summary Sends a WhatsApp template message.
+// @Tags Messenger
+// @Accept json
+// @Produce json
+// @Param request body model.WhatsAppTemplateRequest true "details"
+// @Success 200 {object} model.SuccessResponse "Template sent successfully"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to send the template"
+// @Router /messenger/whatsapp/template [post]
+func (mc *MessengerController) SendWhatsAppTemplate(c echo.Context) error {
+	var request model.WhatsAppTemplateRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
+	}
+
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	if err := mc.messengerService.SendWhatsAppTemplate(userId, request.WorkspaceId, request.ChatId, request.TemplateName, request.LanguageCode, request.Parameters); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "template sent successfully"})
+}
+
+// VerifyWhatsAppWebhook answers the subscription challenge sent by Meta.
+// @Summary Verifies the WhatsApp webhook subscription.
+// @Tags Messenger
+// @Produce plain
+// @Param hub.mode query string true "Subscription mode"
+// @Param hub.verify_token query string true "Verify token"
+// @Param hub.challenge query string true "Challenge"
+// @Success 200 {string} string "Challenge echoed back"
+// @Failure 403 {object} model.ErrorResponse "Invalid verify token"
+// @Router /whatsapp/webhook [get]
+func (mc *MessengerController) VerifyWhatsAppWebhook(c echo.Context) error {
+	challenge, err := mc.messengerService.VerifyWhatsAppWebhook(c.QueryParam("hub.mode"), c.QueryParam("hub.verify_token"), c.QueryParam("hub.challenge"))
+	if err != nil {
+		return c.JSON(http.StatusForbidden, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.String(http.StatusOK, challenge)
+}
+
+// HandleWhatsAppMessage receives notifications pushed by the WhatsApp Cloud API.
+// @Summary Receives WhatsApp messages.
+// @Tags Messenger
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.SuccessResponse "Update handled successfully"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to handle the update"
+// @Router /whatsapp/webhook [post]
+func (mc *MessengerController) HandleWhatsAppMessage(c echo.Context) error {
+	payload, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
+	}
+
+	if err = mc.messengerService.HandleWhatsAppWebhook(payload, c.Request().Header.Get("X-Hub-Signature-256")); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "update handled successfully"})
+}
+
+// VerifyMetaWebhook answers the subscription challenge sent by Meta for pages and Instagram accounts.
+// @Summary Verifies the Meta webhook subscription.
+// @Tags Messenger
+// @Produce plain
+// @Param hub.mode query string true "Subscription mode"
+// @Param hub.verify_token query string true "Verify token"
+// @Param hub.challenge query string true "Challenge"
+// @Success 200 {string} string "Challenge echoed back"
+// @Failure 403 {object} model.ErrorResponse "Invalid verify token"
+// @Router /meta/webhook [get]
+func (mc *MessengerController) VerifyMetaWebhook(c echo.Context) error {
+	challenge, err := mc.messengerService.VerifyMetaWebhook(c.QueryParam("hub.mode"), c.QueryParam("hub.verify_token"), c.QueryParam("hub.challenge"))
+	if err != nil {
+		return c.JSON(http.StatusForbidden, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.String(http.StatusOK, challenge)
+}
+
+// HandleMetaMessage receives Messenger and Instagram direct messages pushed by Meta.
+// @Summary Receives Messenger and Instagram messages.
+// @Tags Messenger
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.SuccessResponse "Update handled successfully"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to handle the update"
+// @Router /meta/webhook [post]
+func (mc *MessengerController) HandleMetaMessage(c echo.Context) error {
+	payload, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
+	}
+
+	if err = mc.messengerService.HandleMetaWebhook(payload, c.Request().Header.Get("X-Hub-Signature-256")); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "update handled successfully"})
+}
+
+func (mc *MessengerController) handleChannelWebhook(c echo.Context, source entity.ChatSource) error {
+	accountId := c.Param("id")
+	payload, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
+	}
+
+	if err = mc.messengerService.HandleChannelWebhook(source, accountId, payload); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "update handled successfully"})
+}
+
+// CreateSLAPolicy adds an SLA policy to a workspace.
+// @Summary Adds an SLA policy to a workspace.
+// @Tags Messenger
+// @Accept json
+// @Produce json
+// @Param request body model.CreateSLAPolicyRequest true "details"
+// @Success 201 {object} model.SuccessResponse "SLA policy created successfully"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to create the SLA policy"
+// @Router /messenger/sla [post]
+func (mc *MessengerController) CreateSLAPolicy(c echo.Context) error {
+	var request model.CreateSLAPolicyRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
+	}
+
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	if err := mc.slaService.CreateSLAPolicy(userId, request); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, model.SuccessResponse{Message: "sla policy created successfully"})
+}
+
+// UpdateSLAPolicy changes an SLA policy of a workspace.
+// @Summary Changes an SLA policy of a workspace.
+// @Tags Messenger
+// @Accept json
+// @Produce json
+// @Param request body model.UpdateSLAPolicyRequest true "details"
+// @Success 200 {object} model.SuccessResponse "SLA policy updated successfully"
+// @Failure 400 {object} model.ErrorResponse "Invalid request parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to update the SLA policy"
+// @Router /messenger/sla [put]
+func (mc *MessengerController) UpdateSLAPolicy(c echo.Context) error {
+	var request model.UpdateSLAPolicyRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request parameters"})
+	}
+
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	if err := mc.slaService.UpdateSLAPolicy(userId, request); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "sla policy updated successfully"})
+}
+
+// GetSLAPolicies lists the SLA policies of a workspace.
+// @Summary Lists the SLA policies of a workspace.
+// @Tags Messenger
+// @Produce json
+// @Param id path string true "Workspace ID"
+// @Success 200 {array} model.SLAPolicyResponse "SLA policies"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /messenger/sla/{id} [get]
+func (mc *MessengerController) GetSLAPolicies(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	workspaceId := c.Param("id")
+
+	policies, err := mc.slaService.GetSLAPolicies(userId, workspaceId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, policies)
+}
+
+// DeleteSLAPolicy removes an SLA policy from a workspace.
+// @Summary Removes an SLA policy from a workspace.
+// @Tags Messenger
+// @Produce json
+// @Param id path string true "Workspace ID"
+// @Param policy_id path string true "SLA policy ID"
+// @Success 200 {object} model.SuccessResponse "SLA policy deleted successfully"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to delete the SLA policy"
+// @Router /messenger/sla/{id}/{policy_id} [delete]
+func (mc *MessengerController) DeleteSLAPolicy(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	workspaceId, policyId := c.Param("id"), c.Param("policy_id")
+
+	if err := mc.slaService.DeleteSLAPolicy(userId, workspaceId, policyId); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "sla policy deleted successfully"})
+}
+
+func auditMeta(c echo.Context) entity.AuditMeta {
+	return entity.AuditMeta{
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+}
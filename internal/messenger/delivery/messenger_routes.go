@@ -3,37 +3,85 @@ package messengerDelivery
 import (
 	"github.com/Point-AI/backend/config"
 	"github.com/Point-AI/backend/internal/messenger/delivery/controller"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	"github.com/Point-AI/backend/internal/messenger/infrastructure/client"
 	"github.com/Point-AI/backend/internal/messenger/infrastructure/repository"
 	"github.com/Point-AI/backend/internal/messenger/service"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
 	"github.com/Point-AI/backend/middleware"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/mongo"
 	"sync"
 )
 
-func RegisterMessengerRoutes(e *echo.Echo, cfg *config.Config, db *mongo.Database, mu *sync.RWMutex) {
+func RegisterMessengerRoutes(e *echo.Echo, cfg *config.Config, db *mongo.Database, mu *sync.RWMutex, denylist middleware.TokenDenylist) {
 	ir := repository.NewMessengerRepositoryImpl(cfg, db, mu)
 	fsi := service.NewFileServiceImpl("../../static")
-	wss := service.NewWebSocketServiceImpl(ir)
-	is := service.NewMessengerServiceImpl(cfg, ir, wss, fsi)
-	ic := controller.NewMessengerController(cfg, is, wss)
+	tbc := client.NewTelegramBotClientManagerImpl(cfg)
+	wac := client.NewWhatsAppClientManagerImpl(cfg)
+	mcm := client.NewMetaClientManagerImpl(cfg)
+	cr := client.NewChannelRegistryImpl()
+	cr.Register(entity.SourceTelegramBot, client.NewTelegramBotAdapterImpl(tbc))
+	cr.Register(entity.SourceTelegram, client.NewTelegramAdapterImpl(cfg))
+	cr.Register(entity.SourceWhatsApp, client.NewWhatsAppAdapterImpl(wac))
+	cr.Register(entity.SourceMeta, client.NewMetaAdapterImpl(mcm, entity.SourceMeta))
+	cr.Register(entity.SourceInstagram, client.NewMetaAdapterImpl(mcm, entity.SourceInstagram))
+	var bb infrastructureInterface.BroadcastBus
+	var pst infrastructureInterface.PresenceStore
+	if cfg.Websocket.Bus == "memory" {
+		bb = repository.NewMemoryBroadcastBusImpl(cfg)
+		pst = repository.NewMemoryPresenceStoreImpl()
+	} else {
+		bb = repository.NewMongoBroadcastBusImpl(db, cfg)
+		pst = repository.NewMongoPresenceStoreImpl(db, cfg)
+	}
+	wss := service.NewWebSocketServiceImpl(cfg, ir, bb)
+	ps := service.NewPresenceServiceImpl(cfg, ir, wss, pst)
+	as := service.NewAssignmentServiceImpl(ir)
+	is := service.NewMessengerServiceImpl(cfg, ir, wss, ps, fsi, tbc, wac, mcm, cr, as)
+	sla := service.NewSLAServiceImpl(ir, wss, is)
+	ic := controller.NewMessengerController(cfg, is, wss, sla)
+
+	go sla.RunScheduler(cfg.SLA.CheckInterval)
+	go ps.RunHeartbeat(cfg.Websocket.PresenceInterval)
 
 	messengerGroup := e.Group("/messenger")
 	messengerGroup.GET("/poop", ic.SendOk)
-	messengerGroup.GET("/chats/ws", ic.ChatWSHandler, middleware.ValidateAccessTokenForWebsocketMiddleware(cfg.Auth.JWTSecretKey))
-	messengerGroup.POST("/ticket/reassign/team", ic.ReassignTicketToTeam, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	messengerGroup.POST("/ticket/reassign/member", ic.ReassignTicketToMember, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	messengerGroup.PUT("/ticket", ic.ChangeTicketStatus, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	messengerGroup.PUT("/chat", ic.UpdateChatInfo, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	messengerGroup.GET("/chats/:id/:type", ic.GetAllChats, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	messengerGroup.GET("/chats/message/:id/:chat_id", ic.GetMessages, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	messengerGroup.GET("/chats/folder/:id/:name", ic.GetChatsByFolder, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	messengerGroup.GET("/chats/tags/:id", ic.GetAllTags, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	messengerGroup.GET("/chats/chat/:id/:chat_id", ic.GetChat, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	messengerGroup.DELETE("/message", ic.DeleteMessage, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
+	messengerGroup.GET("/chats/ws", ic.ChatWSHandler, middleware.ValidateAccessTokenForWebsocketMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.GET("/ws/metrics", ic.GetWebsocketMetrics, middleware.ValidateServerMiddleware(cfg.Auth.IntegrationsServerSecretKey))
+	messengerGroup.POST("/ticket/reassign/team", ic.ReassignTicketToTeam, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.POST("/ticket/reassign/member", ic.ReassignTicketToMember, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.PUT("/ticket", ic.ChangeTicketStatus, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.PUT("/chat", ic.UpdateChatInfo, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.GET("/chats/:id/:type", ic.GetAllChats, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.GET("/chats/message/:id/:chat_id", ic.GetMessages, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.GET("/chats/folder/:id/:name", ic.GetChatsByFolder, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.GET("/chats/tags/:id", ic.GetAllTags, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.GET("/chats/chat/:id/:chat_id", ic.GetChat, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.GET("/presence/:id", ic.GetPresence, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.GET("/search/:id", ic.Search, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.POST("/message", ic.SendMessage, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.POST("/message/resend", ic.ResendMessage, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.DELETE("/message", ic.DeleteMessage, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.POST("/telegram/bot", ic.RegisterTelegramBot, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.POST("/whatsapp", ic.RegisterWhatsApp, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.POST("/whatsapp/template", ic.SendWhatsAppTemplate, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.POST("/sla", ic.CreateSLAPolicy, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.PUT("/sla", ic.UpdateSLAPolicy, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.GET("/sla/:id", ic.GetSLAPolicies, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	messengerGroup.DELETE("/sla/:id/:policy_id", ic.DeleteSLAPolicy, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
 	//messengerGroup.GET("/messages/:id/")
 
 	telegramGroup := e.Group("/telegram")
 	telegramGroup.POST("/import/:id", ic.ImportTelegramChats, middleware.ValidateServerMiddleware(cfg.Auth.IntegrationsServerSecretKey))
-	//telegramGroup.POST("/messages/webhook/:id", ic.HandleTelegramMessage, middleware.ValidateServerMiddleware(cfg.Auth.IntegrationsServerSecretKey))
+	telegramGroup.POST("/messages/webhook", ic.HandleTelegramBotMessage)
+	telegramGroup.POST("/account/webhook/:id", ic.HandleTelegramAccountMessage, middleware.ValidateServerMiddleware(cfg.Auth.IntegrationsServerSecretKey))
+
+	whatsappGroup := e.Group("/whatsapp")
+	whatsappGroup.GET("/webhook", ic.VerifyWhatsAppWebhook)
+	whatsappGroup.POST("/webhook", ic.HandleWhatsAppMessage)
+
+	metaGroup := e.Group("/meta")
+	metaGroup.GET("/webhook", ic.VerifyMetaWebhook)
+	metaGroup.POST("/webhook", ic.HandleMetaMessage)
 }
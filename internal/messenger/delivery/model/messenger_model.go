@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -11,6 +12,33 @@ type RegisterBotRequest struct {
 	BotToken    string `json:"bot_token"`
 }
 
+type RegisterWhatsAppRequest struct {
+	WorkspaceId   string `json:"workspace_id"`
+	PhoneNumberId string `json:"phone_number_id"`
+	AccessToken   string `json:"access_token"`
+}
+
+type WhatsAppTemplateRequest struct {
+	WorkspaceId  string   `json:"workspace_id"`
+	ChatId       string   `json:"chat_id"`
+	TemplateName string   `json:"template_name"`
+	LanguageCode string   `json:"language_code"`
+	Parameters   []string `json:"parameters"`
+}
+
+// EventRequest is a frame sent by a client, an ack carries the id of the last event it handled
+type EventRequest struct {
+	Type    string          `json:"type"`
+	Id      string          `json:"id"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// TypingRequest is the payload of a typing event, state is viewing, typing or idle
+type TypingRequest struct {
+	ChatId string `json:"chat_id"`
+	State  string `json:"state"`
+}
+
 type MessageRequest struct {
 	TicketId    string `json:"ticket_id"`
 	ChatId      string `json:"chat_id"`
@@ -79,9 +107,48 @@ type TelegramChatsRequest struct {
 }
 
 type GetMessagesRequest struct {
-	WorkspaceId     string    `json:"workspace_id"`
-	ChatId          string    `json:"chat_id"`
-	LastMessageDate time.Time `json:"last_message_date"`
+	WorkspaceId string `param:"id"`
+	ChatId      string `param:"chat_id"`
+	Cursor      string `query:"cursor"`
+	Limit       int    `query:"limit"`
+	Direction   string `query:"direction"`
+}
+
+type CreateSLAPolicyRequest struct {
+	WorkspaceId          string `json:"workspace_id"`
+	Name                 string `json:"name"`
+	TeamId               string `json:"team_id"`
+	Tag                  string `json:"tag"`
+	FirstResponseMinutes int    `json:"first_response_minutes"`
+	ResolutionMinutes    int    `json:"resolution_minutes"`
+	WarningMinutes       int    `json:"warning_minutes"`
+	EscalationTeamId     string `json:"escalation_team_id"`
+}
+
+type UpdateSLAPolicyRequest struct {
+	WorkspaceId          string `json:"workspace_id"`
+	PolicyId             string `json:"policy_id"`
+	Name                 string `json:"name"`
+	TeamId               string `json:"team_id"`
+	Tag                  string `json:"tag"`
+	FirstResponseMinutes int    `json:"first_response_minutes"`
+	ResolutionMinutes    int    `json:"resolution_minutes"`
+	WarningMinutes       int    `json:"warning_minutes"`
+	EscalationTeamId     string `json:"escalation_team_id"`
+	IsActive             bool   `json:"is_active"`
+}
+
+type SearchRequest struct {
+	WorkspaceId string `param:"id"`
+	Query       string `query:"q"`
+	Source      string `query:"source"`
+	Tag         string `query:"tag"`
+	Status      string `query:"status"`
+	Assignee    string `query:"assignee"`
+	From        string `query:"from"`
+	To          string `query:"to"`
+	Page        int    `query:"page"`
+	Limit       int    `query:"limit"`
 }
 
 // Responses
@@ -108,13 +175,140 @@ type MessageResponse struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// SendMessageResponse tells whether a reply reached the customer, Status is empty for notes
+type SendMessageResponse struct {
+	MessageId string `json:"message_id"`
+	Status    string `json:"status,omitempty"`
+}
+
+type MessageStatusResponse struct {
+	WorkspaceId string `json:"workspace_id"`
+	ChatId      string `json:"chat_id"`
+	TicketId    string `json:"ticket_id"`
+	MessageId   string `json:"message_id"`
+	Status      string `json:"status"`
+}
+
+type MessagesResponse struct {
+	Messages     []MessageResponse `json:"messages"`
+	BeforeCursor string            `json:"before_cursor"`
+	AfterCursor  string            `json:"after_cursor"`
+	HasMore      bool              `json:"has_more"`
+}
+
+type SearchResult struct {
+	Kind      string    `json:"kind"`
+	Field     string    `json:"field"`
+	ChatId    string    `json:"chat_id"`
+	TicketId  string    `json:"ticket_id,omitempty"`
+	MessageId string    `json:"message_id,omitempty"`
+	Name      string    `json:"name"`
+	Source    string    `json:"source"`
+	Snippet   string    `json:"snippet"`
+	Score     float64   `json:"score"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+	Page    int            `json:"page"`
+	HasMore bool           `json:"has_more"`
+}
+
+type SLAPolicyResponse struct {
+	PolicyId             string    `json:"policy_id"`
+	Name                 string    `json:"name"`
+	TeamId               string    `json:"team_id"`
+	Tag                  string    `json:"tag"`
+	FirstResponseMinutes int       `json:"first_response_minutes"`
+	ResolutionMinutes    int       `json:"resolution_minutes"`
+	WarningMinutes       int       `json:"warning_minutes"`
+	EscalationTeamId     string    `json:"escalation_team_id"`
+	IsActive             bool      `json:"is_active"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+type SLAAlertResponse struct {
+	Type        string    `json:"type"`
+	WorkspaceId string    `json:"workspace_id"`
+	ChatId      string    `json:"chat_id"`
+	TicketId    string    `json:"ticket_id"`
+	PolicyId    string    `json:"policy_id"`
+	Target      string    `json:"target"`
+	DueAt       time.Time `json:"due_at"`
+}
+
+// EventEnvelope wraps every websocket event. Id grows with every event of the workspace,
+// a client that reconnects with the last id it saw gets the events it missed.
+type EventEnvelope struct {
+	Version int             `json:"v"`
+	Type    string          `json:"type"`
+	Id      string          `json:"id"`
+	Ts      time.Time       `json:"ts"`
+	Payload json.RawMessage `json:"payload"`
+}
+
 type DeleteMessageResponse struct {
 	Type        string `json:"type"`
 	WorkspaceId string `json:"workspace_id"`
 	ChatId      string `json:"chat_id"`
+	TicketId    string `json:"ticket_id"`
 	MessageId   string `json:"message_id"`
 }
 
+type TicketStatusResponse struct {
+	WorkspaceId    string `json:"workspace_id"`
+	ChatId         string `json:"chat_id"`
+	TicketId       string `json:"ticket_id"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status"`
+}
+
+// TicketAssignmentResponse describes a ticket that moved to another chat, team or agent
+type TicketAssignmentResponse struct {
+	WorkspaceId    string `json:"workspace_id"`
+	TicketId       string `json:"ticket_id"`
+	PreviousChatId string `json:"previous_chat_id"`
+	ChatId         string `json:"chat_id"`
+	TeamId         string `json:"team_id,omitempty"`
+	AssigneeId     string `json:"assignee_id"`
+}
+
+type ChatUpdateResponse struct {
+	WorkspaceId string   `json:"workspace_id"`
+	ChatId      string   `json:"chat_id"`
+	Tags        []string `json:"tags"`
+	Language    string   `json:"language"`
+	Address     string   `json:"address"`
+	Company     string   `json:"company"`
+	ClientEmail string   `json:"client_email"`
+	ClientPhone string   `json:"client_phone"`
+}
+
+type PresenceResponse struct {
+	WorkspaceId string    `json:"workspace_id"`
+	UserId      string    `json:"user_id"`
+	Status      string    `json:"status"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TypingResponse tells the other agents that someone has a chat open or is writing in it
+type TypingResponse struct {
+	WorkspaceId string `json:"workspace_id"`
+	ChatId      string `json:"chat_id"`
+	UserId      string `json:"user_id"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+}
+
+// WebsocketMetricsResponse counts since the start of this instance, OpenConnections is the current number
+type WebsocketMetricsResponse struct {
+	OpenConnections    int   `json:"open_connections"`
+	MessagesDropped    int64 `json:"messages_dropped"`
+	ConnectionsEvicted int64 `json:"connections_evicted"`
+	WriteFailures      int64 `json:"write_failures"`
+}
+
 type TelegramStatusResponse struct {
 	Status string `json:"status"`
 }
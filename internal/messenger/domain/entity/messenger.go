@@ -14,26 +14,88 @@ type User struct {
 	FullName     string             `bson:"name"`
 	Role         UserRole           `bson:"role"`
 	Status       UserStatus         `bson:"status"`
+	Language     string             `bson:"language"`
 	Tokens       Tokens             `bson:"tokens"`
+	TwoFactor    TwoFactor          `bson:"two_factor"`
 	CreatedAt    time.Time          `bson:"created_at"`
 }
 
+type TwoFactor struct {
+	IsEnabled      bool      `bson:"is_enabled"`
+	Secret         string    `bson:"secret"`
+	PendingSecret  string    `bson:"pending_secret"`
+	RecoveryCodes  []string  `bson:"recovery_codes"`
+	LastUsedStep   int64     `bson:"last_used_step"`
+	ChallengeId    string    `bson:"challenge_id"`
+	FailedAttempts int       `bson:"failed_attempts"`
+	EnabledAt      time.Time `bson:"enabled_at"`
+}
+
 type Tokens struct {
-	ConfirmToken string `bson:"confirm_token"`
-	OAuth2Token  string `bson:"oauth2_token"`
-	ResetToken   string `bson:"reset_token"`
-	RefreshToken string `bson:"refresh_token"`
+	ConfirmToken          string    `bson:"confirm_token"`
+	ConfirmTokenExpiresAt time.Time `bson:"confirm_token_expires_at"`
+	ConfirmSentAt         time.Time `bson:"confirm_sent_at"`
+	OAuth2Token           string    `bson:"oauth2_token"`
+	ResetToken            string    `bson:"reset_token"`
 }
 
 type Workspace struct {
-	Id          primitive.ObjectID                   `bson:"_id,omitempty"`
-	WorkspaceId string                               `bson:"workspace_id"`
-	Name        string                               `bson:"name"`
-	Team        map[primitive.ObjectID]WorkspaceRole `bson:"team"`
-	PendingTeam map[string]WorkspaceRole             `bson:"pending"`
-	Folders     map[string][]string                  `bson:"folders"`
-	Tags        []string                             `bson:"tags"`
-	CreatedAt   time.Time                            `bson:"created_at"`
+	Id               primitive.ObjectID                   `bson:"_id,omitempty"`
+	WorkspaceId      string                               `bson:"workspace_id"`
+	Name             string                               `bson:"name"`
+	Team             map[primitive.ObjectID]WorkspaceRole `bson:"team"`
+	PendingTeam      map[string]WorkspaceRole             `bson:"pending"`
+	Folders          map[string][]string                  `bson:"folders"`
+	Roles            map[string][]string                  `bson:"roles"`
+	RequireTwoFactor bool                                 `bson:"require_two_factor"`
+	Tags             []string                             `bson:"tags"`
+	Integrations     Integrations                         `bson:"integrations"`
+	CreatedAt        time.Time                            `bson:"created_at"`
+}
+
+type Integrations struct {
+	Telegram    *TelegramIntegration     `bson:"telegram"`
+	TelegramBot []TelegramBotIntegration `bson:"telegram_bot"`
+	WhatsApp    []WhatsAppIntegration    `bson:"whatsapp"`
+	Meta        *MetaIntegration         `bson:"meta"`
+}
+
+type TelegramIntegration struct {
+	PhoneNumber string `bson:"phone_number"`
+	IsActive    bool   `bson:"is_active"`
+}
+
+// TelegramBotIntegration is a connected bot, Telegram sends WebhookSecret with every update so the token stays out of the webhook url
+type TelegramBotIntegration struct {
+	BotToken      string    `bson:"bot_token"`
+	BotName       string    `bson:"bot_name"`
+	WebhookSecret string    `bson:"webhook_secret"`
+	IsActive      bool      `bson:"is_active"`
+	CreatedAt     time.Time `bson:"created_at"`
+}
+
+type WhatsAppIntegration struct {
+	PhoneNumberId string    `bson:"phone_number_id"`
+	PhoneNumber   string    `bson:"phone_number"`
+	AccessToken   string    `bson:"access_token"`
+	IsActive      bool      `bson:"is_active"`
+	CreatedAt     time.Time `bson:"created_at"`
+}
+
+type MetaIntegration struct {
+	AccessToken  string                `bson:"access_token"`
+	RefreshToken string                `bson:"refresh_token"`
+	Pages        []MetaPageIntegration `bson:"pages"`
+	IsActive     bool                  `bson:"is_active"`
+	CreatedAt    time.Time             `bson:"created_at"`
+}
+
+type MetaPageIntegration struct {
+	PageId             string `bson:"page_id"`
+	PageName           string `bson:"page_name"`
+	AccessToken        string `bson:"access_token"`
+	InstagramAccountId string `bson:"instagram_account_id"`
+	IsActive           bool   `bson:"is_active"`
 }
 
 type Team struct {
@@ -44,6 +106,53 @@ type Team struct {
 	Members        map[primitive.ObjectID]bool `bson:"members"`
 	PendingMembers map[string]bool             `bson:"pending_members"`
 	IsFirstTeam    bool                        `bson:"is_first_team"`
+	Assignment     TeamAssignment              `bson:"assignment"`
+}
+
+type TeamAssignment struct {
+	Strategy       AssignmentStrategy                    `bson:"strategy"`
+	Sticky         bool                                  `bson:"sticky"`
+	MaxOpenTickets int                                   `bson:"max_open_tickets"`
+	Languages      map[primitive.ObjectID][]ChatLanguage `bson:"languages"`
+	LastAssigneeId primitive.ObjectID                    `bson:"last_assignee_id"`
+}
+
+type SavedReply struct {
+	Id          primitive.ObjectID      `bson:"_id,omitempty"`
+	ReplyId     string                  `bson:"reply_id"`
+	WorkspaceId primitive.ObjectID      `bson:"workspace_id"`
+	TeamId      string                  `bson:"team_id"`
+	Shortcut    string                  `bson:"shortcut"`
+	Title       string                  `bson:"title"`
+	Variants    map[ChatLanguage]string `bson:"variants"`
+	CreatedBy   primitive.ObjectID      `bson:"created_by"`
+	CreatedAt   time.Time               `bson:"created_at"`
+	UpdatedAt   time.Time               `bson:"updated_at"`
+}
+
+type AuditLog struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty"`
+	WorkspaceId primitive.ObjectID `bson:"workspace_id"`
+	ActorId     primitive.ObjectID `bson:"actor_id"`
+	Action      AuditAction        `bson:"action"`
+	TargetType  string             `bson:"target_type"`
+	TargetId    string             `bson:"target_id"`
+	Changes     []AuditChange      `bson:"changes"`
+	IP          string             `bson:"ip"`
+	UserAgent   string             `bson:"user_agent"`
+	CreatedAt   time.Time          `bson:"created_at"`
+}
+
+type AuditChange struct {
+	Field  string `bson:"field"`
+	Before string `bson:"before"`
+	After  string `bson:"after"`
+}
+
+// AuditMeta describes the request an audited action came from
+type AuditMeta struct {
+	IP        string
+	UserAgent string
 }
 
 type Chat struct {
@@ -52,6 +161,8 @@ type Chat struct {
 	WorkspaceId primitive.ObjectID `bson:"workspace_id"`
 	TeamId      primitive.ObjectID `bson:"team_id"`
 	ChatId      string             `bson:"chat_id"`
+	AccountId   string             `bson:"account_id"`
+	ExternalId  string             `bson:"external_id"`
 	TgClientId  int                `bson:"tg_user_id"`
 	TgChatId    int                `bson:"tg_chat_id"`
 	Tags        []string           `bson:"tags"`
@@ -78,38 +189,157 @@ type Note struct {
 }
 
 type Ticket struct {
-	Id         primitive.ObjectID `bson:"_id,omitempty"`
-	TicketId   string             `bson:"ticket_id"`
-	Subject    string             `bson:"subject"`
-	Notes      []Note             `bson:"notes"`
-	Messages   []Message          `bson:"messages"`
-	Status     TicketStatus       `bson:"status"`
-	CreatedAt  time.Time          `bson:"created_at"`
-	ResolvedAt time.Time          `bson:"resolved_at"`
+	Id                 primitive.ObjectID `bson:"_id,omitempty"`
+	TicketId           string             `bson:"ticket_id"`
+	Subject            string             `bson:"subject"`
+	Notes              []Note             `bson:"notes"`
+	MessageCount       int                `bson:"message_count"`
+	Status             TicketStatus       `bson:"status"`
+	SLAPolicyId        string             `bson:"sla_policy_id"`
+	SLAStatus          SLAStatus          `bson:"sla_status"`
+	FirstResponseDueAt time.Time          `bson:"first_response_due_at"`
+	FirstResponseAt    time.Time          `bson:"first_response_at"`
+	SLADueAt           time.Time          `bson:"sla_due_at"`
+	CreatedAt          time.Time          `bson:"created_at"`
+	ResolvedAt         time.Time          `bson:"resolved_at"`
 }
 
+// Message lives in the messages collection, the chat only embeds a copy of the latest one.
+// Source and AccountId are only set on messages received from a channel, they keep a redelivered message from being stored twice.
 type Message struct {
 	Id              primitive.ObjectID `bson:"_id,omitempty"`
+	WorkspaceId     primitive.ObjectID `bson:"workspace_id"`
+	ChatId          string             `bson:"chat_id"`
+	TicketId        string             `bson:"ticket_id"`
 	SenderId        primitive.ObjectID `bson:"sender_id"`
 	MessageId       string             `bson:"message_id"`
 	MessageIdClient int                `bson:"message_id_client"`
+	ExternalId      string             `bson:"external_id"`
+	Source          ChatSource         `bson:"source,omitempty"`
+	AccountId       string             `bson:"account_id,omitempty"`
 	Message         string             `bson:"message"`
 	From            string             `bson:"from"`
 	Type            MessageType        `bson:"type"`
+	Status          MessageStatus      `bson:"status"`
 	CreatedAt       time.Time          `bson:"created_at"`
 }
 
+// ChatHistoryEntry is a ticket message or ticket note as listed in a chat's history
+type ChatHistoryEntry struct {
+	TicketId  string             `bson:"ticket_id"`
+	EntryId   string             `bson:"entry_id"`
+	SenderId  primitive.ObjectID `bson:"sender_id"`
+	Text      string             `bson:"text"`
+	From      string             `bson:"from"`
+	Type      MessageType        `bson:"type"`
+	Status    MessageStatus      `bson:"status"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+type SLAPolicy struct {
+	Id                   primitive.ObjectID `bson:"_id,omitempty"`
+	PolicyId             string             `bson:"policy_id"`
+	WorkspaceId          primitive.ObjectID `bson:"workspace_id"`
+	Name                 string             `bson:"name"`
+	TeamId               string             `bson:"team_id"`
+	Tag                  string             `bson:"tag"`
+	FirstResponseMinutes int                `bson:"first_response_minutes"`
+	ResolutionMinutes    int                `bson:"resolution_minutes"`
+	WarningMinutes       int                `bson:"warning_minutes"`
+	EscalationTeamId     string             `bson:"escalation_team_id"`
+	IsActive             bool               `bson:"is_active"`
+	CreatedAt            time.Time          `bson:"created_at"`
+}
+
+// AgentWorkload is a team member's availability and number of unresolved tickets
+type AgentWorkload struct {
+	UserId      primitive.ObjectID `bson:"_id"`
+	Status      UserStatus         `bson:"status"`
+	OpenTickets int                `bson:"open_tickets"`
+}
+
+type SearchFilter struct {
+	Source     ChatSource
+	Tag        string
+	Status     TicketStatus
+	AssigneeId primitive.ObjectID
+	From       time.Time
+	To         time.Time
+}
+
+type ChatSearchHit struct {
+	Chat  `bson:",inline"`
+	Score float64 `bson:"score"`
+}
+
+type MessageSearchHit struct {
+	Message  `bson:",inline"`
+	ChatName string     `bson:"chat_name"`
+	Source   ChatSource `bson:"source"`
+	Score    float64    `bson:"score"`
+}
+
+// Broadcast is a websocket event on its way to every instance, each one delivers it to the sockets it holds.
+// UserId is the recipient for BroadcastOne and the skipped user for BroadcastAllButOne.
+// Sequence numbers the events of a workspace, clients resume after the last one they saw.
+type Broadcast struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty"`
+	WorkspaceId string             `bson:"workspace_id"`
+	Sequence    int64              `bson:"sequence"`
+	Target      BroadcastTarget    `bson:"target"`
+	UserId      primitive.ObjectID `bson:"user_id,omitempty"`
+	Event       EventType          `bson:"event"`
+	Payload     []byte             `bson:"payload"`
+	CreatedAt   time.Time          `bson:"created_at"`
+	ExpiresAt   time.Time          `bson:"expires_at"`
+}
+
+// EventCursor is the last event a user acknowledged, a reconnect without a last event id resumes after it
+type EventCursor struct {
+	WorkspaceId string             `bson:"workspace_id"`
+	UserId      primitive.ObjectID `bson:"user_id"`
+	Sequence    int64              `bson:"sequence"`
+	ExpiresAt   time.Time          `bson:"expires_at"`
+}
+
+// PresenceSession is what one instance knows about a user, how many sockets it holds and when the user
+// last did something. An instance that stops refreshing its sessions drops out once they expire.
+type PresenceSession struct {
+	InstanceId   string             `bson:"instance_id"`
+	WorkspaceId  string             `bson:"workspace_id"`
+	UserId       primitive.ObjectID `bson:"user_id"`
+	Connections  int                `bson:"connections"`
+	LastActiveAt time.Time          `bson:"last_active_at"`
+	ExpiresAt    time.Time          `bson:"expires_at"`
+}
+
+// Presence is the status last announced for a user, so instances do not announce the same change twice
+type Presence struct {
+	WorkspaceId string             `bson:"workspace_id"`
+	UserId      primitive.ObjectID `bson:"user_id"`
+	Status      UserStatus         `bson:"status"`
+	UpdatedAt   time.Time          `bson:"updated_at"`
+}
+
 type MessageType string
+type MessageStatus string
 type WorkspaceRole string
 type UserRole string
 type ChatSource string
 type TicketStatus string
+type SLAStatus string
 type UserStatus string
 type ChatLanguage string
+type AuditAction string
+type AssignmentStrategy string
+type BroadcastTarget string
+type EventType string
+type ChatActivity string
 
 const (
 	TypeChatNote   MessageType = "chat_note"
 	TypeTicketNote MessageType = "ticket_note"
+	TypeReply      MessageType = "reply"
 	TypeText       MessageType = "text"
 	TypeImage      MessageType = "image"
 	TypeAudio      MessageType = "audio"
@@ -121,6 +351,11 @@ const (
 	TypeGif        MessageType = "gif"
 )
 
+const (
+	MessageSent   MessageStatus = "sent"
+	MessageFailed MessageStatus = "failed"
+)
+
 const (
 	RoleAdmin WorkspaceRole = "admin"
 	RoleAgent WorkspaceRole = "agent"
@@ -133,6 +368,13 @@ const (
 	StatusClosed  TicketStatus = "closed"
 )
 
+const (
+	SLAOk       SLAStatus = "ok"
+	SLAWarning  SLAStatus = "warning"
+	SLABreached SLAStatus = "breached"
+	SLAMet      SLAStatus = "met"
+)
+
 const (
 	SourceTelegram    ChatSource = "telegram"
 	SourceTelegramBot ChatSource = "telegram_bot"
@@ -145,6 +387,8 @@ const (
 	StatusAvailable UserStatus = "online"
 	StatusBusy      UserStatus = "break"
 	StatusOffline   UserStatus = "offline"
+	// StatusIdle is only announced as presence, it is never stored on the user
+	StatusIdle UserStatus = "idle"
 )
 
 const (
@@ -157,3 +401,57 @@ const (
 	Russian ChatLanguage = "ru"
 	Uzbek   ChatLanguage = "uz"
 )
+
+const (
+	AuditMemberRemoved        AuditAction = "member_removed"
+	AuditMembersUpdated       AuditAction = "members_updated"
+	AuditTeamDeleted          AuditAction = "team_deleted"
+	AuditTicketReassignedUser AuditAction = "ticket_reassigned_user"
+	AuditTicketReassignedTeam AuditAction = "ticket_reassigned_team"
+	AuditTicketStatusChanged  AuditAction = "ticket_status_changed"
+	AuditMessageDeleted       AuditAction = "message_deleted"
+	AuditRoleSaved            AuditAction = "role_saved"
+	AuditRoleDeleted          AuditAction = "role_deleted"
+	AuditTwoFactorRequirement AuditAction = "two_factor_requirement_changed"
+)
+
+const (
+	AssignmentLeastOpenTickets AssignmentStrategy = "least_open_tickets"
+	AssignmentRoundRobin       AssignmentStrategy = "round_robin"
+	AssignmentLanguageMatch    AssignmentStrategy = "language_match"
+)
+
+const (
+	BroadcastAll       BroadcastTarget = "all"
+	BroadcastOne       BroadcastTarget = "one"
+	BroadcastAllButOne BroadcastTarget = "all_but_one"
+)
+
+const (
+	EventMessageCreated      EventType = "message.created"
+	EventMessageDeleted      EventType = "message.deleted"
+	EventMessageStatus       EventType = "message.status_changed"
+	EventTicketStatusChanged EventType = "ticket.status_changed"
+	EventTicketAssigned      EventType = "ticket.assigned"
+	EventTicketSLAAlert      EventType = "ticket.sla_alert"
+	EventChatUpdated         EventType = "chat.updated"
+	EventPresenceChanged     EventType = "presence.changed"
+	EventTyping              EventType = "typing"
+	// EventResyncRequired tells a client that the events it missed are gone and it has to reload
+	EventResyncRequired EventType = "resync.required"
+
+	// sent by clients
+	EventAck         EventType = "ack"
+	EventMessageSend EventType = "message.send"
+)
+
+// IsReplayed tells whether a reconnecting client gets the event again, typing indicators are stale by then
+func (e EventType) IsReplayed() bool {
+	return e != EventTyping
+}
+
+const (
+	ActivityViewing ChatActivity = "viewing"
+	ActivityTyping  ChatActivity = "typing"
+	ActivityIdle    ChatActivity = "idle"
+)
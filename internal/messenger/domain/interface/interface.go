@@ -10,33 +10,56 @@ import (
 )
 
 type MessengerService interface {
-	ReassignTicketToTeam(userId primitive.ObjectID, chatId string, ticketId, workspaceId, teamName string) error
-	ReassignTicketToUser(userId primitive.ObjectID, chatId string, ticketId, workspaceId, email string) error
+	ReassignTicketToTeam(userId primitive.ObjectID, chatId string, ticketId, workspaceId, teamName string, meta entity.AuditMeta) error
+	ReassignTicketToUser(userId primitive.ObjectID, chatId string, ticketId, workspaceId, email string, meta entity.AuditMeta) error
 	ValidateUserInWorkspace(userId primitive.ObjectID, workspace *entity.Workspace) error
-	UpdateTicketStatus(userId primitive.ObjectID, ticketId, workspaceId, status string) error
+	UpdateTicketStatus(userId primitive.ObjectID, ticketId, workspaceId, status string, meta entity.AuditMeta) error
 	ValidateUserInWorkspaceById(userId primitive.ObjectID, workspaceId string) error
 	UpdateChatInfo(userId primitive.ObjectID, chatId string, tags []string, workspaceId, language string, address, company, clientEmail, clientPhone string) error
-	HandleMessage(userId primitive.ObjectID, workspaceId, ticketId, chatId, messageType, message string) error
-	DeleteMessage(userId primitive.ObjectID, messageType, workspaceId, ticketId, messageId, chatId string) error
+	HandleMessage(userId primitive.ObjectID, workspaceId, ticketId, chatId, messageType, message string) (model.SendMessageResponse, error)
+	ResendMessage(userId primitive.ObjectID, workspaceId, chatId, messageId string) (model.SendMessageResponse, error)
+	DeleteMessage(userId primitive.ObjectID, messageType, workspaceId, ticketId, messageId, chatId string, meta entity.AuditMeta) error
 	GetAllChats(userId primitive.ObjectID, workspaceId string) ([]model.ChatResponse, error)
 	ImportTelegramChats(workspaceId string, chats []model.TelegramChat) error
+	RegisterTelegramBot(userId primitive.ObjectID, workspaceId, botToken string) error
+	HandleChannelWebhook(source entity.ChatSource, accountId string, payload []byte) error
+	HandleTelegramBotWebhook(payload []byte, webhookSecret string) error
+	RegisterWhatsApp(userId primitive.ObjectID, workspaceId, phoneNumberId, accessToken string) error
+	VerifyWhatsAppWebhook(mode, verifyToken, challenge string) (string, error)
+	HandleWhatsAppWebhook(payload []byte, signature string) error
+	VerifyMetaWebhook(mode, verifyToken, challenge string) (string, error)
+	HandleMetaWebhook(payload []byte, signature string) error
+	SendWhatsAppTemplate(userId primitive.ObjectID, workspaceId, chatId, templateName, languageCode string, parameters []string) error
 	GetChatsByFolder(userId primitive.ObjectID, workspaceId, folderName string) ([]model.ChatResponse, error)
 	GetChat(userId primitive.ObjectID, workspaceId, chatId string) (model.ChatResponse, error)
-	GetMessages(userId primitive.ObjectID, workspaceId, chatId string, lastMessageDate time.Time) ([]model.MessageResponse, error)
+	GetMessages(userId primitive.ObjectID, workspaceId, chatId, cursor string, limit int, direction string) (model.MessagesResponse, error)
+	Search(userId primitive.ObjectID, request model.SearchRequest) (model.SearchResponse, error)
 	GetAllTags(userId primitive.ObjectID, workspaceId string) ([]string, error)
 	GetAllPrimaryChats(userId primitive.ObjectID, workspaceId string) ([]model.ChatResponse, error)
 	GetAllUnassignedChats(userId primitive.ObjectID, workspaceId string) ([]model.ChatResponse, error)
 	HandleChatWS(userId primitive.ObjectID, workspaceId string, w http.ResponseWriter, r *http.Request) error
+	GetPresence(userId primitive.ObjectID, workspaceId string) ([]model.PresenceResponse, error)
 }
 
 type WebsocketService interface {
-	UpgradeConnection(w http.ResponseWriter, r *http.Request, workspaceId string, userId primitive.ObjectID) (*websocket.Conn, error)
-	RemoveConnection(workspaceId string, userId primitive.ObjectID)
-	AddConnection(workspaceId string, conn *websocket.Conn, userId primitive.ObjectID)
-	SendToOne(message []byte, workspaceId string, userId primitive.ObjectID)
-	SendToAll(workspaceId string, message []byte)
-	GetConnections(workspaceId string) map[primitive.ObjectID]*websocket.Conn
-	SendToAllButOne(workspaceId string, message []byte, userId primitive.ObjectID)
+	UpgradeConnection(w http.ResponseWriter, r *http.Request, workspaceId string, userId primitive.ObjectID) (*websocket.Conn, string, error)
+	RemoveConnection(workspaceId string, userId primitive.ObjectID, connectionId string)
+	AddConnection(workspaceId string, conn *websocket.Conn, userId primitive.ObjectID) string
+	Acknowledge(workspaceId string, userId primitive.ObjectID, connectionId, eventId string) error
+	SendToOne(workspaceId string, userId primitive.ObjectID, event entity.EventType, payload interface{})
+	SendToAll(workspaceId string, event entity.EventType, payload interface{})
+	SendToAllButOne(workspaceId string, userId primitive.ObjectID, event entity.EventType, payload interface{})
+	GetConnections(workspaceId string) map[primitive.ObjectID]int
+	GetMetrics() model.WebsocketMetricsResponse
+}
+
+type PresenceService interface {
+	Connect(workspaceId string, userId primitive.ObjectID)
+	Disconnect(workspaceId string, userId primitive.ObjectID, connectionId string)
+	Touch(workspaceId string, userId primitive.ObjectID)
+	SetActivity(workspaceId string, userId primitive.ObjectID, connectionId, chatId string, state entity.ChatActivity) error
+	GetPresences(workspaceId string) ([]model.PresenceResponse, error)
+	RunHeartbeat(interval time.Duration)
 }
 
 type FileService interface {
@@ -45,3 +68,22 @@ type FileService interface {
 	UpdateFileName(oldName, newName string) error
 	UpdateFile(newFileBytes []byte, fileName string) error
 }
+
+type SLAService interface {
+	CreateSLAPolicy(userId primitive.ObjectID, request model.CreateSLAPolicyRequest) error
+	UpdateSLAPolicy(userId primitive.ObjectID, request model.UpdateSLAPolicyRequest) error
+	GetSLAPolicies(userId primitive.ObjectID, workspaceId string) ([]model.SLAPolicyResponse, error)
+	DeleteSLAPolicy(userId primitive.ObjectID, workspaceId, policyId string) error
+	CheckSLAs(now time.Time) error
+	RunScheduler(interval time.Duration)
+}
+
+type AssignmentService interface {
+	Register(strategy entity.AssignmentStrategy, assigner Assigner)
+	AssignAgent(team *entity.Team, language entity.ChatLanguage, previousAgentId primitive.ObjectID) (primitive.ObjectID, error)
+}
+
+// Assigner picks one agent out of candidates that are equally available and under the team's ticket cap
+type Assigner interface {
+	Pick(team *entity.Team, language entity.ChatLanguage, candidates []entity.AgentWorkload) primitive.ObjectID
+}
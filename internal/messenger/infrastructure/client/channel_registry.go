@@ -0,0 +1,38 @@
+package client
+
+import (
+	"fmt"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"sync"
+)
+
+type ChannelRegistryImpl struct {
+	adapters map[entity.ChatSource]infrastructureInterface.ChannelAdapter
+	mu       sync.RWMutex
+}
+
+func NewChannelRegistryImpl() infrastructureInterface.ChannelRegistry {
+	return &ChannelRegistryImpl{
+		adapters: make(map[entity.ChatSource]infrastructureInterface.ChannelAdapter),
+	}
+}
+
+func (cr *ChannelRegistryImpl) Register(source entity.ChatSource, adapter infrastructureInterface.ChannelAdapter) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	cr.adapters[source] = adapter
+}
+
+func (cr *ChannelRegistryImpl) Get(source entity.ChatSource) (infrastructureInterface.ChannelAdapter, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+
+	adapter, exists := cr.adapters[source]
+	if !exists {
+		return nil, fmt.Errorf("unsupported chat source: %s", source)
+	}
+
+	return adapter, nil
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+)
+
+func TestRegistryReturnsTheRegisteredAdapter(t *testing.T) {
+	registry := NewChannelRegistryImpl()
+	adapter := NewMemoryChannelAdapterImpl()
+	registry.Register(entity.SourceWhatsApp, adapter)
+
+	got, err := registry.Get(entity.SourceWhatsApp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != adapter {
+		t.Fatal("registry returned another adapter")
+	}
+}
+
+func TestRegistryRejectsUnknownSource(t *testing.T) {
+	registry := NewChannelRegistryImpl()
+	registry.Register(entity.SourceWhatsApp, NewMemoryChannelAdapterImpl())
+
+	if _, err := registry.Get(entity.SourceInstagram); err == nil {
+		t.Fatal("expected an error for a source without adapter")
+	}
+}
+
+func TestRegistryReplacesAdapterOfASource(t *testing.T) {
+	registry := NewChannelRegistryImpl()
+	registry.Register(entity.SourceMeta, NewMemoryChannelAdapterImpl())
+	replacement := NewMemoryChannelAdapterImpl()
+	registry.Register(entity.SourceMeta, replacement)
+
+	if got, _ := registry.Get(entity.SourceMeta); got != replacement {
+		t.Fatal("expected the adapter registered last")
+	}
+}
+
+func TestMemoryAdapterRecordsDeliveries(t *testing.T) {
+	adapter := NewMemoryChannelAdapterImpl()
+	chat := &entity.Chat{AccountId: "account", ExternalId: "customer"}
+
+	first, err := adapter.SendTextMessage(nil, chat, "one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := adapter.SendTextMessage(nil, chat, "two")
+	if first == second {
+		t.Fatal("expected a new external id for every message")
+	}
+
+	if sent := adapter.Sent(); len(sent) != 2 || sent[1].Text != "two" || sent[1].ExternalChatId != "customer" {
+		t.Fatalf("unexpected deliveries: %+v", sent)
+	}
+}
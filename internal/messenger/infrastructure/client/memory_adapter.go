@@ -0,0 +1,118 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	infrastructureModel "github.com/Point-AI/backend/internal/messenger/infrastructure/model"
+	"strconv"
+	"sync"
+)
+
+// SentMessage is a message the memory adapter was asked to deliver
+type SentMessage struct {
+	AccountId      string
+	ExternalChatId string
+	ExternalId     string
+	Text           string
+}
+
+// MemoryChannelAdapterImpl stands in for a messaging provider in tests. Its webhook payload is a JSON list of
+// inbound messages, replies are only recorded. It is returned as itself so tests can inspect and fail it.
+type MemoryChannelAdapterImpl struct {
+	sent     []SentMessage
+	deleted  []string
+	media    map[string][]byte
+	sendErr  error
+	sequence int
+	mu       sync.Mutex
+}
+
+func NewMemoryChannelAdapterImpl() *MemoryChannelAdapterImpl {
+	return &MemoryChannelAdapterImpl{
+		media: make(map[string][]byte),
+	}
+}
+
+func (ma *MemoryChannelAdapterImpl) ReceiveMessages(accountId string, payload []byte) ([]infrastructureModel.InboundMessage, error) {
+	var messages []infrastructureModel.InboundMessage
+	if err := json.Unmarshal(payload, &messages); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (ma *MemoryChannelAdapterImpl) SendTextMessage(workspace *entity.Workspace, chat *entity.Chat, message string) (string, error) {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+
+	if ma.sendErr != nil {
+		return "", ma.sendErr
+	}
+
+	ma.sequence++
+	externalId := strconv.Itoa(ma.sequence)
+	ma.sent = append(ma.sent, SentMessage{
+		AccountId:      chat.AccountId,
+		ExternalChatId: chat.ExternalId,
+		ExternalId:     externalId,
+		Text:           message,
+	})
+
+	return externalId, nil
+}
+
+func (ma *MemoryChannelAdapterImpl) DownloadMedia(workspace *entity.Workspace, chat *entity.Chat, fileId string) ([]byte, error) {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+
+	content, exists := ma.media[fileId]
+	if !exists {
+		return nil, errors.New("media not found")
+	}
+
+	return content, nil
+}
+
+func (ma *MemoryChannelAdapterImpl) GetAvatar(workspace *entity.Workspace, chat *entity.Chat) ([]byte, error) {
+	return nil, nil
+}
+
+func (ma *MemoryChannelAdapterImpl) DeleteMessage(workspace *entity.Workspace, chat *entity.Chat, externalMessageId string) error {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+
+	ma.deleted = append(ma.deleted, externalMessageId)
+	return nil
+}
+
+// AddMedia makes a file available to DownloadMedia
+func (ma *MemoryChannelAdapterImpl) AddMedia(fileId string, content []byte) {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+
+	ma.media[fileId] = content
+}
+
+// FailSends makes every following send fail with err, nil lets them through again
+func (ma *MemoryChannelAdapterImpl) FailSends(err error) {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+
+	ma.sendErr = err
+}
+
+func (ma *MemoryChannelAdapterImpl) Sent() []SentMessage {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+
+	return append([]SentMessage(nil), ma.sent...)
+}
+
+func (ma *MemoryChannelAdapterImpl) Deleted() []string {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+
+	return append([]string(nil), ma.deleted...)
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"errors"
+	"github.com/Point-AI/backend/config"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"github.com/Point-AI/backend/utils"
+	"github.com/go-resty/resty/v2"
+	"strings"
+)
+
+type MetaClientManagerImpl struct {
+	client *resty.Client
+	config *config.Config
+}
+
+func NewMetaClientManagerImpl(cfg *config.Config) infrastructureInterface.MetaClientManager {
+	return &MetaClientManagerImpl{
+		client: resty.New().SetBaseURL(strings.TrimSuffix(cfg.Integrations.MetaBaseURL, "/")),
+		config: cfg,
+	}
+}
+
+func (mc *MetaClientManagerImpl) VerifyWebhook(mode, verifyToken, challenge string) (string, error) {
+	return utils.VerifyMetaWebhook(mode, verifyToken, mc.config.Integrations.MetaVerifyToken, challenge)
+}
+
+func (mc *MetaClientManagerImpl) ValidateSignature(payload []byte, signature string) bool {
+	return utils.ValidateMetaSignature(mc.config.OAuth2.MetaClientSecret, payload, signature)
+}
+
+// SendTextMessage goes through the Send API, which serves both Messenger and Instagram conversations of a page
+func (mc *MetaClientManagerImpl) SendTextMessage(pageAccessToken, recipientId, text string) (string, error) {
+	var result struct {
+		MessageId string `json:"message_id"`
+		Error     *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	resp, err := mc.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetQueryParam("access_token", pageAccessToken).
+		SetBody(map[string]interface{}{
+			"recipient":      map[string]string{"id": recipientId},
+			"messaging_type": "RESPONSE",
+			"message":        map[string]string{"text": text},
+		}).
+		SetResult(&result).
+		SetError(&result).
+		Post("/me/messages")
+	if err != nil {
+		return "", err
+	}
+
+	if resp.IsError() {
+		if result.Error != nil && result.Error.Message != "" {
+			return "", errors.New("meta api: " + result.Error.Message)
+		}
+		return "", errors.New("failed to deliver the message")
+	}
+
+	return result.MessageId, nil
+}
+
+func (mc *MetaClientManagerImpl) GetProfilePicture(pageAccessToken, userId string) ([]byte, error) {
+	var profile struct {
+		ProfilePic string `json:"profile_pic"`
+	}
+
+	resp, err := mc.client.R().
+		SetQueryParams(map[string]string{
+			"fields":       "profile_pic",
+			"access_token": pageAccessToken,
+		}).
+		SetResult(&profile).
+		Get("/" + userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() || profile.ProfilePic == "" {
+		return nil, nil
+	}
+
+	return mc.DownloadMedia(profile.ProfilePic)
+}
+
+func (mc *MetaClientManagerImpl) DownloadMedia(url string) ([]byte, error) {
+	resp, err := mc.client.R().Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, errors.New("failed to download the file")
+	}
+
+	return resp.Body(), nil
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	infrastructureModel "github.com/Point-AI/backend/internal/messenger/infrastructure/model"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"time"
+)
+
+// MetaAdapterImpl serves Messenger and Instagram DMs; the chat account id is the page id
+// or the Instagram business account id respectively
+type MetaAdapterImpl struct {
+	metaClient infrastructureInterface.MetaClientManager
+	source     entity.ChatSource
+}
+
+func NewMetaAdapterImpl(metaClient infrastructureInterface.MetaClientManager, source entity.ChatSource) infrastructureInterface.ChannelAdapter {
+	return &MetaAdapterImpl{
+		metaClient: metaClient,
+		source:     source,
+	}
+}
+
+func (ma *MetaAdapterImpl) ReceiveMessages(accountId string, payload []byte) ([]infrastructureModel.InboundMessage, error) {
+	var webhook infrastructureModel.MetaWebhook
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return nil, err
+	}
+
+	var inbound []infrastructureModel.InboundMessage
+	for _, entry := range webhook.Entry {
+		for _, event := range entry.Messaging {
+			// echoes are our own replies coming back through the webhook
+			if event.Message == nil || event.Message.IsEcho {
+				continue
+			}
+
+			messageType, fileId := ma.getMessageTypeAndFileId(event.Message)
+			if messageType == "" {
+				continue
+			}
+
+			inbound = append(inbound, infrastructureModel.InboundMessage{
+				AccountId:         entry.Id,
+				ExternalChatId:    event.Sender.Id,
+				ExternalSenderId:  event.Sender.Id,
+				ExternalMessageId: event.Message.Mid,
+				SenderName:        event.Sender.Id,
+				Text:              event.Message.Text,
+				Type:              messageType,
+				FileId:            fileId,
+				CreatedAt:         time.UnixMilli(event.Timestamp),
+			})
+		}
+	}
+
+	return inbound, nil
+}
+
+func (ma *MetaAdapterImpl) SendTextMessage(workspace *entity.Workspace, chat *entity.Chat, message string) (string, error) {
+	page, err := ma.findPage(workspace, chat.AccountId)
+	if err != nil {
+		return "", err
+	}
+
+	return ma.metaClient.SendTextMessage(page.AccessToken, chat.ExternalId, message)
+}
+
+// DownloadMedia receives the attachment url, Meta does not issue separate media ids
+func (ma *MetaAdapterImpl) DownloadMedia(workspace *entity.Workspace, chat *entity.Chat, fileId string) ([]byte, error) {
+	return ma.metaClient.DownloadMedia(fileId)
+}
+
+func (ma *MetaAdapterImpl) GetAvatar(workspace *entity.Workspace, chat *entity.Chat) ([]byte, error) {
+	page, err := ma.findPage(workspace, chat.AccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	return ma.metaClient.GetProfilePicture(page.AccessToken, chat.ExternalId)
+}
+
+func (ma *MetaAdapterImpl) DeleteMessage(workspace *entity.Workspace, chat *entity.Chat, externalMessageId string) error {
+	return errors.New("meta does not support deleting messages")
+}
+
+func (ma *MetaAdapterImpl) findPage(workspace *entity.Workspace, accountId string) (*entity.MetaPageIntegration, error) {
+	if workspace.Integrations.Meta == nil || !workspace.Integrations.Meta.IsActive {
+		return nil, errors.New("meta integration not found")
+	}
+
+	for i := range workspace.Integrations.Meta.Pages {
+		page := &workspace.Integrations.Meta.Pages[i]
+		if !page.IsActive {
+			continue
+		}
+		if (ma.source == entity.SourceInstagram && page.InstagramAccountId == accountId) || (ma.source == entity.SourceMeta && page.PageId == accountId) {
+			return page, nil
+		}
+	}
+
+	return nil, errors.New("meta page not found")
+}
+
+func (ma *MetaAdapterImpl) getMessageTypeAndFileId(message *infrastructureModel.MetaMessage) (entity.MessageType, string) {
+	if len(message.Attachments) == 0 {
+		if message.Text == "" {
+			return "", ""
+		}
+		return entity.TypeText, ""
+	}
+
+	attachment := message.Attachments[0]
+	switch attachment.Type {
+	case "image":
+		return entity.TypeImage, attachment.Payload.Url
+	case "audio":
+		return entity.TypeAudio, attachment.Payload.Url
+	case "video":
+		return entity.TypeVideo, attachment.Payload.Url
+	case "file":
+		return entity.TypeDocument, attachment.Payload.Url
+	}
+
+	return "", ""
+}
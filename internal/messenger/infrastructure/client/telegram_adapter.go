@@ -0,0 +1,123 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	infrastructureModel "github.com/Point-AI/backend/internal/messenger/infrastructure/model"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"github.com/go-resty/resty/v2"
+	"strconv"
+	"time"
+)
+
+// TelegramAdapterImpl talks to Telegram user accounts through the integrations server
+type TelegramAdapterImpl struct {
+	config *config.Config
+}
+
+func NewTelegramAdapterImpl(cfg *config.Config) infrastructureInterface.ChannelAdapter {
+	return &TelegramAdapterImpl{
+		config: cfg,
+	}
+}
+
+func (ta *TelegramAdapterImpl) ReceiveMessages(accountId string, payload []byte) ([]infrastructureModel.InboundMessage, error) {
+	var messages []infrastructureModel.TelegramAccountMessage
+	if err := json.Unmarshal(payload, &messages); err != nil {
+		return nil, err
+	}
+
+	inbound := make([]infrastructureModel.InboundMessage, 0, len(messages))
+	for _, message := range messages {
+		messageType := entity.MessageType(message.Type)
+		if messageType == "" {
+			messageType = entity.TypeText
+		}
+
+		inbound = append(inbound, infrastructureModel.InboundMessage{
+			ExternalChatId:    strconv.FormatInt(message.ChatId, 10),
+			ExternalSenderId:  strconv.FormatInt(message.SenderId, 10),
+			ExternalMessageId: strconv.Itoa(message.MessageId),
+			SenderName:        message.Name,
+			ChatTitle:         message.Title,
+			Text:              message.Text,
+			Type:              messageType,
+			FileId:            message.FileId,
+			CreatedAt:         time.Unix(message.Date, 0),
+		})
+	}
+
+	return inbound, nil
+}
+
+func (ta *TelegramAdapterImpl) SendTextMessage(workspace *entity.Workspace, chat *entity.Chat, message string) (string, error) {
+	var result struct {
+		MessageId int `json:"message_id"`
+	}
+
+	body, err := ta.post("/point_ai/telegram_wrapper/send_message", map[string]interface{}{
+		"workspace_id": workspace.WorkspaceId,
+		"chat_id":      chat.TgChatId,
+		"text":         message,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err = json.Unmarshal(body, &result); err != nil || result.MessageId == 0 {
+		return "", nil
+	}
+
+	return strconv.Itoa(result.MessageId), nil
+}
+
+func (ta *TelegramAdapterImpl) DownloadMedia(workspace *entity.Workspace, chat *entity.Chat, fileId string) ([]byte, error) {
+	return ta.post("/point_ai/telegram_wrapper/get_file", map[string]interface{}{
+		"workspace_id": workspace.WorkspaceId,
+		"chat_id":      chat.TgChatId,
+		"file_id":      fileId,
+	})
+}
+
+func (ta *TelegramAdapterImpl) GetAvatar(workspace *entity.Workspace, chat *entity.Chat) ([]byte, error) {
+	return ta.post("/point_ai/telegram_wrapper/get_user_avatar", map[string]interface{}{
+		"workspace_id": workspace.WorkspaceId,
+		"user_id":      chat.TgChatId,
+	})
+}
+
+func (ta *TelegramAdapterImpl) DeleteMessage(workspace *entity.Workspace, chat *entity.Chat, externalMessageId string) error {
+	messageId, err := strconv.Atoi(externalMessageId)
+	if err != nil {
+		return err
+	}
+
+	_, err = ta.post("/point_ai/telegram_wrapper/delete_message", map[string]interface{}{
+		"workspace_id": workspace.WorkspaceId,
+		"chat_id":      chat.TgChatId,
+		"message_id":   messageId,
+	})
+	return err
+}
+
+func (ta *TelegramAdapterImpl) post(path string, reqBody map[string]interface{}) ([]byte, error) {
+	client := resty.New()
+
+	resp, err := client.R().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Authorization", "Bearer "+ta.config.Auth.IntegrationsServerSecretKey).
+		SetBody(reqBody).
+		Post(ta.config.Website.IntegrationsServerURL + path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, errors.New("integrations server request failed")
+	}
+
+	return resp.Body(), nil
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"errors"
+	"github.com/Point-AI/backend/config"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"github.com/go-resty/resty/v2"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"net/url"
+	"sync"
+)
+
+type TelegramBotClientManagerImpl struct {
+	bots   map[string]*tgbotapi.BotAPI
+	config *config.Config
+	mu     sync.RWMutex
+}
+
+func NewTelegramBotClientManagerImpl(cfg *config.Config) infrastructureInterface.TelegramBotClientManager {
+	return &TelegramBotClientManagerImpl{
+		bots:   make(map[string]*tgbotapi.BotAPI),
+		config: cfg,
+	}
+}
+
+// RegisterNewBot points the bot's webhook at this server, Telegram sends the secret back in the
+// X-Telegram-Bot-Api-Secret-Token header of every update
+func (tbc *TelegramBotClientManagerImpl) RegisterNewBot(botToken, webhookSecret string) error {
+	bot, err := tbc.getBot(botToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = bot.MakeRequest("setWebhook", url.Values{
+		"url":          {tbc.config.Website.BaseURL + "/telegram/messages/webhook"},
+		"secret_token": {webhookSecret},
+	})
+	return err
+}
+
+func (tbc *TelegramBotClientManagerImpl) DeleteWebhook(botToken string) error {
+	bot, err := tbc.getBot(botToken)
+	if err != nil {
+		return err
+	}
+
+	if _, err = bot.RemoveWebhook(); err != nil {
+		return err
+	}
+
+	tbc.mu.Lock()
+	defer tbc.mu.Unlock()
+	delete(tbc.bots, botToken)
+
+	return nil
+}
+
+func (tbc *TelegramBotClientManagerImpl) SendTextMessage(botToken string, chatID int64, messageText string) (int, error) {
+	bot, err := tbc.getBot(botToken)
+	if err != nil {
+		return 0, err
+	}
+
+	sent, err := bot.Send(tgbotapi.NewMessage(chatID, messageText))
+	if err != nil {
+		return 0, err
+	}
+
+	return sent.MessageID, nil
+}
+
+func (tbc *TelegramBotClientManagerImpl) DeleteMessage(botToken string, chatID int64, messageID int) error {
+	bot, err := tbc.getBot(botToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = bot.DeleteMessage(tgbotapi.NewDeleteMessage(chatID, messageID))
+	return err
+}
+
+func (tbc *TelegramBotClientManagerImpl) GetUserAvatar(botToken string, userId int) ([]byte, error) {
+	bot, err := tbc.getBot(botToken)
+	if err != nil {
+		return nil, err
+	}
+
+	photos, err := bot.GetUserProfilePhotos(tgbotapi.NewUserProfilePhotos(userId))
+	if err != nil {
+		return nil, err
+	}
+	if photos.TotalCount == 0 || len(photos.Photos) == 0 || len(photos.Photos[0]) == 0 {
+		return nil, nil
+	}
+
+	sizes := photos.Photos[0]
+	return tbc.HandleFileMessage(botToken, sizes[len(sizes)-1].FileID)
+}
+
+func (tbc *TelegramBotClientManagerImpl) HandleFileMessage(botToken, fileId string) ([]byte, error) {
+	bot, err := tbc.getBot(botToken)
+	if err != nil {
+		return nil, err
+	}
+
+	fileURL, err := bot.GetFileDirectURL(fileId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := resty.New().R().Get(fileURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != 200 {
+		return nil, errors.New("failed to download the file")
+	}
+
+	return resp.Body(), nil
+}
+
+func (tbc *TelegramBotClientManagerImpl) getBot(botToken string) (*tgbotapi.BotAPI, error) {
+	tbc.mu.RLock()
+	bot, exists := tbc.bots[botToken]
+	tbc.mu.RUnlock()
+	if exists {
+		return bot, nil
+	}
+
+	bot, err := tgbotapi.NewBotAPI(botToken)
+	if err != nil {
+		return nil, err
+	}
+
+	tbc.mu.Lock()
+	defer tbc.mu.Unlock()
+	tbc.bots[botToken] = bot
+
+	return bot, nil
+}
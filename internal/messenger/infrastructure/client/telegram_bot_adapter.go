@@ -0,0 +1,93 @@
+package client
+
+import (
+	"encoding/json"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	infrastructureModel "github.com/Point-AI/backend/internal/messenger/infrastructure/model"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"github.com/Point-AI/backend/utils"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TelegramBotAdapterImpl uses the bot token as the chat account id
+type TelegramBotAdapterImpl struct {
+	telegramBotClient infrastructureInterface.TelegramBotClientManager
+}
+
+func NewTelegramBotAdapterImpl(telegramBotClient infrastructureInterface.TelegramBotClientManager) infrastructureInterface.ChannelAdapter {
+	return &TelegramBotAdapterImpl{
+		telegramBotClient: telegramBotClient,
+	}
+}
+
+func (ta *TelegramBotAdapterImpl) ReceiveMessages(accountId string, payload []byte) ([]infrastructureModel.InboundMessage, error) {
+	var update tgbotapi.Update
+	if err := json.Unmarshal(payload, &update); err != nil {
+		return nil, err
+	}
+
+	message := update.Message
+	if message == nil || message.Chat == nil || message.From == nil {
+		return nil, nil
+	}
+
+	messageType, fileId := utils.GetMessageTypeAndFileID(message)
+	if messageType == "" {
+		return nil, nil
+	}
+
+	text := message.Text
+	if text == "" {
+		text = message.Caption
+	}
+
+	return []infrastructureModel.InboundMessage{{
+		ExternalChatId:    strconv.FormatInt(message.Chat.ID, 10),
+		ExternalSenderId:  strconv.Itoa(message.From.ID),
+		ExternalMessageId: strconv.Itoa(message.MessageID),
+		SenderName:        ta.getSenderName(message.From),
+		ChatTitle:         message.Chat.Title,
+		Text:              text,
+		Type:              messageType,
+		FileId:            fileId,
+		CreatedAt:         time.Unix(int64(message.Date), 0),
+	}}, nil
+}
+
+func (ta *TelegramBotAdapterImpl) SendTextMessage(workspace *entity.Workspace, chat *entity.Chat, message string) (string, error) {
+	messageId, err := ta.telegramBotClient.SendTextMessage(chat.AccountId, int64(chat.TgChatId), message)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(messageId), nil
+}
+
+func (ta *TelegramBotAdapterImpl) DownloadMedia(workspace *entity.Workspace, chat *entity.Chat, fileId string) ([]byte, error) {
+	return ta.telegramBotClient.HandleFileMessage(chat.AccountId, fileId)
+}
+
+func (ta *TelegramBotAdapterImpl) GetAvatar(workspace *entity.Workspace, chat *entity.Chat) ([]byte, error) {
+	return ta.telegramBotClient.GetUserAvatar(chat.AccountId, chat.TgClientId)
+}
+
+func (ta *TelegramBotAdapterImpl) DeleteMessage(workspace *entity.Workspace, chat *entity.Chat, externalMessageId string) error {
+	messageId, err := strconv.Atoi(externalMessageId)
+	if err != nil {
+		return err
+	}
+
+	return ta.telegramBotClient.DeleteMessage(chat.AccountId, int64(chat.TgChatId), messageId)
+}
+
+func (ta *TelegramBotAdapterImpl) getSenderName(user *tgbotapi.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		return user.UserName
+	}
+
+	return name
+}
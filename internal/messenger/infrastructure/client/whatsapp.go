@@ -1,43 +1,162 @@
 package client
 
-//
-//import (
-//	"github.com/Point-AI/backend/config"
-//	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
-//	"github.com/Rhymen/go-whatsapp"
-//)
-//
-//type WhatsAppClient struct {
-//	config *config.Config
-//}
-//
-//func NewWhatsAppClientImpl(cfg *config.Config) infrastructureInterface.WhatsAppClient {
-//	return &WhatsAppClient{
-//		config: cfg,
-//	}
-//}
-//
-//func (wc *WhatsAppClient) RegisterNewInstance(instanceName string) error {
-//	// Implement instance registration logic here
-//	return nil
-//}
-//
-//func (wc *WhatsAppClient) SendMessage(instanceName string, recipient string, message string) error {
-//	// Implement message sending logic here
-//	return nil
-//}
-//
-//func (wc *WhatsAppClient) ReceiveMessages(instanceName string) ([]string, error) {
-//	// Implement message receiving logic here
-//	return nil, nil
-//}
-//
-//func (wc *WhatsAppClient) DeleteInstance(instanceName string) error {
-//	// Implement instance deletion logic here
-//	return nil
-//}
-//
-//func (wc *WhatsAppClient) Connect(instanceName string) (*whatsapp.Conn, error) {
-//	// Implement connection logic here
-//	return nil, nil
-//}
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Point-AI/backend/config"
+	infrastructureModel "github.com/Point-AI/backend/internal/messenger/infrastructure/model"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"github.com/Point-AI/backend/utils"
+	"github.com/go-resty/resty/v2"
+	"strings"
+)
+
+type WhatsAppClientManagerImpl struct {
+	client *resty.Client
+	config *config.Config
+}
+
+func NewWhatsAppClientManagerImpl(cfg *config.Config) infrastructureInterface.WhatsAppClientManager {
+	return &WhatsAppClientManagerImpl{
+		client: resty.New().SetBaseURL(strings.TrimSuffix(cfg.Integrations.WhatsappBaseURL, "/")),
+		config: cfg,
+	}
+}
+
+func (wc *WhatsAppClientManagerImpl) VerifyWebhook(mode, verifyToken, challenge string) (string, error) {
+	return utils.VerifyMetaWebhook(mode, verifyToken, wc.config.Integrations.WhatsappVerifyToken, challenge)
+}
+
+func (wc *WhatsAppClientManagerImpl) ValidateSignature(payload []byte, signature string) bool {
+	return utils.ValidateMetaSignature(wc.config.OAuth2.MetaClientSecret, payload, signature)
+}
+
+func (wc *WhatsAppClientManagerImpl) GetPhoneNumber(phoneNumberId, accessToken string) (string, error) {
+	var result struct {
+		DisplayPhoneNumber string                             `json:"display_phone_number"`
+		Error              *infrastructureModel.WhatsAppError `json:"error,omitempty"`
+	}
+
+	resp, err := wc.client.R().
+		SetAuthToken(accessToken).
+		SetQueryParam("fields", "display_phone_number").
+		SetResult(&result).
+		SetError(&result).
+		Get("/" + phoneNumberId)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.IsError() {
+		return "", wc.apiError(result.Error)
+	}
+
+	return result.DisplayPhoneNumber, nil
+}
+
+func (wc *WhatsAppClientManagerImpl) SendTextMessage(phoneNumberId, accessToken, to, text string) (string, error) {
+	return wc.sendMessage(phoneNumberId, accessToken, map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                to,
+		"type":              "text",
+		"text": map[string]interface{}{
+			"preview_url": false,
+			"body":        text,
+		},
+	})
+}
+
+// SendTemplateMessage sends a pre-approved template, the only kind of message allowed outside the 24-hour window
+func (wc *WhatsAppClientManagerImpl) SendTemplateMessage(phoneNumberId, accessToken, to, templateName, languageCode string, parameters []string) (string, error) {
+	template := map[string]interface{}{
+		"name":     templateName,
+		"language": map[string]string{"code": languageCode},
+	}
+
+	if len(parameters) > 0 {
+		bodyParameters := make([]map[string]string, 0, len(parameters))
+		for _, parameter := range parameters {
+			bodyParameters = append(bodyParameters, map[string]string{"type": "text", "text": parameter})
+		}
+		template["components"] = []map[string]interface{}{{
+			"type":       "body",
+			"parameters": bodyParameters,
+		}}
+	}
+
+	return wc.sendMessage(phoneNumberId, accessToken, map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                to,
+		"type":              "template",
+		"template":          template,
+	})
+}
+
+func (wc *WhatsAppClientManagerImpl) DownloadMedia(accessToken, mediaId string) ([]byte, error) {
+	var media struct {
+		Url   string                             `json:"url"`
+		Error *infrastructureModel.WhatsAppError `json:"error,omitempty"`
+	}
+
+	resp, err := wc.client.R().
+		SetAuthToken(accessToken).
+		SetResult(&media).
+		SetError(&media).
+		Get("/" + mediaId)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, wc.apiError(media.Error)
+	}
+
+	// the media url is short-lived and still requires the access token
+	resp, err = wc.client.R().
+		SetAuthToken(accessToken).
+		Get(media.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, errors.New("failed to download the file")
+	}
+
+	return resp.Body(), nil
+}
+
+func (wc *WhatsAppClientManagerImpl) sendMessage(phoneNumberId, accessToken string, reqBody map[string]interface{}) (string, error) {
+	resp, err := wc.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetAuthToken(accessToken).
+		SetBody(reqBody).
+		Post("/" + phoneNumberId + "/messages")
+	if err != nil {
+		return "", err
+	}
+
+	var result infrastructureModel.WhatsAppSendResponse
+	if err = json.Unmarshal(resp.Body(), &result); err != nil {
+		return "", err
+	}
+
+	if resp.IsError() {
+		return "", wc.apiError(result.Error)
+	}
+
+	if len(result.Messages) == 0 {
+		return "", errors.New("failed to deliver the message")
+	}
+
+	return result.Messages[0].Id, nil
+}
+
+func (wc *WhatsAppClientManagerImpl) apiError(apiErr *infrastructureModel.WhatsAppError) error {
+	if apiErr == nil || apiErr.Message == "" {
+		return errors.New("whatsapp api request failed")
+	}
+
+	return errors.New("whatsapp api: " + apiErr.Message)
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	infrastructureModel "github.com/Point-AI/backend/internal/messenger/infrastructure/model"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"strconv"
+	"time"
+)
+
+// WhatsAppAdapterImpl uses the Cloud API phone number id as the chat account id
+type WhatsAppAdapterImpl struct {
+	whatsAppClient infrastructureInterface.WhatsAppClientManager
+}
+
+func NewWhatsAppAdapterImpl(whatsAppClient infrastructureInterface.WhatsAppClientManager) infrastructureInterface.ChannelAdapter {
+	return &WhatsAppAdapterImpl{
+		whatsAppClient: whatsAppClient,
+	}
+}
+
+func (wa *WhatsAppAdapterImpl) ReceiveMessages(accountId string, payload []byte) ([]infrastructureModel.InboundMessage, error) {
+	var webhook infrastructureModel.WhatsAppWebhook
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return nil, err
+	}
+
+	var inbound []infrastructureModel.InboundMessage
+	for _, entry := range webhook.Entry {
+		for _, change := range entry.Changes {
+			if change.Field != "messages" {
+				continue
+			}
+
+			names := make(map[string]string)
+			for _, contact := range change.Value.Contacts {
+				names[contact.WaId] = contact.Profile.Name
+			}
+
+			for _, message := range change.Value.Messages {
+				messageType, text, fileId := wa.getMessageTypeAndFileId(message)
+				if messageType == "" {
+					continue
+				}
+
+				senderName := names[message.From]
+				if senderName == "" {
+					senderName = message.From
+				}
+
+				timestamp, _ := strconv.ParseInt(message.Timestamp, 10, 64)
+				inbound = append(inbound, infrastructureModel.InboundMessage{
+					AccountId:         change.Value.Metadata.PhoneNumberId,
+					ExternalChatId:    message.From,
+					ExternalSenderId:  message.From,
+					ExternalMessageId: message.Id,
+					SenderName:        senderName,
+					Text:              text,
+					Type:              messageType,
+					FileId:            fileId,
+					CreatedAt:         time.Unix(timestamp, 0),
+				})
+			}
+		}
+	}
+
+	return inbound, nil
+}
+
+func (wa *WhatsAppAdapterImpl) SendTextMessage(workspace *entity.Workspace, chat *entity.Chat, message string) (string, error) {
+	integration, err := wa.findIntegration(workspace, chat.AccountId)
+	if err != nil {
+		return "", err
+	}
+
+	return wa.whatsAppClient.SendTextMessage(integration.PhoneNumberId, integration.AccessToken, chat.ExternalId, message)
+}
+
+func (wa *WhatsAppAdapterImpl) DownloadMedia(workspace *entity.Workspace, chat *entity.Chat, fileId string) ([]byte, error) {
+	integration, err := wa.findIntegration(workspace, chat.AccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	return wa.whatsAppClient.DownloadMedia(integration.AccessToken, fileId)
+}
+
+// GetAvatar returns nothing since the Cloud API does not expose profile pictures
+func (wa *WhatsAppAdapterImpl) GetAvatar(workspace *entity.Workspace, chat *entity.Chat) ([]byte, error) {
+	return nil, nil
+}
+
+func (wa *WhatsAppAdapterImpl) DeleteMessage(workspace *entity.Workspace, chat *entity.Chat, externalMessageId string) error {
+	return errors.New("whatsapp does not support deleting messages")
+}
+
+func (wa *WhatsAppAdapterImpl) findIntegration(workspace *entity.Workspace, phoneNumberId string) (*entity.WhatsAppIntegration, error) {
+	for i := range workspace.Integrations.WhatsApp {
+		integration := &workspace.Integrations.WhatsApp[i]
+		if integration.PhoneNumberId == phoneNumberId && integration.IsActive {
+			return integration, nil
+		}
+	}
+
+	return nil, errors.New("whatsapp integration not found")
+}
+
+func (wa *WhatsAppAdapterImpl) getMessageTypeAndFileId(message infrastructureModel.WhatsAppMessage) (entity.MessageType, string, string) {
+	switch {
+	case message.Text != nil:
+		return entity.TypeText, message.Text.Body, ""
+	case message.Image != nil:
+		return entity.TypeImage, message.Image.Caption, message.Image.Id
+	case message.Audio != nil:
+		if message.Audio.Voice {
+			return entity.TypeVoice, "", message.Audio.Id
+		}
+		return entity.TypeAudio, "", message.Audio.Id
+	case message.Video != nil:
+		return entity.TypeVideo, message.Video.Caption, message.Video.Id
+	case message.Document != nil:
+		return entity.TypeDocument, message.Document.Caption, message.Document.Id
+	case message.Sticker != nil:
+		return entity.TypeSticker, "", message.Sticker.Id
+	}
+
+	return "", "", ""
+}
@@ -0,0 +1,311 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+)
+
+const (
+	testPhoneNumberId = "1001"
+	testAccessToken   = "access-token"
+	testAppSecret     = "app-secret"
+)
+
+// graphStandIn answers the Cloud API calls the client makes and records the messages sent through it
+type graphStandIn struct {
+	server *httptest.Server
+	sent   []map[string]interface{}
+	mu     sync.Mutex
+}
+
+func newGraphStandIn(t *testing.T) *graphStandIn {
+	t.Helper()
+
+	g := &graphStandIn{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /{phoneNumberId}/messages", func(w http.ResponseWriter, r *http.Request) {
+		if !g.authorized(w, r) {
+			return
+		}
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		g.mu.Lock()
+		g.sent = append(g.sent, body)
+		g.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.sent"}]}`))
+	})
+	mux.HandleFunc("GET /media/{mediaId}", func(w http.ResponseWriter, r *http.Request) {
+		if !g.authorized(w, r) {
+			return
+		}
+		w.Write([]byte("file of " + r.PathValue("mediaId")))
+	})
+	mux.HandleFunc("GET /{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !g.authorized(w, r) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.PathValue("id") == testPhoneNumberId {
+			w.Write([]byte(`{"display_phone_number":"+1 555 0100","id":"` + testPhoneNumberId + `"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"url": g.server.URL + "/media/" + r.PathValue("id")})
+	})
+
+	g.server = httptest.NewServer(mux)
+	t.Cleanup(g.server.Close)
+
+	return g
+}
+
+func (g *graphStandIn) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Authorization") == "Bearer "+testAccessToken {
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write([]byte(`{"error":{"message":"Invalid OAuth access token.","type":"OAuthException","code":190}}`))
+	return false
+}
+
+func (g *graphStandIn) lastSent(t *testing.T) map[string]interface{} {
+	t.Helper()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.sent) == 0 {
+		t.Fatal("nothing was sent")
+	}
+	return g.sent[len(g.sent)-1]
+}
+
+func newWhatsAppTestConfig(baseURL string) *config.Config {
+	cfg := &config.Config{}
+	cfg.Integrations.WhatsappBaseURL = baseURL
+	cfg.Integrations.WhatsappVerifyToken = "verify-token"
+	cfg.OAuth2.MetaClientSecret = testAppSecret
+	return cfg
+}
+
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWhatsAppVerifyWebhookEchoesChallenge(t *testing.T) {
+	wc := NewWhatsAppClientManagerImpl(newWhatsAppTestConfig(""))
+
+	challenge, err := wc.VerifyWebhook("subscribe", "verify-token", "1158201444")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenge != "1158201444" {
+		t.Fatalf("expected the challenge back, got %q", challenge)
+	}
+}
+
+func TestWhatsAppVerifyWebhookRejectsWrongToken(t *testing.T) {
+	wc := NewWhatsAppClientManagerImpl(newWhatsAppTestConfig(""))
+
+	if _, err := wc.VerifyWebhook("subscribe", "guessed", "1158201444"); err == nil {
+		t.Fatal("expected a wrong verify token to be rejected")
+	}
+	if _, err := wc.VerifyWebhook("unsubscribe", "verify-token", "1158201444"); err == nil {
+		t.Fatal("expected a mode other than subscribe to be rejected")
+	}
+}
+
+func TestWhatsAppValidateSignature(t *testing.T) {
+	wc := NewWhatsAppClientManagerImpl(newWhatsAppTestConfig(""))
+	payload := []byte(`{"object":"whatsapp_business_account"}`)
+
+	if !wc.ValidateSignature(payload, sign(testAppSecret, payload)) {
+		t.Fatal("expected a valid signature to pass")
+	}
+
+	for name, signature := range map[string]string{
+		"other secret":    sign("other-secret", payload),
+		"changed payload": sign(testAppSecret, []byte(`{"object":"page"}`)),
+		"missing prefix":  strings.TrimPrefix(sign(testAppSecret, payload), "sha256="),
+		"not hex":         "sha256=zz",
+		"empty":           "",
+	} {
+		if wc.ValidateSignature(payload, signature) {
+			t.Fatalf("%s: expected the signature to be rejected", name)
+		}
+	}
+}
+
+func TestWhatsAppValidateSignatureFailsClosedWithoutSecret(t *testing.T) {
+	cfg := newWhatsAppTestConfig("")
+	cfg.OAuth2.MetaClientSecret = ""
+	wc := NewWhatsAppClientManagerImpl(cfg)
+	payload := []byte(`{}`)
+
+	if wc.ValidateSignature(payload, sign("", payload)) {
+		t.Fatal("expected every webhook to be rejected without an app secret")
+	}
+}
+
+func TestWhatsAppReceiveMessages(t *testing.T) {
+	payload := []byte(`{
+		"object": "whatsapp_business_account",
+		"entry": [{
+			"id": "waba",
+			"changes": [
+				{"field": "statuses", "value": {"metadata": {"phone_number_id": "1001"}}},
+				{"field": "messages", "value": {
+					"messaging_product": "whatsapp",
+					"metadata": {"display_phone_number": "+1 555 0100", "phone_number_id": "1001"},
+					"contacts": [{"wa_id": "998901234567", "profile": {"name": "Aziza"}}],
+					"messages": [
+						{"from": "998901234567", "id": "wamid.1", "timestamp": "1700000000", "type": "text", "text": {"body": "hello"}},
+						{"from": "998901234567", "id": "wamid.2", "timestamp": "1700000001", "type": "image", "image": {"id": "media.1", "caption": "receipt"}},
+						{"from": "998901234567", "id": "wamid.3", "timestamp": "1700000002", "type": "audio", "audio": {"id": "media.2", "voice": true}},
+						{"from": "998907654321", "id": "wamid.4", "timestamp": "1700000003", "type": "location"}
+					]
+				}}
+			]
+		}]
+	}`)
+
+	messages, err := NewWhatsAppAdapterImpl(nil).ReceiveMessages("", payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 supported messages, got %d", len(messages))
+	}
+
+	text := messages[0]
+	if text.AccountId != "1001" || text.ExternalChatId != "998901234567" || text.ExternalMessageId != "wamid.1" ||
+		text.SenderName != "Aziza" || text.Text != "hello" || text.Type != entity.TypeText || text.CreatedAt.Unix() != 1700000000 {
+		t.Fatalf("unexpected text message: %+v", text)
+	}
+	if image := messages[1]; image.Type != entity.TypeImage || image.FileId != "media.1" || image.Text != "receipt" {
+		t.Fatalf("unexpected image message: %+v", image)
+	}
+	if voice := messages[2]; voice.Type != entity.TypeVoice || voice.FileId != "media.2" {
+		t.Fatalf("unexpected voice message: %+v", voice)
+	}
+}
+
+func TestWhatsAppReceiveMessagesRejectsMalformedPayload(t *testing.T) {
+	if _, err := NewWhatsAppAdapterImpl(nil).ReceiveMessages("", []byte(`{"entry":`)); err == nil {
+		t.Fatal("expected an error for a malformed payload")
+	}
+}
+
+func TestWhatsAppSendTextMessage(t *testing.T) {
+	graph := newGraphStandIn(t)
+	wc := NewWhatsAppClientManagerImpl(newWhatsAppTestConfig(graph.server.URL))
+
+	id, err := wc.SendTextMessage(testPhoneNumberId, testAccessToken, "998901234567", "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "wamid.sent" {
+		t.Fatalf("expected the message id of the response, got %q", id)
+	}
+
+	sent := graph.lastSent(t)
+	text, _ := sent["text"].(map[string]interface{})
+	if sent["type"] != "text" || sent["to"] != "998901234567" || sent["messaging_product"] != "whatsapp" || text["body"] != "hi" {
+		t.Fatalf("unexpected request body: %v", sent)
+	}
+}
+
+func TestWhatsAppSendTemplateMessage(t *testing.T) {
+	graph := newGraphStandIn(t)
+	wc := NewWhatsAppClientManagerImpl(newWhatsAppTestConfig(graph.server.URL))
+
+	if _, err := wc.SendTemplateMessage(testPhoneNumberId, testAccessToken, "998901234567", "order_update", "en_US", []string{"Aziza", "#42"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := graph.lastSent(t)
+	template, _ := sent["template"].(map[string]interface{})
+	language, _ := template["language"].(map[string]interface{})
+	components, _ := template["components"].([]interface{})
+	if sent["type"] != "template" || template["name"] != "order_update" || language["code"] != "en_US" || len(components) != 1 {
+		t.Fatalf("unexpected template request: %v", sent)
+	}
+	parameters, _ := components[0].(map[string]interface{})["parameters"].([]interface{})
+	if len(parameters) != 2 || parameters[1].(map[string]interface{})["text"] != "#42" {
+		t.Fatalf("unexpected template parameters: %v", parameters)
+	}
+}
+
+func TestWhatsAppSendReportsApiError(t *testing.T) {
+	graph := newGraphStandIn(t)
+	wc := NewWhatsAppClientManagerImpl(newWhatsAppTestConfig(graph.server.URL))
+
+	_, err := wc.SendTextMessage(testPhoneNumberId, "expired-token", "998901234567", "hi")
+	if err == nil || !strings.Contains(err.Error(), "Invalid OAuth access token") {
+		t.Fatalf("expected the api error message, got %v", err)
+	}
+}
+
+func TestWhatsAppDownloadMedia(t *testing.T) {
+	graph := newGraphStandIn(t)
+	wc := NewWhatsAppClientManagerImpl(newWhatsAppTestConfig(graph.server.URL))
+
+	content, err := wc.DownloadMedia(testAccessToken, "media.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "file of media.1" {
+		t.Fatalf("unexpected content %q", content)
+	}
+
+	if _, err = wc.DownloadMedia("expired-token", "media.1"); err == nil {
+		t.Fatal("expected the download to fail without a valid token")
+	}
+}
+
+func TestWhatsAppAdapterSendsThroughTheChatIntegration(t *testing.T) {
+	graph := newGraphStandIn(t)
+	adapter := NewWhatsAppAdapterImpl(NewWhatsAppClientManagerImpl(newWhatsAppTestConfig(graph.server.URL)))
+	workspace := &entity.Workspace{Integrations: entity.Integrations{WhatsApp: []entity.WhatsAppIntegration{
+		{PhoneNumberId: testPhoneNumberId, AccessToken: testAccessToken, IsActive: true},
+	}}}
+
+	if _, err := adapter.SendTextMessage(workspace, &entity.Chat{AccountId: testPhoneNumberId, ExternalId: "998901234567"}, "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := adapter.SendTextMessage(workspace, &entity.Chat{AccountId: "2002", ExternalId: "998901234567"}, "hi"); err == nil {
+		t.Fatal("expected an error for a phone number the workspace has not connected")
+	}
+}
+
+func TestWhatsAppGetPhoneNumber(t *testing.T) {
+	graph := newGraphStandIn(t)
+	wc := NewWhatsAppClientManagerImpl(newWhatsAppTestConfig(graph.server.URL))
+
+	phoneNumber, err := wc.GetPhoneNumber(testPhoneNumberId, testAccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if phoneNumber != "+1 555 0100" {
+		t.Fatalf("unexpected phone number %q", phoneNumber)
+	}
+}
@@ -0,0 +1,130 @@
+package infrastructureModel
+
+import (
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	"time"
+)
+
+// InboundMessage is a customer message normalised by a channel adapter
+type InboundMessage struct {
+	AccountId         string
+	ExternalChatId    string
+	ExternalSenderId  string
+	ExternalMessageId string
+	SenderName        string
+	ChatTitle         string
+	Text              string
+	Type              entity.MessageType
+	FileId            string
+	CreatedAt         time.Time
+}
+
+type TelegramAccountMessage struct {
+	ChatId    int64  `json:"chat_id"`
+	SenderId  int64  `json:"sender_id"`
+	Name      string `json:"name"`
+	Title     string `json:"title"`
+	MessageId int    `json:"message_id"`
+	Text      string `json:"text"`
+	Type      string `json:"type"`
+	FileId    string `json:"file_id"`
+	Date      int64  `json:"date"`
+}
+
+// WhatsAppWebhook is the payload the Cloud API posts for a subscribed app
+type WhatsAppWebhook struct {
+	Object string `json:"object"`
+	Entry  []struct {
+		Id      string `json:"id"`
+		Changes []struct {
+			Field string              `json:"field"`
+			Value WhatsAppChangeValue `json:"value"`
+		} `json:"changes"`
+	} `json:"entry"`
+}
+
+type WhatsAppChangeValue struct {
+	MessagingProduct string `json:"messaging_product"`
+	Metadata         struct {
+		DisplayPhoneNumber string `json:"display_phone_number"`
+		PhoneNumberId      string `json:"phone_number_id"`
+	} `json:"metadata"`
+	Contacts []struct {
+		WaId    string `json:"wa_id"`
+		Profile struct {
+			Name string `json:"name"`
+		} `json:"profile"`
+	} `json:"contacts"`
+	Messages []WhatsAppMessage `json:"messages"`
+}
+
+type WhatsAppMessage struct {
+	From      string         `json:"from"`
+	Id        string         `json:"id"`
+	Timestamp string         `json:"timestamp"`
+	Type      string         `json:"type"`
+	Text      *WhatsAppText  `json:"text,omitempty"`
+	Image     *WhatsAppMedia `json:"image,omitempty"`
+	Audio     *WhatsAppMedia `json:"audio,omitempty"`
+	Video     *WhatsAppMedia `json:"video,omitempty"`
+	Document  *WhatsAppMedia `json:"document,omitempty"`
+	Sticker   *WhatsAppMedia `json:"sticker,omitempty"`
+}
+
+type WhatsAppText struct {
+	Body string `json:"body"`
+}
+
+type WhatsAppMedia struct {
+	Id       string `json:"id"`
+	MimeType string `json:"mime_type"`
+	Caption  string `json:"caption"`
+	Filename string `json:"filename"`
+	Voice    bool   `json:"voice"`
+}
+
+type WhatsAppSendResponse struct {
+	Messages []struct {
+		Id string `json:"id"`
+	} `json:"messages"`
+	Error *WhatsAppError `json:"error,omitempty"`
+}
+
+type WhatsAppError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    int    `json:"code"`
+}
+
+// MetaWebhook is the payload Meta posts for subscribed pages and Instagram accounts
+type MetaWebhook struct {
+	Object string `json:"object"`
+	Entry  []struct {
+		Id        string               `json:"id"`
+		Time      int64                `json:"time"`
+		Messaging []MetaMessagingEvent `json:"messaging"`
+	} `json:"entry"`
+}
+
+type MetaMessagingEvent struct {
+	Sender struct {
+		Id string `json:"id"`
+	} `json:"sender"`
+	Recipient struct {
+		Id string `json:"id"`
+	} `json:"recipient"`
+	Timestamp int64        `json:"timestamp"`
+	Message   *MetaMessage `json:"message,omitempty"`
+}
+
+type MetaMessage struct {
+	Mid         string `json:"mid"`
+	Text        string `json:"text"`
+	IsEcho      bool   `json:"is_echo"`
+	Attachments []struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Url string `json:"url"`
+		} `json:"payload"`
+	} `json:"attachments"`
+}
@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"sync"
+	"time"
+)
+
+// watchRetryDelay is how long the mongo bus waits before it reopens a broken change stream
+const watchRetryDelay = time.Second
+
+type MemoryBroadcastBusImpl struct {
+	config    *config.Config
+	handlers  []func(broadcast *entity.Broadcast)
+	sequences map[string]int64
+	kept      map[string][]*entity.Broadcast
+	cursors   map[string]map[primitive.ObjectID]*entity.EventCursor
+	mu        sync.RWMutex
+	// publishMu keeps handlers seeing broadcasts in sequence order, they run outside mu so they can use the bus
+	publishMu sync.Mutex
+}
+
+// NewMemoryBroadcastBusImpl hands broadcasts straight to the subscribers of this process,
+// which is enough for a single instance and for tests
+func NewMemoryBroadcastBusImpl(cfg *config.Config) infrastructureInterface.BroadcastBus {
+	return &MemoryBroadcastBusImpl{
+		config:    cfg,
+		sequences: make(map[string]int64),
+		kept:      make(map[string][]*entity.Broadcast),
+		cursors:   make(map[string]map[primitive.ObjectID]*entity.EventCursor),
+	}
+}
+
+func (mb *MemoryBroadcastBusImpl) Publish(broadcast *entity.Broadcast) error {
+	mb.publishMu.Lock()
+	defer mb.publishMu.Unlock()
+
+	mb.mu.Lock()
+
+	now := time.Now()
+	broadcast.CreatedAt = now
+	broadcast.ExpiresAt = now.Add(mb.config.Websocket.BroadcastRetention)
+
+	// events that are not replayed get no sequence, so they leave no gap in the replayed ones
+	if broadcast.Event.IsReplayed() {
+		mb.sequences[broadcast.WorkspaceId]++
+		broadcast.Sequence = mb.sequences[broadcast.WorkspaceId]
+
+		kept := mb.kept[broadcast.WorkspaceId]
+		expired := 0
+		for expired < len(kept) && kept[expired].ExpiresAt.Before(now) {
+			expired++
+		}
+		mb.kept[broadcast.WorkspaceId] = append(kept[expired:], broadcast)
+	}
+	handlers := mb.handlers
+	mb.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(broadcast)
+	}
+
+	return nil
+}
+
+func (mb *MemoryBroadcastBusImpl) Subscribe(handler func(broadcast *entity.Broadcast)) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	mb.handlers = append(mb.handlers, handler)
+}
+
+func (mb *MemoryBroadcastBusImpl) Replay(workspaceId string, afterSequence int64) ([]*entity.Broadcast, bool, error) {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	now := time.Now()
+	var result []*entity.Broadcast
+	for _, broadcast := range mb.kept[workspaceId] {
+		if broadcast.Sequence > afterSequence && broadcast.ExpiresAt.After(now) {
+			result = append(result, broadcast)
+		}
+	}
+
+	return result, isComplete(result, afterSequence, mb.sequences[workspaceId]), nil
+}
+
+// SaveCursor only moves the resume point forward, a tab that closes late must not take it back
+func (mb *MemoryBroadcastBusImpl) SaveCursor(cursor *entity.EventCursor) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if mb.cursors[cursor.WorkspaceId] == nil {
+		mb.cursors[cursor.WorkspaceId] = make(map[primitive.ObjectID]*entity.EventCursor)
+	}
+	saved := *cursor
+	if previous, exists := mb.cursors[cursor.WorkspaceId][cursor.UserId]; exists && previous.Sequence > saved.Sequence {
+		saved.Sequence = previous.Sequence
+	}
+	saved.ExpiresAt = time.Now().Add(mb.config.Websocket.BroadcastRetention)
+	mb.cursors[cursor.WorkspaceId][cursor.UserId] = &saved
+
+	return nil
+}
+
+func (mb *MemoryBroadcastBusImpl) FindCursor(workspaceId string, userId primitive.ObjectID) (*entity.EventCursor, error) {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	cursor, exists := mb.cursors[workspaceId][userId]
+	if !exists || cursor.ExpiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+
+	found := *cursor
+	return &found, nil
+}
+
+type MongoBroadcastBusImpl struct {
+	database *mongo.Database
+	config   *config.Config
+}
+
+// NewMongoBroadcastBusImpl shares broadcasts between instances through a change stream on the broadcast collection
+func NewMongoBroadcastBusImpl(db *mongo.Database, cfg *config.Config) infrastructureInterface.BroadcastBus {
+	return &MongoBroadcastBusImpl{
+		database: db,
+		config:   cfg,
+	}
+}
+
+// Publish numbers only the events that are replayed, the others still go through the collection
+// to reach the change stream but a replay never finds them
+func (mb *MongoBroadcastBusImpl) Publish(broadcast *entity.Broadcast) error {
+	if broadcast.Event.IsReplayed() {
+		var counter struct {
+			Sequence int64 `bson:"sequence"`
+		}
+		err := mb.database.Collection(mb.config.MongoDB.EventSequenceCollection).FindOneAndUpdate(
+			context.Background(),
+			bson.M{"_id": broadcast.WorkspaceId},
+			bson.M{"$inc": bson.M{"sequence": 1}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&counter)
+		if err != nil {
+			return err
+		}
+		broadcast.Sequence = counter.Sequence
+	}
+
+	now := time.Now()
+	broadcast.CreatedAt = now
+	broadcast.ExpiresAt = now.Add(mb.config.Websocket.BroadcastRetention)
+
+	_, err := mb.database.Collection(mb.config.MongoDB.BroadcastCollection).InsertOne(context.Background(), broadcast)
+	return err
+}
+
+// Replay reads the counter first, so a broadcast published during the query cannot pass for an expired one
+func (mb *MongoBroadcastBusImpl) Replay(workspaceId string, afterSequence int64) ([]*entity.Broadcast, bool, error) {
+	var counter struct {
+		Sequence int64 `bson:"sequence"`
+	}
+	err := mb.database.Collection(mb.config.MongoDB.EventSequenceCollection).FindOne(
+		context.Background(),
+		bson.M{"_id": workspaceId},
+	).Decode(&counter)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, err
+	}
+
+	cursor, err := mb.database.Collection(mb.config.MongoDB.BroadcastCollection).Find(
+		context.Background(),
+		bson.M{"workspace_id": workspaceId, "sequence": bson.M{"$gt": afterSequence}, "expires_at": bson.M{"$gt": time.Now()}},
+		options.Find().SetSort(bson.D{{Key: "sequence", Value: 1}}),
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(context.Background())
+
+	var broadcasts []*entity.Broadcast
+	if err = cursor.All(context.Background(), &broadcasts); err != nil {
+		return nil, false, err
+	}
+
+	return broadcasts, isComplete(broadcasts, afterSequence, counter.Sequence), nil
+}
+
+// SaveCursor uses $max, so of two tabs closing in any order the one that got further wins
+func (mb *MongoBroadcastBusImpl) SaveCursor(cursor *entity.EventCursor) error {
+	_, err := mb.database.Collection(mb.config.MongoDB.EventCursorCollection).UpdateOne(
+		context.Background(),
+		bson.M{"workspace_id": cursor.WorkspaceId, "user_id": cursor.UserId},
+		bson.M{
+			"$max": bson.M{"sequence": cursor.Sequence},
+			"$set": bson.M{"expires_at": time.Now().Add(mb.config.Websocket.BroadcastRetention)},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (mb *MongoBroadcastBusImpl) FindCursor(workspaceId string, userId primitive.ObjectID) (*entity.EventCursor, error) {
+	var cursor entity.EventCursor
+	err := mb.database.Collection(mb.config.MongoDB.EventCursorCollection).FindOne(
+		context.Background(),
+		bson.M{"workspace_id": workspaceId, "user_id": userId, "expires_at": bson.M{"$gt": time.Now()}},
+	).Decode(&cursor)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+// Subscribe watches in the background and reopens the stream whenever it breaks,
+// resuming after the last broadcast it handed to the handler
+func (mb *MongoBroadcastBusImpl) Subscribe(handler func(broadcast *entity.Broadcast)) {
+	go func() {
+		var resumeToken bson.Raw
+		for {
+			token, err := mb.watch(resumeToken, handler)
+			if err != nil {
+				log.Println("broadcast stream failed:", err)
+			}
+			// a token that fell out of the oplog fails again on every retry, start from now instead
+			if token == nil || token.String() == resumeToken.String() {
+				resumeToken = nil
+			} else {
+				resumeToken = token
+			}
+			time.Sleep(watchRetryDelay)
+		}
+	}()
+}
+
+func (mb *MongoBroadcastBusImpl) watch(resumeToken bson.Raw, handler func(broadcast *entity.Broadcast)) (bson.Raw, error) {
+	opts := options.ChangeStream()
+	if resumeToken != nil {
+		opts.SetResumeAfter(resumeToken)
+	}
+
+	stream, err := mb.database.Collection(mb.config.MongoDB.BroadcastCollection).Watch(
+		context.Background(),
+		mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}},
+		opts,
+	)
+	if err != nil {
+		return resumeToken, err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(context.Background()) {
+		var event struct {
+			FullDocument entity.Broadcast `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&event); err != nil {
+			log.Println("failed to decode broadcast:", err)
+			continue
+		}
+
+		handler(&event.FullDocument)
+	}
+
+	return stream.ResumeToken(), stream.Err()
+}
+
+// isComplete tells whether the replayed broadcasts are every one published after the sequence, in order.
+// Expired broadcasts leave a hole at the start, and one that is numbered but not written yet a hole further on.
+func isComplete(broadcasts []*entity.Broadcast, afterSequence, lastSequence int64) bool {
+	next := afterSequence + 1
+	for _, broadcast := range broadcasts {
+		if broadcast.Sequence != next {
+			return false
+		}
+		next++
+	}
+	return next > lastSequence
+}
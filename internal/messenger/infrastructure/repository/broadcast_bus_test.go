@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestBus(retention time.Duration) *MemoryBroadcastBusImpl {
+	cfg := &config.Config{}
+	cfg.Websocket.BroadcastRetention = retention
+	return NewMemoryBroadcastBusImpl(cfg).(*MemoryBroadcastBusImpl)
+}
+
+func sequences(broadcasts []*entity.Broadcast) []int64 {
+	result := make([]int64, 0, len(broadcasts))
+	for _, broadcast := range broadcasts {
+		result = append(result, broadcast.Sequence)
+	}
+	return result
+}
+
+func TestMemoryBusFansOutToEverySubscriber(t *testing.T) {
+	bus := newTestBus(time.Minute)
+
+	var mu sync.Mutex
+	received := make(map[int][]entity.EventType)
+	for subscriber := range 3 {
+		bus.Subscribe(func(broadcast *entity.Broadcast) {
+			mu.Lock()
+			defer mu.Unlock()
+			received[subscriber] = append(received[subscriber], broadcast.Event)
+		})
+	}
+
+	for _, event := range []entity.EventType{entity.EventMessageCreated, entity.EventTyping, entity.EventChatUpdated} {
+		if err := bus.Publish(&entity.Broadcast{WorkspaceId: "workspace", Target: entity.BroadcastAll, Event: event}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for subscriber := range 3 {
+		events := received[subscriber]
+		if len(events) != 3 || events[0] != entity.EventMessageCreated || events[1] != entity.EventTyping || events[2] != entity.EventChatUpdated {
+			t.Fatalf("subscriber %d got %v", subscriber, events)
+		}
+	}
+}
+
+func TestMemoryBusNumbersReplayedEventsPerWorkspace(t *testing.T) {
+	bus := newTestBus(time.Minute)
+
+	publish := func(workspaceId string, event entity.EventType) *entity.Broadcast {
+		broadcast := &entity.Broadcast{WorkspaceId: workspaceId, Target: entity.BroadcastAll, Event: event}
+		if err := bus.Publish(broadcast); err != nil {
+			t.Fatal(err)
+		}
+		return broadcast
+	}
+
+	first := publish("first", entity.EventMessageCreated)
+	typing := publish("first", entity.EventTyping)
+	second := publish("first", entity.EventTicketAssigned)
+	other := publish("second", entity.EventMessageCreated)
+
+	if first.Sequence != 1 || second.Sequence != 2 {
+		t.Fatalf("expected sequences 1 and 2, got %d and %d", first.Sequence, second.Sequence)
+	}
+	if typing.Sequence != 0 {
+		t.Fatalf("typing is not replayed and must not take a sequence, got %d", typing.Sequence)
+	}
+	if other.Sequence != 1 {
+		t.Fatalf("every workspace counts on its own, got %d", other.Sequence)
+	}
+	if first.ExpiresAt.Sub(first.CreatedAt) != time.Minute {
+		t.Fatal("expected the broadcast to be kept for the retention")
+	}
+
+	replayed, complete, err := bus.Replay("first", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sequences(replayed); !complete || len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("expected a complete replay of 1 and 2, got %v complete %v", got, complete)
+	}
+
+	replayed, complete, _ = bus.Replay("first", 1)
+	if got := sequences(replayed); !complete || len(got) != 1 || got[0] != 2 {
+		t.Fatalf("expected only 2 after 1, got %v complete %v", got, complete)
+	}
+
+	replayed, complete, _ = bus.Replay("first", 2)
+	if len(replayed) != 0 || !complete {
+		t.Fatal("a client that saw everything has nothing to replay")
+	}
+}
+
+func TestMemoryBusReplayAfterExpiryIsIncomplete(t *testing.T) {
+	bus := newTestBus(20 * time.Millisecond)
+
+	for range 2 {
+		if err := bus.Publish(&entity.Broadcast{WorkspaceId: "workspace", Event: entity.EventMessageCreated}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(40 * time.Millisecond)
+	if err := bus.Publish(&entity.Broadcast{WorkspaceId: "workspace", Event: entity.EventMessageCreated}); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, complete, err := bus.Replay("workspace", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sequences(replayed); complete || len(got) != 1 || got[0] != 3 {
+		t.Fatalf("expected only 3 and an incomplete replay, got %v complete %v", got, complete)
+	}
+
+	if _, complete, _ = bus.Replay("workspace", 2); !complete {
+		t.Fatal("a client that saw the expired broadcasts is still complete")
+	}
+}
+
+func TestIsComplete(t *testing.T) {
+	broadcasts := func(sequences ...int64) []*entity.Broadcast {
+		result := make([]*entity.Broadcast, 0, len(sequences))
+		for _, sequence := range sequences {
+			result = append(result, &entity.Broadcast{Sequence: sequence})
+		}
+		return result
+	}
+
+	for _, test := range []struct {
+		name          string
+		broadcasts    []*entity.Broadcast
+		afterSequence int64
+		lastSequence  int64
+		complete      bool
+	}{
+		{"nothing published", nil, 0, 0, true},
+		{"nothing new", nil, 5, 5, true},
+		{"everything expired", nil, 2, 5, false},
+		{"contiguous", broadcasts(3, 4, 5), 2, 5, true},
+		{"expired head", broadcasts(4, 5), 2, 5, false},
+		{"hole in the middle", broadcasts(3, 5), 2, 5, false},
+		{"last still being written", broadcasts(3, 4), 2, 5, false},
+		{"published during the replay", broadcasts(3, 4, 5, 6), 2, 5, true},
+	} {
+		if complete := isComplete(test.broadcasts, test.afterSequence, test.lastSequence); complete != test.complete {
+			t.Errorf("%s: expected %v, got %v", test.name, test.complete, complete)
+		}
+	}
+}
+
+func TestMemoryBusCursors(t *testing.T) {
+	bus := newTestBus(time.Minute)
+	userId := primitive.NewObjectID()
+
+	if cursor, err := bus.FindCursor("workspace", userId); err != nil || cursor != nil {
+		t.Fatalf("expected no cursor yet, got %v %v", cursor, err)
+	}
+
+	if err := bus.SaveCursor(&entity.EventCursor{WorkspaceId: "workspace", UserId: userId, Sequence: 7}); err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := bus.FindCursor("workspace", userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor == nil || cursor.Sequence != 7 {
+		t.Fatalf("expected the saved cursor, got %+v", cursor)
+	}
+	if other, _ := bus.FindCursor("other", userId); other != nil {
+		t.Fatal("cursors belong to one workspace")
+	}
+}
+
+func TestMemoryBusCursorNeverMovesBack(t *testing.T) {
+	bus := newTestBus(time.Minute)
+	userId := primitive.NewObjectID()
+
+	for _, sequence := range []int64{5, 9, 7} {
+		if err := bus.SaveCursor(&entity.EventCursor{WorkspaceId: "workspace", UserId: userId, Sequence: sequence}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if cursor, _ := bus.FindCursor("workspace", userId); cursor == nil || cursor.Sequence != 9 {
+		t.Fatalf("expected the furthest sequence 9, got %+v", cursor)
+	}
+}
+
+func TestMemoryBusHandlerCanUseTheBus(t *testing.T) {
+	bus := newTestBus(time.Minute)
+	userId := primitive.NewObjectID()
+
+	bus.Subscribe(func(broadcast *entity.Broadcast) {
+		if err := bus.SaveCursor(&entity.EventCursor{WorkspaceId: broadcast.WorkspaceId, UserId: userId, Sequence: broadcast.Sequence}); err != nil {
+			t.Error(err)
+		}
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.Publish(&entity.Broadcast{WorkspaceId: "workspace", Target: entity.BroadcastAll, Event: entity.EventMessageCreated})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("publish deadlocked on a handler that saves a cursor")
+	}
+
+	if cursor, _ := bus.FindCursor("workspace", userId); cursor == nil || cursor.Sequence != 1 {
+		t.Fatalf("expected the cursor at 1, got %+v", cursor)
+	}
+}
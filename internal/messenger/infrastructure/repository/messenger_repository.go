@@ -10,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
 	"sync"
 	"time"
 )
@@ -72,6 +73,31 @@ func (mr *MessengerRepositoryImpl) FindWorkspaceByTelegramBotToken(botToken stri
 	return &workspace, nil
 }
 
+func (mr *MessengerRepositoryImpl) FindWorkspaceByTelegramWebhookSecret(webhookSecret string) (*entity.Workspace, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	var workspace entity.Workspace
+	err := mr.database.Collection(mr.config.MongoDB.WorkspaceCollection).FindOne(
+		context.Background(),
+		bson.M{
+			"integrations.telegram_bot": bson.M{
+				"$elemMatch": bson.M{
+					"webhook_secret": webhookSecret,
+					"is_active":      true,
+				},
+			},
+		}).Decode(&workspace)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("workspace not found")
+		}
+		return nil, err
+	}
+
+	return &workspace, nil
+}
+
 func (mr *MessengerRepositoryImpl) FindChatByWorkspaceIdAndChatId(workspaceId primitive.ObjectID, chatId string) (*entity.Chat, error) {
 	mr.mu.RLock()
 	defer mr.mu.RUnlock()
@@ -93,7 +119,7 @@ func (mr *MessengerRepositoryImpl) FindTeamByWorkspaceIdAndTeamId(workspaceId pr
 	defer mr.mu.RUnlock()
 
 	var team entity.Team
-	err := mr.database.Collection(mr.config.MongoDB.ChatCollection).FindOne(
+	err := mr.database.Collection(mr.config.MongoDB.TeamCollection).FindOne(
 		context.Background(),
 		bson.M{"workspace_id": workspaceId, "team_id": teamId},
 	).Decode(&team)
@@ -104,6 +130,80 @@ func (mr *MessengerRepositoryImpl) FindTeamByWorkspaceIdAndTeamId(workspaceId pr
 	return &team, nil
 }
 
+func (mr *MessengerRepositoryImpl) FindFirstTeamByWorkspaceId(workspaceId primitive.ObjectID) (*entity.Team, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	var team entity.Team
+	err := mr.database.Collection(mr.config.MongoDB.TeamCollection).FindOne(
+		context.Background(),
+		bson.M{"workspace_id": workspaceId, "is_first_team": true},
+	).Decode(&team)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &team, nil
+}
+
+func (mr *MessengerRepositoryImpl) FindChatByWorkspaceIdAndExternalId(ctx mongo.SessionContext, workspaceId primitive.ObjectID, source entity.ChatSource, accountId, externalId string) (*entity.Chat, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	var chat entity.Chat
+	err := mr.database.Collection(mr.config.MongoDB.ChatCollection).FindOne(
+		ctx,
+		bson.M{"workspace_id": workspaceId, "source": source, "account_id": accountId, "external_id": externalId},
+	).Decode(&chat)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &chat, nil
+}
+
+// FindWorkspaceByChannelAccount returns nil when no workspace has the account connected
+func (mr *MessengerRepositoryImpl) FindWorkspaceByChannelAccount(source entity.ChatSource, accountId string) (*entity.Workspace, error) {
+	var filter bson.M
+	switch source {
+	case entity.SourceTelegramBot:
+		filter = bson.M{"integrations.telegram_bot": bson.M{"$elemMatch": bson.M{"bot_token": accountId, "is_active": true}}}
+	case entity.SourceTelegram:
+		filter = bson.M{"workspace_id": accountId}
+	case entity.SourceWhatsApp:
+		filter = bson.M{"integrations.whatsapp": bson.M{"$elemMatch": bson.M{"phone_number_id": accountId, "is_active": true}}}
+	case entity.SourceMeta:
+		filter = bson.M{"integrations.meta.pages": bson.M{"$elemMatch": bson.M{"page_id": accountId, "is_active": true}}}
+	case entity.SourceInstagram:
+		filter = bson.M{"integrations.meta.pages": bson.M{"$elemMatch": bson.M{"instagram_account_id": accountId, "is_active": true}}}
+	default:
+		return nil, errors.New("unsupported chat source")
+	}
+
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	var workspace entity.Workspace
+	err := mr.database.Collection(mr.config.MongoDB.WorkspaceCollection).FindOne(
+		context.Background(),
+		filter,
+	).Decode(&workspace)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &workspace, nil
+}
+
 func (mr *MessengerRepositoryImpl) FindUserById(id primitive.ObjectID) (*entity.User, error) {
 	mr.mu.RLock()
 	defer mr.mu.RUnlock()
@@ -219,6 +319,363 @@ func (mr *MessengerRepositoryImpl) FindLatestTicketsByChatIdBeforeDate(workspace
 	return tickets, nil
 }
 
+// AppendMessage stores the message and refreshes the chat summary without rewriting the chat document
+func (mr *MessengerRepositoryImpl) AppendMessage(ctx mongo.SessionContext, message *entity.Message) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	if _, err := mr.database.Collection(mr.config.MongoDB.MessageCollection).InsertOne(ctx, message); err != nil {
+		return err
+	}
+
+	_, err := mr.database.Collection(mr.config.MongoDB.ChatCollection).UpdateOne(
+		ctx,
+		bson.M{"chat_id": message.ChatId},
+		bson.M{
+			"$set": bson.M{"last_message": message},
+			"$inc": bson.M{"tickets.$[ticket].message_count": 1},
+		},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"ticket.ticket_id": message.TicketId}},
+		}),
+	)
+	return err
+}
+
+func (mr *MessengerRepositoryImpl) FindMessageByChatIdAndMessageId(chatId, messageId string) (*entity.Message, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	var message entity.Message
+	err := mr.database.Collection(mr.config.MongoDB.MessageCollection).FindOne(
+		context.Background(),
+		bson.M{"chat_id": chatId, "message_id": messageId},
+	).Decode(&message)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("message not found")
+		}
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+func (mr *MessengerRepositoryImpl) UpdateMessageDelivery(message *entity.Message) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	res, err := mr.database.Collection(mr.config.MongoDB.MessageCollection).UpdateOne(
+		context.Background(),
+		bson.M{"chat_id": message.ChatId, "message_id": message.MessageId},
+		bson.M{"$set": bson.M{"external_id": message.ExternalId, "status": message.Status}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("message not found")
+	}
+
+	return nil
+}
+
+// DeleteMessage also points the chat's last message at the newest one left, so chat lists stop showing
+// the deleted text, or removes it when the chat has no message left
+func (mr *MessengerRepositoryImpl) DeleteMessage(message *entity.Message) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	messages := mr.database.Collection(mr.config.MongoDB.MessageCollection)
+	res, err := messages.DeleteOne(
+		context.Background(),
+		bson.M{"chat_id": message.ChatId, "message_id": message.MessageId},
+	)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return errors.New("message not found")
+	}
+
+	update := bson.M{"$inc": bson.M{"tickets.$[ticket].message_count": -1}}
+	var newest entity.Message
+	err = messages.FindOne(
+		context.Background(),
+		bson.M{"chat_id": message.ChatId},
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	).Decode(&newest)
+	switch {
+	case err == nil:
+		update["$set"] = bson.M{"last_message": newest}
+	case errors.Is(err, mongo.ErrNoDocuments):
+		update["$unset"] = bson.M{"last_message": ""}
+	default:
+		return err
+	}
+
+	_, err = mr.database.Collection(mr.config.MongoDB.ChatCollection).UpdateOne(
+		context.Background(),
+		bson.M{"chat_id": message.ChatId},
+		update,
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"ticket.ticket_id": message.TicketId}},
+		}),
+	)
+	return err
+}
+
+// UpdateMessagesChatIdByTicketId follows a ticket that was moved to another chat
+func (mr *MessengerRepositoryImpl) UpdateMessagesChatIdByTicketId(ctx mongo.SessionContext, ticketId, chatId string) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	_, err := mr.database.Collection(mr.config.MongoDB.MessageCollection).UpdateMany(
+		ctx,
+		bson.M{"ticket_id": ticketId},
+		bson.M{"$set": bson.M{"chat_id": chatId}},
+	)
+	return err
+}
+
+// FindChatHistory pages through the messages and ticket notes of a chat, starting from the cursor position.
+// Entries come back newest first regardless of the direction.
+func (mr *MessengerRepositoryImpl) FindChatHistory(workspaceId primitive.ObjectID, chatId string, cursorTime time.Time, cursorId string, limit int, after bool) ([]entity.ChatHistoryEntry, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	comparison, sortOrder := "$lt", -1
+	if after {
+		comparison, sortOrder = "$gt", 1
+	}
+
+	var cursorFilter bson.M
+	if !cursorTime.IsZero() {
+		cursorFilter = bson.M{"$or": []bson.M{
+			{"created_at": bson.M{comparison: cursorTime}},
+			{"created_at": cursorTime, "entry_id": bson.M{comparison: cursorId}},
+		}}
+	}
+	sortStage := bson.D{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: sortOrder}, {Key: "entry_id", Value: sortOrder}}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	messagesPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"workspace_id": workspaceId, "chat_id": chatId}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":        0,
+			"ticket_id":  1,
+			"entry_id":   "$message_id",
+			"sender_id":  1,
+			"text":       "$message",
+			"from":       1,
+			"type":       1,
+			"status":     1,
+			"created_at": 1,
+		}}},
+	}
+	if cursorFilter != nil {
+		messagesPipeline = append(messagesPipeline, bson.D{{Key: "$match", Value: cursorFilter}})
+	}
+	messagesPipeline = append(messagesPipeline, sortStage, bson.D{{Key: "$limit", Value: limit}})
+
+	messages, err := mr.aggregateHistory(ctx, mr.config.MongoDB.MessageCollection, messagesPipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	notesPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"workspace_id": workspaceId, "chat_id": chatId}}},
+		{{Key: "$unwind", Value: "$tickets"}},
+		{{Key: "$unwind", Value: "$tickets.notes"}},
+		{{Key: "$project", Value: bson.M{
+			"_id":        0,
+			"ticket_id":  "$tickets.ticket_id",
+			"entry_id":   "$tickets.notes.note_id",
+			"sender_id":  "$tickets.notes.user_id",
+			"text":       "$tickets.notes.text",
+			"type":       entity.TypeTicketNote,
+			"created_at": "$tickets.notes.created_at",
+		}}},
+	}
+	if cursorFilter != nil {
+		notesPipeline = append(notesPipeline, bson.D{{Key: "$match", Value: cursorFilter}})
+	}
+	notesPipeline = append(notesPipeline, sortStage, bson.D{{Key: "$limit", Value: limit}})
+
+	notes, err := mr.aggregateHistory(ctx, mr.config.MongoDB.ChatCollection, notesPipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := append(messages, notes...)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].EntryId > entries[j].EntryId
+		}
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+
+	if len(entries) > limit {
+		if after {
+			entries = entries[len(entries)-limit:]
+		} else {
+			entries = entries[:limit]
+		}
+	}
+
+	return entries, nil
+}
+
+func (mr *MessengerRepositoryImpl) aggregateHistory(ctx context.Context, collection string, pipeline mongo.Pipeline) ([]entity.ChatHistoryEntry, error) {
+	cursor, err := mr.database.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []entity.ChatHistoryEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (mr *MessengerRepositoryImpl) SearchChats(workspaceId primitive.ObjectID, query string, filter entity.SearchFilter, limit int) ([]entity.ChatSearchHit, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	match := bson.M{"$text": bson.M{"$search": query}, "workspace_id": workspaceId}
+	for key, value := range mr.chatSearchFilter(filter, "") {
+		match[key] = value
+	}
+	if dateRange := mr.dateRangeFilter(filter); dateRange != nil {
+		match["last_message.created_at"] = dateRange
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "last_message.created_at", Value: -1}}).
+		SetLimit(int64(limit))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := mr.database.Collection(mr.config.MongoDB.ChatCollection).Find(ctx, match, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hits []entity.ChatSearchHit
+	if err = cursor.All(ctx, &hits); err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+func (mr *MessengerRepositoryImpl) SearchMessages(workspaceId primitive.ObjectID, query string, filter entity.SearchFilter, limit int) ([]entity.MessageSearchHit, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	match := bson.M{"$text": bson.M{"$search": query}, "workspace_id": workspaceId}
+	if dateRange := mr.dateRangeFilter(filter); dateRange != nil {
+		match["created_at"] = dateRange
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         mr.config.MongoDB.ChatCollection,
+			"localField":   "chat_id",
+			"foreignField": "chat_id",
+			"as":           "chat",
+		}}},
+		{{Key: "$unwind", Value: "$chat"}},
+	}
+
+	chatMatch := mr.chatSearchFilter(filter, "chat.")
+	delete(chatMatch, "chat.tickets.status")
+	if filter.Status != "" {
+		// the status belongs to the ticket the message was posted in, not to any ticket of the chat
+		chatMatch["$expr"] = bson.M{"$gt": []interface{}{
+			bson.M{"$size": bson.M{"$filter": bson.M{
+				"input": "$chat.tickets",
+				"as":    "ticket",
+				"cond": bson.M{"$and": []interface{}{
+					bson.M{"$eq": []interface{}{"$$ticket.ticket_id", "$ticket_id"}},
+					bson.M{"$eq": []interface{}{"$$ticket.status", filter.Status}},
+				}},
+			}}},
+			0,
+		}}
+	}
+	if len(chatMatch) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: chatMatch}})
+	}
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "created_at", Value: -1}}}},
+		bson.D{{Key: "$limit", Value: limit}},
+		bson.D{{Key: "$addFields", Value: bson.M{"chat_name": "$chat.name", "source": "$chat.source"}}},
+		bson.D{{Key: "$project", Value: bson.M{"chat": 0}}},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := mr.database.Collection(mr.config.MongoDB.MessageCollection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hits []entity.MessageSearchHit
+	if err = cursor.All(ctx, &hits); err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+func (mr *MessengerRepositoryImpl) chatSearchFilter(filter entity.SearchFilter, prefix string) bson.M {
+	match := bson.M{}
+	if filter.Source != "" {
+		match[prefix+"source"] = filter.Source
+	}
+	if filter.Tag != "" {
+		match[prefix+"tags"] = filter.Tag
+	}
+	if filter.Status != "" {
+		match[prefix+"tickets.status"] = filter.Status
+	}
+	if !filter.AssigneeId.IsZero() {
+		match[prefix+"user_id"] = filter.AssigneeId
+	}
+
+	return match
+}
+
+func (mr *MessengerRepositoryImpl) dateRangeFilter(filter entity.SearchFilter) bson.M {
+	if filter.From.IsZero() && filter.To.IsZero() {
+		return nil
+	}
+
+	dateRange := bson.M{}
+	if !filter.From.IsZero() {
+		dateRange["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		dateRange["$lte"] = filter.To
+	}
+
+	return dateRange
+}
+
 func (mr *MessengerRepositoryImpl) FindChatByTicketId(ctx mongo.SessionContext, ticketId string) (*entity.Chat, error) {
 	mr.mu.RLock()
 	defer mr.mu.RUnlock()
@@ -483,51 +940,66 @@ func (mr *MessengerRepositoryImpl) InsertNewChat(ctx mongo.SessionContext, chat
 	mr.mu.Lock()
 	defer mr.mu.Unlock()
 
-	//c := context.Background()
-	//
-	//if ctx != nil {
-	//	c = ctx
-	//}
-
-	_, err := mr.database.Collection(mr.config.MongoDB.ChatCollection).InsertOne(
-		context.Background(),
-		chat,
-	)
+	_, err := mr.database.Collection(mr.config.MongoDB.ChatCollection).InsertOne(ctx, chat)
 
 	return err
 }
 
-func (mr *MessengerRepositoryImpl) CountActiveTickets(memberId primitive.ObjectID) (int, error) {
+// FindAgentWorkloads returns the status and unresolved ticket count of each member in one aggregation
+func (mr *MessengerRepositoryImpl) FindAgentWorkloads(memberIds []primitive.ObjectID) ([]entity.AgentWorkload, error) {
 	mr.mu.RLock()
 	defer mr.mu.RUnlock()
 
-	filter := bson.M{
-		"user_id":        memberId,
-		"tickets.status": entity.StatusOpen,
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	unresolved := []entity.TicketStatus{entity.StatusOpen, entity.StatusPending}
 	pipeline := mongo.Pipeline{
-		{{"$match", filter}},
-		{{"$unwind", "$tickets"}},
-		{{"$match", bson.M{"tickets.status": "open"}}},
-		{{"$count", "activeTickets"}},
+		{{Key: "$match", Value: bson.M{"_id": bson.M{"$in": memberIds}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": mr.config.MongoDB.ChatCollection,
+			"let":  bson.M{"userId": "$_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{
+					"$expr":          bson.M{"$eq": []string{"$user_id", "$$userId"}},
+					"tickets.status": bson.M{"$in": unresolved},
+				}}},
+				{{Key: "$unwind", Value: "$tickets"}},
+				{{Key: "$match", Value: bson.M{"tickets.status": bson.M{"$in": unresolved}}}},
+				{{Key: "$count", Value: "count"}},
+			},
+			"as": "tickets",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"status":       1,
+			"open_tickets": bson.M{"$ifNull": []interface{}{bson.M{"$arrayElemAt": []interface{}{"$tickets.count", 0}}, 0}},
+		}}},
 	}
-	cursor, err := mr.database.Collection(mr.config.MongoDB.ChatCollection).Aggregate(
-		context.Background(),
-		pipeline,
-	)
+
+	cursor, err := mr.database.Collection(mr.config.MongoDB.UserCollection).Aggregate(ctx, pipeline)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	var results []bson.M
-	if err := cursor.All(context.Background(), &results); err != nil {
-		return 0, err
-	}
-	if len(results) == 0 {
-		return 0, nil
+	var workloads []entity.AgentWorkload
+	if err = cursor.All(ctx, &workloads); err != nil {
+		return nil, err
 	}
 
-	return int(results[0]["activeTickets"].(int32)), nil
+	return workloads, nil
+}
+
+func (mr *MessengerRepositoryImpl) UpdateTeamLastAssignee(teamId, assigneeId primitive.ObjectID) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	_, err := mr.database.Collection(mr.config.MongoDB.TeamCollection).UpdateOne(
+		context.Background(),
+		bson.M{"_id": teamId},
+		bson.M{"$set": bson.M{"assignment.last_assignee_id": assigneeId}},
+	)
+	return err
 }
 
 func (mr *MessengerRepositoryImpl) GetUserById(id primitive.ObjectID) (*entity.User, error) {
@@ -549,6 +1021,22 @@ func (mr *MessengerRepositoryImpl) GetUserById(id primitive.ObjectID) (*entity.U
 	return &user, nil
 }
 
+// CheckInboundMessageExists includes the chat, Telegram numbers messages per chat rather than per bot
+func (mr *MessengerRepositoryImpl) CheckInboundMessageExists(source entity.ChatSource, accountId, chatId, externalId string) (bool, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	count, err := mr.database.Collection(mr.config.MongoDB.MessageCollection).CountDocuments(
+		context.Background(),
+		bson.M{"source": source, "account_id": accountId, "chat_id": chatId, "external_id": externalId},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
 func (mr *MessengerRepositoryImpl) CheckBotExists(botToken string) (bool, error) {
 	mr.mu.RLock()
 	defer mr.mu.RUnlock()
@@ -567,6 +1055,20 @@ func (mr *MessengerRepositoryImpl) CheckBotExists(botToken string) (bool, error)
 	return false, nil
 }
 
+func (mr *MessengerRepositoryImpl) CheckWhatsAppNumberExists(phoneNumberId string) (bool, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	count, err := mr.database.Collection(mr.config.MongoDB.WorkspaceCollection).CountDocuments(
+		context.Background(),
+		bson.M{"integrations.whatsapp": bson.M{"$elemMatch": bson.M{"phone_number_id": phoneNumberId}}})
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
 func (mr *MessengerRepositoryImpl) FindUserByEmail(ctx mongo.SessionContext, email string) (primitive.ObjectID, error) {
 	mr.mu.RLock()
 	defer mr.mu.RUnlock()
@@ -613,3 +1115,182 @@ func (mr *MessengerRepositoryImpl) StartSession() (mongo.Session, error) {
 	}
 	return session, nil
 }
+
+func (mr *MessengerRepositoryImpl) FindTeamById(id primitive.ObjectID) (*entity.Team, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	var team entity.Team
+	err := mr.database.Collection(mr.config.MongoDB.TeamCollection).FindOne(
+		context.Background(),
+		bson.M{"_id": id},
+	).Decode(&team)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &team, nil
+}
+
+func (mr *MessengerRepositoryImpl) InsertSLAPolicy(policy *entity.SLAPolicy) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	_, err := mr.database.Collection(mr.config.MongoDB.SLAPolicyCollection).InsertOne(
+		context.Background(),
+		policy,
+	)
+	return err
+}
+
+func (mr *MessengerRepositoryImpl) UpdateSLAPolicy(policy *entity.SLAPolicy) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	_, err := mr.database.Collection(mr.config.MongoDB.SLAPolicyCollection).UpdateOne(
+		context.Background(),
+		bson.M{"_id": policy.Id},
+		bson.M{"$set": policy},
+	)
+	return err
+}
+
+func (mr *MessengerRepositoryImpl) DeleteSLAPolicy(workspaceId primitive.ObjectID, policyId string) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	result, err := mr.database.Collection(mr.config.MongoDB.SLAPolicyCollection).DeleteOne(
+		context.Background(),
+		bson.M{"workspace_id": workspaceId, "policy_id": policyId},
+	)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("sla policy not found")
+	}
+
+	return nil
+}
+
+func (mr *MessengerRepositoryImpl) FindSLAPolicyByPolicyId(policyId string) (*entity.SLAPolicy, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	var policy entity.SLAPolicy
+	err := mr.database.Collection(mr.config.MongoDB.SLAPolicyCollection).FindOne(
+		context.Background(),
+		bson.M{"policy_id": policyId},
+	).Decode(&policy)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("sla policy not found")
+		}
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+func (mr *MessengerRepositoryImpl) FindSLAPoliciesByWorkspaceId(workspaceId primitive.ObjectID) ([]entity.SLAPolicy, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := mr.database.Collection(mr.config.MongoDB.SLAPolicyCollection).Find(
+		ctx,
+		bson.M{"workspace_id": workspaceId},
+		options.Find().SetSort(bson.M{"created_at": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var policies []entity.SLAPolicy
+	if err = cursor.All(ctx, &policies); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+func (mr *MessengerRepositoryImpl) FindChatsWithRunningSLA() ([]entity.Chat, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{"tickets": bson.M{"$elemMatch": bson.M{
+		"status":     bson.M{"$ne": entity.StatusClosed},
+		"sla_status": bson.M{"$in": []entity.SLAStatus{entity.SLAOk, entity.SLAWarning}},
+	}}}
+
+	cursor, err := mr.database.Collection(mr.config.MongoDB.ChatCollection).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var chats []entity.Chat
+	if err = cursor.All(ctx, &chats); err != nil {
+		return nil, err
+	}
+
+	return chats, nil
+}
+
+// UpdateTicketSLAStatus only moves the ticket forward from the expected status, so concurrent checks cannot alert twice
+func (mr *MessengerRepositoryImpl) UpdateTicketSLAStatus(ticketId string, from, to entity.SLAStatus) (bool, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	result, err := mr.database.Collection(mr.config.MongoDB.ChatCollection).UpdateOne(
+		context.Background(),
+		bson.M{"tickets": bson.M{"$elemMatch": bson.M{"ticket_id": ticketId, "sla_status": from}}},
+		bson.M{"$set": bson.M{"tickets.$.sla_status": to}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
+func (mr *MessengerRepositoryImpl) FindSavedRepliesByShortcut(workspaceId primitive.ObjectID, shortcut string) ([]entity.SavedReply, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	cursor, err := mr.database.Collection(mr.config.MongoDB.SavedReplyCollection).Find(
+		context.Background(),
+		bson.M{"workspace_id": workspaceId, "shortcut": shortcut},
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var replies []entity.SavedReply
+	if err = cursor.All(context.Background(), &replies); err != nil {
+		return nil, err
+	}
+
+	return replies, nil
+}
+
+func (mr *MessengerRepositoryImpl) InsertAuditLog(auditLog *entity.AuditLog) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	_, err := mr.database.Collection(mr.config.MongoDB.AuditLogCollection).InsertOne(
+		context.Background(),
+		auditLog,
+	)
+	return err
+}
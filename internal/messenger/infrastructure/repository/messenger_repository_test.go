@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func newTestRepository(mt *mtest.T) *MessengerRepositoryImpl {
+	cfg := &config.Config{}
+	cfg.MongoDB.ChatCollection = "chats"
+	cfg.MongoDB.MessageCollection = "messages"
+	return NewMessengerRepositoryImpl(cfg, mt.DB, &sync.RWMutex{}).(*MessengerRepositoryImpl)
+}
+
+// chatUpdate returns the update DeleteMessage sent to the chats collection
+func chatUpdate(mt *mtest.T) bson.Raw {
+	mt.Helper()
+
+	for {
+		event := mt.GetStartedEvent()
+		if event == nil {
+			mt.Fatal("the chat was not updated")
+		}
+		if event.CommandName == "update" && event.Command.Lookup("update").StringValue() == "chats" {
+			return event.Command.Lookup("updates", "0", "u").Document()
+		}
+	}
+}
+
+func TestDeleteMessageMovesTheLastMessageBack(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("to the newest message left", func(mt *mtest.T) {
+		newest := entity.Message{ChatId: "chat", TicketId: "ticket", MessageId: "earlier", Message: "still here", CreatedAt: time.Now().Add(-time.Minute)}
+		raw, err := bson.Marshal(newest)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		var document bson.D
+		if err = bson.Unmarshal(raw, &document); err != nil {
+			mt.Fatal(err)
+		}
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			mtest.CreateCursorResponse(0, "test.messages", mtest.FirstBatch, document),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		err = newTestRepository(mt).DeleteMessage(&entity.Message{Id: primitive.NewObjectID(), ChatId: "chat", TicketId: "ticket", MessageId: "deleted"})
+		if err != nil {
+			mt.Fatal(err)
+		}
+
+		update := chatUpdate(mt)
+		if got := update.Lookup("$set", "last_message", "message_id").StringValue(); got != "earlier" {
+			mt.Fatalf("expected the last message to be the earlier one, got %q", got)
+		}
+		if got := update.Lookup("$inc", "tickets.$[ticket].message_count").AsInt64(); got != -1 {
+			mt.Fatalf("expected the ticket's message count to drop by one, got %d", got)
+		}
+	})
+
+	mt.Run("away when no message is left", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			mtest.CreateCursorResponse(0, "test.messages", mtest.FirstBatch),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+		)
+
+		err := newTestRepository(mt).DeleteMessage(&entity.Message{ChatId: "chat", TicketId: "ticket", MessageId: "only"})
+		if err != nil {
+			mt.Fatal(err)
+		}
+
+		update := chatUpdate(mt)
+		if _, err = update.LookupErr("$unset", "last_message"); err != nil {
+			mt.Fatalf("expected the last message to be removed, got %s", update)
+		}
+		if _, err = update.LookupErr("$set"); err == nil {
+			mt.Fatalf("nothing should be set, got %s", update)
+		}
+	})
+
+	mt.Run("unless the message is gone already", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}})
+
+		err := newTestRepository(mt).DeleteMessage(&entity.Message{ChatId: "chat", TicketId: "ticket", MessageId: "missing"})
+		if err == nil || err.Error() != "message not found" {
+			mt.Fatalf("expected message not found, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sync"
+	"time"
+)
+
+type presenceKey struct {
+	workspaceId string
+	userId      primitive.ObjectID
+}
+
+type MemoryPresenceStoreImpl struct {
+	sessions  map[presenceKey]map[string]entity.PresenceSession
+	presences map[presenceKey]entity.Presence
+	mu        sync.RWMutex
+}
+
+// NewMemoryPresenceStoreImpl keeps presence in this process, which is enough for a single instance
+func NewMemoryPresenceStoreImpl() infrastructureInterface.PresenceStore {
+	return &MemoryPresenceStoreImpl{
+		sessions:  make(map[presenceKey]map[string]entity.PresenceSession),
+		presences: make(map[presenceKey]entity.Presence),
+	}
+}
+
+func (ms *MemoryPresenceStoreImpl) SaveSession(session *entity.PresenceSession) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	key := presenceKey{workspaceId: session.WorkspaceId, userId: session.UserId}
+	if ms.sessions[key] == nil {
+		ms.sessions[key] = make(map[string]entity.PresenceSession)
+	}
+	ms.sessions[key][session.InstanceId] = *session
+
+	return nil
+}
+
+func (ms *MemoryPresenceStoreImpl) DeleteSession(instanceId, workspaceId string, userId primitive.ObjectID) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	key := presenceKey{workspaceId: workspaceId, userId: userId}
+	delete(ms.sessions[key], instanceId)
+	if len(ms.sessions[key]) == 0 {
+		delete(ms.sessions, key)
+	}
+
+	return nil
+}
+
+func (ms *MemoryPresenceStoreImpl) FindSessions(workspaceId string, userId primitive.ObjectID) ([]entity.PresenceSession, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	now := time.Now()
+	var sessions []entity.PresenceSession
+	for _, session := range ms.sessions[presenceKey{workspaceId: workspaceId, userId: userId}] {
+		if session.ExpiresAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions, nil
+}
+
+func (ms *MemoryPresenceStoreImpl) SwapStatus(workspaceId string, userId primitive.ObjectID, status entity.UserStatus) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	key := presenceKey{workspaceId: workspaceId, userId: userId}
+	if presence, exists := ms.presences[key]; exists && presence.Status == status {
+		return false, nil
+	}
+	ms.presences[key] = entity.Presence{WorkspaceId: workspaceId, UserId: userId, Status: status, UpdatedAt: time.Now()}
+
+	return true, nil
+}
+
+func (ms *MemoryPresenceStoreImpl) FindPresences(workspaceId string) ([]entity.Presence, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var presences []entity.Presence
+	for key, presence := range ms.presences {
+		if key.workspaceId == workspaceId {
+			presences = append(presences, presence)
+		}
+	}
+
+	return presences, nil
+}
+
+func (ms *MemoryPresenceStoreImpl) FindOnlinePresences() ([]entity.Presence, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var presences []entity.Presence
+	for _, presence := range ms.presences {
+		if presence.Status != entity.StatusOffline {
+			presences = append(presences, presence)
+		}
+	}
+
+	return presences, nil
+}
+
+type MongoPresenceStoreImpl struct {
+	database *mongo.Database
+	config   *config.Config
+}
+
+// NewMongoPresenceStoreImpl shares presence between instances, a session left by a crashed instance expires on its own
+func NewMongoPresenceStoreImpl(db *mongo.Database, cfg *config.Config) infrastructureInterface.PresenceStore {
+	return &MongoPresenceStoreImpl{
+		database: db,
+		config:   cfg,
+	}
+}
+
+func (ms *MongoPresenceStoreImpl) SaveSession(session *entity.PresenceSession) error {
+	_, err := ms.database.Collection(ms.config.MongoDB.PresenceSessionCollection).ReplaceOne(
+		context.Background(),
+		bson.M{"instance_id": session.InstanceId, "workspace_id": session.WorkspaceId, "user_id": session.UserId},
+		session,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+func (ms *MongoPresenceStoreImpl) DeleteSession(instanceId, workspaceId string, userId primitive.ObjectID) error {
+	_, err := ms.database.Collection(ms.config.MongoDB.PresenceSessionCollection).DeleteOne(
+		context.Background(),
+		bson.M{"instance_id": instanceId, "workspace_id": workspaceId, "user_id": userId},
+	)
+	return err
+}
+
+func (ms *MongoPresenceStoreImpl) FindSessions(workspaceId string, userId primitive.ObjectID) ([]entity.PresenceSession, error) {
+	cursor, err := ms.database.Collection(ms.config.MongoDB.PresenceSessionCollection).Find(
+		context.Background(),
+		bson.M{"workspace_id": workspaceId, "user_id": userId, "expires_at": bson.M{"$gt": time.Now()}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var sessions []entity.PresenceSession
+	if err = cursor.All(context.Background(), &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// SwapStatus matches only a different status, so when the same one is stored already the upsert
+// runs into the unique index instead of writing
+func (ms *MongoPresenceStoreImpl) SwapStatus(workspaceId string, userId primitive.ObjectID, status entity.UserStatus) (bool, error) {
+	res, err := ms.database.Collection(ms.config.MongoDB.PresenceCollection).UpdateOne(
+		context.Background(),
+		bson.M{"workspace_id": workspaceId, "user_id": userId, "status": bson.M{"$ne": status}},
+		bson.M{"$set": bson.M{"status": status, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return res.ModifiedCount > 0 || res.UpsertedCount > 0, nil
+}
+
+func (ms *MongoPresenceStoreImpl) FindPresences(workspaceId string) ([]entity.Presence, error) {
+	return ms.findPresences(bson.M{"workspace_id": workspaceId})
+}
+
+func (ms *MongoPresenceStoreImpl) FindOnlinePresences() ([]entity.Presence, error) {
+	return ms.findPresences(bson.M{"status": bson.M{"$ne": entity.StatusOffline}})
+}
+
+func (ms *MongoPresenceStoreImpl) findPresences(filter bson.M) ([]entity.Presence, error) {
+	cursor, err := ms.database.Collection(ms.config.MongoDB.PresenceCollection).Find(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var presences []entity.Presence
+	if err = cursor.All(context.Background(), &presences); err != nil {
+		return nil, err
+	}
+
+	return presences, nil
+}
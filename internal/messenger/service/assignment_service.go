@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	_interface "github.com/Point-AI/backend/internal/messenger/domain/interface"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"sync"
+)
+
+type AssignmentServiceImpl struct {
+	messengerRepo infrastructureInterface.MessengerRepository
+	assigners     map[entity.AssignmentStrategy]_interface.Assigner
+	mu            sync.RWMutex
+}
+
+func NewAssignmentServiceImpl(messengerRepo infrastructureInterface.MessengerRepository) _interface.AssignmentService {
+	return &AssignmentServiceImpl{
+		messengerRepo: messengerRepo,
+		assigners: map[entity.AssignmentStrategy]_interface.Assigner{
+			entity.AssignmentLeastOpenTickets: &LeastOpenTicketsAssignerImpl{},
+			entity.AssignmentRoundRobin:       &RoundRobinAssignerImpl{},
+			entity.AssignmentLanguageMatch:    &LanguageMatchAssignerImpl{},
+		},
+	}
+}
+
+func (as *AssignmentServiceImpl) Register(strategy entity.AssignmentStrategy, assigner _interface.Assigner) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.assigners[strategy] = assigner
+}
+
+// AssignAgent picks a team member for a ticket. Members at the ticket cap are skipped, a sticky team keeps the
+// previous agent while they are not offline, and otherwise the strategy chooses among online, then break, then offline members.
+func (as *AssignmentServiceImpl) AssignAgent(team *entity.Team, language entity.ChatLanguage, previousAgentId primitive.ObjectID) (primitive.ObjectID, error) {
+	memberIds := make([]primitive.ObjectID, 0, len(team.Members))
+	for memberId := range team.Members {
+		memberIds = append(memberIds, memberId)
+	}
+	if len(memberIds) == 0 {
+		return primitive.NilObjectID, errors.New("no suitable team member found")
+	}
+
+	workloads, err := as.messengerRepo.FindAgentWorkloads(memberIds)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	candidates := make([]entity.AgentWorkload, 0, len(workloads))
+	for _, workload := range workloads {
+		if team.Assignment.MaxOpenTickets > 0 && workload.OpenTickets >= team.Assignment.MaxOpenTickets {
+			continue
+		}
+		candidates = append(candidates, workload)
+	}
+	// a stable order keeps round-robin fair and ties predictable
+	slices.SortFunc(candidates, func(a, b entity.AgentWorkload) int {
+		return slices.Compare(a.UserId[:], b.UserId[:])
+	})
+
+	if team.Assignment.Sticky && !previousAgentId.IsZero() {
+		for _, candidate := range candidates {
+			if candidate.UserId == previousAgentId && candidate.Status != entity.StatusOffline {
+				return previousAgentId, nil
+			}
+		}
+	}
+
+	assigner := as.getAssigner(team.Assignment.Strategy)
+	for _, status := range []entity.UserStatus{entity.StatusAvailable, entity.StatusBusy, entity.StatusOffline} {
+		var tier []entity.AgentWorkload
+		for _, candidate := range candidates {
+			if candidate.Status == status {
+				tier = append(tier, candidate)
+			}
+		}
+		if len(tier) == 0 {
+			continue
+		}
+
+		assigneeId := assigner.Pick(team, language, tier)
+		if err = as.messengerRepo.UpdateTeamLastAssignee(team.Id, assigneeId); err != nil {
+			return primitive.NilObjectID, err
+		}
+		team.Assignment.LastAssigneeId = assigneeId
+
+		return assigneeId, nil
+	}
+
+	return primitive.NilObjectID, errors.New("no suitable team member found")
+}
+
+func (as *AssignmentServiceImpl) getAssigner(strategy entity.AssignmentStrategy) _interface.Assigner {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	if assigner, ok := as.assigners[strategy]; ok {
+		return assigner
+	}
+	return as.assigners[entity.AssignmentLeastOpenTickets]
+}
+
+type LeastOpenTicketsAssignerImpl struct{}
+
+func (la *LeastOpenTicketsAssignerImpl) Pick(team *entity.Team, language entity.ChatLanguage, candidates []entity.AgentWorkload) primitive.ObjectID {
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if candidate.OpenTickets < best.OpenTickets {
+			best = candidate
+		}
+	}
+
+	return best.UserId
+}
+
+type RoundRobinAssignerImpl struct{}
+
+// Pick takes the first candidate after the team's last assignee, wrapping around to the start
+func (ra *RoundRobinAssignerImpl) Pick(team *entity.Team, language entity.ChatLanguage, candidates []entity.AgentWorkload) primitive.ObjectID {
+	last := team.Assignment.LastAssigneeId
+	for _, candidate := range candidates {
+		if slices.Compare(candidate.UserId[:], last[:]) > 0 {
+			return candidate.UserId
+		}
+	}
+
+	return candidates[0].UserId
+}
+
+type LanguageMatchAssignerImpl struct{}
+
+// Pick prefers the least busy candidate speaking the chat's language and falls back to the least busy one overall
+func (lm *LanguageMatchAssignerImpl) Pick(team *entity.Team, language entity.ChatLanguage, candidates []entity.AgentWorkload) primitive.ObjectID {
+	var speakers []entity.AgentWorkload
+	for _, candidate := range candidates {
+		if slices.Contains(team.Assignment.Languages[candidate.UserId], language) {
+			speakers = append(speakers, candidate)
+		}
+	}
+
+	if len(speakers) == 0 {
+		speakers = candidates
+	}
+
+	return (&LeastOpenTicketsAssignerImpl{}).Pick(team, language, speakers)
+}
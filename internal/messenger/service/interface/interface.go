@@ -2,6 +2,7 @@ package infrastructureInterface
 
 import (
 	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	infrastructureModel "github.com/Point-AI/backend/internal/messenger/infrastructure/model"
 	"github.com/celestix/gotgproto/ext"
 	"go.mongodb.org/mongo-driver/mongo"
 	"time"
@@ -11,12 +12,27 @@ import (
 )
 
 type TelegramBotClientManager interface {
-	RegisterNewBot(botToken string) error
+	RegisterNewBot(botToken, webhookSecret string) error
 	DeleteWebhook(botToken string) error
-	SendTextMessage(botToken string, chatID int64, messageText string) error
+	SendTextMessage(botToken string, chatID int64, messageText string) (int, error)
 	HandleFileMessage(botToken, fileId string) ([]byte, error)
+	GetUserAvatar(botToken string, userId int) ([]byte, error)
+	DeleteMessage(botToken string, chatID int64, messageID int) error
 	//SendTyping(chatID int, botToken string) error
-	//DeleteMessage(botToken string, chatID int, messageID int) error
+}
+
+// ChannelAdapter hides the messaging source a chat belongs to
+type ChannelAdapter interface {
+	ReceiveMessages(accountId string, payload []byte) ([]infrastructureModel.InboundMessage, error)
+	SendTextMessage(workspace *entity.Workspace, chat *entity.Chat, message string) (string, error)
+	DownloadMedia(workspace *entity.Workspace, chat *entity.Chat, fileId string) ([]byte, error)
+	GetAvatar(workspace *entity.Workspace, chat *entity.Chat) ([]byte, error)
+	DeleteMessage(workspace *entity.Workspace, chat *entity.Chat, externalMessageId string) error
+}
+
+type ChannelRegistry interface {
+	Register(source entity.ChatSource, adapter ChannelAdapter)
+	Get(source entity.ChatSource) (ChannelAdapter, error)
 }
 
 type TelegramClientManager interface {
@@ -31,13 +47,54 @@ type TelegramClientManager interface {
 }
 
 type WhatsAppClientManager interface {
+	VerifyWebhook(mode, verifyToken, challenge string) (string, error)
+	ValidateSignature(payload []byte, signature string) bool
+	GetPhoneNumber(phoneNumberId, accessToken string) (string, error)
+	SendTextMessage(phoneNumberId, accessToken, to, text string) (string, error)
+	SendTemplateMessage(phoneNumberId, accessToken, to, templateName, languageCode string, parameters []string) (string, error)
+	DownloadMedia(accessToken, mediaId string) ([]byte, error)
+}
+
+type MetaClientManager interface {
+	VerifyWebhook(mode, verifyToken, challenge string) (string, error)
+	ValidateSignature(payload []byte, signature string) bool
+	SendTextMessage(pageAccessToken, recipientId, text string) (string, error)
+	GetProfilePicture(pageAccessToken, userId string) ([]byte, error)
+	DownloadMedia(url string) ([]byte, error)
+}
+
+// BroadcastBus carries websocket events between instances. Every subscriber gets every broadcast,
+// including the ones its own instance published. Publish numbers the broadcast within its workspace.
+type BroadcastBus interface {
+	Publish(broadcast *entity.Broadcast) error
+	Subscribe(handler func(broadcast *entity.Broadcast))
+	// Replay returns the kept broadcasts after the sequence, oldest first, and whether none of them is missing
+	Replay(workspaceId string, afterSequence int64) ([]*entity.Broadcast, bool, error)
+	SaveCursor(cursor *entity.EventCursor) error
+	FindCursor(workspaceId string, userId primitive.ObjectID) (*entity.EventCursor, error)
+}
+
+// PresenceStore shares the sessions of every instance, so presence covers agents connected anywhere
+type PresenceStore interface {
+	SaveSession(session *entity.PresenceSession) error
+	DeleteSession(instanceId, workspaceId string, userId primitive.ObjectID) error
+	// FindSessions returns the unexpired sessions of the user on any instance
+	FindSessions(workspaceId string, userId primitive.ObjectID) ([]entity.PresenceSession, error)
+	// SwapStatus stores the announced status and reports whether it differs from the one stored before
+	SwapStatus(workspaceId string, userId primitive.ObjectID, status entity.UserStatus) (bool, error)
+	FindPresences(workspaceId string) ([]entity.Presence, error)
+	// FindOnlinePresences returns every presence in all workspaces that is not offline
+	FindOnlinePresences() ([]entity.Presence, error)
 }
 
 type MessengerRepository interface {
 	FindWorkspaceByWorkspaceId(ctx mongo.SessionContext, workspaceId string) (*entity.Workspace, error)
 	CheckBotExists(botToken string) (bool, error)
+	CheckInboundMessageExists(source entity.ChatSource, accountId, chatId, externalId string) (bool, error)
+	CheckWhatsAppNumberExists(phoneNumberId string) (bool, error)
 	UpdateWorkspace(workspace *entity.Workspace) error
 	FindWorkspaceByTelegramBotToken(botToken string) (*entity.Workspace, error)
+	FindWorkspaceByTelegramWebhookSecret(webhookSecret string) (*entity.Workspace, error)
 	FindUserByEmail(ctx mongo.SessionContext, email string) (primitive.ObjectID, error)
 	GetAllWorkspaceRepositories() ([]*entity.Workspace, error)
 	FindWorkspaceByPhoneNumber(phoneNumber string) (*entity.Workspace, error)
@@ -50,15 +107,37 @@ type MessengerRepository interface {
 	FindWorkspaceById(id primitive.ObjectID) (*entity.Workspace, error)
 	FindChatByUserId(ctx mongo.SessionContext, tgClientId int, workspaceId, assigneeId primitive.ObjectID) (*entity.Chat, error)
 	InsertNewChat(ctx mongo.SessionContext, chat *entity.Chat) error
-	CountActiveTickets(memberId primitive.ObjectID) (int, error)
+	FindAgentWorkloads(memberIds []primitive.ObjectID) ([]entity.AgentWorkload, error)
+	UpdateTeamLastAssignee(teamId, assigneeId primitive.ObjectID) error
 	StartSession() (mongo.Session, error)
 	FindChatByChatId(chatId string) (*entity.Chat, error)
 	FindLatestChatsByWorkspaceId(workspaceId primitive.ObjectID, n int) ([]entity.Chat, error)
 	FindLatestChatsByWorkspaceIdAndAllTags(workspaceId primitive.ObjectID, tags []string, chatNumber int) ([]entity.Chat, error)
 	FindLatestTicketsByChatIdBeforeDate(workspaceId primitive.ObjectID, chatId string, beforeDate time.Time) ([]entity.Ticket, error)
+	AppendMessage(ctx mongo.SessionContext, message *entity.Message) error
+	FindMessageByChatIdAndMessageId(chatId, messageId string) (*entity.Message, error)
+	UpdateMessageDelivery(message *entity.Message) error
+	DeleteMessage(message *entity.Message) error
+	UpdateMessagesChatIdByTicketId(ctx mongo.SessionContext, ticketId, chatId string) error
+	SearchChats(workspaceId primitive.ObjectID, query string, filter entity.SearchFilter, limit int) ([]entity.ChatSearchHit, error)
+	SearchMessages(workspaceId primitive.ObjectID, query string, filter entity.SearchFilter, limit int) ([]entity.MessageSearchHit, error)
+	FindChatHistory(workspaceId primitive.ObjectID, chatId string, cursorTime time.Time, cursorId string, limit int, after bool) ([]entity.ChatHistoryEntry, error)
 	FindUniqueTagsByWorkspaceId(workspaceId primitive.ObjectID) ([]string, error)
 	FindTeamByWorkspaceIdAndTeamId(workspaceId primitive.ObjectID, teamId string) (*entity.Team, error)
+	FindFirstTeamByWorkspaceId(workspaceId primitive.ObjectID) (*entity.Team, error)
+	FindChatByWorkspaceIdAndExternalId(ctx mongo.SessionContext, workspaceId primitive.ObjectID, source entity.ChatSource, accountId, externalId string) (*entity.Chat, error)
+	FindWorkspaceByChannelAccount(source entity.ChatSource, accountId string) (*entity.Workspace, error)
 	FindUserById(id primitive.ObjectID) (*entity.User, error)
 	FindLatestChatsByWorkspaceIdAndUserId(workspaceId primitive.ObjectID, userId primitive.ObjectID, n int) ([]entity.Chat, error)
 	FindLatestUnassignedChatsByWorkspaceId(workspaceId primitive.ObjectID, n int) ([]entity.Chat, error)
+	FindTeamById(id primitive.ObjectID) (*entity.Team, error)
+	InsertAuditLog(auditLog *entity.AuditLog) error
+	InsertSLAPolicy(policy *entity.SLAPolicy) error
+	UpdateSLAPolicy(policy *entity.SLAPolicy) error
+	DeleteSLAPolicy(workspaceId primitive.ObjectID, policyId string) error
+	FindSLAPolicyByPolicyId(policyId string) (*entity.SLAPolicy, error)
+	FindSLAPoliciesByWorkspaceId(workspaceId primitive.ObjectID) ([]entity.SLAPolicy, error)
+	FindChatsWithRunningSLA() ([]entity.Chat, error)
+	UpdateTicketSLAStatus(ticketId string, from, to entity.SLAStatus) (bool, error)
+	FindSavedRepliesByShortcut(workspaceId primitive.ObjectID, shortcut string) ([]entity.SavedReply, error)
 }
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,34 +10,59 @@ import (
 	"github.com/Point-AI/backend/internal/messenger/delivery/model"
 	"github.com/Point-AI/backend/internal/messenger/domain/entity"
 	_interface "github.com/Point-AI/backend/internal/messenger/domain/interface"
+	infrastructureModel "github.com/Point-AI/backend/internal/messenger/infrastructure/model"
 	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
-	"github.com/go-resty/resty/v2"
+	"github.com/Point-AI/backend/utils"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"html"
 	"log"
 	"net/http"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	defaultMessagesLimit = 30
+	maxMessagesLimit     = 100
+	defaultSearchLimit   = 20
+	maxSearchLimit       = 50
+	snippetRadius        = 60
+)
+
 type MessengerServiceImpl struct {
-	messengerRepo    infrastructureInterface.MessengerRepository
-	websocketService _interface.WebsocketService
-	fileService      _interface.FileService
-	config           *config.Config
+	messengerRepo     infrastructureInterface.MessengerRepository
+	websocketService  _interface.WebsocketService
+	presenceService   _interface.PresenceService
+	fileService       _interface.FileService
+	telegramBotClient infrastructureInterface.TelegramBotClientManager
+	whatsAppClient    infrastructureInterface.WhatsAppClientManager
+	metaClient        infrastructureInterface.MetaClientManager
+	channelRegistry   infrastructureInterface.ChannelRegistry
+	assignmentService _interface.AssignmentService
+	config            *config.Config
 }
 
-func NewMessengerServiceImpl(cfg *config.Config, messengerRepo infrastructureInterface.MessengerRepository, websocketService _interface.WebsocketService, fileService _interface.FileService) _interface.MessengerService {
+func NewMessengerServiceImpl(cfg *config.Config, messengerRepo infrastructureInterface.MessengerRepository, websocketService _interface.WebsocketService, presenceService _interface.PresenceService, fileService _interface.FileService, telegramBotClient infrastructureInterface.TelegramBotClientManager, whatsAppClient infrastructureInterface.WhatsAppClientManager, metaClient infrastructureInterface.MetaClientManager, channelRegistry infrastructureInterface.ChannelRegistry, assignmentService _interface.AssignmentService) _interface.MessengerService {
 	return &MessengerServiceImpl{
-		messengerRepo:    messengerRepo,
-		websocketService: websocketService,
-		fileService:      fileService,
-		config:           cfg,
+		messengerRepo:     messengerRepo,
+		websocketService:  websocketService,
+		presenceService:   presenceService,
+		fileService:       fileService,
+		telegramBotClient: telegramBotClient,
+		whatsAppClient:    whatsAppClient,
+		metaClient:        metaClient,
+		channelRegistry:   channelRegistry,
+		assignmentService: assignmentService,
+		config:            cfg,
 	}
 }
 
-func (ms *MessengerServiceImpl) ReassignTicketToTeam(userId primitive.ObjectID, chatId string, ticketId, workspaceId, teamId string) error {
+func (ms *MessengerServiceImpl) ReassignTicketToTeam(userId primitive.ObjectID, chatId string, ticketId, workspaceId, teamId string, meta entity.AuditMeta) error {
 	session, err := ms.messengerRepo.StartSession()
 	if err != nil {
 		return err
@@ -48,50 +74,74 @@ func (ms *MessengerServiceImpl) ReassignTicketToTeam(userId primitive.ObjectID,
 		return err
 	}
 
+	var auditLog *entity.AuditLog
+	var assignment model.TicketAssignmentResponse
 	err = mongo.WithSession(context.Background(), session, func(sc mongo.SessionContext) error {
 		originalChat, err := ms.messengerRepo.FindChatByTicketId(sc, ticketId)
 		if err != nil {
 			return err
 		}
 
-		ticketToMove, err := ms.findTicketInChat(originalChat, ticketId)
+		workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(sc, workspaceId)
 		if err != nil {
 			return err
 		}
 
-		if len(originalChat.Tickets) == 0 {
-			if err := ms.messengerRepo.DeleteChat(sc, originalChat.Id); err != nil {
-				return err
-			}
-		} else {
-			if err := ms.messengerRepo.UpdateChat(sc, originalChat); err != nil {
-				return err
-			}
+		if err = ms.authorizeChat(workspace, userId, originalChat, utils.PermissionTicketReassign); err != nil {
+			return err
 		}
 
-		workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(sc, workspaceId)
+		ticketToMove, err := ms.takeTicketFromChat(sc, originalChat, ticketId)
 		if err != nil {
 			return err
 		}
 
-		if err = ms.ValidateUserInWorkspace(userId, workspace); err != nil {
+		team, err := ms.messengerRepo.FindTeamByWorkspaceIdAndTeamId(workspace.Id, teamId)
+		if err != nil {
 			return err
 		}
 
-		assigneeId, err := ms.getAssigneeIdByTeam(workspace, teamId)
+		assigneeId, err := ms.assignmentService.AssignAgent(team, originalChat.Language, primitive.NilObjectID)
 		if err != nil {
 			return err
 		}
 
+		var previousTeamName string
+		if previousTeam, _ := ms.messengerRepo.FindTeamById(originalChat.TeamId); previousTeam != nil {
+			previousTeamName = previousTeam.TeamName
+		}
+		auditLog = ms.createAuditLog(workspace.Id, userId, entity.AuditTicketReassignedTeam, "ticket", ticketId, meta,
+			entity.AuditChange{Field: "team", Before: previousTeamName, After: team.TeamName},
+			entity.AuditChange{Field: "assignee", Before: ms.findUserEmail(originalChat.UserId), After: ms.findUserEmail(assigneeId)},
+		)
+
+		assignment = model.TicketAssignmentResponse{
+			WorkspaceId:    workspaceId,
+			TicketId:       ticketId,
+			PreviousChatId: originalChat.ChatId,
+			TeamId:         team.TeamId,
+			AssigneeId:     assigneeId.Hex(),
+		}
+
 		chat, err := ms.messengerRepo.FindChatByUserId(sc, originalChat.TgClientId, workspace.Id, assigneeId)
 		if err != nil {
 			return err
 		} else if chat == nil {
-			newChat := ms.createChat(originalChat.TgChatId, originalChat.TgClientId, originalChat.Source, *ticketToMove, workspace.Id, originalChat.UserId, originalChat.TeamId, originalChat.IsImported, originalChat.LastMessage, originalChat.Name, originalChat.Company, originalChat.ClientEmail, originalChat.ClientPhone, originalChat.Address)
-			return ms.messengerRepo.InsertNewChat(sc, newChat)
+			newChat := ms.createChat(originalChat.TgChatId, originalChat.TgClientId, originalChat.Source, ticketToMove, workspace.Id, assigneeId, team.Id, originalChat.IsImported, originalChat.LastMessage, originalChat.Name, originalChat.Company, originalChat.ClientEmail, originalChat.ClientPhone, originalChat.Address)
+			newChat.AccountId, newChat.ExternalId = originalChat.AccountId, originalChat.ExternalId
+			if err = ms.messengerRepo.InsertNewChat(sc, newChat); err != nil {
+				return err
+			}
+			assignment.ChatId = newChat.ChatId
+			return ms.messengerRepo.UpdateMessagesChatIdByTicketId(sc, ticketId, newChat.ChatId)
 		} else if chat != nil {
-			chat.Tickets = append(chat.Tickets, *ticketToMove)
-			return ms.messengerRepo.UpdateChat(sc, chat)
+			chat.Tickets = append(chat.Tickets, ticketToMove)
+			chat.TeamId = team.Id
+			if err = ms.messengerRepo.UpdateChat(sc, chat); err != nil {
+				return err
+			}
+			assignment.ChatId = chat.ChatId
+			return ms.messengerRepo.UpdateMessagesChatIdByTicketId(sc, ticketId, chat.ChatId)
 		}
 
 		return nil
@@ -101,10 +151,16 @@ func (ms *MessengerServiceImpl) ReassignTicketToTeam(userId primitive.ObjectID,
 		_ = session.AbortTransaction(context.Background())
 		return err
 	}
-	return session.CommitTransaction(context.Background())
+	if err = session.CommitTransaction(context.Background()); err != nil {
+		return err
+	}
+
+	ms.recordAudit(auditLog)
+	ms.websocketService.SendToAll(workspaceId, entity.EventTicketAssigned, assignment)
+	return nil
 }
 
-func (ms *MessengerServiceImpl) ReassignTicketToUser(userId primitive.ObjectID, chatId string, ticketId, workspaceId, email string) error {
+func (ms *MessengerServiceImpl) ReassignTicketToUser(userId primitive.ObjectID, chatId string, ticketId, workspaceId, email string, meta entity.AuditMeta) error {
 	session, err := ms.messengerRepo.StartSession()
 	if err != nil {
 		return err
@@ -116,33 +172,25 @@ func (ms *MessengerServiceImpl) ReassignTicketToUser(userId primitive.ObjectID,
 		return err
 	}
 
+	var auditLog *entity.AuditLog
+	var assignment model.TicketAssignmentResponse
 	err = mongo.WithSession(context.Background(), session, func(sc mongo.SessionContext) error {
 		originalChat, err := ms.messengerRepo.FindChatByTicketId(sc, ticketId)
 		if err != nil {
 			return err
 		}
 
-		ticketToMove, err := ms.findTicketInChat(originalChat, ticketId)
+		workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(sc, workspaceId)
 		if err != nil {
 			return err
 		}
 
-		if len(originalChat.Tickets) == 0 {
-			if err := ms.messengerRepo.DeleteChat(sc, originalChat.Id); err != nil {
-				return err
-			}
-		} else {
-			if err := ms.messengerRepo.UpdateChat(sc, originalChat); err != nil {
-				return err
-			}
-		}
-
-		workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(sc, workspaceId)
-		if err != nil {
+		if err = ms.authorizeChat(workspace, userId, originalChat, utils.PermissionTicketReassign); err != nil {
 			return err
 		}
 
-		if err = ms.ValidateUserInWorkspace(userId, workspace); err != nil {
+		ticketToMove, err := ms.takeTicketFromChat(sc, originalChat, ticketId)
+		if err != nil {
 			return err
 		}
 
@@ -151,27 +199,58 @@ func (ms *MessengerServiceImpl) ReassignTicketToUser(userId primitive.ObjectID,
 			return err
 		}
 
+		if _, isMember := workspace.Team[reassignUserId]; !isMember {
+			return errors.New("user is not a member of this workspace")
+		}
+
+		auditLog = ms.createAuditLog(workspace.Id, userId, entity.AuditTicketReassignedUser, "ticket", ticketId, meta,
+			entity.AuditChange{Field: "assignee", Before: ms.findUserEmail(originalChat.UserId), After: email},
+		)
+
 		chat, err := ms.messengerRepo.FindChatByUserId(sc, originalChat.TgClientId, workspace.Id, reassignUserId)
 		if err != nil {
 			return err
-		} else if chat == nil && err == nil {
-			newChat := ms.createChat(originalChat.TgChatId, originalChat.TgClientId, originalChat.Source, *ticketToMove, workspace.Id, originalChat.UserId, originalChat.TeamId, originalChat.IsImported, originalChat.LastMessage, originalChat.Name, originalChat.Company, originalChat.ClientEmail, originalChat.ClientPhone, originalChat.Address)
+		}
+		if chat == nil {
+			chat = ms.createChat(originalChat.TgChatId, originalChat.TgClientId, originalChat.Source, ticketToMove, workspace.Id, reassignUserId, originalChat.TeamId, originalChat.IsImported, originalChat.LastMessage, originalChat.Name, originalChat.Company, originalChat.ClientEmail, originalChat.ClientPhone, originalChat.Address)
+			chat.AccountId, chat.ExternalId = originalChat.AccountId, originalChat.ExternalId
+			err = ms.messengerRepo.InsertNewChat(sc, chat)
+		} else {
+			chat.Tickets = append(chat.Tickets, ticketToMove)
+			err = ms.messengerRepo.UpdateChat(sc, chat)
+		}
+		if err != nil {
+			return err
+		}
 
-			return ms.messengerRepo.InsertNewChat(sc, newChat)
-		} else if chat != nil {
-			chat.Tickets = append(chat.Tickets, *ticketToMove)
-			return ms.messengerRepo.UpdateChat(sc, chat)
+		// the event reports the chat as saved, so clients see the same assignee and team as a reload would
+		assignment = model.TicketAssignmentResponse{
+			WorkspaceId:    workspaceId,
+			TicketId:       ticketId,
+			PreviousChatId: originalChat.ChatId,
+			ChatId:         chat.ChatId,
+			AssigneeId:     chat.UserId.Hex(),
+		}
+		if !chat.TeamId.IsZero() {
+			if team, _ := ms.messengerRepo.FindTeamById(chat.TeamId); team != nil {
+				assignment.TeamId = team.TeamId
+			}
 		}
 
-		return nil
+		return ms.messengerRepo.UpdateMessagesChatIdByTicketId(sc, ticketId, chat.ChatId)
 	})
 
 	if err != nil {
 		_ = session.AbortTransaction(context.Background())
 		return err
 	}
-	err = session.CommitTransaction(context.Background())
-	return err
+	if err = session.CommitTransaction(context.Background()); err != nil {
+		return err
+	}
+
+	ms.recordAudit(auditLog)
+	ms.websocketService.SendToAll(workspaceId, entity.EventTicketAssigned, assignment)
+	return nil
 }
 
 func (ms *MessengerServiceImpl) GetAllChats(userId primitive.ObjectID, workspaceId string) ([]model.ChatResponse, error) {
@@ -180,9 +259,10 @@ func (ms *MessengerServiceImpl) GetAllChats(userId primitive.ObjectID, workspace
 		return nil, err
 	}
 
-	if _, ok := workspace.Team[userId]; !ok {
-		return nil, errors.New("unauthorised")
+	if err = ms.authorize(workspace, userId, utils.PermissionChatReadAll); err != nil {
+		return nil, err
 	}
+
 	chats, err := ms.messengerRepo.FindLatestChatsByWorkspaceId(workspace.Id, 50)
 	if err != nil {
 		return nil, err
@@ -192,13 +272,13 @@ func (ms *MessengerServiceImpl) GetAllChats(userId primitive.ObjectID, workspace
 
 	for _, chat := range chats {
 		if chat.IsImported {
-			go ms.updateWallpaper(workspaceId, chat.ChatId, chat.TgChatId)
+			go ms.updateWallpaper(workspace, &chat)
 		}
 		totalTickets, averageSolutionTime, averageNumberOfMessagesPerTicket := ms.extractTicketData(chat.Tickets)
 
 		logo, _ := ms.fileService.LoadFile("chat." + chat.ChatId)
 		messageResponse := ms.createMessageResponse(nil, chat.LastMessage.CreatedAt, userId == chat.LastMessage.SenderId, chat.LastMessage.From, "", workspaceId, chat.Tickets[0].TicketId, chat.ChatId, chat.LastMessage.MessageId, chat.LastMessage.Message, string(chat.LastMessage.Type))
-		responseChats = append(responseChats, *ms.createChatResponse(workspace.WorkspaceId, chat.ChatId, chat.TgClientId, chat.TgChatId, chat.Tags, *messageResponse, string(entity.SourceTelegram), chat.IsImported, chat.CreatedAt, chat.Name, logo, nil, string(chat.Language), chat.Company, chat.ClientEmail, chat.ClientPhone, chat.Address, totalTickets, averageSolutionTime, averageNumberOfMessagesPerTicket))
+		responseChats = append(responseChats, *ms.createChatResponse(workspace.WorkspaceId, chat.ChatId, chat.TgClientId, chat.TgChatId, chat.Tags, *messageResponse, string(chat.Source), chat.IsImported, chat.CreatedAt, chat.Name, logo, nil, string(chat.Language), chat.Company, chat.ClientEmail, chat.ClientPhone, chat.Address, totalTickets, averageSolutionTime, averageNumberOfMessagesPerTicket))
 	}
 
 	return responseChats, nil
@@ -210,9 +290,10 @@ func (ms *MessengerServiceImpl) GetAllUnassignedChats(userId primitive.ObjectID,
 		return nil, err
 	}
 
-	if _, ok := workspace.Team[userId]; !ok {
-		return nil, errors.New("unauthorised")
+	if err = ms.authorize(workspace, userId, utils.PermissionChatReadAll); err != nil {
+		return nil, err
 	}
+
 	chats, err := ms.messengerRepo.FindLatestUnassignedChatsByWorkspaceId(workspace.Id, 50)
 	if err != nil {
 		return nil, err
@@ -228,7 +309,7 @@ func (ms *MessengerServiceImpl) GetAllUnassignedChats(userId primitive.ObjectID,
 
 		logo, _ := ms.fileService.LoadFile("chat." + chat.ChatId)
 		messageResponse := ms.createMessageResponse(nil, chat.LastMessage.CreatedAt, false, chat.LastMessage.From, "", workspaceId, chat.Tickets[0].TicketId, chat.ChatId, chat.LastMessage.MessageId, chat.LastMessage.Message, string(chat.LastMessage.Type))
-		responseChats = append(responseChats, *ms.createChatResponse(workspace.WorkspaceId, chat.ChatId, chat.TgClientId, chat.TgChatId, chat.Tags, *messageResponse, string(entity.SourceTelegram), chat.IsImported, chat.CreatedAt, chat.Name, logo, nil, string(chat.Language), chat.Company, chat.ClientEmail, chat.ClientPhone, chat.Address, totalTickets, averageSolutionTime, averageNumberOfMessagesPerTicket))
+		responseChats = append(responseChats, *ms.createChatResponse(workspace.WorkspaceId, chat.ChatId, chat.TgClientId, chat.TgChatId, chat.Tags, *messageResponse, string(chat.Source), chat.IsImported, chat.CreatedAt, chat.Name, logo, nil, string(chat.Language), chat.Company, chat.ClientEmail, chat.ClientPhone, chat.Address, totalTickets, averageSolutionTime, averageNumberOfMessagesPerTicket))
 	}
 
 	return responseChats, nil
@@ -240,9 +321,10 @@ func (ms *MessengerServiceImpl) GetAllPrimaryChats(userId primitive.ObjectID, wo
 		return nil, err
 	}
 
-	if _, ok := workspace.Team[userId]; !ok {
-		return nil, errors.New("unauthorised")
+	if err = ms.authorize(workspace, userId, utils.PermissionChatRead); err != nil {
+		return nil, err
 	}
+
 	chats, err := ms.messengerRepo.FindLatestChatsByWorkspaceIdAndUserId(workspace.Id, userId, 50)
 	if err != nil {
 		return nil, err
@@ -258,53 +340,72 @@ func (ms *MessengerServiceImpl) GetAllPrimaryChats(userId primitive.ObjectID, wo
 
 		logo, _ := ms.fileService.LoadFile("chat." + chat.ChatId)
 		messageResponse := ms.createMessageResponse(nil, chat.LastMessage.CreatedAt, true, chat.LastMessage.From, "", workspaceId, chat.Tickets[0].TicketId, chat.ChatId, chat.LastMessage.MessageId, chat.LastMessage.Message, string(chat.LastMessage.Type))
-		responseChats = append(responseChats, *ms.createChatResponse(workspace.WorkspaceId, chat.ChatId, chat.TgClientId, chat.TgChatId, chat.Tags, *messageResponse, string(entity.SourceTelegram), chat.IsImported, chat.CreatedAt, chat.Name, logo, nil, string(chat.Language), chat.Company, chat.ClientEmail, chat.ClientPhone, chat.Address, totalTickets, averageSolutionTime, averageNumberOfMessagesPerTicket))
+		responseChats = append(responseChats, *ms.createChatResponse(workspace.WorkspaceId, chat.ChatId, chat.TgClientId, chat.TgChatId, chat.Tags, *messageResponse, string(chat.Source), chat.IsImported, chat.CreatedAt, chat.Name, logo, nil, string(chat.Language), chat.Company, chat.ClientEmail, chat.ClientPhone, chat.Address, totalTickets, averageSolutionTime, averageNumberOfMessagesPerTicket))
 	}
 
 	return responseChats, nil
 }
 
-func (ms *MessengerServiceImpl) UpdateTicketStatus(userId primitive.ObjectID, ticketId, workspaceId, status string) error {
+func (ms *MessengerServiceImpl) UpdateTicketStatus(userId primitive.ObjectID, ticketId, workspaceId, status string, meta entity.AuditMeta) error {
 	workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
 	if err != nil {
 		return err
 	}
 
-	if _, ok := workspace.Team[userId]; !ok {
-		return errors.New("unauthorised")
-	}
-
 	chat, err := ms.messengerRepo.FindChatByTicketId(nil, ticketId)
 	if err != nil {
 		return err
 	}
 
+	if err = ms.authorizeChat(workspace, userId, chat, utils.PermissionTicketStatus); err != nil {
+		return err
+	}
+
 	fmtdStatus, err := ms.validateTicketStatus(status)
 	if err != nil {
 		return err
 	}
 
-	found := false
+	index := -1
 	for i, ticket := range chat.Tickets {
 		if ticket.TicketId == ticketId {
-			chat.Tickets[i].Status = fmtdStatus
-			found = true
+			index = i
 			break
 		}
 	}
-	if !found {
+	if index == -1 {
 		return errors.New("ticket not found")
 	}
 
-	return ms.messengerRepo.UpdateChat(nil, chat)
-}
+	ticket := &chat.Tickets[index]
+	previousStatus := ticket.Status
+	if fmtdStatus == entity.StatusClosed && ticket.Status != entity.StatusClosed {
+		ticket.ResolvedAt = time.Now()
+		ms.resolveSLA(ticket)
+	}
+	ticket.Status = fmtdStatus
 
-func (ms *MessengerServiceImpl) ValidateUserInWorkspace(userId primitive.ObjectID, workspace *entity.Workspace) error {
-	if _, exists := workspace.Team[userId]; exists {
-		return nil
+	if err = ms.messengerRepo.UpdateChat(nil, chat); err != nil {
+		return err
+	}
+
+	if previousStatus != fmtdStatus {
+		ms.recordAudit(ms.createAuditLog(workspace.Id, userId, entity.AuditTicketStatusChanged, "ticket", ticketId, meta,
+			entity.AuditChange{Field: "status", Before: string(previousStatus), After: string(fmtdStatus)},
+		))
+		ms.websocketService.SendToAll(workspaceId, entity.EventTicketStatusChanged, model.TicketStatusResponse{
+			WorkspaceId:    workspaceId,
+			ChatId:         chat.ChatId,
+			TicketId:       ticketId,
+			Status:         string(fmtdStatus),
+			PreviousStatus: string(previousStatus),
+		})
 	}
+	return nil
+}
 
-	return errors.New("user does not have the permissions")
+func (ms *MessengerServiceImpl) ValidateUserInWorkspace(userId primitive.ObjectID, workspace *entity.Workspace) error {
+	return ms.authorize(workspace, userId, utils.PermissionWorkspaceRead)
 }
 
 func (ms *MessengerServiceImpl) ImportTelegramChats(workspaceId string, chats []model.TelegramChat) error {
@@ -315,64 +416,389 @@ func (ms *MessengerServiceImpl) ImportTelegramChats(workspaceId string, chats []
 
 	for _, chat := range chats {
 		message := ms.createMessage(primitive.ObjectID{}, chat.LastMessage.Id, chat.LastMessage.Text, chat.Title, entity.TypeText, time.Now())
-		ticket := ms.createTicket([]entity.Note{}, []entity.Message{*message}, time.Now())
+		ticket := ms.createTicket([]entity.Note{}, time.Now())
 		newChat := ms.createChat(int(chat.Id), int(chat.LastMessage.SenderId), entity.SourceTelegram, *ticket, workspace.Id, primitive.NilObjectID, primitive.NilObjectID, true, *message, chat.Name, "", "", "", "")
-		go ms.updateWallpaper(workspaceId, newChat.ChatId, int(chat.Id))
+		newChat.AccountId = workspaceId
+		newChat.ExternalId = strconv.FormatInt(chat.Id, 10)
+		go ms.updateWallpaper(workspace, newChat)
 
 		err := ms.messengerRepo.InsertNewChat(nil, newChat)
 		if err != nil {
 			return err
 		}
+
+		message.WorkspaceId, message.ChatId, message.TicketId = workspace.Id, newChat.ChatId, ticket.TicketId
+		if err = ms.messengerRepo.AppendMessage(nil, message); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (ms *MessengerServiceImpl) ValidateUserInWorkspaceById(userId primitive.ObjectID, workspaceId string) error {
+func (ms *MessengerServiceImpl) RegisterTelegramBot(userId primitive.ObjectID, workspaceId, botToken string) error {
 	workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
 	if err != nil {
 		return err
 	}
-	if _, exists := workspace.Team[userId]; exists {
-		return nil
+
+	if err = ms.authorize(workspace, userId, utils.PermissionIntegrationManage); err != nil {
+		return err
+	}
+
+	exists, err := ms.messengerRepo.CheckBotExists(botToken)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("bot is already registered")
+	}
+
+	webhookSecret, err := utils.GenerateToken()
+	if err != nil {
+		return err
 	}
 
-	return errors.New("user does not have the permissions")
+	if err = ms.telegramBotClient.RegisterNewBot(botToken, webhookSecret); err != nil {
+		return err
+	}
+
+	workspace.Integrations.TelegramBot = append(workspace.Integrations.TelegramBot, entity.TelegramBotIntegration{
+		BotToken:      botToken,
+		WebhookSecret: webhookSecret,
+		IsActive:      true,
+		CreatedAt:     time.Now(),
+	})
+
+	return ms.messengerRepo.UpdateWorkspace(workspace)
 }
 
-func (ms *MessengerServiceImpl) HandleChatWS(userId primitive.ObjectID, workspaceId string, w http.ResponseWriter, r *http.Request) error {
+func (ms *MessengerServiceImpl) RegisterWhatsApp(userId primitive.ObjectID, workspaceId, phoneNumberId, accessToken string) error {
+	workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
+	if err != nil {
+		return err
+	}
+
+	if err = ms.authorize(workspace, userId, utils.PermissionIntegrationManage); err != nil {
+		return err
+	}
+
+	exists, err := ms.messengerRepo.CheckWhatsAppNumberExists(phoneNumberId)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("phone number is already registered")
+	}
+
+	phoneNumber, err := ms.whatsAppClient.GetPhoneNumber(phoneNumberId, accessToken)
+	if err != nil {
+		return err
+	}
+
+	workspace.Integrations.WhatsApp = append(workspace.Integrations.WhatsApp, entity.WhatsAppIntegration{
+		PhoneNumberId: phoneNumberId,
+		PhoneNumber:   phoneNumber,
+		AccessToken:   accessToken,
+		IsActive:      true,
+		CreatedAt:     time.Now(),
+	})
+
+	return ms.messengerRepo.UpdateWorkspace(workspace)
+}
+
+// HandleTelegramBotWebhook finds the bot by the secret Telegram sends along, the bot token is the chat account id
+func (ms *MessengerServiceImpl) HandleTelegramBotWebhook(payload []byte, webhookSecret string) error {
+	if webhookSecret == "" {
+		return errors.New("invalid secret token")
+	}
+
+	workspace, err := ms.messengerRepo.FindWorkspaceByTelegramWebhookSecret(webhookSecret)
+	if err != nil {
+		return errors.New("invalid secret token")
+	}
+
+	for _, bot := range workspace.Integrations.TelegramBot {
+		if bot.IsActive && subtle.ConstantTimeCompare([]byte(bot.WebhookSecret), []byte(webhookSecret)) == 1 {
+			return ms.HandleChannelWebhook(entity.SourceTelegramBot, bot.BotToken, payload)
+		}
+	}
+
+	return errors.New("invalid secret token")
+}
+
+func (ms *MessengerServiceImpl) VerifyWhatsAppWebhook(mode, verifyToken, challenge string) (string, error) {
+	return ms.whatsAppClient.VerifyWebhook(mode, verifyToken, challenge)
+}
+
+func (ms *MessengerServiceImpl) HandleWhatsAppWebhook(payload []byte, signature string) error {
+	if !ms.whatsAppClient.ValidateSignature(payload, signature) {
+		return errors.New("invalid signature")
+	}
+
+	return ms.HandleChannelWebhook(entity.SourceWhatsApp, "", payload)
+}
+
+func (ms *MessengerServiceImpl) VerifyMetaWebhook(mode, verifyToken, challenge string) (string, error) {
+	return ms.metaClient.VerifyWebhook(mode, verifyToken, challenge)
+}
+
+func (ms *MessengerServiceImpl) HandleMetaWebhook(payload []byte, signature string) error {
+	if !ms.metaClient.ValidateSignature(payload, signature) {
+		return errors.New("invalid signature")
+	}
+
+	var webhook struct {
+		Object string `json:"object"`
+	}
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return err
+	}
+
+	switch webhook.Object {
+	case "page":
+		return ms.HandleChannelWebhook(entity.SourceMeta, "", payload)
+	case "instagram":
+		return ms.HandleChannelWebhook(entity.SourceInstagram, "", payload)
+	default:
+		return fmt.Errorf("unsupported webhook object: %s", webhook.Object)
+	}
+}
+
+func (ms *MessengerServiceImpl) SendWhatsAppTemplate(userId primitive.ObjectID, workspaceId, chatId, templateName, languageCode string, parameters []string) error {
 	workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
 	if err != nil {
 		return err
 	}
-	if _, exists := workspace.Team[userId]; !exists {
-		return errors.New("user does not have the permissions")
+
+	chat, err := ms.messengerRepo.FindChatByWorkspaceIdAndChatId(workspace.Id, chatId)
+	if err != nil {
+		return err
+	}
+
+	if err = ms.authorizeChat(workspace, userId, chat, utils.PermissionChatReply); err != nil {
+		return err
+	}
+	if chat.Source != entity.SourceWhatsApp {
+		return errors.New("templates are only supported for whatsapp chats")
+	}
+
+	var integration *entity.WhatsAppIntegration
+	for i := range workspace.Integrations.WhatsApp {
+		if workspace.Integrations.WhatsApp[i].PhoneNumberId == chat.AccountId {
+			integration = &workspace.Integrations.WhatsApp[i]
+		}
+	}
+	if integration == nil || !integration.IsActive {
+		return errors.New("whatsapp integration not found")
+	}
+
+	user, err := ms.messengerRepo.GetUserById(userId)
+	if err != nil {
+		return err
+	}
+
+	externalId, err := ms.whatsAppClient.SendTemplateMessage(integration.PhoneNumberId, integration.AccessToken, chat.ExternalId, templateName, languageCode, parameters)
+	if err != nil {
+		return err
+	}
+
+	index := ms.findTicketIndexInChat(chat, "")
+	if index == -1 {
+		return errors.New("ticket not found")
 	}
 
-	if err = ms.ValidateUserInWorkspaceById(userId, workspaceId); err != nil {
+	newMessage := ms.createMessage(userId, 0, templateName, user.FullName, entity.TypeText, time.Now())
+	newMessage.WorkspaceId, newMessage.ChatId, newMessage.TicketId = workspace.Id, chat.ChatId, chat.Tickets[index].TicketId
+	newMessage.ExternalId = externalId
+	newMessage.Status = entity.MessageSent
+	if err = ms.messengerRepo.AppendMessage(nil, newMessage); err != nil {
 		return err
 	}
 
-	ws, err := ms.websocketService.UpgradeConnection(w, r, workspaceId, userId)
+	ms.websocketService.SendToOne(workspaceId, userId, entity.EventMessageCreated, ms.createMessageResponse(nil, newMessage.CreatedAt, true, user.FullName, string(newMessage.Status), workspaceId, chat.Tickets[index].TicketId, chatId, newMessage.MessageId, templateName, string(newMessage.Type)))
+	ms.websocketService.SendToAllButOne(workspaceId, userId, entity.EventMessageCreated, ms.createMessageResponse(nil, newMessage.CreatedAt, false, user.FullName, string(newMessage.Status), workspaceId, chat.Tickets[index].TicketId, chatId, newMessage.MessageId, templateName, string(newMessage.Type)))
+
+	return nil
+}
+
+func (ms *MessengerServiceImpl) HandleChannelWebhook(source entity.ChatSource, accountId string, payload []byte) error {
+	adapter, err := ms.channelRegistry.Get(source)
 	if err != nil {
 		return err
 	}
 
+	messages, err := adapter.ReceiveMessages(accountId, payload)
+	if err != nil {
+		return err
+	}
+
+	// a single webhook can carry messages for several accounts, e.g. whatsapp phone numbers
+	workspaces := make(map[string]*entity.Workspace)
+	for _, message := range messages {
+		messageAccountId := accountId
+		if message.AccountId != "" {
+			messageAccountId = message.AccountId
+		}
+
+		workspace, ok := workspaces[messageAccountId]
+		if !ok {
+			if workspace, err = ms.messengerRepo.FindWorkspaceByChannelAccount(source, messageAccountId); err != nil {
+				return err
+			}
+			workspaces[messageAccountId] = workspace
+		}
+		// a retry cannot route it either, so it is dropped and the provider gets a success
+		if workspace == nil {
+			log.Printf("dropped %s message for unknown account %s", source, messageAccountId)
+			continue
+		}
+
+		if err = ms.handleInboundMessage(adapter, workspace, source, messageAccountId, message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ms *MessengerServiceImpl) handleInboundMessage(adapter infrastructureInterface.ChannelAdapter, workspace *entity.Workspace, source entity.ChatSource, accountId string, inbound infrastructureModel.InboundMessage) error {
+	chat, err := ms.messengerRepo.FindChatByWorkspaceIdAndExternalId(nil, workspace.Id, source, accountId, inbound.ExternalChatId)
+	if err != nil {
+		return err
+	}
+
+	// providers redeliver a webhook they got no success for, a message that was stored already is skipped
+	if chat != nil && inbound.ExternalMessageId != "" {
+		exists, err := ms.messengerRepo.CheckInboundMessageExists(source, accountId, chat.ChatId, inbound.ExternalMessageId)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+	}
+
+	newMessage := ms.createMessage(primitive.NilObjectID, 0, inbound.Text, inbound.SenderName, inbound.Type, inbound.CreatedAt)
+	newMessage.ExternalId = inbound.ExternalMessageId
+	newMessage.Source, newMessage.AccountId = source, accountId
+
+	if chat == nil {
+		ticket := ms.createTicket([]entity.Note{}, time.Now())
+		ticket.Status = entity.StatusOpen
+		newMessage.TicketId = ticket.TicketId
+
+		teamId, assigneeId := primitive.NilObjectID, primitive.NilObjectID
+		team, err := ms.messengerRepo.FindFirstTeamByWorkspaceId(workspace.Id)
+		if err != nil {
+			return err
+		}
+		if team != nil {
+			teamId = team.Id
+			if assigneeId, err = ms.assignmentService.AssignAgent(team, "", primitive.NilObjectID); err != nil {
+				assigneeId = primitive.NilObjectID
+			}
+		}
+
+		if err = ms.applySLAPolicy(workspace.Id, teamId, nil, ticket); err != nil {
+			return err
+		}
+
+		chatName := inbound.SenderName
+		if inbound.ChatTitle != "" {
+			chatName = inbound.ChatTitle
+		}
+
+		// telegram sources keep the numeric ids the existing clients rely on
+		tgChatId, _ := strconv.Atoi(inbound.ExternalChatId)
+		tgClientId, _ := strconv.Atoi(inbound.ExternalSenderId)
+		chat = ms.createChat(tgChatId, tgClientId, source, *ticket, workspace.Id, assigneeId, teamId, false, *newMessage, chatName, "", "", "", "")
+		chat.AccountId = accountId
+		chat.ExternalId = inbound.ExternalChatId
+		if err = ms.messengerRepo.InsertNewChat(nil, chat); err != nil {
+			return err
+		}
+		go ms.updateWallpaper(workspace, chat)
+	} else if index := ms.findOpenTicketIndex(chat); index != -1 {
+		newMessage.TicketId = chat.Tickets[index].TicketId
+	} else {
+		ticket := ms.createTicket([]entity.Note{}, time.Now())
+		ticket.Status = entity.StatusOpen
+		if err = ms.routeReturningChat(workspace, chat); err != nil {
+			return err
+		}
+		if err = ms.applySLAPolicy(workspace.Id, chat.TeamId, chat.Tags, ticket); err != nil {
+			return err
+		}
+		chat.Tickets = append(chat.Tickets, *ticket)
+		newMessage.TicketId = ticket.TicketId
+
+		if err = ms.messengerRepo.UpdateChat(nil, chat); err != nil {
+			return err
+		}
+	}
+
+	newMessage.WorkspaceId, newMessage.ChatId = workspace.Id, chat.ChatId
+	if err = ms.messengerRepo.AppendMessage(nil, newMessage); err != nil {
+		// the same message arrived twice at once, the other delivery stored it
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return err
+	}
+
+	// the message is stored already, failing now would only make the provider deliver it again
+	var content []byte
+	if inbound.FileId != "" {
+		if content, err = adapter.DownloadMedia(workspace, chat, inbound.FileId); err != nil {
+			log.Printf("failed to download media of message %s: %v", newMessage.MessageId, err)
+		} else {
+			go ms.fileService.SaveFile("message."+newMessage.MessageId, content)
+		}
+	}
+
+	ms.websocketService.SendToAll(workspace.WorkspaceId, entity.EventMessageCreated, ms.createMessageResponse(content, newMessage.CreatedAt, false, inbound.SenderName, "", workspace.WorkspaceId, newMessage.TicketId, chat.ChatId, newMessage.MessageId, inbound.Text, string(inbound.Type)))
+
+	return nil
+}
+
+func (ms *MessengerServiceImpl) ValidateUserInWorkspaceById(userId primitive.ObjectID, workspaceId string) error {
+	workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
+	if err != nil {
+		return err
+	}
+
+	return ms.authorize(workspace, userId, utils.PermissionWorkspaceRead)
+}
+
+func (ms *MessengerServiceImpl) HandleChatWS(userId primitive.ObjectID, workspaceId string, w http.ResponseWriter, r *http.Request) error {
+	workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
+	if err != nil {
+		return err
+	}
+	if err = ms.ValidateUserInWorkspace(userId, workspace); err != nil {
+		return err
+	}
+
+	ws, connectionId, err := ms.websocketService.UpgradeConnection(w, r, workspaceId, userId)
+	if err != nil {
+		return err
+	}
+
+	ms.presenceService.Connect(workspaceId, userId)
+
 	go func() {
-		defer ms.websocketService.RemoveConnection(workspaceId, userId)
+		defer func() {
+			ms.websocketService.RemoveConnection(workspaceId, userId, connectionId)
+			ms.presenceService.Disconnect(workspaceId, userId, connectionId)
+		}()
 		for {
 			_, message, err := ws.ReadMessage()
 			if err != nil {
 				break
 			}
 
-			var receivedMessage model.MessageRequest
-			if err := json.Unmarshal(message, &receivedMessage); err != nil {
-				continue
-			}
-			log.Print(receivedMessage)
-
-			if err = ms.HandleMessage(userId, workspaceId, receivedMessage.TicketId, receivedMessage.ChatId, receivedMessage.Type, receivedMessage.Message); err != nil {
-				log.Println("i am in the error", err)
+			if err = ms.handleClientEvent(userId, workspaceId, connectionId, message); err != nil {
+				log.Println("failed to handle websocket event:", err)
 			}
 		}
 	}()
@@ -380,15 +806,93 @@ func (ms *MessengerServiceImpl) HandleChatWS(userId primitive.ObjectID, workspac
 	return nil
 }
 
-func (ms *MessengerServiceImpl) HandleMessage(userId primitive.ObjectID, workspaceId, ticketId, chatId, messageType, message string) error {
-	chat, err := ms.messengerRepo.FindChatByChatId(chatId)
-	if err != nil {
+// handleClientEvent reads an event envelope from the client. A frame without a known event type is
+// the bare message request older clients send.
+func (ms *MessengerServiceImpl) handleClientEvent(userId primitive.ObjectID, workspaceId, connectionId string, frame []byte) error {
+	var event model.EventRequest
+	if err := json.Unmarshal(frame, &event); err != nil {
 		return err
 	}
 
+	var receivedMessage model.MessageRequest
+	switch entity.EventType(event.Type) {
+	case entity.EventAck:
+		return ms.websocketService.Acknowledge(workspaceId, userId, connectionId, event.Id)
+	case entity.EventTyping:
+		var typing model.TypingRequest
+		if err := json.Unmarshal(event.Payload, &typing); err != nil {
+			return err
+		}
+		return ms.handleTyping(userId, workspaceId, connectionId, typing)
+	case entity.EventMessageSend:
+		if err := json.Unmarshal(event.Payload, &receivedMessage); err != nil {
+			return err
+		}
+	default:
+		if err := json.Unmarshal(frame, &receivedMessage); err != nil {
+			return err
+		}
+	}
+
+	_, err := ms.HandleMessage(userId, workspaceId, receivedMessage.TicketId, receivedMessage.ChatId, receivedMessage.Type, receivedMessage.Message)
+	return err
+}
+
+// handleTyping checks the agent may see the chat before the others learn they have it open,
+// idle needs no check as it only releases a chat the connection had open
+func (ms *MessengerServiceImpl) handleTyping(userId primitive.ObjectID, workspaceId, connectionId string, typing model.TypingRequest) error {
+	state := entity.ChatActivity(typing.State)
+	if state != entity.ActivityIdle {
+		workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
+		if err != nil {
+			return err
+		}
+
+		chat, err := ms.messengerRepo.FindChatByWorkspaceIdAndChatId(workspace.Id, typing.ChatId)
+		if err != nil {
+			return err
+		}
+
+		if err = ms.authorizeChat(workspace, userId, chat, utils.PermissionChatRead); err != nil {
+			return err
+		}
+	}
+
+	return ms.presenceService.SetActivity(workspaceId, userId, connectionId, typing.ChatId, state)
+}
+
+func (ms *MessengerServiceImpl) GetPresence(userId primitive.ObjectID, workspaceId string) ([]model.PresenceResponse, error) {
+	workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ms.authorize(workspace, userId, utils.PermissionWorkspaceRead); err != nil {
+		return nil, err
+	}
+
+	return ms.presenceService.GetPresences(workspaceId)
+}
+
+func (ms *MessengerServiceImpl) HandleMessage(userId primitive.ObjectID, workspaceId, ticketId, chatId, messageType, message string) (model.SendMessageResponse, error) {
+	workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
+	if err != nil {
+		return model.SendMessageResponse{}, err
+	}
+
+	chat, err := ms.messengerRepo.FindChatByWorkspaceIdAndChatId(workspace.Id, chatId)
+	if err != nil {
+		return model.SendMessageResponse{}, err
+	}
+
+	if err = ms.authorizeChat(workspace, userId, chat, utils.PermissionChatReply); err != nil {
+		return model.SendMessageResponse{}, err
+	}
+	ms.presenceService.Touch(workspaceId, userId)
+
 	user, err := ms.messengerRepo.GetUserById(userId)
 	if err != nil {
-		return err
+		return model.SendMessageResponse{}, err
 	}
 
 	switch messageType {
@@ -397,50 +901,125 @@ func (ms *MessengerServiceImpl) HandleMessage(userId primitive.ObjectID, workspa
 		chat.Notes = append(chat.Notes, *note)
 
 		if err = ms.messengerRepo.UpdateChat(nil, chat); err != nil {
-			return err
+			return model.SendMessageResponse{}, err
 		}
 
-		res, err := json.Marshal(ms.createMessageResponse(nil, note.CreatedAt, true, user.FullName, "", workspaceId, ticketId, chatId, note.NoteId, message, messageType))
+		ms.websocketService.SendToOne(workspaceId, userId, entity.EventMessageCreated, ms.createMessageResponse(nil, note.CreatedAt, true, user.FullName, "", workspaceId, ticketId, chatId, note.NoteId, message, messageType))
+		ms.websocketService.SendToAllButOne(workspaceId, userId, entity.EventMessageCreated, ms.createMessageResponse(nil, note.CreatedAt, false, user.FullName, "", workspaceId, ticketId, chatId, note.NoteId, message, messageType))
+
+		return model.SendMessageResponse{MessageId: note.NoteId}, nil
+	case "ticket_note":
+		ticket, err := ms.findTicketInChat(chat, ticketId)
 		if err != nil {
-			return err
+			return model.SendMessageResponse{}, err
 		}
-		ms.websocketService.SendToOne(res, workspaceId, userId)
 
-		res, err = json.Marshal(ms.createMessageResponse(nil, note.CreatedAt, false, user.FullName, "", workspaceId, ticketId, chatId, note.NoteId, message, messageType))
-		if err != nil {
-			return err
+		note := ms.createNote(userId, message)
+		ticket.Notes = append(ticket.Notes, *note)
+		if err = ms.messengerRepo.UpdateChat(nil, chat); err != nil {
+			return model.SendMessageResponse{}, err
 		}
-		ms.websocketService.SendToAllButOne(workspaceId, res, userId)
 
-		return nil
-	case "ticket_note":
-		ticket, err := ms.findTicketInChat(chat, ticketId)
-		if err != nil {
-			return err
+		ms.websocketService.SendToOne(workspaceId, userId, entity.EventMessageCreated, ms.createMessageResponse(nil, note.CreatedAt, true, user.FullName, "", workspaceId, ticketId, chatId, note.NoteId, message, messageType))
+		ms.websocketService.SendToAllButOne(workspaceId, userId, entity.EventMessageCreated, ms.createMessageResponse(nil, note.CreatedAt, false, user.FullName, "", workspaceId, ticketId, chatId, note.NoteId, message, messageType))
+
+		return model.SendMessageResponse{MessageId: note.NoteId}, nil
+	case "saved_reply":
+		if message, err = ms.expandSavedReply(workspace, chat, user, message); err != nil {
+			return model.SendMessageResponse{}, err
+		}
+		fallthrough
+	case "reply":
+		index := ms.findTicketIndexInChat(chat, ticketId)
+		if index == -1 {
+			return model.SendMessageResponse{}, errors.New("ticket not found")
 		}
 
-		note := ms.createNote(userId, message)
-		ticket.Notes = append(ticket.Notes, *note)
-		if err = ms.messengerRepo.UpdateChat(nil, chat); err != nil {
-			return err
-		}
+		newMessage := ms.createMessage(userId, 0, message, user.FullName, entity.TypeText, time.Now())
+		externalId, deliveryErr := ms.deliverMessage(workspace, chat, message)
+		newMessage.ExternalId = externalId
+		if deliveryErr != nil {
+			log.Printf("failed to deliver message to chat %s: %v", chat.ChatId, deliveryErr)
+			newMessage.Status = entity.MessageFailed
+		} else {
+			newMessage.Status = entity.MessageSent
+		}
+
+		ticketId = chat.Tickets[index].TicketId
+		// the chat is saved before the message is appended, so last_message and message_count are not overwritten
+		if deliveryErr == nil && chat.Tickets[index].FirstResponseAt.IsZero() {
+			chat.Tickets[index].FirstResponseAt = newMessage.CreatedAt
+			if err = ms.messengerRepo.UpdateChat(nil, chat); err != nil {
+				return model.SendMessageResponse{}, err
+			}
+		}
+
+		newMessage.WorkspaceId, newMessage.ChatId, newMessage.TicketId = workspace.Id, chat.ChatId, ticketId
+		if err = ms.messengerRepo.AppendMessage(nil, newMessage); err != nil {
+			return model.SendMessageResponse{}, err
+		}
+
+		ms.websocketService.SendToOne(workspaceId, userId, entity.EventMessageCreated, ms.createMessageResponse(nil, newMessage.CreatedAt, true, user.FullName, string(newMessage.Status), workspaceId, ticketId, chatId, newMessage.MessageId, message, string(newMessage.Type)))
+		ms.websocketService.SendToAllButOne(workspaceId, userId, entity.EventMessageCreated, ms.createMessageResponse(nil, newMessage.CreatedAt, false, user.FullName, string(newMessage.Status), workspaceId, ticketId, chatId, newMessage.MessageId, message, string(newMessage.Type)))
+
+		// the message is saved either way, a failed one is sent again through ResendMessage rather than a new request
+		return model.SendMessageResponse{MessageId: newMessage.MessageId, Status: string(newMessage.Status)}, nil
+	default:
+		return model.SendMessageResponse{}, errors.New("unknown message type")
+	}
+}
+
+// ResendMessage delivers a reply that failed before once more, the message keeps its id and place in the chat
+func (ms *MessengerServiceImpl) ResendMessage(userId primitive.ObjectID, workspaceId, chatId, messageId string) (model.SendMessageResponse, error) {
+	workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
+	if err != nil {
+		return model.SendMessageResponse{}, err
+	}
+
+	chat, err := ms.messengerRepo.FindChatByWorkspaceIdAndChatId(workspace.Id, chatId)
+	if err != nil {
+		return model.SendMessageResponse{}, err
+	}
+
+	if err = ms.authorizeChat(workspace, userId, chat, utils.PermissionChatReply); err != nil {
+		return model.SendMessageResponse{}, err
+	}
+
+	message, err := ms.messengerRepo.FindMessageByChatIdAndMessageId(chat.ChatId, messageId)
+	if err != nil {
+		return model.SendMessageResponse{}, err
+	}
+	if message.Status != entity.MessageFailed {
+		return model.SendMessageResponse{}, errors.New("only failed messages can be resent")
+	}
+
+	externalId, deliveryErr := ms.deliverMessage(workspace, chat, message.Message)
+	if deliveryErr != nil {
+		log.Printf("failed to deliver message to chat %s: %v", chat.ChatId, deliveryErr)
+		return model.SendMessageResponse{MessageId: message.MessageId, Status: string(message.Status)}, nil
+	}
 
-		res, err := json.Marshal(ms.createMessageResponse(nil, note.CreatedAt, true, user.FullName, "", workspaceId, ticketId, chatId, note.NoteId, message, messageType))
-		if err != nil {
-			return err
-		}
-		ms.websocketService.SendToOne(res, workspaceId, userId)
+	message.ExternalId, message.Status = externalId, entity.MessageSent
+	if err = ms.messengerRepo.UpdateMessageDelivery(message); err != nil {
+		return model.SendMessageResponse{}, err
+	}
 
-		res, err = json.Marshal(ms.createMessageResponse(nil, note.CreatedAt, false, user.FullName, "", workspaceId, ticketId, chatId, note.NoteId, message, messageType))
-		if err != nil {
-			return err
+	if index := ms.findTicketIndexInChat(chat, message.TicketId); index != -1 && chat.Tickets[index].FirstResponseAt.IsZero() {
+		chat.Tickets[index].FirstResponseAt = time.Now()
+		if err = ms.messengerRepo.UpdateChat(nil, chat); err != nil {
+			return model.SendMessageResponse{}, err
 		}
-		ms.websocketService.SendToAllButOne(workspaceId, res, userId)
-
-		return nil
-	default:
-		return errors.New("unknown message type")
 	}
+
+	ms.websocketService.SendToAll(workspaceId, entity.EventMessageStatus, model.MessageStatusResponse{
+		WorkspaceId: workspaceId,
+		ChatId:      chat.ChatId,
+		TicketId:    message.TicketId,
+		MessageId:   message.MessageId,
+		Status:      string(message.Status),
+	})
+
+	return model.SendMessageResponse{MessageId: message.MessageId, Status: string(message.Status)}, nil
 }
 
 func (ms *MessengerServiceImpl) UpdateChatInfo(userId primitive.ObjectID, chatId string, tags []string, workspaceId, language string, address, company, clientEmail, clientPhone string) error {
@@ -449,12 +1028,12 @@ func (ms *MessengerServiceImpl) UpdateChatInfo(userId primitive.ObjectID, chatId
 		return err
 	}
 
-	if err = ms.ValidateUserInWorkspace(userId, workspace); err != nil {
+	chat, err := ms.messengerRepo.FindChatByWorkspaceIdAndChatId(workspace.Id, chatId)
+	if err != nil {
 		return err
 	}
 
-	chat, err := ms.messengerRepo.FindChatByWorkspaceIdAndChatId(workspace.Id, chatId)
-	if err != nil {
+	if err = ms.authorizeChat(workspace, userId, chat, utils.PermissionChatUpdate); err != nil {
 		return err
 	}
 	chat.Tags = tags
@@ -498,7 +1077,21 @@ func (ms *MessengerServiceImpl) UpdateChatInfo(userId primitive.ObjectID, chatId
 		return err
 	}
 
-	return ms.messengerRepo.UpdateChat(nil, chat)
+	if err := ms.messengerRepo.UpdateChat(nil, chat); err != nil {
+		return err
+	}
+
+	ms.websocketService.SendToAll(workspaceId, entity.EventChatUpdated, model.ChatUpdateResponse{
+		WorkspaceId: workspaceId,
+		ChatId:      chat.ChatId,
+		Tags:        chat.Tags,
+		Language:    string(chat.Language),
+		Address:     chat.Address,
+		Company:     chat.Company,
+		ClientEmail: chat.ClientEmail,
+		ClientPhone: chat.ClientPhone,
+	})
+	return nil
 }
 
 func (ms *MessengerServiceImpl) GetChat(userId primitive.ObjectID, workspaceId, chatId string) (model.ChatResponse, error) {
@@ -507,12 +1100,12 @@ func (ms *MessengerServiceImpl) GetChat(userId primitive.ObjectID, workspaceId,
 		return model.ChatResponse{}, err
 	}
 
-	if err = ms.ValidateUserInWorkspace(userId, workspace); err != nil {
+	chat, err := ms.messengerRepo.FindChatByWorkspaceIdAndChatId(workspace.Id, chatId)
+	if err != nil {
 		return model.ChatResponse{}, err
 	}
 
-	chat, err := ms.messengerRepo.FindChatByWorkspaceIdAndChatId(workspace.Id, chatId)
-	if err != nil {
+	if err = ms.authorizeChat(workspace, userId, chat, utils.PermissionChatRead); err != nil {
 		return model.ChatResponse{}, err
 	}
 
@@ -530,25 +1123,260 @@ func (ms *MessengerServiceImpl) GetChat(userId primitive.ObjectID, workspaceId,
 	return *responseChat, nil
 }
 
-func (ms *MessengerServiceImpl) GetMessages(userId primitive.ObjectID, workspaceId, chatId string, lastMessageDate time.Time) ([]model.MessageResponse, error) {
-	//workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
-	//if err != nil {
-	//	return model.ChatResponse{}, err
-	//}
+func (ms *MessengerServiceImpl) GetMessages(userId primitive.ObjectID, workspaceId, chatId, cursor string, limit int, direction string) (model.MessagesResponse, error) {
+	workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
+	if err != nil {
+		return model.MessagesResponse{}, err
+	}
 
-	//if err = ms.ValidateUserInWorkspace(userId, workspace); err != nil {
-	//	return model.ChatResponse{}, err
-	//}
-	//
-	//chat, err := ms.messengerRepo.FindChatByWorkspaceIdAndChatId(workspace.Id, chatId)
-	//if err != nil {
-	//	return model.ChatResponse{}, err
-	//}
-	//
-	//responseMessage := ms.createMessageResponse(nil, chat.LastMessage.CreatedAt, chat.UserId == userId, chat.LastMessage.From, "", workspaceId, "", chat.ChatId, chat.LastMessage.MessageId, chat.LastMessage.Message, string(entity.TypeText))
-	//responseChat := ms.createChatResponse(workspaceId, chatId, chat.TgClientId, chat.TgChatId, chat.Tags, *responseMessage, string(chat.Source), chat.IsImported, chat.CreatedAt, chat.Name)
+	chat, err := ms.messengerRepo.FindChatByWorkspaceIdAndChatId(workspace.Id, chatId)
+	if err != nil {
+		return model.MessagesResponse{}, err
+	}
+
+	if err = ms.authorizeChat(workspace, userId, chat, utils.PermissionChatRead); err != nil {
+		return model.MessagesResponse{}, err
+	}
+
+	if limit <= 0 {
+		limit = defaultMessagesLimit
+	}
+	if limit > maxMessagesLimit {
+		limit = maxMessagesLimit
+	}
+
+	var after bool
+	switch direction {
+	case "", "before":
+	case "after":
+		after = true
+	default:
+		return model.MessagesResponse{}, fmt.Errorf("invalid direction: %s", direction)
+	}
+
+	var cursorTime time.Time
+	var cursorId string
+	if cursor != "" {
+		if cursorTime, cursorId, err = utils.DecodeCursor(cursor); err != nil {
+			return model.MessagesResponse{}, err
+		}
+	}
+
+	// one extra entry tells whether another page exists
+	entries, err := ms.messengerRepo.FindChatHistory(workspace.Id, chatId, cursorTime, cursorId, limit+1, after)
+	if err != nil {
+		return model.MessagesResponse{}, err
+	}
+
+	response := model.MessagesResponse{Messages: []model.MessageResponse{}}
+	if len(entries) > limit {
+		response.HasMore = true
+		if after {
+			entries = entries[1:]
+		} else {
+			entries = entries[:limit]
+		}
+	}
+	if len(entries) == 0 {
+		return response, nil
+	}
+
+	names := make(map[primitive.ObjectID]string)
+	for _, entry := range entries {
+		name := entry.From
+		if entry.Type == entity.TypeTicketNote {
+			if _, ok := names[entry.SenderId]; !ok {
+				if user, err := ms.messengerRepo.FindUserById(entry.SenderId); err == nil {
+					names[entry.SenderId] = user.FullName
+				}
+			}
+			name = names[entry.SenderId]
+		}
+
+		var content []byte
+		if entry.Type != entity.TypeText && entry.Type != entity.TypeTicketNote {
+			content, _ = ms.fileService.LoadFile("message." + entry.EntryId)
+		}
+
+		response.Messages = append(response.Messages, *ms.createMessageResponse(content, entry.CreatedAt, entry.SenderId == userId, name, string(entry.Status), workspaceId, entry.TicketId, chatId, entry.EntryId, entry.Text, string(entry.Type)))
+	}
+
+	newest, oldest := entries[0], entries[len(entries)-1]
+	response.AfterCursor = utils.EncodeCursor(newest.CreatedAt, newest.EntryId)
+	response.BeforeCursor = utils.EncodeCursor(oldest.CreatedAt, oldest.EntryId)
+
+	return response, nil
+}
+
+func (ms *MessengerServiceImpl) Search(userId primitive.ObjectID, request model.SearchRequest) (model.SearchResponse, error) {
+	workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, request.WorkspaceId)
+	if err != nil {
+		return model.SearchResponse{}, err
+	}
+
+	if err = ms.authorize(workspace, userId, utils.PermissionChatRead); err != nil {
+		return model.SearchResponse{}, err
+	}
+
+	query := strings.TrimSpace(request.Query)
+	if query == "" {
+		return model.SearchResponse{}, errors.New("search query is empty")
+	}
+
+	if request.Limit <= 0 {
+		request.Limit = defaultSearchLimit
+	}
+	if request.Limit > maxSearchLimit {
+		request.Limit = maxSearchLimit
+	}
+	if request.Page <= 0 {
+		request.Page = 1
+	}
+
+	filter, err := ms.parseSearchFilter(request)
+	if err != nil {
+		return model.SearchResponse{}, err
+	}
+
+	// without access to every chat the search is limited to the caller's own
+	if ms.authorize(workspace, userId, utils.PermissionChatReadAll) != nil {
+		if !filter.AssigneeId.IsZero() && filter.AssigneeId != userId {
+			return model.SearchResponse{}, errors.New("unauthorised")
+		}
+		filter.AssigneeId = userId
+	}
+
+	// both queries are ranked separately, so each has to return everything up to the end of the requested page
+	fetch := request.Page*request.Limit + 1
+
+	chats, err := ms.messengerRepo.SearchChats(workspace.Id, query, filter, fetch)
+	if err != nil {
+		return model.SearchResponse{}, err
+	}
 
-	return nil, nil
+	messages, err := ms.messengerRepo.SearchMessages(workspace.Id, query, filter, fetch)
+	if err != nil {
+		return model.SearchResponse{}, err
+	}
+
+	terms := utils.SearchTerms(query)
+	results := make([]model.SearchResult, 0, len(chats)+len(messages))
+	for _, hit := range chats {
+		results = append(results, ms.createChatSearchResult(hit, terms))
+	}
+	for _, hit := range messages {
+		snippet, _ := utils.HighlightSnippet(hit.Message.Message, terms, snippetRadius)
+		results = append(results, model.SearchResult{
+			Kind:      "message",
+			Field:     "message",
+			ChatId:    hit.ChatId,
+			TicketId:  hit.TicketId,
+			MessageId: hit.MessageId,
+			Name:      hit.ChatName,
+			Source:    string(hit.Source),
+			Snippet:   snippet,
+			Score:     hit.Score,
+			CreatedAt: hit.CreatedAt,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+
+	response := model.SearchResponse{Results: []model.SearchResult{}, Page: request.Page}
+	start := (request.Page - 1) * request.Limit
+	if start >= len(results) {
+		return response, nil
+	}
+
+	end := start + request.Limit
+	if end < len(results) {
+		response.HasMore = true
+	} else {
+		end = len(results)
+	}
+	response.Results = results[start:end]
+
+	return response, nil
+}
+
+func (ms *MessengerServiceImpl) parseSearchFilter(request model.SearchRequest) (entity.SearchFilter, error) {
+	filter := entity.SearchFilter{
+		Source: entity.ChatSource(request.Source),
+		Tag:    request.Tag,
+		Status: entity.TicketStatus(request.Status),
+	}
+
+	if request.Assignee != "" {
+		assigneeId, err := ms.messengerRepo.FindUserByEmail(nil, request.Assignee)
+		if err != nil {
+			return entity.SearchFilter{}, err
+		}
+		filter.AssigneeId = assigneeId
+	}
+
+	var err error
+	if filter.From, err = utils.ParseDate(request.From, false); err != nil {
+		return entity.SearchFilter{}, err
+	}
+	if filter.To, err = utils.ParseDate(request.To, true); err != nil {
+		return entity.SearchFilter{}, err
+	}
+
+	return filter, nil
+}
+
+func (ms *MessengerServiceImpl) createChatSearchResult(hit entity.ChatSearchHit, terms []string) model.SearchResult {
+	result := model.SearchResult{
+		Kind:      "chat",
+		ChatId:    hit.ChatId,
+		Name:      hit.Name,
+		Source:    string(hit.Source),
+		Score:     hit.Score,
+		CreatedAt: hit.LastMessage.CreatedAt,
+	}
+
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"name", hit.Name},
+		{"company", hit.Company},
+		{"client_email", hit.ClientEmail},
+		{"client_phone", hit.ClientPhone},
+	}
+	for _, field := range fields {
+		if snippet, ok := utils.HighlightSnippet(field.value, terms, snippetRadius); ok {
+			result.Field, result.Snippet = field.name, snippet
+			return result
+		}
+	}
+
+	for _, note := range hit.Notes {
+		if snippet, ok := utils.HighlightSnippet(note.Text, terms, snippetRadius); ok {
+			result.Kind, result.Field, result.Snippet, result.CreatedAt = "note", "chat_note", snippet, note.CreatedAt
+			result.MessageId = note.NoteId
+			return result
+		}
+	}
+
+	for _, ticket := range hit.Tickets {
+		for _, note := range ticket.Notes {
+			if snippet, ok := utils.HighlightSnippet(note.Text, terms, snippetRadius); ok {
+				result.Kind, result.Field, result.Snippet, result.CreatedAt = "note", "ticket_note", snippet, note.CreatedAt
+				result.TicketId, result.MessageId = ticket.TicketId, note.NoteId
+				return result
+			}
+		}
+	}
+
+	// the text index tokenises on punctuation, so a match inside an email or phone may not be found verbatim
+	result.Field, result.Snippet = "name", html.EscapeString(hit.Name)
+	return result
 }
 
 func (ms *MessengerServiceImpl) GetChatsByFolder(userId primitive.ObjectID, workspaceId, folderName string) ([]model.ChatResponse, error) {
@@ -557,8 +1385,8 @@ func (ms *MessengerServiceImpl) GetChatsByFolder(userId primitive.ObjectID, work
 		return nil, err
 	}
 
-	if _, exists := workspace.Team[userId]; !exists {
-		return nil, errors.New("unauthorised")
+	if err = ms.authorize(workspace, userId, utils.PermissionChatReadAll); err != nil {
+		return nil, err
 	}
 
 	chats, err := ms.messengerRepo.FindLatestChatsByWorkspaceIdAndAllTags(workspace.Id, workspace.Folders[folderName], 50)
@@ -576,7 +1404,7 @@ func (ms *MessengerServiceImpl) GetChatsByFolder(userId primitive.ObjectID, work
 
 		logo, _ := ms.fileService.LoadFile("chat." + chat.ChatId)
 		messageResponse := ms.createMessageResponse(nil, chat.LastMessage.CreatedAt, userId == chat.LastMessage.SenderId, chat.LastMessage.From, "", workspaceId, chat.Tickets[0].TicketId, chat.ChatId, chat.LastMessage.MessageId, chat.LastMessage.Message, string(chat.LastMessage.Type))
-		responseChats = append(responseChats, *ms.createChatResponse(workspace.WorkspaceId, chat.ChatId, chat.TgClientId, chat.TgChatId, chat.Tags, *messageResponse, string(entity.SourceTelegram), chat.IsImported, chat.CreatedAt, chat.Name, logo, nil, string(chat.Language), chat.Company, chat.ClientEmail, chat.ClientPhone, chat.Address, totalTickets, averageSolutionTime, averageNumberOfMessagesPerTicket))
+		responseChats = append(responseChats, *ms.createChatResponse(workspace.WorkspaceId, chat.ChatId, chat.TgClientId, chat.TgChatId, chat.Tags, *messageResponse, string(chat.Source), chat.IsImported, chat.CreatedAt, chat.Name, logo, nil, string(chat.Language), chat.Company, chat.ClientEmail, chat.ClientPhone, chat.Address, totalTickets, averageSolutionTime, averageNumberOfMessagesPerTicket))
 	}
 
 	return responseChats, nil
@@ -588,27 +1416,27 @@ func (ms *MessengerServiceImpl) GetAllTags(userId primitive.ObjectID, workspaceI
 		return nil, err
 	}
 
-	if !ms.isAdmin(workspace.Team[userId]) && !ms.isOwner(workspace.Team[userId]) {
-		return nil, errors.New("unauthorised")
+	if err = ms.authorize(workspace, userId, utils.PermissionTagRead); err != nil {
+		return nil, err
 	}
 
 	return workspace.Tags, nil
 }
 
-func (ms *MessengerServiceImpl) DeleteMessage(userId primitive.ObjectID, messageType, workspaceId, ticketId, messageId, chatId string) error {
+func (ms *MessengerServiceImpl) DeleteMessage(userId primitive.ObjectID, messageType, workspaceId, ticketId, messageId, chatId string, meta entity.AuditMeta) error {
 	workspace, err := ms.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
 	if err != nil {
 		return err
 	}
-	if _, exists := workspace.Team[userId]; !exists {
-		return errors.New("unauthorized")
-	}
-
 	chat, err := ms.messengerRepo.FindChatByWorkspaceIdAndChatId(workspace.Id, chatId)
 	if err != nil {
 		return err
 	}
 
+	if err = ms.authorizeChat(workspace, userId, chat, utils.PermissionMessageDelete); err != nil {
+		return err
+	}
+
 	switch messageType {
 	case "chat_note":
 		index, err := ms.findNoteIndexInChat(chat, messageId)
@@ -616,17 +1444,22 @@ func (ms *MessengerServiceImpl) DeleteMessage(userId primitive.ObjectID, message
 			return err
 		}
 
+		deletedText := chat.Notes[index].Text
 		chat.Notes = append(chat.Notes[:index], chat.Notes[index+1:]...)
 		if err = ms.messengerRepo.UpdateChat(nil, chat); err != nil {
 			return err
 		}
 
-		res, err := json.Marshal(ms.createMessageResponse(nil, time.Time{}, false, "", "delete", workspaceId, "", chatId, messageId, "", messageType))
-		if err != nil {
-			return err
-		}
+		ms.recordAudit(ms.createAuditLog(workspace.Id, userId, entity.AuditMessageDeleted, messageType, messageId, meta,
+			entity.AuditChange{Field: "text", Before: deletedText},
+		))
 
-		ms.websocketService.SendToAll(workspaceId, res)
+		ms.websocketService.SendToAll(workspaceId, entity.EventMessageDeleted, model.DeleteMessageResponse{
+			Type:        messageType,
+			WorkspaceId: workspaceId,
+			ChatId:      chatId,
+			MessageId:   messageId,
+		})
 		return nil
 	case "ticket_note":
 		ticketIndex, noteIndex, err := ms.findTicketIdAndNoteIdByNoteId(chat, messageId)
@@ -634,56 +1467,238 @@ func (ms *MessengerServiceImpl) DeleteMessage(userId primitive.ObjectID, message
 			return err
 		}
 
+		deletedText := chat.Tickets[ticketIndex].Notes[noteIndex].Text
 		chat.Tickets[ticketIndex].Notes = append(chat.Tickets[ticketIndex].Notes[:noteIndex], chat.Tickets[ticketIndex].Notes[noteIndex+1:]...)
 		if err = ms.messengerRepo.UpdateChat(nil, chat); err != nil {
 			return err
 		}
 
-		res, err := json.Marshal(ms.createMessageResponse(nil, time.Time{}, false, "", "delete", workspaceId, ticketId, chatId, messageId, "", messageType))
+		ms.recordAudit(ms.createAuditLog(workspace.Id, userId, entity.AuditMessageDeleted, messageType, messageId, meta,
+			entity.AuditChange{Field: "text", Before: deletedText},
+		))
+
+		ms.websocketService.SendToAll(workspaceId, entity.EventMessageDeleted, model.DeleteMessageResponse{
+			Type:        messageType,
+			WorkspaceId: workspaceId,
+			ChatId:      chatId,
+			TicketId:    ticketId,
+			MessageId:   messageId,
+		})
+		return nil
+	case "reply":
+		message, err := ms.messengerRepo.FindMessageByChatIdAndMessageId(chat.ChatId, messageId)
 		if err != nil {
 			return err
 		}
 
-		ms.websocketService.SendToAll(workspaceId, res)
+		if externalId := message.ExternalId; externalId != "" {
+			adapter, err := ms.channelRegistry.Get(chat.Source)
+			if err != nil {
+				return err
+			}
+			if err = adapter.DeleteMessage(workspace, chat, externalId); err != nil {
+				return err
+			}
+		}
+
+		if err = ms.messengerRepo.DeleteMessage(message); err != nil {
+			return err
+		}
+
+		ms.recordAudit(ms.createAuditLog(workspace.Id, userId, entity.AuditMessageDeleted, messageType, messageId, meta,
+			entity.AuditChange{Field: "text", Before: message.Message},
+		))
+
+		ms.websocketService.SendToAll(workspaceId, entity.EventMessageDeleted, model.DeleteMessageResponse{
+			Type:        messageType,
+			WorkspaceId: workspaceId,
+			ChatId:      chatId,
+			TicketId:    message.TicketId,
+			MessageId:   messageId,
+		})
 		return nil
 	default:
 		return errors.New("invalid message type")
 	}
 }
 
-func (ms *MessengerServiceImpl) getAssigneeIdByTeam(workspace *entity.Workspace, teamId string) (primitive.ObjectID, error) {
-	team, err := ms.messengerRepo.FindTeamByWorkspaceIdAndTeamId(workspace.Id, teamId)
+// routeReturningChat runs the team's assignment when a known customer opens a new ticket.
+// The current agent is kept when nobody else can take the ticket.
+func (ms *MessengerServiceImpl) routeReturningChat(workspace *entity.Workspace, chat *entity.Chat) error {
+	var team *entity.Team
+	var err error
+	if !chat.TeamId.IsZero() {
+		team, err = ms.messengerRepo.FindTeamById(chat.TeamId)
+	} else {
+		team, err = ms.messengerRepo.FindFirstTeamByWorkspaceId(workspace.Id)
+	}
+	if err != nil || team == nil {
+		return err
+	}
+
+	assigneeId, err := ms.assignmentService.AssignAgent(team, chat.Language, chat.UserId)
 	if err != nil {
-		return primitive.ObjectID{}, err
+		return nil
 	}
 
-	var leastBusyMember primitive.ObjectID
-	minTickets := int(^uint(0) >> 1)
+	chat.UserId, chat.TeamId = assigneeId, team.Id
+	return nil
+}
 
-	findMember := func(status entity.UserStatus) bool {
-		for memberId := range team.Members {
-			user, err := ms.messengerRepo.FindUserById(memberId)
-			if err != nil || user.Status != status {
-				continue
-			}
+func (ms *MessengerServiceImpl) applySLAPolicy(workspaceId, teamId primitive.ObjectID, tags []string, ticket *entity.Ticket) error {
+	policies, err := ms.messengerRepo.FindSLAPoliciesByWorkspaceId(workspaceId)
+	if err != nil || len(policies) == 0 {
+		return err
+	}
 
-			activeTicketsCount, err := ms.messengerRepo.CountActiveTickets(memberId)
-			if err != nil {
-				continue
-			}
-			if activeTicketsCount < minTickets {
-				minTickets = activeTicketsCount
-				leastBusyMember = memberId
-			}
+	var chatTeamId string
+	if !teamId.IsZero() {
+		team, err := ms.messengerRepo.FindTeamById(teamId)
+		if err != nil {
+			return err
+		}
+		if team != nil {
+			chatTeamId = team.TeamId
+		}
+	}
+
+	var policy *entity.SLAPolicy
+	bestRank := 0
+	for i := range policies {
+		if !policies[i].IsActive {
+			continue
+		}
+		if policies[i].TeamId != "" && policies[i].TeamId != chatTeamId {
+			continue
+		}
+		if policies[i].Tag != "" && !slices.Contains(tags, policies[i].Tag) {
+			continue
+		}
+
+		rank := 1
+		if policies[i].Tag != "" {
+			rank += 2
+		}
+		if policies[i].TeamId != "" {
+			rank++
+		}
+		if rank > bestRank {
+			policy, bestRank = &policies[i], rank
+		}
+	}
+	if policy == nil {
+		return nil
+	}
+
+	ticket.SLAPolicyId = policy.PolicyId
+	ticket.SLAStatus = entity.SLAOk
+	if policy.FirstResponseMinutes > 0 {
+		ticket.FirstResponseDueAt = ticket.CreatedAt.Add(time.Duration(policy.FirstResponseMinutes) * time.Minute)
+	}
+	if policy.ResolutionMinutes > 0 {
+		ticket.SLADueAt = ticket.CreatedAt.Add(time.Duration(policy.ResolutionMinutes) * time.Minute)
+	}
+
+	return nil
+}
+
+// resolveSLA settles the SLA of a ticket that is being closed
+func (ms *MessengerServiceImpl) resolveSLA(ticket *entity.Ticket) {
+	if ticket.SLAStatus != entity.SLAOk && ticket.SLAStatus != entity.SLAWarning {
+		return
+	}
+
+	firstResponseLate := !ticket.FirstResponseDueAt.IsZero() && (ticket.FirstResponseAt.IsZero() || ticket.FirstResponseAt.After(ticket.FirstResponseDueAt))
+	resolutionLate := !ticket.SLADueAt.IsZero() && ticket.ResolvedAt.After(ticket.SLADueAt)
+	if firstResponseLate || resolutionLate {
+		ticket.SLAStatus = entity.SLABreached
+	} else {
+		ticket.SLAStatus = entity.SLAMet
+	}
+}
+
+// expandSavedReply fills a saved reply in the chat's language, preferring the chat team's reply over the workspace one
+func (ms *MessengerServiceImpl) expandSavedReply(workspace *entity.Workspace, chat *entity.Chat, agent *entity.User, shortcut string) (string, error) {
+	replies, err := ms.messengerRepo.FindSavedRepliesByShortcut(workspace.Id, strings.ToLower(strings.TrimSpace(shortcut)))
+	if err != nil {
+		return "", err
+	}
+
+	var chatTeamId string
+	if !chat.TeamId.IsZero() {
+		team, err := ms.messengerRepo.FindTeamById(chat.TeamId)
+		if err != nil {
+			return "", err
 		}
-		return !leastBusyMember.IsZero()
+		if team != nil {
+			chatTeamId = team.TeamId
+		}
+	}
+
+	var reply *entity.SavedReply
+	for i := range replies {
+		if replies[i].TeamId == chatTeamId && chatTeamId != "" {
+			reply = &replies[i]
+			break
+		}
+		if replies[i].TeamId == "" {
+			reply = &replies[i]
+		}
+	}
+	if reply == nil {
+		return "", errors.New("saved reply not found")
+	}
+
+	text, ok := reply.Variants[chat.Language]
+	for _, language := range []entity.ChatLanguage{entity.English, entity.Russian, entity.Uzbek} {
+		if ok {
+			break
+		}
+		text, ok = reply.Variants[language]
+	}
+
+	return utils.FillPlaceholders(text, map[string]string{
+		"name":         chat.Name,
+		"company":      chat.Company,
+		"client_email": chat.ClientEmail,
+		"agent_name":   agent.FullName,
+	}), nil
+}
+
+func (ms *MessengerServiceImpl) createAuditLog(workspaceId, actorId primitive.ObjectID, action entity.AuditAction, targetType, targetId string, meta entity.AuditMeta, changes ...entity.AuditChange) *entity.AuditLog {
+	return &entity.AuditLog{
+		WorkspaceId: workspaceId,
+		ActorId:     actorId,
+		Action:      action,
+		TargetType:  targetType,
+		TargetId:    targetId,
+		Changes:     changes,
+		IP:          meta.IP,
+		UserAgent:   meta.UserAgent,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// recordAudit writes the entry after the action has succeeded, so a failed write is logged instead of undoing it
+func (ms *MessengerServiceImpl) recordAudit(auditLog *entity.AuditLog) {
+	if auditLog == nil {
+		return
+	}
+	if err := ms.messengerRepo.InsertAuditLog(auditLog); err != nil {
+		log.Println("failed to write the audit log:", auditLog.Action, auditLog.TargetId, err)
 	}
+}
 
-	if findMember(entity.StatusAvailable) || findMember(entity.StatusBusy) || findMember(entity.StatusOffline) {
-		return leastBusyMember, nil
+func (ms *MessengerServiceImpl) findUserEmail(userId primitive.ObjectID) string {
+	if userId.IsZero() {
+		return ""
 	}
 
-	return primitive.NilObjectID, errors.New("no suitable team member found")
+	user, err := ms.messengerRepo.FindUserById(userId)
+	if err != nil || user == nil {
+		return userId.Hex()
+	}
+	return user.Email
 }
 
 func (ms *MessengerServiceImpl) validateTicketStatus(status string) (entity.TicketStatus, error) {
@@ -696,14 +1711,60 @@ func (ms *MessengerServiceImpl) validateTicketStatus(status string) (entity.Tick
 }
 
 func (ms *MessengerServiceImpl) findTicketInChat(chat *entity.Chat, ticketId string) (*entity.Ticket, error) {
-	for _, ticket := range chat.Tickets {
-		if ticket.TicketId == ticketId {
-			return &ticket, nil
+	for i := range chat.Tickets {
+		if chat.Tickets[i].TicketId == ticketId {
+			return &chat.Tickets[i], nil
 		}
 	}
 	return nil, errors.New("ticket not found")
 }
 
+// takeTicketFromChat removes the ticket from the chat it is moved out of, and deletes the chat when no ticket is left
+func (ms *MessengerServiceImpl) takeTicketFromChat(sc mongo.SessionContext, chat *entity.Chat, ticketId string) (entity.Ticket, error) {
+	index := slices.IndexFunc(chat.Tickets, func(ticket entity.Ticket) bool {
+		return ticket.TicketId == ticketId
+	})
+	if index == -1 {
+		return entity.Ticket{}, errors.New("ticket not found")
+	}
+
+	ticket := chat.Tickets[index]
+	chat.Tickets = slices.Delete(chat.Tickets, index, index+1)
+
+	if len(chat.Tickets) == 0 {
+		return ticket, ms.messengerRepo.DeleteChat(sc, chat.Id)
+	}
+	return ticket, ms.messengerRepo.UpdateChat(sc, chat)
+}
+
+// findTicketIndexInChat falls back to the open ticket, or the latest one, when no ticket id is given
+func (ms *MessengerServiceImpl) findTicketIndexInChat(chat *entity.Chat, ticketId string) int {
+	if ticketId == "" {
+		if index := ms.findOpenTicketIndex(chat); index != -1 {
+			return index
+		}
+		return len(chat.Tickets) - 1
+	}
+
+	for i, ticket := range chat.Tickets {
+		if ticket.TicketId == ticketId {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (ms *MessengerServiceImpl) findOpenTicketIndex(chat *entity.Chat) int {
+	for i := len(chat.Tickets) - 1; i >= 0; i-- {
+		if chat.Tickets[i].Status != entity.StatusClosed {
+			return i
+		}
+	}
+
+	return -1
+}
+
 func (ms *MessengerServiceImpl) findNoteIndexInChat(chat *entity.Chat, noteId string) (int, error) {
 	for i, note := range chat.Notes {
 		if note.NoteId == noteId {
@@ -726,34 +1787,35 @@ func (ms *MessengerServiceImpl) findTicketIdAndNoteIdByNoteId(chat *entity.Chat,
 	return -1, -1, errors.New("invalid noteId")
 }
 
-func (ms *MessengerServiceImpl) updateWallpaper(workspaceId, chatId string, userId int) error {
-	client := resty.New()
-
-	reqBody := map[string]interface{}{
-		"workspace_id": workspaceId,
-		"user_id":      userId,
+func (ms *MessengerServiceImpl) deliverMessage(workspace *entity.Workspace, chat *entity.Chat, message string) (string, error) {
+	adapter, err := ms.channelRegistry.Get(chat.Source)
+	if err != nil {
+		return "", err
 	}
 
-	resp, err := client.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", "Bearer "+ms.config.Auth.IntegrationsServerSecretKey).
-		SetBody(reqBody).
-		Post(ms.config.Website.IntegrationsServerURL + "/point_ai/telegram_wrapper/get_user_avatar")
+	return adapter.SendTextMessage(workspace, chat, message)
+}
 
+func (ms *MessengerServiceImpl) updateWallpaper(workspace *entity.Workspace, chat *entity.Chat) error {
+	adapter, err := ms.channelRegistry.Get(chat.Source)
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode() != 200 {
-		return errors.New("an error occured")
+	avatar, err := adapter.GetAvatar(workspace, chat)
+	if err != nil {
+		return err
+	}
+	if len(avatar) == 0 {
+		return nil
 	}
 
-	//compressedPhoto, err := utils.ValidatePhoto(resp.Body())
+	//compressedPhoto, err := utils.ValidatePhoto(avatar)
 	//if err == nil {
-	//	ms.fileService.SaveFile("chat."+chatId, compressedPhoto)
+	//	ms.fileService.SaveFile("chat."+chat.ChatId, compressedPhoto)
 	//}
 
-	ms.fileService.SaveFile("chat."+chatId, resp.Body())
+	ms.fileService.SaveFile("chat."+chat.ChatId, avatar)
 
 	return nil
 }
@@ -773,7 +1835,7 @@ func (ms *MessengerServiceImpl) extractTicketData(tickets []entity.Ticket) (int,
 			totalSolutionTime += solutionTime
 		}
 
-		totalMessages += len(ticket.Messages)
+		totalMessages += ticket.MessageCount
 	}
 
 	averageSolutionTime := totalSolutionTime / time.Duration(totalTickets)
@@ -806,12 +1868,11 @@ func (ms *MessengerServiceImpl) createChat(tgChatId int, tgClientId int, source
 	}
 }
 
-func (ms *MessengerServiceImpl) createTicket(notes []entity.Note, messages []entity.Message, createdAt time.Time) *entity.Ticket {
+func (ms *MessengerServiceImpl) createTicket(notes []entity.Note, createdAt time.Time) *entity.Ticket {
 	return &entity.Ticket{
 		TicketId:  uuid.New().String(),
 		Subject:   "",
 		Notes:     notes,
-		Messages:  messages,
 		Status:    entity.StatusClosed,
 		CreatedAt: createdAt,
 	}
@@ -881,22 +1942,29 @@ func (ms *MessengerServiceImpl) createChatResponse(workspaceId, chatId string, t
 	}
 }
 
-func (ms *MessengerServiceImpl) GetLatestMessage(ticket entity.Ticket) *entity.Message {
-	if len(ticket.Messages) == 0 {
-		return nil
-	}
-
-	sort.Slice(ticket.Messages, func(i, j int) bool {
-		return ticket.Messages[i].CreatedAt.After(ticket.Messages[j].CreatedAt)
-	})
-
-	return &ticket.Messages[0]
+func (ms *MessengerServiceImpl) authorize(workspace *entity.Workspace, userId primitive.ObjectID, permissions ...utils.Permission) error {
+	return authorize(ms.messengerRepo, workspace, userId, permissions...)
 }
 
-func (ms *MessengerServiceImpl) isAdmin(userRole entity.WorkspaceRole) bool {
-	return userRole == entity.RoleAdmin
+// authorize is shared with the sla service, which has no messenger service of its own to ask
+func authorize(messengerRepo infrastructureInterface.MessengerRepository, workspace *entity.Workspace, userId primitive.ObjectID, permissions ...utils.Permission) error {
+	return utils.NewAuthorizer(workspace.Roles).Authorize(string(workspace.Team[userId]), workspace.RequireTwoFactor, func() (bool, error) {
+		user, err := messengerRepo.FindUserById(userId)
+		if err != nil {
+			return false, err
+		}
+		return user.TwoFactor.IsEnabled, nil
+	}, permissions...)
 }
 
-func (ms *MessengerServiceImpl) isOwner(userRole entity.WorkspaceRole) bool {
-	return userRole == entity.RoleOwner
+// authorizeChat checks the permission for an action on a chat. Chats assigned to someone else also need chat.read_all.
+func (ms *MessengerServiceImpl) authorizeChat(workspace *entity.Workspace, userId primitive.ObjectID, chat *entity.Chat, permission utils.Permission) error {
+	if chat.WorkspaceId != workspace.Id {
+		return errors.New("chat not found")
+	}
+
+	if chat.UserId != userId {
+		return ms.authorize(workspace, userId, permission, utils.PermissionChatReadAll)
+	}
+	return ms.authorize(workspace, userId, permission)
 }
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	"github.com/Point-AI/backend/internal/messenger/infrastructure/client"
+	infrastructureModel "github.com/Point-AI/backend/internal/messenger/infrastructure/model"
+	"github.com/Point-AI/backend/internal/messenger/infrastructure/repository"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const testSource entity.ChatSource = "memory"
+
+// memoryRepository keeps the documents the inbound and reply paths touch, anything else panics on the nil interface
+type memoryRepository struct {
+	infrastructureInterface.MessengerRepository
+	workspace *entity.Workspace
+	accountId string
+	users     map[primitive.ObjectID]*entity.User
+	chats     map[string]*entity.Chat
+	messages  []*entity.Message
+	mu        sync.Mutex
+}
+
+func newMemoryRepository(workspace *entity.Workspace, accountId string) *memoryRepository {
+	return &memoryRepository{
+		workspace: workspace,
+		accountId: accountId,
+		users:     make(map[primitive.ObjectID]*entity.User),
+		chats:     make(map[string]*entity.Chat),
+	}
+}
+
+func (mr *memoryRepository) FindWorkspaceByChannelAccount(source entity.ChatSource, accountId string) (*entity.Workspace, error) {
+	if source != testSource || accountId != mr.accountId {
+		return nil, nil
+	}
+	return mr.workspace, nil
+}
+
+func (mr *memoryRepository) FindWorkspaceByWorkspaceId(ctx mongo.SessionContext, workspaceId string) (*entity.Workspace, error) {
+	if workspaceId != mr.workspace.WorkspaceId {
+		return nil, errors.New("workspace not found")
+	}
+	return mr.workspace, nil
+}
+
+func (mr *memoryRepository) FindChatByWorkspaceIdAndExternalId(ctx mongo.SessionContext, workspaceId primitive.ObjectID, source entity.ChatSource, accountId, externalId string) (*entity.Chat, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	for _, chat := range mr.chats {
+		if chat.WorkspaceId == workspaceId && chat.Source == source && chat.AccountId == accountId && chat.ExternalId == externalId {
+			copied := *chat
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (mr *memoryRepository) FindChatByWorkspaceIdAndChatId(workspaceId primitive.ObjectID, chatId string) (*entity.Chat, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	chat, exists := mr.chats[chatId]
+	if !exists || chat.WorkspaceId != workspaceId {
+		return nil, errors.New("chat not found")
+	}
+	copied := *chat
+	return &copied, nil
+}
+
+func (mr *memoryRepository) CheckInboundMessageExists(source entity.ChatSource, accountId, chatId, externalId string) (bool, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	for _, message := range mr.messages {
+		if message.Source == source && message.AccountId == accountId && message.ChatId == chatId && message.ExternalId == externalId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (mr *memoryRepository) FindFirstTeamByWorkspaceId(workspaceId primitive.ObjectID) (*entity.Team, error) {
+	return nil, nil
+}
+
+func (mr *memoryRepository) FindSLAPoliciesByWorkspaceId(workspaceId primitive.ObjectID) ([]entity.SLAPolicy, error) {
+	return nil, nil
+}
+
+func (mr *memoryRepository) InsertNewChat(ctx mongo.SessionContext, chat *entity.Chat) error {
+	return mr.UpdateChat(ctx, chat)
+}
+
+func (mr *memoryRepository) UpdateChat(ctx mongo.SessionContext, chat *entity.Chat) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	copied := *chat
+	mr.chats[chat.ChatId] = &copied
+	return nil
+}
+
+func (mr *memoryRepository) DeleteChat(ctx mongo.SessionContext, chatId primitive.ObjectID) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	for key, chat := range mr.chats {
+		if chat.Id == chatId {
+			delete(mr.chats, key)
+		}
+	}
+	return nil
+}
+
+func (mr *memoryRepository) AppendMessage(ctx mongo.SessionContext, message *entity.Message) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	copied := *message
+	mr.messages = append(mr.messages, &copied)
+	return nil
+}
+
+func (mr *memoryRepository) GetUserById(id primitive.ObjectID) (*entity.User, error) {
+	user, exists := mr.users[id]
+	if !exists {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+func (mr *memoryRepository) storedMessages() []*entity.Message {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	return append([]*entity.Message(nil), mr.messages...)
+}
+
+func (mr *memoryRepository) onlyChat(t *testing.T) *entity.Chat {
+	t.Helper()
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	if len(mr.chats) != 1 {
+		t.Fatalf("expected one chat, got %d", len(mr.chats))
+	}
+	for _, chat := range mr.chats {
+		return chat
+	}
+	return nil
+}
+
+type messengerFixture struct {
+	service    *MessengerServiceImpl
+	repo       *memoryRepository
+	adapter    *client.MemoryChannelAdapterImpl
+	agentId    primitive.ObjectID
+	broadcasts chan *entity.Broadcast
+}
+
+func newMessengerFixture(t *testing.T) *messengerFixture {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Websocket.BroadcastRetention = time.Minute
+	cfg.Websocket.IdleTimeout = time.Minute
+
+	agentId := primitive.NewObjectID()
+	workspace := &entity.Workspace{
+		Id:          primitive.NewObjectID(),
+		WorkspaceId: "workspace",
+		Team:        map[primitive.ObjectID]entity.WorkspaceRole{agentId: "agent"},
+	}
+	repo := newMemoryRepository(workspace, "account")
+	repo.users[agentId] = &entity.User{Id: agentId, FullName: "Agent"}
+
+	adapter := client.NewMemoryChannelAdapterImpl()
+	registry := client.NewChannelRegistryImpl()
+	registry.Register(testSource, adapter)
+
+	broadcasts := make(chan *entity.Broadcast, 16)
+	bus := repository.NewMemoryBroadcastBusImpl(cfg)
+	bus.Subscribe(func(broadcast *entity.Broadcast) {
+		broadcasts <- broadcast
+	})
+	wss := NewWebSocketServiceImpl(cfg, repo, bus)
+	ps := NewPresenceServiceImpl(cfg, repo, wss, repository.NewMemoryPresenceStoreImpl())
+
+	ms := NewMessengerServiceImpl(cfg, repo, wss, ps, NewFileServiceImpl(t.TempDir()), nil, nil, nil, registry, nil).(*MessengerServiceImpl)
+
+	return &messengerFixture{
+		service:    ms,
+		repo:       repo,
+		adapter:    adapter,
+		agentId:    agentId,
+		broadcasts: broadcasts,
+	}
+}
+
+func (f *messengerFixture) receive(t *testing.T, accountId string, messages ...infrastructureModel.InboundMessage) error {
+	t.Helper()
+
+	payload, err := json.Marshal(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.service.HandleChannelWebhook(testSource, accountId, payload)
+}
+
+func (f *messengerFixture) nextBroadcast(t *testing.T) *entity.Broadcast {
+	t.Helper()
+
+	select {
+	case broadcast := <-f.broadcasts:
+		return broadcast
+	case <-time.After(time.Second):
+		t.Fatal("no broadcast published")
+		return nil
+	}
+}
+
+func inbound(externalMessageId, text string) infrastructureModel.InboundMessage {
+	return infrastructureModel.InboundMessage{
+		ExternalChatId:    "customer-chat",
+		ExternalSenderId:  "customer",
+		ExternalMessageId: externalMessageId,
+		SenderName:        "Customer",
+		Text:              text,
+		Type:              entity.TypeText,
+		CreatedAt:         time.Now(),
+	}
+}
+
+func TestInboundMessageOpensChatAndTicket(t *testing.T) {
+	f := newMessengerFixture(t)
+
+	if err := f.receive(t, "account", inbound("1", "hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	chat := f.repo.onlyChat(t)
+	if chat.Source != testSource || chat.AccountId != "account" || chat.ExternalId != "customer-chat" {
+		t.Fatalf("chat not keyed by its channel: %+v", chat)
+	}
+	if len(chat.Tickets) != 1 || chat.Tickets[0].Status != entity.StatusOpen {
+		t.Fatalf("expected one open ticket, got %+v", chat.Tickets)
+	}
+
+	messages := f.repo.storedMessages()
+	if len(messages) != 1 || messages[0].Message != "hello" || messages[0].TicketId != chat.Tickets[0].TicketId {
+		t.Fatalf("message not stored on the ticket: %+v", messages)
+	}
+
+	if broadcast := f.nextBroadcast(t); broadcast.Event != entity.EventMessageCreated || broadcast.Target != entity.BroadcastAll {
+		t.Fatalf("unexpected broadcast %s to %s", broadcast.Event, broadcast.Target)
+	}
+}
+
+func TestInboundMessagesOfOneCustomerShareTheChat(t *testing.T) {
+	f := newMessengerFixture(t)
+
+	if err := f.receive(t, "account", inbound("1", "hello"), inbound("2", "anyone there?")); err != nil {
+		t.Fatal(err)
+	}
+
+	chat := f.repo.onlyChat(t)
+	messages := f.repo.storedMessages()
+	if len(messages) != 2 {
+		t.Fatalf("expected two messages, got %d", len(messages))
+	}
+	for _, message := range messages {
+		if message.ChatId != chat.ChatId || message.TicketId != chat.Tickets[0].TicketId {
+			t.Fatalf("message %s not routed to the open ticket", message.ExternalId)
+		}
+	}
+}
+
+func TestRedeliveredInboundMessageIsStoredOnce(t *testing.T) {
+	f := newMessengerFixture(t)
+
+	for range 2 {
+		if err := f.receive(t, "account", inbound("1", "hello")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if messages := f.repo.storedMessages(); len(messages) != 1 {
+		t.Fatalf("expected the redelivery to be skipped, got %d messages", len(messages))
+	}
+}
+
+func TestInboundMessageForUnknownAccountIsDropped(t *testing.T) {
+	f := newMessengerFixture(t)
+
+	if err := f.receive(t, "unknown", inbound("1", "hello")); err != nil {
+		t.Fatalf("an unroutable message must not fail the webhook: %v", err)
+	}
+	if messages := f.repo.storedMessages(); len(messages) != 0 {
+		t.Fatalf("expected nothing stored, got %d messages", len(messages))
+	}
+}
+
+func TestInboundMediaFailureKeepsTheMessage(t *testing.T) {
+	f := newMessengerFixture(t)
+
+	message := inbound("1", "")
+	message.Type, message.FileId = entity.TypeImage, "missing"
+	if err := f.receive(t, "account", message); err != nil {
+		t.Fatalf("a failed download must not fail the webhook: %v", err)
+	}
+
+	if messages := f.repo.storedMessages(); len(messages) != 1 {
+		t.Fatalf("expected the message to be stored, got %d messages", len(messages))
+	}
+}
+
+func TestReplyIsDeliveredThroughTheChatAdapter(t *testing.T) {
+	f := newMessengerFixture(t)
+	if err := f.receive(t, "account", inbound("1", "hello")); err != nil {
+		t.Fatal(err)
+	}
+	chat := f.repo.onlyChat(t)
+	f.nextBroadcast(t)
+
+	response, err := f.service.HandleMessage(f.agentId, "workspace", "", chat.ChatId, "reply", "hi, how can I help?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != string(entity.MessageSent) {
+		t.Fatalf("expected status sent, got %q", response.Status)
+	}
+
+	sent := f.adapter.Sent()
+	if len(sent) != 1 || sent[0].AccountId != "account" || sent[0].ExternalChatId != "customer-chat" || sent[0].Text != "hi, how can I help?" {
+		t.Fatalf("reply not delivered to the customer chat: %+v", sent)
+	}
+
+	messages := f.repo.storedMessages()
+	reply := messages[len(messages)-1]
+	if reply.MessageId != response.MessageId || reply.ExternalId != sent[0].ExternalId || reply.Status != entity.MessageSent {
+		t.Fatalf("stored reply does not match the delivery: %+v", reply)
+	}
+	if f.repo.onlyChat(t).Tickets[0].FirstResponseAt.IsZero() {
+		t.Fatal("first response time not recorded")
+	}
+}
+
+func TestFailedReplyIsSavedWithItsStatus(t *testing.T) {
+	f := newMessengerFixture(t)
+	if err := f.receive(t, "account", inbound("1", "hello")); err != nil {
+		t.Fatal(err)
+	}
+	chat := f.repo.onlyChat(t)
+
+	f.adapter.FailSends(errors.New("provider unavailable"))
+	response, err := f.service.HandleMessage(f.agentId, "workspace", "", chat.ChatId, "reply", "hi")
+	if err != nil {
+		t.Fatalf("a failed delivery must not fail the request: %v", err)
+	}
+	if response.Status != string(entity.MessageFailed) {
+		t.Fatalf("expected status failed, got %q", response.Status)
+	}
+	if messages := f.repo.storedMessages(); len(messages) != 2 || messages[1].Status != entity.MessageFailed {
+		t.Fatalf("failed reply not saved: %+v", messages)
+	}
+	if len(f.adapter.Sent()) != 0 {
+		t.Fatal("nothing should have been delivered")
+	}
+}
+
+func TestTicketNoteIsSavedOnTheTicket(t *testing.T) {
+	f := newMessengerFixture(t)
+	if err := f.receive(t, "account", inbound("1", "hello")); err != nil {
+		t.Fatal(err)
+	}
+	chat := f.repo.onlyChat(t)
+
+	if _, err := f.service.HandleMessage(f.agentId, "workspace", chat.Tickets[0].TicketId, chat.ChatId, "ticket_note", "called them back"); err != nil {
+		t.Fatal(err)
+	}
+
+	if notes := f.repo.onlyChat(t).Tickets[0].Notes; len(notes) != 1 || notes[0].Text != "called them back" {
+		t.Fatalf("note not saved on the ticket: %+v", notes)
+	}
+}
+
+func TestTakeTicketFromChatKeepsTheOtherTickets(t *testing.T) {
+	f := newMessengerFixture(t)
+	if err := f.receive(t, "account", inbound("1", "hello")); err != nil {
+		t.Fatal(err)
+	}
+	chat := f.repo.onlyChat(t)
+	chat.Tickets = append(chat.Tickets, entity.Ticket{TicketId: "second"})
+
+	ticket, err := f.service.takeTicketFromChat(nil, chat, "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.TicketId != "second" {
+		t.Fatalf("took the wrong ticket %s", ticket.TicketId)
+	}
+	if tickets := f.repo.onlyChat(t).Tickets; len(tickets) != 1 || tickets[0].TicketId == "second" {
+		t.Fatalf("ticket not removed from the stored chat: %+v", tickets)
+	}
+}
+
+func TestTakeTicketFromChatDeletesTheEmptyChat(t *testing.T) {
+	f := newMessengerFixture(t)
+	if err := f.receive(t, "account", inbound("1", "hello")); err != nil {
+		t.Fatal(err)
+	}
+	chat := f.repo.onlyChat(t)
+	chat.Id = primitive.NewObjectID()
+
+	if _, err := f.service.takeTicketFromChat(nil, chat, chat.Tickets[0].TicketId); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.repo.chats) != 0 {
+		t.Fatalf("expected the chat without tickets to be deleted, %d left", len(f.repo.chats))
+	}
+
+	if _, err := f.service.takeTicketFromChat(nil, chat, "missing"); err == nil {
+		t.Fatal("expected an error for a ticket that is not in the chat")
+	}
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"errors"
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/messenger/delivery/model"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	_interface "github.com/Point-AI/backend/internal/messenger/domain/interface"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"sync"
+	"time"
+)
+
+type presenceKey struct {
+	workspaceId string
+	userId      primitive.ObjectID
+}
+
+// localPresence is a user with sockets on this instance
+type localPresence struct {
+	name         string
+	lastActiveAt time.Time
+}
+
+// chatView is the chat a connection has open
+type chatView struct {
+	workspaceId string
+	userId      primitive.ObjectID
+	chatId      string
+}
+
+type PresenceServiceImpl struct {
+	messengerRepo    infrastructureInterface.MessengerRepository
+	websocketService _interface.WebsocketService
+	presenceStore    infrastructureInterface.PresenceStore
+	config           *config.Config
+	instanceId       string
+	local            map[presenceKey]*localPresence
+	viewing          map[string]chatView
+	mu               sync.Mutex
+}
+
+// NewPresenceServiceImpl derives presence from the sockets every instance holds. Each instance keeps a session
+// per user in the presence store, the status announced to the workspace is worked out from all of them.
+func NewPresenceServiceImpl(cfg *config.Config, messengerRepo infrastructureInterface.MessengerRepository, websocketService _interface.WebsocketService, presenceStore infrastructureInterface.PresenceStore) _interface.PresenceService {
+	return &PresenceServiceImpl{
+		messengerRepo:    messengerRepo,
+		websocketService: websocketService,
+		presenceStore:    presenceStore,
+		config:           cfg,
+		instanceId:       uuid.New().String(),
+		local:            make(map[presenceKey]*localPresence),
+		viewing:          make(map[string]chatView),
+	}
+}
+
+// Connect is called once the socket is registered, a new connection counts as activity
+func (ps *PresenceServiceImpl) Connect(workspaceId string, userId primitive.ObjectID) {
+	var name string
+	if user, err := ps.messengerRepo.GetUserById(userId); err == nil {
+		name = user.FullName
+	}
+
+	key := presenceKey{workspaceId: workspaceId, userId: userId}
+	ps.mu.Lock()
+	ps.local[key] = &localPresence{name: name, lastActiveAt: time.Now()}
+	ps.mu.Unlock()
+
+	ps.sync(key)
+}
+
+// Disconnect is called once the socket left the websocket service. The chat it had open is released,
+// the user goes offline only when no other connection is left on any instance.
+func (ps *PresenceServiceImpl) Disconnect(workspaceId string, userId primitive.ObjectID, connectionId string) {
+	key := presenceKey{workspaceId: workspaceId, userId: userId}
+
+	ps.mu.Lock()
+	view, viewed := ps.viewing[connectionId]
+	delete(ps.viewing, connectionId)
+	name := ps.nameOf(key)
+	ps.mu.Unlock()
+
+	if viewed {
+		ps.sendActivity(view, name, entity.ActivityIdle)
+	}
+
+	ps.sync(key)
+}
+
+// Touch records activity of a user connected to this instance, an idle user is back online right away
+func (ps *PresenceServiceImpl) Touch(workspaceId string, userId primitive.ObjectID) {
+	key := presenceKey{workspaceId: workspaceId, userId: userId}
+
+	ps.mu.Lock()
+	presence, exists := ps.local[key]
+	if !exists {
+		ps.mu.Unlock()
+		return
+	}
+	wasIdle := time.Since(presence.lastActiveAt) > ps.config.Websocket.IdleTimeout
+	presence.lastActiveAt = time.Now()
+	ps.mu.Unlock()
+
+	if wasIdle {
+		ps.sync(key)
+	}
+}
+
+// SetActivity tells the other agents which chat the connection has open and whether the agent is typing in it.
+// Opening another chat releases the previous one, idle only releases the chat the connection still has open.
+func (ps *PresenceServiceImpl) SetActivity(workspaceId string, userId primitive.ObjectID, connectionId, chatId string, state entity.ChatActivity) error {
+	switch state {
+	case entity.ActivityViewing, entity.ActivityTyping, entity.ActivityIdle:
+	default:
+		return errors.New("invalid activity state")
+	}
+	if chatId == "" {
+		return errors.New("chat id is required")
+	}
+
+	key := presenceKey{workspaceId: workspaceId, userId: userId}
+	view := chatView{workspaceId: workspaceId, userId: userId, chatId: chatId}
+
+	ps.mu.Lock()
+	previous, viewed := ps.viewing[connectionId]
+	if state == entity.ActivityIdle {
+		if viewed && previous.chatId == chatId {
+			delete(ps.viewing, connectionId)
+		}
+	} else {
+		ps.viewing[connectionId] = view
+	}
+	name := ps.nameOf(key)
+	ps.mu.Unlock()
+
+	ps.Touch(workspaceId, userId)
+
+	if state == entity.ActivityIdle {
+		if viewed && previous.chatId == chatId {
+			ps.sendActivity(view, name, entity.ActivityIdle)
+		}
+		return nil
+	}
+
+	if viewed && previous.chatId != chatId {
+		ps.sendActivity(previous, name, entity.ActivityIdle)
+	}
+	ps.sendActivity(view, name, state)
+
+	return nil
+}
+
+// GetPresences returns the last announced status of every member that has been connected, the others are offline
+func (ps *PresenceServiceImpl) GetPresences(workspaceId string) ([]model.PresenceResponse, error) {
+	presences, err := ps.presenceStore.FindPresences(workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]model.PresenceResponse, 0, len(presences))
+	for _, presence := range presences {
+		response = append(response, ps.createPresenceResponse(presence.WorkspaceId, presence.UserId, presence.Status, presence.UpdatedAt))
+	}
+
+	return response, nil
+}
+
+// RunHeartbeat refreshes the sessions of this instance and picks up idle timers and manual status changes.
+// It also settles users whose sessions expired, which is how a crashed instance's users go offline.
+func (ps *PresenceServiceImpl) RunHeartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ps.mu.Lock()
+		keys := make([]presenceKey, 0, len(ps.local))
+		for key := range ps.local {
+			keys = append(keys, key)
+		}
+		ps.mu.Unlock()
+
+		for _, key := range keys {
+			ps.sync(key)
+		}
+
+		presences, err := ps.presenceStore.FindOnlinePresences()
+		if err != nil {
+			log.Println("failed to load presences:", err)
+			continue
+		}
+		for _, presence := range presences {
+			key := presenceKey{workspaceId: presence.WorkspaceId, userId: presence.UserId}
+			if !ps.isLocal(key) {
+				ps.announce(key)
+			}
+		}
+	}
+}
+
+// sync stores what this instance knows about the user and announces the resulting status if it changed
+func (ps *PresenceServiceImpl) sync(key presenceKey) {
+	connections := ps.websocketService.GetConnections(key.workspaceId)[key.userId]
+
+	ps.mu.Lock()
+	presence, exists := ps.local[key]
+	var lastActiveAt time.Time
+	if connections == 0 {
+		delete(ps.local, key)
+	} else if exists {
+		lastActiveAt = presence.lastActiveAt
+	} else {
+		lastActiveAt = time.Now()
+		ps.local[key] = &localPresence{lastActiveAt: lastActiveAt}
+	}
+	ps.mu.Unlock()
+
+	var err error
+	if connections == 0 {
+		err = ps.presenceStore.DeleteSession(ps.instanceId, key.workspaceId, key.userId)
+	} else {
+		err = ps.presenceStore.SaveSession(&entity.PresenceSession{
+			InstanceId:   ps.instanceId,
+			WorkspaceId:  key.workspaceId,
+			UserId:       key.userId,
+			Connections:  connections,
+			LastActiveAt: lastActiveAt,
+			ExpiresAt:    time.Now().Add(3 * ps.config.Websocket.PresenceInterval),
+		})
+	}
+	if err != nil {
+		log.Println("failed to save presence session:", err)
+		return
+	}
+
+	ps.announce(key)
+}
+
+// announce works the status out from the sessions of all instances, only the instance
+// that changes the stored status broadcasts it
+func (ps *PresenceServiceImpl) announce(key presenceKey) {
+	status, err := ps.resolve(key)
+	if err != nil {
+		log.Println("failed to resolve presence:", err)
+		return
+	}
+
+	changed, err := ps.presenceStore.SwapStatus(key.workspaceId, key.userId, status)
+	if err != nil {
+		log.Println("failed to save presence:", err)
+		return
+	}
+	if changed {
+		ps.websocketService.SendToAll(key.workspaceId, entity.EventPresenceChanged, ps.createPresenceResponse(key.workspaceId, key.userId, status, time.Now()))
+	}
+}
+
+// resolve puts a manual break or offline status above what the connections say,
+// a user without connections is offline whatever was set
+func (ps *PresenceServiceImpl) resolve(key presenceKey) (entity.UserStatus, error) {
+	sessions, err := ps.presenceStore.FindSessions(key.workspaceId, key.userId)
+	if err != nil {
+		return "", err
+	}
+
+	connections := 0
+	var lastActiveAt time.Time
+	for _, session := range sessions {
+		connections += session.Connections
+		if session.LastActiveAt.After(lastActiveAt) {
+			lastActiveAt = session.LastActiveAt
+		}
+	}
+	if connections == 0 {
+		return entity.StatusOffline, nil
+	}
+
+	user, err := ps.messengerRepo.GetUserById(key.userId)
+	if err != nil {
+		return "", err
+	}
+	switch user.Status {
+	case entity.StatusBusy, entity.StatusOffline:
+		return user.Status, nil
+	}
+
+	if time.Since(lastActiveAt) > ps.config.Websocket.IdleTimeout {
+		return entity.StatusIdle, nil
+	}
+
+	return entity.StatusAvailable, nil
+}
+
+func (ps *PresenceServiceImpl) sendActivity(view chatView, name string, state entity.ChatActivity) {
+	ps.websocketService.SendToAllButOne(view.workspaceId, view.userId, entity.EventTyping, model.TypingResponse{
+		WorkspaceId: view.workspaceId,
+		ChatId:      view.chatId,
+		UserId:      view.userId.Hex(),
+		Name:        name,
+		State:       string(state),
+	})
+}
+
+func (ps *PresenceServiceImpl) isLocal(key presenceKey) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	_, exists := ps.local[key]
+	return exists
+}
+
+// nameOf must be called with mu held
+func (ps *PresenceServiceImpl) nameOf(key presenceKey) string {
+	if presence, exists := ps.local[key]; exists {
+		return presence.name
+	}
+	return ""
+}
+
+func (ps *PresenceServiceImpl) createPresenceResponse(workspaceId string, userId primitive.ObjectID, status entity.UserStatus, updatedAt time.Time) model.PresenceResponse {
+	return model.PresenceResponse{
+		WorkspaceId: workspaceId,
+		UserId:      userId.Hex(),
+		Status:      string(status),
+		UpdatedAt:   updatedAt,
+	}
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"errors"
+	"github.com/Point-AI/backend/internal/messenger/delivery/model"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	_interface "github.com/Point-AI/backend/internal/messenger/domain/interface"
+	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"github.com/Point-AI/backend/utils"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"time"
+)
+
+const (
+	slaTargetFirstResponse = "first_response"
+	slaTargetResolution    = "resolution"
+
+	// slaEscalationAgent marks audit entries written by the scheduler rather than by a request
+	slaEscalationAgent = "sla-scheduler"
+)
+
+type SLAServiceImpl struct {
+	messengerRepo    infrastructureInterface.MessengerRepository
+	websocketService _interface.WebsocketService
+	messengerService _interface.MessengerService
+}
+
+func NewSLAServiceImpl(messengerRepo infrastructureInterface.MessengerRepository, websocketService _interface.WebsocketService, messengerService _interface.MessengerService) _interface.SLAService {
+	return &SLAServiceImpl{
+		messengerRepo:    messengerRepo,
+		websocketService: websocketService,
+		messengerService: messengerService,
+	}
+}
+
+func (ss *SLAServiceImpl) CreateSLAPolicy(userId primitive.ObjectID, request model.CreateSLAPolicyRequest) error {
+	workspace, err := ss.findWorkspaceAsManager(userId, request.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	policy := &entity.SLAPolicy{
+		PolicyId:             uuid.New().String(),
+		WorkspaceId:          workspace.Id,
+		Name:                 request.Name,
+		TeamId:               request.TeamId,
+		Tag:                  request.Tag,
+		FirstResponseMinutes: request.FirstResponseMinutes,
+		ResolutionMinutes:    request.ResolutionMinutes,
+		WarningMinutes:       request.WarningMinutes,
+		EscalationTeamId:     request.EscalationTeamId,
+		IsActive:             true,
+		CreatedAt:            time.Now(),
+	}
+	if err = ss.validateSLAPolicy(workspace, policy); err != nil {
+		return err
+	}
+
+	return ss.messengerRepo.InsertSLAPolicy(policy)
+}
+
+func (ss *SLAServiceImpl) UpdateSLAPolicy(userId primitive.ObjectID, request model.UpdateSLAPolicyRequest) error {
+	workspace, err := ss.findWorkspaceAsManager(userId, request.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	policy, err := ss.messengerRepo.FindSLAPolicyByPolicyId(request.PolicyId)
+	if err != nil {
+		return err
+	}
+	if policy.WorkspaceId != workspace.Id {
+		return errors.New("sla policy not found")
+	}
+
+	policy.Name = request.Name
+	policy.TeamId = request.TeamId
+	policy.Tag = request.Tag
+	policy.FirstResponseMinutes = request.FirstResponseMinutes
+	policy.ResolutionMinutes = request.ResolutionMinutes
+	policy.WarningMinutes = request.WarningMinutes
+	policy.EscalationTeamId = request.EscalationTeamId
+	policy.IsActive = request.IsActive
+	if err = ss.validateSLAPolicy(workspace, policy); err != nil {
+		return err
+	}
+
+	return ss.messengerRepo.UpdateSLAPolicy(policy)
+}
+
+func (ss *SLAServiceImpl) GetSLAPolicies(userId primitive.ObjectID, workspaceId string) ([]model.SLAPolicyResponse, error) {
+	workspace, err := ss.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = authorize(ss.messengerRepo, workspace, userId, utils.PermissionWorkspaceRead); err != nil {
+		return nil, err
+	}
+
+	policies, err := ss.messengerRepo.FindSLAPoliciesByWorkspaceId(workspace.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]model.SLAPolicyResponse, 0, len(policies))
+	for _, policy := range policies {
+		response = append(response, model.SLAPolicyResponse{
+			PolicyId:             policy.PolicyId,
+			Name:                 policy.Name,
+			TeamId:               policy.TeamId,
+			Tag:                  policy.Tag,
+			FirstResponseMinutes: policy.FirstResponseMinutes,
+			ResolutionMinutes:    policy.ResolutionMinutes,
+			WarningMinutes:       policy.WarningMinutes,
+			EscalationTeamId:     policy.EscalationTeamId,
+			IsActive:             policy.IsActive,
+			CreatedAt:            policy.CreatedAt,
+		})
+	}
+
+	return response, nil
+}
+
+func (ss *SLAServiceImpl) DeleteSLAPolicy(userId primitive.ObjectID, workspaceId, policyId string) error {
+	workspace, err := ss.findWorkspaceAsManager(userId, workspaceId)
+	if err != nil {
+		return err
+	}
+
+	return ss.messengerRepo.DeleteSLAPolicy(workspace.Id, policyId)
+}
+
+// CheckSLAs moves tickets with a running SLA to warning or breached, alerting the workspace and escalating breaches
+func (ss *SLAServiceImpl) CheckSLAs(now time.Time) error {
+	chats, err := ss.messengerRepo.FindChatsWithRunningSLA()
+	if err != nil {
+		return err
+	}
+
+	policies := make(map[string]*entity.SLAPolicy)
+	workspaces := make(map[primitive.ObjectID]*entity.Workspace)
+	for _, chat := range chats {
+		for _, ticket := range chat.Tickets {
+			if ticket.Status == entity.StatusClosed || (ticket.SLAStatus != entity.SLAOk && ticket.SLAStatus != entity.SLAWarning) {
+				continue
+			}
+
+			target, dueAt := ss.nextDeadline(ticket)
+			if dueAt.IsZero() {
+				continue
+			}
+
+			policy, ok := policies[ticket.SLAPolicyId]
+			if !ok {
+				// a deleted policy still holds the ticket to the deadlines it was given
+				policy, _ = ss.messengerRepo.FindSLAPolicyByPolicyId(ticket.SLAPolicyId)
+				policies[ticket.SLAPolicyId] = policy
+			}
+
+			var next entity.SLAStatus
+			switch {
+			case !now.Before(dueAt):
+				next = entity.SLABreached
+			case ticket.SLAStatus == entity.SLAOk && policy != nil && policy.WarningMinutes > 0 && !now.Before(dueAt.Add(-time.Duration(policy.WarningMinutes)*time.Minute)):
+				next = entity.SLAWarning
+			default:
+				continue
+			}
+
+			moved, err := ss.messengerRepo.UpdateTicketSLAStatus(ticket.TicketId, ticket.SLAStatus, next)
+			if err != nil {
+				log.Println("failed to update the sla status of ticket", ticket.TicketId, err)
+				continue
+			}
+			if !moved {
+				continue
+			}
+
+			workspace, ok := workspaces[chat.WorkspaceId]
+			if !ok {
+				if workspace, err = ss.messengerRepo.FindWorkspaceById(chat.WorkspaceId); err != nil {
+					log.Println("failed to find the workspace of ticket", ticket.TicketId, err)
+					continue
+				}
+				workspaces[chat.WorkspaceId] = workspace
+			}
+
+			if err = ss.sendAlert(workspace, &chat, ticket, next, target, dueAt); err != nil {
+				log.Println("failed to send the sla alert of ticket", ticket.TicketId, err)
+			}
+
+			if next == entity.SLABreached && policy != nil && policy.EscalationTeamId != "" {
+				if err = ss.escalate(workspace, &chat, ticket, policy.EscalationTeamId); err != nil {
+					log.Println("failed to escalate ticket", ticket.TicketId, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (ss *SLAServiceImpl) RunScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if err := ss.CheckSLAs(now); err != nil {
+			log.Println("sla check failed:", err)
+		}
+	}
+}
+
+// nextDeadline returns the earliest deadline the ticket can still miss
+func (ss *SLAServiceImpl) nextDeadline(ticket entity.Ticket) (string, time.Time) {
+	var target string
+	var dueAt time.Time
+
+	if !ticket.FirstResponseDueAt.IsZero() && (ticket.FirstResponseAt.IsZero() || ticket.FirstResponseAt.After(ticket.FirstResponseDueAt)) {
+		target, dueAt = slaTargetFirstResponse, ticket.FirstResponseDueAt
+	}
+	if !ticket.SLADueAt.IsZero() && (dueAt.IsZero() || ticket.SLADueAt.Before(dueAt)) {
+		target, dueAt = slaTargetResolution, ticket.SLADueAt
+	}
+
+	return target, dueAt
+}
+
+func (ss *SLAServiceImpl) sendAlert(workspace *entity.Workspace, chat *entity.Chat, ticket entity.Ticket, status entity.SLAStatus, target string, dueAt time.Time) error {
+	ss.websocketService.SendToAll(workspace.WorkspaceId, entity.EventTicketSLAAlert, model.SLAAlertResponse{
+		Type:        "sla_" + string(status),
+		WorkspaceId: workspace.WorkspaceId,
+		ChatId:      chat.ChatId,
+		TicketId:    ticket.TicketId,
+		PolicyId:    ticket.SLAPolicyId,
+		Target:      target,
+		DueAt:       dueAt,
+	})
+	return nil
+}
+
+// escalate reassigns the ticket on behalf of its assignee, or of the workspace owner when the chat is unassigned
+// or the assignee may not reassign tickets
+func (ss *SLAServiceImpl) escalate(workspace *entity.Workspace, chat *entity.Chat, ticket entity.Ticket, teamId string) error {
+	actorId := chat.UserId
+	if authorize(ss.messengerRepo, workspace, actorId, utils.PermissionTicketReassign) != nil {
+		actorId = primitive.NilObjectID
+		for memberId, role := range workspace.Team {
+			if role == entity.RoleOwner {
+				actorId = memberId
+				break
+			}
+		}
+	}
+	if actorId.IsZero() {
+		return errors.New("no workspace member to escalate on behalf of")
+	}
+
+	return ss.messengerService.ReassignTicketToTeam(actorId, chat.ChatId, ticket.TicketId, workspace.WorkspaceId, teamId, entity.AuditMeta{UserAgent: slaEscalationAgent})
+}
+
+func (ss *SLAServiceImpl) findWorkspaceAsManager(userId primitive.ObjectID, workspaceId string) (*entity.Workspace, error) {
+	workspace, err := ss.messengerRepo.FindWorkspaceByWorkspaceId(nil, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = authorize(ss.messengerRepo, workspace, userId, utils.PermissionSLAManage); err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+func (ss *SLAServiceImpl) validateSLAPolicy(workspace *entity.Workspace, policy *entity.SLAPolicy) error {
+	if policy.Name == "" {
+		return errors.New("sla policy name is required")
+	}
+	if policy.FirstResponseMinutes < 0 || policy.ResolutionMinutes < 0 || policy.WarningMinutes < 0 {
+		return errors.New("sla times cannot be negative")
+	}
+	if policy.FirstResponseMinutes == 0 && policy.ResolutionMinutes == 0 {
+		return errors.New("sla policy needs a first response or a resolution time")
+	}
+
+	for _, teamId := range []string{policy.TeamId, policy.EscalationTeamId} {
+		if teamId == "" {
+			continue
+		}
+		if _, err := ss.messengerRepo.FindTeamByWorkspaceIdAndTeamId(workspace.Id, teamId); err != nil {
+			return errors.New("team not found")
+		}
+	}
+
+	return nil
+}
@@ -1,24 +1,48 @@
 package service
 
 import (
+	"encoding/json"
+	"errors"
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/messenger/delivery/model"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
 	_interface "github.com/Point-AI/backend/internal/messenger/domain/interface"
 	infrastructureInterface "github.com/Point-AI/backend/internal/messenger/service/interface"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
 	"net/http"
-	"sync"
+	"strconv"
+	"sync/atomic"
+	"time"
 )
 
+// eventVersion is the version of the event envelope, it changes only when clients have to change with it
+const eventVersion = 1
+
 type WebSocketServiceImpl struct {
 	messengerRepo infrastructureInterface.MessengerRepository
-	connections   map[string]map[primitive.ObjectID]*websocket.Conn
+	broadcastBus  infrastructureInterface.BroadcastBus
+	hub           *connectionHub
 	upgrader      *websocket.Upgrader
-	mu            sync.RWMutex
+	config        *config.Config
+	metrics       websocketMetrics
+}
+
+type websocketMetrics struct {
+	messagesDropped    atomic.Int64
+	connectionsEvicted atomic.Int64
+	writeFailures      atomic.Int64
 }
 
-func NewWebSocketServiceImpl(messengerRepo infrastructureInterface.MessengerRepository) _interface.WebsocketService {
-	return &WebSocketServiceImpl{
+// NewWebSocketServiceImpl sends every event through the broadcast bus, so agents connected to another
+// instance get it too. Each instance writes only to the sockets it holds.
+func NewWebSocketServiceImpl(cfg *config.Config, messengerRepo infrastructureInterface.MessengerRepository, broadcastBus infrastructureInterface.BroadcastBus) _interface.WebsocketService {
+	wss := &WebSocketServiceImpl{
+		config:        cfg,
 		messengerRepo: messengerRepo,
+		broadcastBus:  broadcastBus,
 		upgrader: &websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
@@ -26,81 +50,249 @@ func NewWebSocketServiceImpl(messengerRepo infrastructureInterface.MessengerRepo
 				return true
 			},
 		},
-		connections: make(map[string]map[primitive.ObjectID]*websocket.Conn),
+		hub: newConnectionHub(),
 	}
+	broadcastBus.Subscribe(wss.deliver)
+
+	return wss
 }
 
-func (wss *WebSocketServiceImpl) UpgradeConnection(w http.ResponseWriter, r *http.Request, workspaceId string, userId primitive.ObjectID) (*websocket.Conn, error) {
+// UpgradeConnection replays the events after the last_event_id query parameter before any new one.
+// Without it the connection resumes after the last event the user acknowledged.
+func (wss *WebSocketServiceImpl) UpgradeConnection(w http.ResponseWriter, r *http.Request, workspaceId string, userId primitive.ObjectID) (*websocket.Conn, string, error) {
 	conn, err := wss.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	wss.AddConnection(workspaceId, conn, userId)
-	return conn, nil
+	c := newWSConnection(uuid.New().String(), workspaceId, userId, conn, wss.config.Websocket)
+	// registered before the backlog is read, so nothing published in between is lost
+	wss.hub.add(c)
+	wss.start(c, wss.replay(workspaceId, userId, r.URL.Query().Get("last_event_id")))
+
+	return conn, c.id, nil
+}
+
+func (wss *WebSocketServiceImpl) SendToAll(workspaceId string, event entity.EventType, payload interface{}) {
+	wss.publish(&entity.Broadcast{
+		WorkspaceId: workspaceId,
+		Target:      entity.BroadcastAll,
+		Event:       event,
+	}, payload)
 }
 
-func (wss *WebSocketServiceImpl) SendToAll(workspaceId string, message []byte) {
-	wss.mu.RLock()
-	defer wss.mu.RUnlock()
+func (wss *WebSocketServiceImpl) SendToAllButOne(workspaceId string, userId primitive.ObjectID, event entity.EventType, payload interface{}) {
+	wss.publish(&entity.Broadcast{
+		WorkspaceId: workspaceId,
+		Target:      entity.BroadcastAllButOne,
+		UserId:      userId,
+		Event:       event,
+	}, payload)
+}
 
-	conns := wss.connections[workspaceId]
-	if conns == nil {
+// SendToOne reaches every tab the user has open
+func (wss *WebSocketServiceImpl) SendToOne(workspaceId string, userId primitive.ObjectID, event entity.EventType, payload interface{}) {
+	wss.publish(&entity.Broadcast{
+		WorkspaceId: workspaceId,
+		Target:      entity.BroadcastOne,
+		UserId:      userId,
+		Event:       event,
+	}, payload)
+}
+
+// publish only logs failures, the change behind the event is already saved
+func (wss *WebSocketServiceImpl) publish(broadcast *entity.Broadcast, payload interface{}) {
+	var err error
+	if broadcast.Payload, err = json.Marshal(payload); err != nil {
+		log.Printf("failed to encode %s event: %v", broadcast.Event, err)
 		return
 	}
 
-	for id, conn := range conns {
-		if err := conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
-			wss.RemoveConnection(workspaceId, id)
+	if err = wss.broadcastBus.Publish(broadcast); err != nil {
+		log.Printf("failed to publish %s event: %v", broadcast.Event, err)
+	}
+}
+
+// deliver queues a broadcast on the matching connections of this instance. A client that cannot keep up
+// is disconnected rather than allowed to hold up the others, it reconnects and replays what it missed.
+func (wss *WebSocketServiceImpl) deliver(broadcast *entity.Broadcast) {
+	frame, err := wss.createFrame(broadcast.Event, broadcast.Sequence, broadcast.CreatedAt, broadcast.Payload)
+	if err != nil {
+		log.Printf("failed to encode %s event: %v", broadcast.Event, err)
+		return
+	}
+
+	conns := wss.hub.matching(broadcast.WorkspaceId, func(userId primitive.ObjectID) bool {
+		return wss.isRecipient(broadcast, userId)
+	})
+	for _, c := range conns {
+		if !c.enqueue(queuedEvent{sequence: broadcast.Sequence, frame: frame}) {
+			wss.evict(c)
 		}
 	}
 }
 
-func (wss *WebSocketServiceImpl) SendToAllButOne(workspaceId string, message []byte, userId primitive.ObjectID) {
-	wss.mu.RLock()
-	defer wss.mu.RUnlock()
+// replay collects the events a reconnecting client missed. When some of them expired already
+// the client is only told to reload, a partial history would look complete to it.
+func (wss *WebSocketServiceImpl) replay(workspaceId string, userId primitive.ObjectID, lastEventId string) []queuedEvent {
+	var afterSequence int64
+	if lastEventId != "" {
+		sequence, err := strconv.ParseInt(lastEventId, 10, 64)
+		if err != nil {
+			return wss.resync()
+		}
+		afterSequence = sequence
+	} else {
+		cursor, err := wss.broadcastBus.FindCursor(workspaceId, userId)
+		if err != nil {
+			log.Println("failed to load event cursor:", err)
+			return wss.resync()
+		}
+		if cursor == nil {
+			return nil
+		}
+		afterSequence = cursor.Sequence
+	}
 
-	conns := wss.connections[workspaceId]
-	if conns == nil {
-		return
+	broadcasts, complete, err := wss.broadcastBus.Replay(workspaceId, afterSequence)
+	if err != nil {
+		log.Println("failed to replay events:", err)
+		return wss.resync()
+	}
+	if !complete {
+		return wss.resync()
 	}
 
-	for id, conn := range conns {
-		if id == userId {
+	var backlog []queuedEvent
+	for _, broadcast := range broadcasts {
+		if !wss.isRecipient(broadcast, userId) {
 			continue
 		}
 
-		if err := conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
-			wss.RemoveConnection(workspaceId, id)
+		frame, err := wss.createFrame(broadcast.Event, broadcast.Sequence, broadcast.CreatedAt, broadcast.Payload)
+		if err != nil {
+			log.Printf("failed to encode %s event: %v", broadcast.Event, err)
+			continue
 		}
+		backlog = append(backlog, queuedEvent{sequence: broadcast.Sequence, frame: frame})
 	}
+
+	return backlog
 }
 
-func (wss *WebSocketServiceImpl) SendToOne(message []byte, workspaceId string, userId primitive.ObjectID) {
-	if err := wss.connections[workspaceId][userId].WriteMessage(websocket.BinaryMessage, message); err != nil {
-		wss.RemoveConnection(workspaceId, userId)
+func (wss *WebSocketServiceImpl) resync() []queuedEvent {
+	frame, err := wss.createFrame(entity.EventResyncRequired, 0, time.Now(), []byte("{}"))
+	if err != nil {
+		return nil
 	}
+	return []queuedEvent{{frame: frame}}
 }
 
-func (wss *WebSocketServiceImpl) AddConnection(workspaceId string, conn *websocket.Conn, userId primitive.ObjectID) {
-	wss.mu.Lock()
-	defer wss.mu.Unlock()
+// Acknowledge records the last event the connection handled, it becomes the user's resume point once it closes
+func (wss *WebSocketServiceImpl) Acknowledge(workspaceId string, userId primitive.ObjectID, connectionId, eventId string) error {
+	sequence, err := strconv.ParseInt(eventId, 10, 64)
+	if err != nil || sequence <= 0 {
+		return errors.New("invalid event id")
+	}
 
-	if wss.connections[workspaceId] == nil {
-		wss.connections[workspaceId] = make(map[primitive.ObjectID]*websocket.Conn)
+	c := wss.hub.find(workspaceId, userId, connectionId)
+	if c == nil {
+		return errors.New("connection not found")
 	}
+	c.acknowledge(sequence)
 
-	wss.connections[workspaceId][userId] = conn
+	return nil
 }
 
-func (wss *WebSocketServiceImpl) RemoveConnection(workspaceId string, userId primitive.ObjectID) {
-	wss.mu.Lock()
-	defer wss.mu.Unlock()
-	wss.connections[workspaceId][userId] = nil
+// AddConnection registers the socket next to the other tabs of the user and returns its connection id
+func (wss *WebSocketServiceImpl) AddConnection(workspaceId string, conn *websocket.Conn, userId primitive.ObjectID) string {
+	c := newWSConnection(uuid.New().String(), workspaceId, userId, conn, wss.config.Websocket)
+	wss.hub.add(c)
+	wss.start(c, nil)
+
+	return c.id
 }
 
-func (wss *WebSocketServiceImpl) GetConnections(workspaceId string) map[primitive.ObjectID]*websocket.Conn {
-	wss.mu.RLock()
-	defer wss.mu.RUnlock()
-	return wss.connections[workspaceId]
+func (wss *WebSocketServiceImpl) start(c *wsConnection, backlog []queuedEvent) {
+	// a write also fails when the connection was closed under the writer, only count the ones that were still open
+	go c.writePump(backlog, func() {
+		if wss.hub.remove(c.workspaceId, c.userId, c.id) != nil {
+			wss.metrics.writeFailures.Add(1)
+			wss.disconnect(c, websocket.CloseGoingAway, "write failed")
+		}
+	})
+}
+
+func (wss *WebSocketServiceImpl) evict(c *wsConnection) {
+	if wss.hub.remove(c.workspaceId, c.userId, c.id) == nil {
+		return
+	}
+
+	pending := c.close(websocket.CloseTryAgainLater, "slow consumer")
+	// evict runs while the bus hands out a broadcast, the cursor is saved outside of it
+	go wss.saveCursor(c)
+	wss.metrics.connectionsEvicted.Add(1)
+	wss.metrics.messagesDropped.Add(int64(pending) + 1)
+	log.Printf("evicted slow websocket connection %s of user %s, %d messages dropped", c.id, c.userId.Hex(), pending+1)
+}
+
+// disconnect closes a connection that already left the hub and keeps its acknowledged position
+func (wss *WebSocketServiceImpl) disconnect(c *wsConnection, code int, reason string) {
+	c.close(code, reason)
+	wss.saveCursor(c)
+}
+
+func (wss *WebSocketServiceImpl) saveCursor(c *wsConnection) {
+	if sequence := c.acked.Load(); sequence > 0 {
+		if err := wss.broadcastBus.SaveCursor(&entity.EventCursor{WorkspaceId: c.workspaceId, UserId: c.userId, Sequence: sequence}); err != nil {
+			log.Println("failed to save event cursor:", err)
+		}
+	}
+}
+
+// RemoveConnection closes the socket, the other tabs of the user stay open
+func (wss *WebSocketServiceImpl) RemoveConnection(workspaceId string, userId primitive.ObjectID, connectionId string) {
+	if c := wss.hub.remove(workspaceId, userId, connectionId); c != nil {
+		wss.disconnect(c, websocket.CloseNormalClosure, "")
+	}
+}
+
+// GetConnections returns how many connections every user of the workspace has open on this instance
+func (wss *WebSocketServiceImpl) GetConnections(workspaceId string) map[primitive.ObjectID]int {
+	return wss.hub.counts(workspaceId)
+}
+
+func (wss *WebSocketServiceImpl) GetMetrics() model.WebsocketMetricsResponse {
+	return model.WebsocketMetricsResponse{
+		OpenConnections:    wss.hub.total(),
+		MessagesDropped:    wss.metrics.messagesDropped.Load(),
+		ConnectionsEvicted: wss.metrics.connectionsEvicted.Load(),
+		WriteFailures:      wss.metrics.writeFailures.Load(),
+	}
+}
+
+func (wss *WebSocketServiceImpl) isRecipient(broadcast *entity.Broadcast, userId primitive.ObjectID) bool {
+	switch broadcast.Target {
+	case entity.BroadcastOne:
+		return userId == broadcast.UserId
+	case entity.BroadcastAllButOne:
+		return userId != broadcast.UserId
+	default:
+		return true
+	}
+}
+
+// createFrame wraps a payload in the event envelope, events that are not replayed have no id
+func (wss *WebSocketServiceImpl) createFrame(event entity.EventType, sequence int64, createdAt time.Time, payload []byte) ([]byte, error) {
+	envelope := model.EventEnvelope{
+		Version: eventVersion,
+		Type:    string(event),
+		Ts:      createdAt,
+		Payload: payload,
+	}
+	if sequence > 0 {
+		envelope.Id = strconv.FormatInt(sequence, 10)
+	}
+
+	return json.Marshal(envelope)
 }
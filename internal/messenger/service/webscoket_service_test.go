@@ -0,0 +1,411 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/messenger/delivery/model"
+	"github.com/Point-AI/backend/internal/messenger/domain/entity"
+	"github.com/Point-AI/backend/internal/messenger/infrastructure/repository"
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testWorkspaceId = "workspace"
+
+// wsFixture serves the websocket service on an httptest server. Like HandleChatWS, the handler reads
+// until the socket fails and then removes the connection, unless reading is turned off.
+type wsFixture struct {
+	service  *WebSocketServiceImpl
+	server   *httptest.Server
+	readErrs chan error
+}
+
+func testWebsocketSettings() config.Websocket {
+	return config.Websocket{
+		BroadcastRetention: time.Minute,
+		SendQueueSize:      16,
+		PingInterval:       time.Minute,
+		PongTimeout:        time.Minute,
+		WriteTimeout:       2 * time.Second,
+	}
+}
+
+func newWSFixture(t *testing.T, settings config.Websocket, serverReads bool) *wsFixture {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Websocket = settings
+	f := &wsFixture{
+		service:  NewWebSocketServiceImpl(cfg, nil, repository.NewMemoryBroadcastBusImpl(cfg)).(*WebSocketServiceImpl),
+		readErrs: make(chan error, 16),
+	}
+
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId, err := primitive.ObjectIDFromHex(r.URL.Query().Get("user"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn, connectionId, err := f.service.UpgradeConnection(w, r, testWorkspaceId, userId)
+		if err != nil || !serverReads {
+			return
+		}
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				f.service.RemoveConnection(testWorkspaceId, userId, connectionId)
+				f.readErrs <- err
+				return
+			}
+
+			var event model.EventRequest
+			if json.Unmarshal(message, &event) == nil && entity.EventType(event.Type) == entity.EventAck {
+				f.service.Acknowledge(testWorkspaceId, userId, connectionId, event.Id)
+			}
+		}
+	}))
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+// dial connects as the user and waits until the service holds the connection
+func (f *wsFixture) dial(t *testing.T, userId primitive.ObjectID, query string) *websocket.Conn {
+	t.Helper()
+
+	before := f.service.GetConnections(testWorkspaceId)[userId]
+	url := "ws" + strings.TrimPrefix(f.server.URL, "http") + "/?user=" + userId.Hex() + query
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	waitFor(t, "the connection to be registered", func() bool {
+		return f.service.GetConnections(testWorkspaceId)[userId] == before+1
+	})
+	return conn
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) model.EventEnvelope {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	messageType, frame, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageType != websocket.TextMessage {
+		t.Fatalf("expected a text frame, got %d", messageType)
+	}
+
+	var envelope model.EventEnvelope
+	if err = json.Unmarshal(frame, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	return envelope
+}
+
+// readUntilClosed drains the socket and returns the error that ended it
+func readUntilClosed(conn *websocket.Conn, delay time.Duration) error {
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return err
+		}
+		time.Sleep(delay)
+	}
+}
+
+func TestWritePumpDeliversEventsInOrderToTheirRecipients(t *testing.T) {
+	f := newWSFixture(t, testWebsocketSettings(), true)
+	agentId, otherId := primitive.NewObjectID(), primitive.NewObjectID()
+	conn := f.dial(t, agentId, "")
+
+	f.service.SendToAll(testWorkspaceId, entity.EventMessageCreated, map[string]string{"text": "hello"})
+	f.service.SendToOne(testWorkspaceId, otherId, entity.EventChatUpdated, map[string]string{})
+	f.service.SendToAllButOne(testWorkspaceId, agentId, entity.EventTicketAssigned, map[string]string{})
+	f.service.SendToOne(testWorkspaceId, agentId, entity.EventTicketStatusChanged, map[string]string{})
+	f.service.SendToAll(testWorkspaceId, entity.EventTyping, map[string]string{})
+
+	expected := []struct {
+		event entity.EventType
+		id    string
+	}{
+		{entity.EventMessageCreated, "1"},
+		{entity.EventTicketStatusChanged, "4"},
+		{entity.EventTyping, ""},
+	}
+	for _, want := range expected {
+		envelope := readEvent(t, conn)
+		if envelope.Type != string(want.event) || envelope.Id != want.id || envelope.Version != eventVersion {
+			t.Fatalf("expected %s with id %q, got %+v", want.event, want.id, envelope)
+		}
+	}
+}
+
+func TestEveryTabOfTheUserGetsTheEvent(t *testing.T) {
+	f := newWSFixture(t, testWebsocketSettings(), true)
+	agentId := primitive.NewObjectID()
+	first, second := f.dial(t, agentId, ""), f.dial(t, agentId, "")
+
+	f.service.SendToOne(testWorkspaceId, agentId, entity.EventChatUpdated, map[string]string{})
+
+	for _, conn := range []*websocket.Conn{first, second} {
+		if envelope := readEvent(t, conn); envelope.Type != string(entity.EventChatUpdated) {
+			t.Fatalf("unexpected event %+v", envelope)
+		}
+	}
+	if metrics := f.service.GetMetrics(); metrics.OpenConnections != 2 {
+		t.Fatalf("expected two open connections, got %d", metrics.OpenConnections)
+	}
+}
+
+func TestReconnectReplaysMissedEvents(t *testing.T) {
+	f := newWSFixture(t, testWebsocketSettings(), true)
+	agentId := primitive.NewObjectID()
+
+	for range 3 {
+		f.service.SendToAll(testWorkspaceId, entity.EventMessageCreated, map[string]string{})
+	}
+	conn := f.dial(t, agentId, "&last_event_id=1")
+	f.service.SendToAll(testWorkspaceId, entity.EventChatUpdated, map[string]string{})
+
+	for _, id := range []string{"2", "3", "4"} {
+		if envelope := readEvent(t, conn); envelope.Id != id {
+			t.Fatalf("expected event %s, got %+v", id, envelope)
+		}
+	}
+
+	invalid := f.dial(t, agentId, "&last_event_id=latest")
+	if envelope := readEvent(t, invalid); envelope.Type != string(entity.EventResyncRequired) {
+		t.Fatalf("expected a resync for an unreadable event id, got %+v", envelope)
+	}
+}
+
+func TestPingKeepsAResponsiveClientConnected(t *testing.T) {
+	settings := testWebsocketSettings()
+	settings.PingInterval = 20 * time.Millisecond
+	settings.PongTimeout = 100 * time.Millisecond
+	f := newWSFixture(t, settings, true)
+	agentId := primitive.NewObjectID()
+	conn := f.dial(t, agentId, "")
+
+	pings := make(chan struct{}, 64)
+	conn.SetPingHandler(func(data string) error {
+		pings <- struct{}{}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go readUntilClosed(conn, 0)
+
+	time.Sleep(4 * settings.PongTimeout)
+
+	if len(pings) < 5 {
+		t.Fatalf("expected regular pings, got %d", len(pings))
+	}
+	if count := f.service.GetConnections(testWorkspaceId)[agentId]; count != 1 {
+		t.Fatal("a client answering pings must stay connected")
+	}
+}
+
+func TestSilentClientIsDisconnectedAtTheReadDeadline(t *testing.T) {
+	settings := testWebsocketSettings()
+	settings.PingInterval = 20 * time.Millisecond
+	settings.PongTimeout = 100 * time.Millisecond
+	f := newWSFixture(t, settings, true)
+	agentId := primitive.NewObjectID()
+	conn := f.dial(t, agentId, "")
+
+	// the client reads, so it sees the pings, but never answers them
+	conn.SetPingHandler(func(string) error { return nil })
+	closed := make(chan error, 1)
+	go func() { closed <- readUntilClosed(conn, 0) }()
+
+	select {
+	case err := <-f.readErrs:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Fatalf("expected the server read to time out, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the silent client was not disconnected")
+	}
+
+	if err := <-closed; !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("expected a normal close frame, got %v", err)
+	}
+	if count := f.service.GetConnections(testWorkspaceId)[agentId]; count != 0 {
+		t.Fatal("expected the connection to be removed")
+	}
+}
+
+func TestSlowConsumerIsEvicted(t *testing.T) {
+	settings := testWebsocketSettings()
+	settings.SendQueueSize = 1
+	f := newWSFixture(t, settings, true)
+	slowId, fastId := primitive.NewObjectID(), primitive.NewObjectID()
+	slow := f.dial(t, slowId, "")
+	fast := f.dial(t, fastId, "")
+
+	// the slow client drains a little at a time, so the writer blocks on a full socket and the queue fills up
+	closed := make(chan error, 1)
+	go func() { closed <- readUntilClosed(slow, time.Millisecond) }()
+	go readUntilClosed(fast, 0)
+
+	payload := map[string]string{"text": strings.Repeat("x", 32*1024)}
+	for sent := 0; f.service.GetMetrics().ConnectionsEvicted == 0; sent++ {
+		if sent == 5000 {
+			t.Fatal("the slow consumer was never evicted")
+		}
+		f.service.SendToOne(testWorkspaceId, slowId, entity.EventMessageCreated, payload)
+	}
+
+	select {
+	case err := <-closed:
+		if !websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+			t.Fatalf("expected close code 1013, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the slow client was not closed")
+	}
+
+	metrics := f.service.GetMetrics()
+	if metrics.ConnectionsEvicted != 1 || metrics.MessagesDropped < 1 || metrics.WriteFailures != 0 {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+	if metrics.OpenConnections != 1 || f.service.GetConnections(testWorkspaceId)[fastId] != 1 {
+		t.Fatal("the other client must stay connected")
+	}
+}
+
+func TestEvictingAnAcknowledgingConsumerKeepsItsPosition(t *testing.T) {
+	settings := testWebsocketSettings()
+	settings.SendQueueSize = 1
+	f := newWSFixture(t, settings, true)
+	slowId := primitive.NewObjectID()
+	slow := f.dial(t, slowId, "")
+
+	f.service.SendToOne(testWorkspaceId, slowId, entity.EventChatUpdated, map[string]string{})
+	if envelope := readEvent(t, slow); envelope.Id != "1" {
+		t.Fatalf("expected event 1, got %+v", envelope)
+	}
+	if err := slow.WriteJSON(model.EventRequest{Type: string(entity.EventAck), Id: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the ack to be recorded", func() bool {
+		conns := f.service.hub.matching(testWorkspaceId, func(userId primitive.ObjectID) bool { return userId == slowId })
+		return len(conns) == 1 && conns[0].acked.Load() == 1
+	})
+
+	// the eviction saves the cursor while the bus is publishing, a later publish must still go through
+	go readUntilClosed(slow, time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		payload := map[string]string{"text": strings.Repeat("x", 32*1024)}
+		for sent := 0; f.service.GetMetrics().ConnectionsEvicted == 0 && sent < 5000; sent++ {
+			f.service.SendToOne(testWorkspaceId, slowId, entity.EventMessageCreated, payload)
+		}
+		f.service.SendToAll(testWorkspaceId, entity.EventChatUpdated, map[string]string{})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("publishing hung after the eviction")
+	}
+	if f.service.GetMetrics().ConnectionsEvicted != 1 {
+		t.Fatal("the slow consumer was never evicted")
+	}
+
+	waitFor(t, "the cursor to be saved", func() bool {
+		cursor, err := f.service.broadcastBus.FindCursor(testWorkspaceId, slowId)
+		return err == nil && cursor != nil && cursor.Sequence == 1
+	})
+}
+
+func TestFailedWriteDropsTheConnection(t *testing.T) {
+	// the server does not read, so only the writer can notice the client is gone
+	f := newWSFixture(t, testWebsocketSettings(), false)
+	agentId := primitive.NewObjectID()
+	conn := f.dial(t, agentId, "")
+
+	conn.UnderlyingConn().Close()
+
+	for sent := 0; f.service.GetMetrics().WriteFailures == 0; sent++ {
+		if sent == 500 {
+			t.Fatal("the write never failed")
+		}
+		f.service.SendToOne(testWorkspaceId, agentId, entity.EventChatUpdated, map[string]string{})
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	metrics := f.service.GetMetrics()
+	if metrics.WriteFailures != 1 || metrics.OpenConnections != 0 || metrics.ConnectionsEvicted != 0 {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestClosedConnectionIsNotCountedAsWriteFailure(t *testing.T) {
+	f := newWSFixture(t, testWebsocketSettings(), true)
+	agentId := primitive.NewObjectID()
+	conn := f.dial(t, agentId, "")
+
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	<-f.readErrs
+	f.service.SendToOne(testWorkspaceId, agentId, entity.EventChatUpdated, map[string]string{})
+
+	if metrics := f.service.GetMetrics(); metrics.WriteFailures != 0 || metrics.OpenConnections != 0 || metrics.MessagesDropped != 0 {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestReconnectResumesAfterTheFurthestAcknowledgedEvent(t *testing.T) {
+	f := newWSFixture(t, testWebsocketSettings(), true)
+	agentId := primitive.NewObjectID()
+	ahead, behind := f.dial(t, agentId, ""), f.dial(t, agentId, "")
+
+	for range 3 {
+		f.service.SendToAll(testWorkspaceId, entity.EventMessageCreated, map[string]string{})
+	}
+	ack := func(conn *websocket.Conn, id string) {
+		if err := conn.WriteJSON(model.EventRequest{Type: string(entity.EventAck), Id: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ack(ahead, "3")
+	ack(behind, "1")
+
+	// the tab that got further closes first, the one closing later must not move the resume point back
+	for _, conn := range []*websocket.Conn{ahead, behind} {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		<-f.readErrs
+	}
+
+	f.service.SendToAll(testWorkspaceId, entity.EventChatUpdated, map[string]string{})
+	conn := f.dial(t, agentId, "")
+	if envelope := readEvent(t, conn); envelope.Id != "4" || envelope.Type != string(entity.EventChatUpdated) {
+		t.Fatalf("expected to resume with event 4, got %+v", envelope)
+	}
+}
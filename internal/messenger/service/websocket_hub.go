@@ -0,0 +1,234 @@
+package service
+
+import (
+	"github.com/Point-AI/backend/config"
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// queuedEvent is an encoded event waiting for the writer, sequence is 0 for events that are not replayed
+type queuedEvent struct {
+	sequence int64
+	frame    []byte
+}
+
+// wsConnection is one open socket. An agent has one for every tab, only its writer goroutine writes messages to conn.
+type wsConnection struct {
+	id          string
+	workspaceId string
+	userId      primitive.ObjectID
+	conn        *websocket.Conn
+	settings    config.Websocket
+	send        chan queuedEvent
+	done        chan struct{}
+	closeOnce   sync.Once
+	acked       atomic.Int64
+}
+
+func newWSConnection(id, workspaceId string, userId primitive.ObjectID, conn *websocket.Conn, settings config.Websocket) *wsConnection {
+	c := &wsConnection{
+		id:          id,
+		workspaceId: workspaceId,
+		userId:      userId,
+		conn:        conn,
+		settings:    settings,
+		send:        make(chan queuedEvent, settings.SendQueueSize),
+		done:        make(chan struct{}),
+	}
+
+	// any pong proves the client is alive, the reader fails once it stays silent past the timeout
+	conn.SetReadDeadline(time.Now().Add(settings.PongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(settings.PongTimeout))
+	})
+
+	return c
+}
+
+// enqueue never waits, it reports false when the queue is full or the connection is closed
+func (c *wsConnection) enqueue(event queuedEvent) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+// acknowledge moves the acked position forward only, acks may arrive out of order
+func (c *wsConnection) acknowledge(sequence int64) {
+	for {
+		current := c.acked.Load()
+		if sequence <= current || c.acked.CompareAndSwap(current, sequence) {
+			return
+		}
+	}
+}
+
+// writePump writes the replayed backlog first, then queued events and pings until the connection
+// is closed or a write fails. Events queued while the backlog was loaded are skipped if it had them already.
+func (c *wsConnection) writePump(backlog []queuedEvent, onFailure func()) {
+	ticker := time.NewTicker(c.settings.PingInterval)
+	defer ticker.Stop()
+
+	replayed := make(map[int64]bool, len(backlog))
+	for _, event := range backlog {
+		if err := c.write(websocket.TextMessage, event.frame); err != nil {
+			onFailure()
+			return
+		}
+		if event.sequence > 0 {
+			replayed[event.sequence] = true
+		}
+	}
+
+	for {
+		var err error
+		select {
+		case event := <-c.send:
+			if replayed[event.sequence] {
+				continue
+			}
+			err = c.write(websocket.TextMessage, event.frame)
+		case <-ticker.C:
+			err = c.write(websocket.PingMessage, nil)
+		case <-c.done:
+			return
+		}
+
+		if err != nil {
+			onFailure()
+			return
+		}
+	}
+}
+
+func (c *wsConnection) write(messageType int, data []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(c.settings.WriteTimeout))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// close tells the client why with a close frame, which is safe next to the writer, then drops the socket.
+// It returns the number of messages that were still queued.
+func (c *wsConnection) close(code int, reason string) int {
+	pending := 0
+	c.closeOnce.Do(func() {
+		close(c.done)
+		pending = len(c.send)
+		c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(c.settings.WriteTimeout))
+		c.conn.Close()
+	})
+	return pending
+}
+
+// connectionHub tracks the open connections of every agent by workspace, user and connection id
+type connectionHub struct {
+	connections map[string]map[primitive.ObjectID]map[string]*wsConnection
+	mu          sync.RWMutex
+}
+
+func newConnectionHub() *connectionHub {
+	return &connectionHub{
+		connections: make(map[string]map[primitive.ObjectID]map[string]*wsConnection),
+	}
+}
+
+func (h *connectionHub) add(c *wsConnection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	users := h.connections[c.workspaceId]
+	if users == nil {
+		users = make(map[primitive.ObjectID]map[string]*wsConnection)
+		h.connections[c.workspaceId] = users
+	}
+	if users[c.userId] == nil {
+		users[c.userId] = make(map[string]*wsConnection)
+	}
+
+	users[c.userId][c.id] = c
+}
+
+// remove forgets the connection and the maps it leaves empty, it returns nil if it was already gone
+func (h *connectionHub) remove(workspaceId string, userId primitive.ObjectID, connectionId string) *wsConnection {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	users := h.connections[workspaceId]
+	c, exists := users[userId][connectionId]
+	if !exists {
+		return nil
+	}
+
+	delete(users[userId], connectionId)
+	if len(users[userId]) == 0 {
+		delete(users, userId)
+	}
+	if len(users) == 0 {
+		delete(h.connections, workspaceId)
+	}
+
+	return c
+}
+
+func (h *connectionHub) find(workspaceId string, userId primitive.ObjectID, connectionId string) *wsConnection {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.connections[workspaceId][userId][connectionId]
+}
+
+// matching returns the workspace connections of every user include accepts
+func (h *connectionHub) matching(workspaceId string, include func(userId primitive.ObjectID) bool) []*wsConnection {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var result []*wsConnection
+	for userId, conns := range h.connections[workspaceId] {
+		if !include(userId) {
+			continue
+		}
+		for _, c := range conns {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
+// counts returns how many connections each user of the workspace has open
+func (h *connectionHub) counts(workspaceId string) map[primitive.ObjectID]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	result := make(map[primitive.ObjectID]int, len(h.connections[workspaceId]))
+	for userId, conns := range h.connections[workspaceId] {
+		result[userId] = len(conns)
+	}
+
+	return result
+}
+
+// total returns the number of open connections across all workspaces
+func (h *connectionHub) total() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	total := 0
+	for _, users := range h.connections {
+		for _, conns := range users {
+			total += len(conns)
+		}
+	}
+
+	return total
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"github.com/Point-AI/backend/config"
 	"github.com/Point-AI/backend/internal/system/delivery/model"
+	"github.com/Point-AI/backend/internal/system/domain/entity"
 	_interface "github.com/Point-AI/backend/internal/system/domain/interface"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -262,7 +263,7 @@ func (sc *SystemController) UpdateWorkspaceMember(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
 	}
 
-	if err := sc.systemService.UpdateWorkspaceMembers(userId, request.Team, request.WorkspaceId); err != nil {
+	if err := sc.systemService.UpdateWorkspaceMembers(userId, request.Team, request.WorkspaceId, auditMeta(c)); err != nil {
 		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 	}
 
@@ -283,7 +284,7 @@ func (sc *SystemController) DeleteWorkspaceMember(c echo.Context) error {
 	memberEmail, workspaceId := c.Param("email"), c.Param("id")
 	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
 
-	if err := sc.systemService.DeleteWorkspaceMember(userId, workspaceId, memberEmail); err != nil {
+	if err := sc.systemService.DeleteWorkspaceMember(userId, workspaceId, memberEmail, auditMeta(c)); err != nil {
 		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 	}
 
@@ -417,11 +418,302 @@ func (sc *SystemController) UpdateTeam(c echo.Context) error {
 	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "team updated successfully"})
 }
 
+// UpdateTeamAssignment configures how new tickets are assigned within a team.
+// @Summary Configures the ticket assignment of a team.
+// @Tags System
+// @Accept json
+// @Produce json
+// @Param request body model.UpdateTeamAssignmentRequest true "Assignment settings, languages are keyed by member email"
+// @Success 200 {object} model.SuccessResponse "Team assignment updated successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad request, unable to parse the request body"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to update the team assignment"
+// @Router /system/workspace/team/assignment [put]
+func (sc *SystemController) UpdateTeamAssignment(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	var request model.UpdateTeamAssignmentRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := sc.systemService.UpdateTeamAssignment(userId, request); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "team assignment updated successfully"})
+}
+
+// CreateSavedReply adds a saved reply to a workspace or one of its teams.
+// @Summary Adds a saved reply.
+// @Tags System
+// @Accept json
+// @Produce json
+// @Param request body model.CreateSavedReplyRequest true "Saved reply with its shortcut and language variants"
+// @Success 201 {object} model.SuccessResponse "Saved reply created successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad request, unable to parse the request body"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to create the saved reply"
+// @Router /system/workspace/replies [post]
+func (sc *SystemController) CreateSavedReply(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	var request model.CreateSavedReplyRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := sc.systemService.CreateSavedReply(userId, request); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, model.SuccessResponse{Message: "saved reply created successfully"})
+}
+
+// UpdateSavedReply changes a saved reply.
+// @Summary Changes a saved reply.
+// @Tags System
+// @Accept json
+// @Produce json
+// @Param request body model.UpdateSavedReplyRequest true "Saved reply with its shortcut and language variants"
+// @Success 200 {object} model.SuccessResponse "Saved reply updated successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad request, unable to parse the request body"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to update the saved reply"
+// @Router /system/workspace/replies [put]
+func (sc *SystemController) UpdateSavedReply(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	var request model.UpdateSavedReplyRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := sc.systemService.UpdateSavedReply(userId, request); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "saved reply updated successfully"})
+}
+
+// GetSavedReplies lists the saved replies available to the user.
+// @Summary Lists saved replies.
+// @Tags System
+// @Produce json
+// @Param id path string true "Workspace ID"
+// @Success 200 {array} model.SavedReplyResponse "Saved replies"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to list the saved replies"
+// @Router /system/workspace/replies/{id} [get]
+func (sc *SystemController) GetSavedReplies(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	workspaceId := c.Param("id")
+
+	replies, err := sc.systemService.GetSavedReplies(userId, workspaceId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, replies)
+}
+
+// DeleteSavedReply removes a saved reply.
+// @Summary Removes a saved reply.
+// @Tags System
+// @Produce json
+// @Param id path string true "Workspace ID"
+// @Param reply_id path string true "Saved reply ID"
+// @Success 200 {object} model.SuccessResponse "Saved reply deleted successfully"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to delete the saved reply"
+// @Router /system/workspace/replies/{id}/{reply_id} [delete]
+func (sc *SystemController) DeleteSavedReply(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	workspaceId, replyId := c.Param("id"), c.Param("reply_id")
+
+	if err := sc.systemService.DeleteSavedReply(userId, workspaceId, replyId); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "saved reply deleted successfully"})
+}
+
+// GetAuditLogs lists the workspace audit log, newest first.
+// @Summary Lists the workspace audit log.
+// @Tags System
+// @Produce json
+// @Param id path string true "Workspace ID"
+// @Param actor query string false "Actor email"
+// @Param action query string false "Action type"
+// @Param from query string false "Start of the time range, RFC3339 or YYYY-MM-DD"
+// @Param to query string false "End of the time range, RFC3339 or YYYY-MM-DD"
+// @Param page query int false "Page number"
+// @Param limit query int false "Entries per page"
+// @Success 200 {object} model.AuditLogsResponse "Audit log entries"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to list the audit log"
+// @Router /system/workspace/audit/{id} [get]
+func (sc *SystemController) GetAuditLogs(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	var request model.AuditLogRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	response, err := sc.systemService.GetAuditLogs(userId, request)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// ExportAuditLogs downloads the workspace audit log as CSV.
+// @Summary Exports the workspace audit log as CSV.
+// @Tags System
+// @Produce text/csv
+// @Param id path string true "Workspace ID"
+// @Param actor query string false "Actor email"
+// @Param action query string false "Action type"
+// @Param from query string false "Start of the time range, RFC3339 or YYYY-MM-DD"
+// @Param to query string false "End of the time range, RFC3339 or YYYY-MM-DD"
+// @Success 200 {file} file "Audit log CSV"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to export the audit log"
+// @Router /system/workspace/audit/{id}/export [get]
+func (sc *SystemController) ExportAuditLogs(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	var request model.AuditLogRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	data, err := sc.systemService.ExportAuditLogs(userId, request)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="audit-`+request.WorkspaceId+`.csv"`)
+	return c.Blob(http.StatusOK, "text/csv", data)
+}
+
+// GetRoles lists the built-in and custom roles of a workspace with their permissions.
+// @Summary Lists workspace roles.
+// @Tags System
+// @Produce json
+// @Param id path string true "Workspace ID"
+// @Success 200 {array} model.RoleResponse "Roles"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to list the roles"
+// @Router /system/workspace/roles/{id} [get]
+func (sc *SystemController) GetRoles(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	workspaceId := c.Param("id")
+
+	roles, err := sc.systemService.GetRoles(userId, workspaceId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, roles)
+}
+
+// SaveRole creates a custom role or replaces its permissions.
+// @Summary Creates or updates a custom role.
+// @Tags System
+// @Accept json
+// @Produce json
+// @Param request body model.SaveRoleRequest true "Role details"
+// @Success 200 {object} model.SuccessResponse "Role saved successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to save the role"
+// @Router /system/workspace/roles [put]
+func (sc *SystemController) SaveRole(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	var request model.SaveRoleRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := sc.systemService.SaveRole(userId, request, auditMeta(c)); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "role saved successfully"})
+}
+
+// GetInvitationDeliveries shows whether the latest invitation to each address was sent.
+// @Summary Lists the delivery status of workspace invitations.
+// @Tags System
+// @Produce json
+// @Param id path string true "Workspace ID"
+// @Success 200 {array} model.InvitationDeliveryResponse "Invitation deliveries"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to list the invitations"
+// @Router /system/workspace/invitations/{id} [get]
+func (sc *SystemController) GetInvitationDeliveries(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	workspaceId := c.Param("id")
+
+	deliveries, err := sc.systemService.GetInvitationDeliveries(userId, workspaceId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+// ResendInvitation sends a new invitation to an address that has not joined yet.
+// @Summary Resends a workspace invitation.
+// @Tags System
+// @Accept json
+// @Produce json
+// @Param request body model.ResendInvitationRequest true "Invited address"
+// @Success 200 {object} model.SuccessResponse "Invitation queued successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to resend the invitation"
+// @Router /system/workspace/invitations/resend [post]
+func (sc *SystemController) ResendInvitation(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	var request model.ResendInvitationRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := sc.systemService.ResendInvitation(userId, request); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "invitation queued successfully"})
+}
+
+// UpdateTwoFactorRequirement requires two-factor authentication from every member, or stops requiring it.
+// @Summary Requires two-factor authentication for the workspace.
+// @Tags System
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorRequirementRequest true "Requirement"
+// @Success 200 {object} model.SuccessResponse "Requirement updated successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to update the requirement"
+// @Router /system/workspace/2fa [put]
+func (sc *SystemController) UpdateTwoFactorRequirement(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	var request model.TwoFactorRequirementRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := sc.systemService.UpdateTwoFactorRequirement(userId, request, auditMeta(c)); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "two-factor requirement updated successfully"})
+}
+
+// DeleteRole removes a custom role that no member holds.
+// @Summary Removes a custom role.
+// @Tags System
+// @Produce json
+// @Param id path string true "Workspace ID"
+// @Param name path string true "Role name"
+// @Success 200 {object} model.SuccessResponse "Role deleted successfully"
+// @Failure 500 {object} model.ErrorResponse "Internal server error, failed to delete the role"
+// @Router /system/workspace/roles/{id}/{name} [delete]
+func (sc *SystemController) DeleteRole(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	workspaceId, name := c.Param("id"), c.Param("name")
+
+	if err := sc.systemService.DeleteRole(userId, workspaceId, name, auditMeta(c)); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "role deleted successfully"})
+}
+
 func (sc *SystemController) DeleteTeam(c echo.Context) error {
 	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
 	workspaceId, teamId := c.Param("id"), c.Param("team_id")
 
-	if err := sc.systemService.DeleteTeam(userId, workspaceId, teamId); err != nil {
+	if err := sc.systemService.DeleteTeam(userId, workspaceId, teamId, auditMeta(c)); err != nil {
 		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 	}
 	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "new team created successfully"})
@@ -462,3 +754,10 @@ func (sc *SystemController) GetAllUsers(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, users)
 }
+
+func auditMeta(c echo.Context) entity.AuditMeta {
+	return entity.AuditMeta{
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+}
@@ -30,6 +30,58 @@ type AddTeamMembersRequest struct {
 	Members     map[string]string `json:"members"`
 }
 
+type UpdateTeamAssignmentRequest struct {
+	TeamId         string              `json:"team_id"`
+	WorkspaceId    string              `json:"workspace_id"`
+	Strategy       string              `json:"strategy"`
+	Sticky         bool                `json:"sticky"`
+	MaxOpenTickets int                 `json:"max_open_tickets"`
+	Languages      map[string][]string `json:"languages"`
+}
+
+type CreateSavedReplyRequest struct {
+	WorkspaceId string            `json:"workspace_id"`
+	TeamId      string            `json:"team_id"`
+	Shortcut    string            `json:"shortcut"`
+	Title       string            `json:"title"`
+	Variants    map[string]string `json:"variants"`
+}
+
+type UpdateSavedReplyRequest struct {
+	WorkspaceId string            `json:"workspace_id"`
+	ReplyId     string            `json:"reply_id"`
+	TeamId      string            `json:"team_id"`
+	Shortcut    string            `json:"shortcut"`
+	Title       string            `json:"title"`
+	Variants    map[string]string `json:"variants"`
+}
+
+type AuditLogRequest struct {
+	WorkspaceId string `param:"id"`
+	Actor       string `query:"actor"`
+	Action      string `query:"action"`
+	From        string `query:"from"`
+	To          string `query:"to"`
+	Page        int    `query:"page"`
+	Limit       int    `query:"limit"`
+}
+
+type SaveRoleRequest struct {
+	WorkspaceId string   `json:"workspace_id"`
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+type ResendInvitationRequest struct {
+	WorkspaceId string `json:"workspace_id"`
+	Email       string `json:"email"`
+}
+
+type TwoFactorRequirementRequest struct {
+	WorkspaceId string `json:"workspace_id"`
+	Required    bool   `json:"required"`
+}
+
 type AddWorkspaceMemberRequest struct {
 	Team        map[string]string `json:"team"`
 	WorkspaceId string            `json:"workspace_id"`
@@ -89,10 +141,68 @@ type FoldersResponse struct {
 }
 
 type TeamResponse struct {
-	TeamId      string   `json:"team_id"`
-	TeamName    string   `json:"team_name"`
-	MemberCount int      `json:"member_count"`
-	AdminNames  []string `json:"admin_names"`
-	ChatCount   int      `json:"chat_count"`
-	Logo        []byte   `json:"logo"`
+	TeamId      string                 `json:"team_id"`
+	TeamName    string                 `json:"team_name"`
+	MemberCount int                    `json:"member_count"`
+	AdminNames  []string               `json:"admin_names"`
+	ChatCount   int                    `json:"chat_count"`
+	Logo        []byte                 `json:"logo"`
+	Assignment  TeamAssignmentResponse `json:"assignment"`
+}
+
+type TeamAssignmentResponse struct {
+	Strategy       string              `json:"strategy"`
+	Sticky         bool                `json:"sticky"`
+	MaxOpenTickets int                 `json:"max_open_tickets"`
+	Languages      map[string][]string `json:"languages"`
+}
+
+type SavedReplyResponse struct {
+	ReplyId   string            `json:"reply_id"`
+	TeamId    string            `json:"team_id"`
+	Shortcut  string            `json:"shortcut"`
+	Title     string            `json:"title"`
+	Variants  map[string]string `json:"variants"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+type AuditLogResponse struct {
+	Actor      string                `json:"actor"`
+	Action     string                `json:"action"`
+	TargetType string                `json:"target_type"`
+	TargetId   string                `json:"target_id"`
+	Changes    []AuditChangeResponse `json:"changes"`
+	IP         string                `json:"ip"`
+	UserAgent  string                `json:"user_agent"`
+	CreatedAt  time.Time             `json:"created_at"`
+}
+
+type AuditChangeResponse struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type AuditLogsResponse struct {
+	Logs    []AuditLogResponse `json:"logs"`
+	Page    int                `json:"page"`
+	HasMore bool               `json:"has_more"`
+}
+
+type RoleResponse struct {
+	Name        string   `json:"name"`
+	IsBuiltIn   bool     `json:"is_built_in"`
+	Permissions []string `json:"permissions"`
+}
+
+type InvitationDeliveryResponse struct {
+	Email         string     `json:"email"`
+	Status        string     `json:"status"`
+	IsPending     bool       `json:"is_pending"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
 }
@@ -3,9 +3,9 @@ package systemDelivery
 import (
 	"github.com/Point-AI/backend/config"
 	"github.com/Point-AI/backend/internal/system/delivery/controller"
-	"github.com/Point-AI/backend/internal/system/infrastructure/client"
 	"github.com/Point-AI/backend/internal/system/infrastructure/repository"
 	"github.com/Point-AI/backend/internal/system/service"
+	"github.com/Point-AI/backend/mailer"
 	"github.com/Point-AI/backend/middleware"
 	"github.com/labstack/echo/v4"
 	"github.com/minio/minio-go/v7"
@@ -13,39 +13,52 @@ import (
 	"sync"
 )
 
-func RegisterSystemRoutes(e *echo.Echo, cfg *config.Config, db *mongo.Database, str *minio.Client, repoMu *sync.RWMutex, storageMu *sync.RWMutex) {
+func RegisterSystemRoutes(e *echo.Echo, cfg *config.Config, db *mongo.Database, str *minio.Client, repoMu *sync.RWMutex, storageMu *sync.RWMutex, denylist middleware.TokenDenylist) {
 	systemGroup := e.Group("/system")
 
-	ec := client.NewEmailClientImpl(cfg.Email.SMTPUsername, cfg.Email.SMTPPassword, cfg.Email.SMTPHost, cfg.Email.SMTPPort)
+	ec := mailer.NewOutbox(db, cfg)
 	//src := client.NewStorageClientImpl(str, storageMu)
 	sr := repository.NewSystemRepositoryImpl(cfg, db, repoMu)
-	es := service.NewEmailServiceImpl(ec)
 	fsi := service.NewFileServiceImpl("../../static")
+	es := service.NewEmailServiceImpl(ec, fsi, cfg)
 	ss := service.NewSystemServiceImpl(cfg, sr, es, fsi)
 	sc := controller.NewSystemController(cfg, ss)
 
 	workspaceGroup := systemGroup.Group("/workspace")
-	workspaceGroup.POST("", sc.CreateWorkspace, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.POST("/member", sc.AddWorkspaceMembers, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.POST("/team/members", sc.AddTeamsMembers, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.POST("/folders", sc.AddFolders, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.POST("/team/:id/:team_id", sc.SetFirstTeam, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.POST("/team", sc.CreateTeam, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.GET("/:id", sc.GetWorkspaceById, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.GET("/members/:id", sc.GetUserProfiles, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.GET("", sc.GetAllWorkspaces, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.GET("/team/:id", sc.GetAllTeams, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.GET("/user/:id", sc.GetAllUsers, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.PUT("/update", sc.UpdateWorkspaceMember, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.GET("/folders/:id", sc.GetAllFolders, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.PUT("/status/:id/:status", sc.UpdateWorkspacePendingStatus, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.PUT("/team", sc.UpdateTeam, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.PUT("/:id", sc.UpdateWorkspace, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.DELETE("/leave/:id", sc.LeaveWorkspace, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.DELETE("/team/:id/:team_id", sc.DeleteTeam, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.DELETE("/member/:id/:email", sc.DeleteWorkspaceMember, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	workspaceGroup.DELETE("/workspace/:id", sc.DeleteWorkspaceById, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
+	workspaceGroup.POST("", sc.CreateWorkspace, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.POST("/member", sc.AddWorkspaceMembers, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.POST("/team/members", sc.AddTeamsMembers, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.POST("/folders", sc.AddFolders, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.POST("/team/:id/:team_id", sc.SetFirstTeam, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.POST("/team", sc.CreateTeam, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.GET("/:id", sc.GetWorkspaceById, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.GET("/members/:id", sc.GetUserProfiles, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.GET("", sc.GetAllWorkspaces, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.GET("/team/:id", sc.GetAllTeams, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.GET("/user/:id", sc.GetAllUsers, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.PUT("/update", sc.UpdateWorkspaceMember, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.GET("/folders/:id", sc.GetAllFolders, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.PUT("/status/:id/:status", sc.UpdateWorkspacePendingStatus, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.PUT("/team", sc.UpdateTeam, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.PUT("/team/assignment", sc.UpdateTeamAssignment, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.POST("/replies", sc.CreateSavedReply, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.PUT("/replies", sc.UpdateSavedReply, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.GET("/replies/:id", sc.GetSavedReplies, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.DELETE("/replies/:id/:reply_id", sc.DeleteSavedReply, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.GET("/audit/:id", sc.GetAuditLogs, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.GET("/audit/:id/export", sc.ExportAuditLogs, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.GET("/roles/:id", sc.GetRoles, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.PUT("/roles", sc.SaveRole, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.DELETE("/roles/:id/:name", sc.DeleteRole, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.PUT("/2fa", sc.UpdateTwoFactorRequirement, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.GET("/invitations/:id", sc.GetInvitationDeliveries, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.POST("/invitations/resend", sc.ResendInvitation, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.PUT("/:id", sc.UpdateWorkspace, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.DELETE("/leave/:id", sc.LeaveWorkspace, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.DELETE("/team/:id/:team_id", sc.DeleteTeam, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.DELETE("/member/:id/:email", sc.DeleteWorkspaceMember, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	workspaceGroup.DELETE("/workspace/:id", sc.DeleteWorkspaceById, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
 
 	integrationsAuthGroup := systemGroup.Group("/integrations")
-	integrationsAuthGroup.GET("/telegram/:id", sc.RegisterTelegramIntegration, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
+	integrationsAuthGroup.GET("/telegram/:id", sc.RegisterTelegramIntegration, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
 }
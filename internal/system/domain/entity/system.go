@@ -14,26 +14,87 @@ type User struct {
 	FullName     string             `bson:"name"`
 	Role         UserRole           `bson:"role"`
 	Status       UserStatus         `bson:"status"`
+	Language     string             `bson:"language"`
 	Tokens       Tokens             `bson:"tokens"`
+	TwoFactor    TwoFactor          `bson:"two_factor"`
 	CreatedAt    time.Time          `bson:"created_at"`
 }
 
+type TwoFactor struct {
+	IsEnabled      bool      `bson:"is_enabled"`
+	Secret         string    `bson:"secret"`
+	PendingSecret  string    `bson:"pending_secret"`
+	RecoveryCodes  []string  `bson:"recovery_codes"`
+	LastUsedStep   int64     `bson:"last_used_step"`
+	ChallengeId    string    `bson:"challenge_id"`
+	FailedAttempts int       `bson:"failed_attempts"`
+	EnabledAt      time.Time `bson:"enabled_at"`
+}
+
 type Tokens struct {
-	ConfirmToken string `bson:"confirm_token"`
-	OAuth2Token  string `bson:"oauth2_token"`
-	ResetToken   string `bson:"reset_token"`
-	RefreshToken string `bson:"refresh_token"`
+	ConfirmToken          string    `bson:"confirm_token"`
+	ConfirmTokenExpiresAt time.Time `bson:"confirm_token_expires_at"`
+	ConfirmSentAt         time.Time `bson:"confirm_sent_at"`
+	OAuth2Token           string    `bson:"oauth2_token"`
+	ResetToken            string    `bson:"reset_token"`
 }
 
 type Workspace struct {
-	Id          primitive.ObjectID                   `bson:"_id,omitempty"`
-	WorkspaceId string                               `bson:"workspace_id"`
-	Name        string                               `bson:"name"`
-	Team        map[primitive.ObjectID]WorkspaceRole `bson:"team"`
-	PendingTeam map[string]WorkspaceRole             `bson:"pending"`
-	Folders     map[string][]string                  `bson:"folders"`
-	Tags        []string                             `bson:"tags"`
-	CreatedAt   time.Time                            `bson:"created_at"`
+	Id               primitive.ObjectID                   `bson:"_id,omitempty"`
+	WorkspaceId      string                               `bson:"workspace_id"`
+	Name             string                               `bson:"name"`
+	Team             map[primitive.ObjectID]WorkspaceRole `bson:"team"`
+	PendingTeam      map[string]WorkspaceRole             `bson:"pending"`
+	Folders          map[string][]string                  `bson:"folders"`
+	Roles            map[string][]string                  `bson:"roles"`
+	RequireTwoFactor bool                                 `bson:"require_two_factor"`
+	Tags             []string                             `bson:"tags"`
+	Integrations     Integrations                         `bson:"integrations"`
+	CreatedAt        time.Time                            `bson:"created_at"`
+}
+
+type Integrations struct {
+	Telegram    *TelegramIntegration     `bson:"telegram"`
+	TelegramBot []TelegramBotIntegration `bson:"telegram_bot"`
+	WhatsApp    []WhatsAppIntegration    `bson:"whatsapp"`
+	Meta        *MetaIntegration         `bson:"meta"`
+}
+
+type TelegramIntegration struct {
+	PhoneNumber string `bson:"phone_number"`
+	IsActive    bool   `bson:"is_active"`
+}
+
+type TelegramBotIntegration struct {
+	BotToken      string    `bson:"bot_token"`
+	BotName       string    `bson:"bot_name"`
+	WebhookSecret string    `bson:"webhook_secret"`
+	IsActive      bool      `bson:"is_active"`
+	CreatedAt     time.Time `bson:"created_at"`
+}
+
+type WhatsAppIntegration struct {
+	PhoneNumberId string    `bson:"phone_number_id"`
+	PhoneNumber   string    `bson:"phone_number"`
+	AccessToken   string    `bson:"access_token"`
+	IsActive      bool      `bson:"is_active"`
+	CreatedAt     time.Time `bson:"created_at"`
+}
+
+type MetaIntegration struct {
+	AccessToken  string                `bson:"access_token"`
+	RefreshToken string                `bson:"refresh_token"`
+	Pages        []MetaPageIntegration `bson:"pages"`
+	IsActive     bool                  `bson:"is_active"`
+	CreatedAt    time.Time             `bson:"created_at"`
+}
+
+type MetaPageIntegration struct {
+	PageId             string `bson:"page_id"`
+	PageName           string `bson:"page_name"`
+	AccessToken        string `bson:"access_token"`
+	InstagramAccountId string `bson:"instagram_account_id"`
+	IsActive           bool   `bson:"is_active"`
 }
 
 type Team struct {
@@ -44,6 +105,60 @@ type Team struct {
 	Members        map[primitive.ObjectID]bool `bson:"members"`
 	PendingMembers map[string]bool             `bson:"pending_members"`
 	IsFirstTeam    bool                        `bson:"is_first_team"`
+	Assignment     TeamAssignment              `bson:"assignment"`
+}
+
+type TeamAssignment struct {
+	Strategy       AssignmentStrategy                    `bson:"strategy"`
+	Sticky         bool                                  `bson:"sticky"`
+	MaxOpenTickets int                                   `bson:"max_open_tickets"`
+	Languages      map[primitive.ObjectID][]ChatLanguage `bson:"languages"`
+	LastAssigneeId primitive.ObjectID                    `bson:"last_assignee_id"`
+}
+
+type SavedReply struct {
+	Id          primitive.ObjectID      `bson:"_id,omitempty"`
+	ReplyId     string                  `bson:"reply_id"`
+	WorkspaceId primitive.ObjectID      `bson:"workspace_id"`
+	TeamId      string                  `bson:"team_id"`
+	Shortcut    string                  `bson:"shortcut"`
+	Title       string                  `bson:"title"`
+	Variants    map[ChatLanguage]string `bson:"variants"`
+	CreatedBy   primitive.ObjectID      `bson:"created_by"`
+	CreatedAt   time.Time               `bson:"created_at"`
+	UpdatedAt   time.Time               `bson:"updated_at"`
+}
+
+type AuditLog struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty"`
+	WorkspaceId primitive.ObjectID `bson:"workspace_id"`
+	ActorId     primitive.ObjectID `bson:"actor_id"`
+	Action      AuditAction        `bson:"action"`
+	TargetType  string             `bson:"target_type"`
+	TargetId    string             `bson:"target_id"`
+	Changes     []AuditChange      `bson:"changes"`
+	IP          string             `bson:"ip"`
+	UserAgent   string             `bson:"user_agent"`
+	CreatedAt   time.Time          `bson:"created_at"`
+}
+
+type AuditChange struct {
+	Field  string `bson:"field"`
+	Before string `bson:"before"`
+	After  string `bson:"after"`
+}
+
+type AuditFilter struct {
+	ActorId primitive.ObjectID
+	Action  AuditAction
+	From    time.Time
+	To      time.Time
+}
+
+// AuditMeta describes the request an audited action came from
+type AuditMeta struct {
+	IP        string
+	UserAgent string
 }
 
 type Chat struct {
@@ -78,14 +193,19 @@ type Note struct {
 }
 
 type Ticket struct {
-	Id         primitive.ObjectID `bson:"_id,omitempty"`
-	TicketId   string             `bson:"ticket_id"`
-	Subject    string             `bson:"subject"`
-	Notes      []Note             `bson:"notes"`
-	Messages   []Message          `bson:"messages"`
-	Status     TicketStatus       `bson:"status"`
-	CreatedAt  time.Time          `bson:"created_at"`
-	ResolvedAt time.Time          `bson:"resolved_at"`
+	Id                 primitive.ObjectID `bson:"_id,omitempty"`
+	TicketId           string             `bson:"ticket_id"`
+	Subject            string             `bson:"subject"`
+	Notes              []Note             `bson:"notes"`
+	MessageCount       int                `bson:"message_count"`
+	Status             TicketStatus       `bson:"status"`
+	SLAPolicyId        string             `bson:"sla_policy_id"`
+	SLAStatus          SLAStatus          `bson:"sla_status"`
+	FirstResponseDueAt time.Time          `bson:"first_response_due_at"`
+	FirstResponseAt    time.Time          `bson:"first_response_at"`
+	SLADueAt           time.Time          `bson:"sla_due_at"`
+	CreatedAt          time.Time          `bson:"created_at"`
+	ResolvedAt         time.Time          `bson:"resolved_at"`
 }
 
 type Message struct {
@@ -104,8 +224,11 @@ type WorkspaceRole string
 type UserRole string
 type ChatSource string
 type TicketStatus string
+type SLAStatus string
 type UserStatus string
 type ChatLanguage string
+type AuditAction string
+type AssignmentStrategy string
 
 const (
 	TypeChatNote   MessageType = "chat_note"
@@ -157,3 +280,22 @@ const (
 	Russian ChatLanguage = "ru"
 	Uzbek   ChatLanguage = "uz"
 )
+
+const (
+	AuditMemberRemoved        AuditAction = "member_removed"
+	AuditMembersUpdated       AuditAction = "members_updated"
+	AuditTeamDeleted          AuditAction = "team_deleted"
+	AuditTicketReassignedUser AuditAction = "ticket_reassigned_user"
+	AuditTicketReassignedTeam AuditAction = "ticket_reassigned_team"
+	AuditTicketStatusChanged  AuditAction = "ticket_status_changed"
+	AuditMessageDeleted       AuditAction = "message_deleted"
+	AuditRoleSaved            AuditAction = "role_saved"
+	AuditRoleDeleted          AuditAction = "role_deleted"
+	AuditTwoFactorRequirement AuditAction = "two_factor_requirement_changed"
+)
+
+const (
+	AssignmentLeastOpenTickets AssignmentStrategy = "least_open_tickets"
+	AssignmentRoundRobin       AssignmentStrategy = "round_robin"
+	AssignmentLanguageMatch    AssignmentStrategy = "language_match"
+)
@@ -2,6 +2,7 @@ package _interface
 
 import (
 	"github.com/Point-AI/backend/internal/system/delivery/model"
+	"github.com/Point-AI/backend/internal/system/domain/entity"
 	infrastructureModel "github.com/Point-AI/backend/internal/system/infrastructure/model"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -11,11 +12,11 @@ type SystemService interface {
 	LeaveWorkspace(WorkspaceId string, userId primitive.ObjectID) error
 	GetAllWorkspaces(userId primitive.ObjectID) ([]infrastructureModel.Workspace, error)
 	AddWorkspaceMembers(userId primitive.ObjectID, team map[string]string, WorkspaceId string) error
-	DeleteWorkspaceMember(userId primitive.ObjectID, WorkspaceId, memberEmail string) error
+	DeleteWorkspaceMember(userId primitive.ObjectID, WorkspaceId, memberEmail string, meta entity.AuditMeta) error
 	DeleteWorkspaceById(workspaceId string, userId primitive.ObjectID) error
 	GetWorkspaceById(WorkspaceId string, userId primitive.ObjectID) (infrastructureModel.Workspace, error)
 	UpdateWorkspace(userId primitive.ObjectID, newLogo []byte, WorkspaceId, newWorkspaceId, newName string) error
-	UpdateWorkspaceMembers(userId primitive.ObjectID, team map[string]string, WorkspaceId string) error
+	UpdateWorkspaceMembers(userId primitive.ObjectID, team map[string]string, WorkspaceId string, meta entity.AuditMeta) error
 	GetUserProfiles(WorkspaceId string, userId primitive.ObjectID) ([]infrastructureModel.User, error)
 	UpdateWorkspacePendingStatus(userId primitive.ObjectID, workspaceId string, status bool) error
 	AddTeamsMembers(userId primitive.ObjectID, members map[string]string, teamId, workspaceId string) error
@@ -25,14 +26,27 @@ type SystemService interface {
 	GetAllTeams(userId primitive.ObjectID, workspaceId string) ([]model.TeamResponse, int, error)
 	GetAllFolders(userId primitive.ObjectID, workspaceId string) (map[string][]string, error, int)
 	CreateTeam(userId primitive.ObjectID, workspaceId, teamName string, members map[string]string, logo []byte) error
-	DeleteTeam(userId primitive.ObjectID, workspaceId, teamName string) error
+	DeleteTeam(userId primitive.ObjectID, workspaceId, teamName string, meta entity.AuditMeta) error
 	UpdateTeam(userId primitive.ObjectID, newLogo []byte, workspaceId, newTeamName, teamId string) error
+	UpdateTeamAssignment(userId primitive.ObjectID, request model.UpdateTeamAssignmentRequest) error
+	CreateSavedReply(userId primitive.ObjectID, request model.CreateSavedReplyRequest) error
+	UpdateSavedReply(userId primitive.ObjectID, request model.UpdateSavedReplyRequest) error
+	GetSavedReplies(userId primitive.ObjectID, workspaceId string) ([]model.SavedReplyResponse, error)
+	DeleteSavedReply(userId primitive.ObjectID, workspaceId, replyId string) error
 	GetAllUsers(userId primitive.ObjectID, workspaceId, teamId string) ([]model.UserResponse, error)
+	GetAuditLogs(userId primitive.ObjectID, request model.AuditLogRequest) (model.AuditLogsResponse, error)
+	ExportAuditLogs(userId primitive.ObjectID, request model.AuditLogRequest) ([]byte, error)
+	GetRoles(userId primitive.ObjectID, workspaceId string) ([]model.RoleResponse, error)
+	SaveRole(userId primitive.ObjectID, request model.SaveRoleRequest, meta entity.AuditMeta) error
+	UpdateTwoFactorRequirement(userId primitive.ObjectID, request model.TwoFactorRequirementRequest, meta entity.AuditMeta) error
+	DeleteRole(userId primitive.ObjectID, workspaceId, name string, meta entity.AuditMeta) error
+	GetInvitationDeliveries(userId primitive.ObjectID, workspaceId string) ([]model.InvitationDeliveryResponse, error)
+	ResendInvitation(userId primitive.ObjectID, request model.ResendInvitationRequest) error
 }
 
 type EmailService interface {
-	SendInvitationEmail(recipientEmail, confirmationLink string) error
-	SendWorkspaceInvitationEmail(recipientEmail, inviteLink string) error
+	SendInvitationEmail(recipientEmail, language, inviteLink string) error
+	SendWorkspaceInvitationEmail(recipientEmail, language, inviteLink string, workspace *entity.Workspace, inviterName string) error
 }
 
 type FileService interface {
@@ -5,6 +5,8 @@ type Workspace struct {
 	Logo        []byte
 	Team        map[string]string
 	WorkspaceId string `bson:"workspace_id"`
+	// RequireTwoFactor tells members without two-factor authentication why the workspace refuses them
+	RequireTwoFactor bool
 }
 
 type User struct {
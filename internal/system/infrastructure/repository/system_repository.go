@@ -7,9 +7,12 @@ import (
 	"github.com/Point-AI/backend/internal/system/domain/entity"
 	"github.com/Point-AI/backend/internal/system/infrastructure/model"
 	"github.com/Point-AI/backend/internal/system/service/interface"
+	"github.com/Point-AI/backend/mailer"
+	"github.com/Point-AI/backend/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"sync"
 	"time"
 )
@@ -67,7 +70,7 @@ func (sr *SystemRepositoryImpl) CountChatsByTeamId(teamId primitive.ObjectID) (i
 	return int(count), nil
 }
 
-func (sr *SystemRepositoryImpl) ValidateTeam(team map[string]string, ownerId primitive.ObjectID) (map[primitive.ObjectID]entity.WorkspaceRole, map[string]entity.WorkspaceRole, error) {
+func (sr *SystemRepositoryImpl) ValidateTeam(team map[string]string, ownerId primitive.ObjectID, customRoles map[string][]string) (map[primitive.ObjectID]entity.WorkspaceRole, map[string]entity.WorkspaceRole, error) {
 	sr.mu.RLock()
 	defer sr.mu.RUnlock()
 
@@ -82,12 +85,7 @@ func (sr *SystemRepositoryImpl) ValidateTeam(team map[string]string, ownerId pri
 		).Decode(&user)
 		if err != nil {
 			if errors.Is(err, mongo.ErrNoDocuments) {
-				switch role {
-				case string(entity.RoleAdmin), string(entity.RoleAgent), string(entity.RoleOwner):
-					pendingUserRoles[email] = entity.WorkspaceRole(role)
-				default:
-					pendingUserRoles[email] = entity.RoleAgent
-				}
+				pendingUserRoles[email] = sr.resolveRole(role, customRoles)
 
 				continue
 			}
@@ -97,12 +95,7 @@ func (sr *SystemRepositoryImpl) ValidateTeam(team map[string]string, ownerId pri
 			continue
 		}
 
-		switch role {
-		case string(entity.RoleAdmin), string(entity.RoleAgent), string(entity.RoleOwner):
-			userRoles[user.Id] = entity.WorkspaceRole(role)
-		default:
-			userRoles[user.Id] = entity.RoleAgent
-		}
+		userRoles[user.Id] = sr.resolveRole(role, customRoles)
 	}
 
 	return userRoles, pendingUserRoles, nil
@@ -610,3 +603,185 @@ func (sr *SystemRepositoryImpl) GetTeamNamesByUserId(userId primitive.ObjectID)
 
 	return teamNames
 }
+
+func (sr *SystemRepositoryImpl) InsertSavedReply(reply *entity.SavedReply) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	_, err := sr.database.Collection(sr.config.MongoDB.SavedReplyCollection).InsertOne(context.Background(), reply)
+	return err
+}
+
+func (sr *SystemRepositoryImpl) UpdateSavedReply(reply *entity.SavedReply) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	_, err := sr.database.Collection(sr.config.MongoDB.SavedReplyCollection).ReplaceOne(
+		context.Background(),
+		bson.M{"_id": reply.Id},
+		reply,
+	)
+	return err
+}
+
+func (sr *SystemRepositoryImpl) DeleteSavedReply(workspaceId primitive.ObjectID, replyId string) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	res, err := sr.database.Collection(sr.config.MongoDB.SavedReplyCollection).DeleteOne(
+		context.Background(),
+		bson.M{"workspace_id": workspaceId, "reply_id": replyId},
+	)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return errors.New("saved reply not found")
+	}
+
+	return nil
+}
+
+func (sr *SystemRepositoryImpl) FindSavedReplyByReplyId(workspaceId primitive.ObjectID, replyId string) (*entity.SavedReply, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	var reply entity.SavedReply
+	err := sr.database.Collection(sr.config.MongoDB.SavedReplyCollection).FindOne(
+		context.Background(),
+		bson.M{"workspace_id": workspaceId, "reply_id": replyId},
+	).Decode(&reply)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("saved reply not found")
+		}
+		return nil, err
+	}
+
+	return &reply, nil
+}
+
+func (sr *SystemRepositoryImpl) FindSavedRepliesByWorkspaceId(workspaceId primitive.ObjectID) ([]entity.SavedReply, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	cursor, err := sr.database.Collection(sr.config.MongoDB.SavedReplyCollection).Find(
+		context.Background(),
+		bson.M{"workspace_id": workspaceId},
+		options.Find().SetSort(bson.M{"shortcut": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var replies []entity.SavedReply
+	if err = cursor.All(context.Background(), &replies); err != nil {
+		return nil, err
+	}
+
+	return replies, nil
+}
+
+func (sr *SystemRepositoryImpl) CheckShortcutExists(workspaceId primitive.ObjectID, teamId, shortcut, exceptReplyId string) (bool, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	count, err := sr.database.Collection(sr.config.MongoDB.SavedReplyCollection).CountDocuments(
+		context.Background(),
+		bson.M{"workspace_id": workspaceId, "team_id": teamId, "shortcut": shortcut, "reply_id": bson.M{"$ne": exceptReplyId}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (sr *SystemRepositoryImpl) InsertAuditLog(auditLog *entity.AuditLog) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	_, err := sr.database.Collection(sr.config.MongoDB.AuditLogCollection).InsertOne(
+		context.Background(),
+		auditLog,
+	)
+	return err
+}
+
+func (sr *SystemRepositoryImpl) FindAuditLogs(workspaceId primitive.ObjectID, filter entity.AuditFilter, skip, limit int) ([]entity.AuditLog, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	query := bson.M{"workspace_id": workspaceId}
+	if !filter.ActorId.IsZero() {
+		query["actor_id"] = filter.ActorId
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	createdAt := bson.M{}
+	if !filter.From.IsZero() {
+		createdAt["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		createdAt["$lte"] = filter.To
+	}
+	if len(createdAt) > 0 {
+		query["created_at"] = createdAt
+	}
+
+	cursor, err := sr.database.Collection(sr.config.MongoDB.AuditLogCollection).Find(
+		context.Background(),
+		query,
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+			SetSkip(int64(skip)).
+			SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var auditLogs []entity.AuditLog
+	if err = cursor.All(context.Background(), &auditLogs); err != nil {
+		return nil, err
+	}
+
+	return auditLogs, nil
+}
+
+// resolveRole keeps built-in and workspace roles and falls back to agent for anything else
+// FindWorkspaceEmails lists the queued and delivered emails of a workspace, newest first. The rendered
+// bodies are left out, the status view does not need them.
+func (sr *SystemRepositoryImpl) FindWorkspaceEmails(workspaceId primitive.ObjectID, template mailer.Template) ([]mailer.OutboxEmail, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	cursor, err := sr.database.Collection(sr.config.MongoDB.OutboxCollection).Find(
+		context.Background(),
+		bson.M{"workspace_id": workspaceId, "template": template},
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: -1}}).
+			SetProjection(bson.M{"text": 0, "html": 0, "attachments": 0}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var emails []mailer.OutboxEmail
+	if err = cursor.All(context.Background(), &emails); err != nil {
+		return nil, err
+	}
+
+	return emails, nil
+}
+
+func (sr *SystemRepositoryImpl) resolveRole(role string, customRoles map[string][]string) entity.WorkspaceRole {
+	if !utils.NewAuthorizer(customRoles).HasRole(role) {
+		return entity.RoleAgent
+	}
+	return entity.WorkspaceRole(role)
+}
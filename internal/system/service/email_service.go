@@ -1,29 +1,57 @@
 package service
 
 import (
-	"fmt"
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/system/domain/entity"
 	_interface "github.com/Point-AI/backend/internal/system/domain/interface"
 	infrastructureInterface "github.com/Point-AI/backend/internal/system/service/interface"
+	"github.com/Point-AI/backend/mailer"
 )
 
 type EmailServiceImpl struct {
 	emailClient infrastructureInterface.EmailClient
+	fileService _interface.FileService
+	config      *config.Config
 }
 
-func NewEmailServiceImpl(emailClient infrastructureInterface.EmailClient) _interface.EmailService {
+func NewEmailServiceImpl(emailClient infrastructureInterface.EmailClient, fileService _interface.FileService, cfg *config.Config) _interface.EmailService {
 	return &EmailServiceImpl{
 		emailClient: emailClient,
+		fileService: fileService,
+		config:      cfg,
 	}
 }
 
-func (es *EmailServiceImpl) SendInvitationEmail(recipientEmail, inviteLink string) error {
-	subject := "Confirm your email"
-	body := fmt.Sprintf("Click the following link to confirm your email: %s", inviteLink)
-	return es.emailClient.SendEmail(recipientEmail, subject, body)
+func (es *EmailServiceImpl) SendInvitationEmail(recipientEmail, language, inviteLink string) error {
+	return es.send(mailer.TemplateInvitation, recipientEmail, language, mailer.Data{
+		Brand: mailer.Brand{Name: es.config.Email.FromName},
+		Link:  inviteLink,
+	})
 }
 
-func (es *EmailServiceImpl) SendWorkspaceInvitationEmail(recipientEmail, inviteLink string) error {
-	subject := "You were invited to a workspace"
-	body := fmt.Sprintf("Click the following link to create your account: %s", inviteLink)
-	return es.emailClient.SendEmail(recipientEmail, subject, body)
+// SendWorkspaceInvitationEmail brands the invitation with the workspace name and, when one was uploaded, its logo.
+// The message is labeled with the workspace, so its delivery shows up in the workspace's invitation status.
+func (es *EmailServiceImpl) SendWorkspaceInvitationEmail(recipientEmail, language, inviteLink string, workspace *entity.Workspace, inviterName string) error {
+	logo, _ := es.fileService.LoadFile("wp." + workspace.WorkspaceId)
+
+	message, err := mailer.Render(mailer.TemplateWorkspaceInvitation, mailer.ParseLocale(language), recipientEmail, mailer.Data{
+		Brand:   mailer.Brand{Name: workspace.Name, Logo: logo},
+		Link:    inviteLink,
+		Inviter: inviterName,
+	})
+	if err != nil {
+		return err
+	}
+	message.WorkspaceId = workspace.Id
+
+	return es.emailClient.Send(message)
+}
+
+func (es *EmailServiceImpl) send(template mailer.Template, recipientEmail, language string, data mailer.Data) error {
+	message, err := mailer.Render(template, mailer.ParseLocale(language), recipientEmail, data)
+	if err != nil {
+		return err
+	}
+
+	return es.emailClient.Send(message)
 }
@@ -3,6 +3,7 @@ package infrastructureInterface
 import (
 	"github.com/Point-AI/backend/internal/system/domain/entity"
 	"github.com/Point-AI/backend/internal/system/infrastructure/model"
+	"github.com/Point-AI/backend/mailer"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -14,7 +15,7 @@ type StorageClient interface {
 }
 
 type SystemRepository interface {
-	ValidateTeam(team map[string]string, ownerId primitive.ObjectID) (map[primitive.ObjectID]entity.WorkspaceRole, map[string]entity.WorkspaceRole, error)
+	ValidateTeam(team map[string]string, ownerId primitive.ObjectID, customRoles map[string][]string) (map[primitive.ObjectID]entity.WorkspaceRole, map[string]entity.WorkspaceRole, error)
 	CreateWorkspace(ownerId primitive.ObjectID, workspaceId, name string) error
 	RemoveUserFromWorkspace(workspace *entity.Workspace, userId primitive.ObjectID) error
 	FindWorkspaceByWorkspaceId(workspaceId string) (*entity.Workspace, error)
@@ -41,8 +42,17 @@ type SystemRepository interface {
 	DeleteTeam(id primitive.ObjectID) error
 	UpdateChatTeamIdToNil(teamId primitive.ObjectID) error
 	GetTeamNamesByUserId(userId primitive.ObjectID) []entity.Team
+	InsertSavedReply(reply *entity.SavedReply) error
+	UpdateSavedReply(reply *entity.SavedReply) error
+	DeleteSavedReply(workspaceId primitive.ObjectID, replyId string) error
+	FindSavedReplyByReplyId(workspaceId primitive.ObjectID, replyId string) (*entity.SavedReply, error)
+	FindSavedRepliesByWorkspaceId(workspaceId primitive.ObjectID) ([]entity.SavedReply, error)
+	CheckShortcutExists(workspaceId primitive.ObjectID, teamId, shortcut, exceptReplyId string) (bool, error)
+	InsertAuditLog(auditLog *entity.AuditLog) error
+	FindAuditLogs(workspaceId primitive.ObjectID, filter entity.AuditFilter, skip, limit int) ([]entity.AuditLog, error)
+	FindWorkspaceEmails(workspaceId primitive.ObjectID, template mailer.Template) ([]mailer.OutboxEmail, error)
 }
 
 type EmailClient interface {
-	SendEmail(to, subject, body string) error
+	Send(message *mailer.Message) error
 }
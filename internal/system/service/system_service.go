@@ -1,6 +1,8 @@
 package service
 
 import (
+	"bytes"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"github.com/Point-AI/backend/config"
@@ -9,10 +11,28 @@ import (
 	_interface "github.com/Point-AI/backend/internal/system/domain/interface"
 	"github.com/Point-AI/backend/internal/system/infrastructure/model"
 	infrastructureInterface "github.com/Point-AI/backend/internal/system/service/interface"
+	"github.com/Point-AI/backend/mailer"
 	"github.com/Point-AI/backend/utils"
 	"github.com/go-resty/resty/v2"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+	auditExportLimit  = 10000
+)
+
+var (
+	savedReplyShortcutPattern = regexp.MustCompile(`^/[a-z0-9_-]+$`)
+	roleNamePattern           = regexp.MustCompile(`^[a-z0-9_-]{2,32}$`)
 )
 
 type SystemServiceImpl struct {
@@ -36,9 +56,15 @@ func (ss *SystemServiceImpl) CreateWorkspace(logo []byte, ownerId primitive.Obje
 		return err
 	}
 
-	//var finalLogo []byte
-	var err error
+	existing, err := ss.systemRepo.FindWorkspaceByWorkspaceId(workspaceId)
+	if err != nil {
+		return err
+	}
+	if existing.Id != primitive.NilObjectID {
+		return errors.New("workspace id already taken")
+	}
 
+	//var finalLogo []byte
 	if logo != nil {
 		go ss.fileService.SaveFile("wp."+workspaceId, logo)
 		//if finalLogo, err = utils.ValidatePhoto(logo); err != nil {
@@ -46,11 +72,6 @@ func (ss *SystemServiceImpl) CreateWorkspace(logo []byte, ownerId primitive.Obje
 		//}
 	}
 
-	_, err = ss.systemRepo.FindWorkspaceByWorkspaceId(workspaceId)
-	if err != nil {
-		return err
-	}
-
 	if err := ss.systemRepo.CreateWorkspace(ownerId, workspaceId, name); err != nil {
 		return err
 	}
@@ -89,8 +110,8 @@ func (ss *SystemServiceImpl) SetFirstTeam(userId primitive.ObjectID, teamId, wor
 		return err
 	}
 
-	if !ss.isAdmin(workspace.Team[userId]) && !ss.isOwner(workspace.Team[userId]) {
-		return errors.New("unauthorised")
+	if err = ss.authorize(workspace, userId, utils.PermissionTeamManage); err != nil {
+		return err
 	}
 
 	internalTeam, err := ss.systemRepo.FindTeamByTeamIdAndWorkspaceId(teamId, workspace.Id)
@@ -116,8 +137,8 @@ func (ss *SystemServiceImpl) AddTeamsMembers(userId primitive.ObjectID, members
 		workspace.PendingTeam = make(map[string]entity.WorkspaceRole)
 	}
 
-	if !ss.isAdmin(workspace.Team[userId]) && !ss.isOwner(workspace.Team[userId]) {
-		return errors.New("unauthorised")
+	if err = ss.authorize(workspace, userId, utils.PermissionTeamManage); err != nil {
+		return err
 	}
 
 	internalTeam, err := ss.systemRepo.FindTeamByTeamIdAndWorkspaceId(teamId, workspace.Id)
@@ -132,11 +153,15 @@ func (ss *SystemServiceImpl) AddTeamsMembers(userId primitive.ObjectID, members
 		internalTeam.PendingMembers = make(map[string]bool)
 	}
 
+	var invitedEmails []string
 	if members != nil {
-		teamRoles, pendingTeamRoles, err := ss.systemRepo.ValidateTeam(members, userId)
+		teamRoles, pendingTeamRoles, err := ss.systemRepo.ValidateTeam(members, userId, workspace.Roles)
 		if err != nil {
 			return err
 		}
+		if err = ss.authorizeNewMembers(workspace, userId, teamRoles, pendingTeamRoles); err != nil {
+			return err
+		}
 
 		for id, role := range teamRoles {
 			if _, exists := workspace.Team[id]; !exists {
@@ -148,8 +173,7 @@ func (ss *SystemServiceImpl) AddTeamsMembers(userId primitive.ObjectID, members
 		}
 
 		for email, role := range pendingTeamRoles {
-			emailHash, _ := utils.GenerateInvitationJWTToken(ss.config.Auth.JWTSecretKey, email)
-			ss.emailService.SendWorkspaceInvitationEmail(email, fmt.Sprintf("%s/signin/confirm?id=%s&email=%s", ss.config.Website.WebURL, workspaceId, emailHash))
+			invitedEmails = append(invitedEmails, email)
 
 			if _, exists := workspace.PendingTeam[email]; !exists {
 				workspace.PendingTeam[email] = role
@@ -162,7 +186,11 @@ func (ss *SystemServiceImpl) AddTeamsMembers(userId primitive.ObjectID, members
 		return err
 	}
 
-	return ss.systemRepo.UpdateWorkspace(workspace)
+	if err := ss.systemRepo.UpdateWorkspace(workspace); err != nil {
+		return err
+	}
+
+	return ss.sendWorkspaceInvitations(workspace, userId, invitedEmails)
 }
 
 // GetWorkspaceById TODO: update function not to return team
@@ -172,8 +200,8 @@ func (ss *SystemServiceImpl) GetWorkspaceById(workspaceId string, userId primiti
 		return infrastructureModel.Workspace{}, err
 	}
 
-	if _, exists := workspace.Team[userId]; !exists {
-		return infrastructureModel.Workspace{}, errors.New("user is not in the Workspace")
+	if err = ss.authorize(workspace, userId, utils.PermissionWorkspaceRead); err != nil {
+		return infrastructureModel.Workspace{}, err
 	}
 
 	fmtWorkspace, err := ss.formatWorkspaces([]entity.Workspace{*workspace})
@@ -204,16 +232,19 @@ func (ss *SystemServiceImpl) CreateTeam(userId primitive.ObjectID, workspaceId,
 		return err
 	}
 
-	if !ss.isAdmin(workspace.Team[userId]) && !ss.isOwner(workspace.Team[userId]) {
-		return errors.New("unauthorised")
+	if err = ss.authorize(workspace, userId, utils.PermissionTeamManage); err != nil {
+		return err
 	}
 
 	internalTeam := ss.createTeam(workspace.Id, teamName, map[primitive.ObjectID]bool{}, map[string]bool{}, false)
 	if members != nil {
-		teamRoles, pendingTeamRoles, err := ss.systemRepo.ValidateTeam(members, userId)
+		teamRoles, pendingTeamRoles, err := ss.systemRepo.ValidateTeam(members, userId, workspace.Roles)
 		if err != nil {
 			return err
 		}
+		if err = ss.authorizeNewMembers(workspace, userId, teamRoles, pendingTeamRoles); err != nil {
+			return err
+		}
 
 		for id, role := range teamRoles {
 			if _, exists := workspace.Team[id]; !exists {
@@ -243,14 +274,14 @@ func (ss *SystemServiceImpl) CreateTeam(userId primitive.ObjectID, workspaceId,
 	return ss.systemRepo.UpdateWorkspace(workspace)
 }
 
-func (ss *SystemServiceImpl) DeleteTeam(userId primitive.ObjectID, workspaceId, teamId string) error {
+func (ss *SystemServiceImpl) DeleteTeam(userId primitive.ObjectID, workspaceId, teamId string, meta entity.AuditMeta) error {
 	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(workspaceId)
 	if err != nil {
 		return err
 	}
 
-	if !ss.isOwner(workspace.Team[userId]) && !ss.isAdmin(workspace.Team[userId]) {
-		return errors.New("unauthorized to make the changes")
+	if err = ss.authorize(workspace, userId, utils.PermissionTeamManage); err != nil {
+		return err
 	}
 
 	team, err := ss.systemRepo.FindTeamByTeamIdAndWorkspaceId(teamId, workspace.Id)
@@ -262,7 +293,15 @@ func (ss *SystemServiceImpl) DeleteTeam(userId primitive.ObjectID, workspaceId,
 		return err
 	}
 
-	return ss.systemRepo.DeleteTeam(team.Id)
+	if err = ss.systemRepo.DeleteTeam(team.Id); err != nil {
+		return err
+	}
+
+	ss.recordAudit(workspace.Id, userId, entity.AuditTeamDeleted, "team", team.TeamId, meta,
+		entity.AuditChange{Field: "team_name", Before: team.TeamName},
+		entity.AuditChange{Field: "members", Before: strconv.Itoa(len(team.Members))},
+	)
+	return nil
 }
 
 func (ss *SystemServiceImpl) UpdateWorkspace(userId primitive.ObjectID, newLogo []byte, workspaceId, newWorkspaceId, newName string) error {
@@ -271,8 +310,8 @@ func (ss *SystemServiceImpl) UpdateWorkspace(userId primitive.ObjectID, newLogo
 		return err
 	}
 
-	if !ss.isOwner(workspace.Team[userId]) && !ss.isAdmin(workspace.Team[userId]) {
-		return errors.New("unauthorized to make the changes")
+	if err = ss.authorize(workspace, userId, utils.PermissionWorkspaceManage); err != nil {
+		return err
 	}
 
 	if newWorkspaceId != "" {
@@ -308,51 +347,88 @@ func (ss *SystemServiceImpl) AddWorkspaceMembers(userId primitive.ObjectID, team
 		return err
 	}
 
-	if !ss.isAdmin(workspace.Team[userId]) && !ss.isOwner(workspace.Team[userId]) {
-		return errors.New("unauthorised")
+	if err = ss.authorize(workspace, userId, utils.PermissionMemberManage); err != nil {
+		return err
 	}
 
-	teamRoles, pendingTeamRoles, err := ss.systemRepo.ValidateTeam(team, userId)
+	teamRoles, pendingTeamRoles, err := ss.systemRepo.ValidateTeam(team, userId, workspace.Roles)
 	if err != nil {
 		return err
 	}
+	if err = ss.authorizeGrant(workspace, userId, teamRoles, pendingTeamRoles); err != nil {
+		return err
+	}
 
-	for email, _ := range pendingTeamRoles {
-		emailHash, _ := utils.GenerateInvitationJWTToken(ss.config.Auth.JWTSecretKey, email)
-		go ss.emailService.SendWorkspaceInvitationEmail(email, fmt.Sprintf("%s/signin/confirm?id=%s&email=%s", ss.config.Website.WebURL, workspaceId, emailHash))
+	if err = ss.systemRepo.AddUsersToWorkspace(workspace, teamRoles, pendingTeamRoles); err != nil {
+		return err
+	}
+
+	invitedEmails := make([]string, 0, len(pendingTeamRoles))
+	for email := range pendingTeamRoles {
+		invitedEmails = append(invitedEmails, email)
 	}
 
-	return ss.systemRepo.AddUsersToWorkspace(workspace, teamRoles, pendingTeamRoles)
+	return ss.sendWorkspaceInvitations(workspace, userId, invitedEmails)
 }
 
-func (ss *SystemServiceImpl) UpdateWorkspaceMembers(userId primitive.ObjectID, team map[string]string, workspaceId string) error {
+func (ss *SystemServiceImpl) UpdateWorkspaceMembers(userId primitive.ObjectID, team map[string]string, workspaceId string, meta entity.AuditMeta) error {
 	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(workspaceId)
 	if err != nil {
 		return err
 	}
 
-	if ss.isAdmin(workspace.Team[userId]) || ss.isOwner(workspace.Team[userId]) {
-		teamRoles, _, err := ss.systemRepo.ValidateTeam(team, userId)
-		if err != nil {
-			return err
-		}
+	if err = ss.authorize(workspace, userId, utils.PermissionMemberManage); err != nil {
+		return err
+	}
 
-		if err := ss.systemRepo.UpdateUsersInWorkspace(workspace, teamRoles); err != nil {
-			return err
+	teamRoles, _, err := ss.systemRepo.ValidateTeam(team, userId, workspace.Roles)
+	if err != nil {
+		return err
+	}
+	// both the role a member leaves and the one they get must be within the caller's own permissions
+	currentRoles := make(map[primitive.ObjectID]entity.WorkspaceRole, len(teamRoles))
+	for memberId := range teamRoles {
+		currentRoles[memberId] = workspace.Team[memberId]
+	}
+	if err = ss.authorizeGrant(workspace, userId, teamRoles, nil); err != nil {
+		return err
+	}
+	if err = ss.authorizeGrant(workspace, userId, currentRoles, nil); err != nil {
+		return err
+	}
+
+	previousRoles := make(map[primitive.ObjectID]entity.WorkspaceRole, len(workspace.Team))
+	for memberId, role := range workspace.Team {
+		previousRoles[memberId] = role
+	}
+
+	if err = ss.systemRepo.UpdateUsersInWorkspace(workspace, teamRoles); err != nil {
+		return err
+	}
+
+	var changes []entity.AuditChange
+	for memberId, role := range teamRoles {
+		previousRole, exists := previousRoles[memberId]
+		if !exists || previousRole == role {
+			continue
 		}
+		changes = append(changes, entity.AuditChange{Field: ss.findUserEmail(memberId), Before: string(previousRole), After: string(role)})
+	}
+	if len(changes) > 0 {
+		ss.recordAudit(workspace.Id, userId, entity.AuditMembersUpdated, "workspace", workspace.WorkspaceId, meta, changes...)
 	}
 
-	return errors.New("user does not have the permissions")
+	return nil
 }
 
-func (ss *SystemServiceImpl) DeleteWorkspaceMember(userId primitive.ObjectID, workspaceId, memberEmail string) error {
+func (ss *SystemServiceImpl) DeleteWorkspaceMember(userId primitive.ObjectID, workspaceId, memberEmail string, meta entity.AuditMeta) error {
 	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(workspaceId)
 	if err != nil {
 		return err
 	}
 
-	if !ss.isAdmin(workspace.Team[userId]) && !ss.isOwner(workspace.Team[userId]) {
-		return errors.New("unauthorised")
+	if err = ss.authorize(workspace, userId, utils.PermissionMemberManage); err != nil {
+		return err
 	}
 
 	user, err := ss.systemRepo.FindUserByEmail(memberEmail)
@@ -360,18 +436,32 @@ func (ss *SystemServiceImpl) DeleteWorkspaceMember(userId primitive.ObjectID, wo
 		return err
 	}
 
+	previousRole := workspace.Team[user]
+	if err = ss.authorizeGrant(workspace, userId, map[primitive.ObjectID]entity.WorkspaceRole{user: previousRole}, nil); err != nil {
+		return err
+	}
 	internalTeams, _ := ss.systemRepo.FindTeamsByWorkspaceId(workspace.Id)
 
+	var teamNames []string
 	for _, internalTeam := range internalTeams {
 		if _, exists := internalTeam.Members[user]; exists {
 			delete(internalTeam.Members, user)
 			if err = ss.systemRepo.UpdateTeam(internalTeam); err != nil {
 				return err
 			}
+			teamNames = append(teamNames, internalTeam.TeamName)
 		}
 	}
 
-	return ss.systemRepo.RemoveUserFromWorkspace(workspace, user)
+	if err = ss.systemRepo.RemoveUserFromWorkspace(workspace, user); err != nil {
+		return err
+	}
+
+	ss.recordAudit(workspace.Id, userId, entity.AuditMemberRemoved, "member", memberEmail, meta,
+		entity.AuditChange{Field: "role", Before: string(previousRole)},
+		entity.AuditChange{Field: "teams", Before: strings.Join(teamNames, ", ")},
+	)
+	return nil
 }
 
 func (ss *SystemServiceImpl) DeleteWorkspaceById(workspaceId string, userId primitive.ObjectID) error {
@@ -380,13 +470,11 @@ func (ss *SystemServiceImpl) DeleteWorkspaceById(workspaceId string, userId prim
 		return err
 	}
 
-	if ss.isOwner(workspace.Team[userId]) {
-		if err := ss.systemRepo.DeleteWorkspace(workspace.Id); err != nil {
-			return err
-		}
+	if err = ss.authorize(workspace, userId, utils.PermissionWorkspaceDelete); err != nil {
+		return err
 	}
 
-	return errors.New("user does not have a valid permission")
+	return ss.systemRepo.DeleteWorkspace(workspace.Id)
 }
 
 func (ss *SystemServiceImpl) GetUserProfiles(workspaceId string, userId primitive.ObjectID) ([]infrastructureModel.User, error) {
@@ -395,20 +483,20 @@ func (ss *SystemServiceImpl) GetUserProfiles(workspaceId string, userId primitiv
 		return nil, err
 	}
 
-	if _, exists := workspace.Team[userId]; exists {
-		users, err := ss.systemRepo.GetUserProfiles(*workspace)
-		if err != nil {
-			return nil, err
-		}
+	if err = ss.authorize(workspace, userId, utils.PermissionWorkspaceRead); err != nil {
+		return nil, err
+	}
 
-		for _, user := range *users {
-			user.Logo, _ = ss.fileService.LoadFile("user." + user.Email)
-		}
+	users, err := ss.systemRepo.GetUserProfiles(*workspace)
+	if err != nil {
+		return nil, err
+	}
 
-		return *users, nil
+	for _, user := range *users {
+		user.Logo, _ = ss.fileService.LoadFile("user." + user.Email)
 	}
 
-	return nil, errors.New("user does not have a valid permission")
+	return *users, nil
 }
 
 func (ss *SystemServiceImpl) RegisterTelegramIntegration(userId primitive.ObjectID, workspaceId, stage, value string) (int, error) {
@@ -417,8 +505,8 @@ func (ss *SystemServiceImpl) RegisterTelegramIntegration(userId primitive.Object
 		return 500, err
 	}
 
-	if _, exists := workspace.Team[userId]; !exists {
-		return 500, errors.New("user does not have valid permissions")
+	if err = ss.authorize(workspace, userId, utils.PermissionIntegrationManage); err != nil {
+		return 403, err
 	}
 
 	client := resty.New()
@@ -477,6 +565,8 @@ func (ss *SystemServiceImpl) formatWorkspaces(workspaces []entity.Workspace) ([]
 			WorkspaceId: p.WorkspaceId,
 			Team:        team,
 			Logo:        logo,
+
+			RequireTwoFactor: p.RequireTwoFactor,
 		}
 
 		formattedWorkspaces[i] = formattedWorkspace
@@ -491,11 +581,12 @@ func (ss *SystemServiceImpl) EditFolders(userId primitive.ObjectID, workspaceId
 		return err
 	}
 
-	if _, exists := workspace.Team[userId]; exists {
-		workspace.Folders = folders
-		return ss.systemRepo.UpdateWorkspace(workspace)
+	if err = ss.authorize(workspace, userId, utils.PermissionFolderManage); err != nil {
+		return err
 	}
-	return errors.New("unauthorized")
+
+	workspace.Folders = folders
+	return ss.systemRepo.UpdateWorkspace(workspace)
 }
 
 func (ss *SystemServiceImpl) GetAllUsers(userId primitive.ObjectID, workspaceId, teamId string) ([]model.UserResponse, error) {
@@ -504,8 +595,8 @@ func (ss *SystemServiceImpl) GetAllUsers(userId primitive.ObjectID, workspaceId,
 		return nil, err
 	}
 
-	if _, exists := workspace.Team[userId]; !exists {
-		return nil, errors.New("unauthorized")
+	if err = ss.authorize(workspace, userId, utils.PermissionWorkspaceRead); err != nil {
+		return nil, err
 	}
 
 	var team *entity.Team
@@ -560,8 +651,8 @@ func (ss *SystemServiceImpl) UpdateTeam(userId primitive.ObjectID, newLogo []byt
 		return err
 	}
 
-	if !ss.isAdmin(workspace.Team[userId]) && !ss.isOwner(workspace.Team[userId]) {
-		return errors.New("unauthorised")
+	if err = ss.authorize(workspace, userId, utils.PermissionTeamManage); err != nil {
+		return err
 	}
 
 	team, err := ss.systemRepo.FindTeamByTeamIdAndWorkspaceId(teamId, workspace.Id)
@@ -577,6 +668,473 @@ func (ss *SystemServiceImpl) UpdateTeam(userId primitive.ObjectID, newLogo []byt
 	return ss.systemRepo.UpdateTeam(team)
 }
 
+func (ss *SystemServiceImpl) UpdateTeamAssignment(userId primitive.ObjectID, request model.UpdateTeamAssignmentRequest) error {
+	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(request.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	if err = ss.authorize(workspace, userId, utils.PermissionTeamManage); err != nil {
+		return err
+	}
+
+	team, err := ss.systemRepo.FindTeamByTeamIdAndWorkspaceId(request.TeamId, workspace.Id)
+	if err != nil {
+		return err
+	}
+
+	strategy := entity.AssignmentStrategy(request.Strategy)
+	switch strategy {
+	case entity.AssignmentLeastOpenTickets, entity.AssignmentRoundRobin, entity.AssignmentLanguageMatch:
+	default:
+		return fmt.Errorf("invalid assignment strategy: %s", request.Strategy)
+	}
+
+	if request.MaxOpenTickets < 0 {
+		return errors.New("max open tickets cannot be negative")
+	}
+
+	languages := make(map[primitive.ObjectID][]entity.ChatLanguage)
+	for email, codes := range request.Languages {
+		memberId, err := ss.systemRepo.FindUserByEmail(email)
+		if err != nil {
+			return err
+		}
+		if !team.Members[memberId] {
+			return fmt.Errorf("%s is not a member of the team", email)
+		}
+
+		for _, code := range codes {
+			language := entity.ChatLanguage(code)
+			switch language {
+			case entity.English, entity.Russian, entity.Uzbek:
+				languages[memberId] = append(languages[memberId], language)
+			default:
+				return fmt.Errorf("invalid language: %s", code)
+			}
+		}
+	}
+
+	team.Assignment.Strategy = strategy
+	team.Assignment.Sticky = request.Sticky
+	team.Assignment.MaxOpenTickets = request.MaxOpenTickets
+	team.Assignment.Languages = languages
+
+	return ss.systemRepo.UpdateTeam(team)
+}
+
+func (ss *SystemServiceImpl) CreateSavedReply(userId primitive.ObjectID, request model.CreateSavedReplyRequest) error {
+	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(request.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	if err = ss.authorize(workspace, userId, utils.PermissionSavedReplyManage); err != nil {
+		return err
+	}
+
+	reply := &entity.SavedReply{
+		ReplyId:     uuid.New().String(),
+		WorkspaceId: workspace.Id,
+		TeamId:      request.TeamId,
+		Shortcut:    strings.ToLower(strings.TrimSpace(request.Shortcut)),
+		Title:       request.Title,
+		CreatedBy:   userId,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if reply.Variants, err = ss.validateSavedReply(workspace, reply, request.Variants); err != nil {
+		return err
+	}
+
+	return ss.systemRepo.InsertSavedReply(reply)
+}
+
+func (ss *SystemServiceImpl) UpdateSavedReply(userId primitive.ObjectID, request model.UpdateSavedReplyRequest) error {
+	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(request.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	if err = ss.authorize(workspace, userId, utils.PermissionSavedReplyManage); err != nil {
+		return err
+	}
+
+	reply, err := ss.systemRepo.FindSavedReplyByReplyId(workspace.Id, request.ReplyId)
+	if err != nil {
+		return err
+	}
+
+	reply.TeamId = request.TeamId
+	reply.Shortcut = strings.ToLower(strings.TrimSpace(request.Shortcut))
+	reply.Title = request.Title
+	reply.UpdatedAt = time.Now()
+	if reply.Variants, err = ss.validateSavedReply(workspace, reply, request.Variants); err != nil {
+		return err
+	}
+
+	return ss.systemRepo.UpdateSavedReply(reply)
+}
+
+// GetSavedReplies lists the workspace-wide replies and those of the user's teams; admins see every team's replies
+func (ss *SystemServiceImpl) GetSavedReplies(userId primitive.ObjectID, workspaceId string) ([]model.SavedReplyResponse, error) {
+	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ss.authorize(workspace, userId, utils.PermissionWorkspaceRead); err != nil {
+		return nil, err
+	}
+	// members who cannot manage saved replies only see the workspace-wide ones and their own teams'
+	canManage := ss.authorize(workspace, userId, utils.PermissionSavedReplyManage) == nil
+
+	replies, err := ss.systemRepo.FindSavedRepliesByWorkspaceId(workspace.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	userTeams := make(map[string]bool)
+	if !canManage {
+		teams, err := ss.systemRepo.FindTeamsByWorkspaceId(workspace.Id)
+		if err != nil {
+			return nil, err
+		}
+		for _, team := range teams {
+			if team.Members[userId] {
+				userTeams[team.TeamId] = true
+			}
+		}
+	}
+
+	response := make([]model.SavedReplyResponse, 0, len(replies))
+	for _, reply := range replies {
+		if reply.TeamId != "" && !canManage && !userTeams[reply.TeamId] {
+			continue
+		}
+
+		variants := make(map[string]string, len(reply.Variants))
+		for language, text := range reply.Variants {
+			variants[string(language)] = text
+		}
+
+		response = append(response, model.SavedReplyResponse{
+			ReplyId:   reply.ReplyId,
+			TeamId:    reply.TeamId,
+			Shortcut:  reply.Shortcut,
+			Title:     reply.Title,
+			Variants:  variants,
+			CreatedAt: reply.CreatedAt,
+			UpdatedAt: reply.UpdatedAt,
+		})
+	}
+
+	return response, nil
+}
+
+func (ss *SystemServiceImpl) DeleteSavedReply(userId primitive.ObjectID, workspaceId, replyId string) error {
+	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(workspaceId)
+	if err != nil {
+		return err
+	}
+
+	if err = ss.authorize(workspace, userId, utils.PermissionSavedReplyManage); err != nil {
+		return err
+	}
+
+	return ss.systemRepo.DeleteSavedReply(workspace.Id, replyId)
+}
+
+func (ss *SystemServiceImpl) GetAuditLogs(userId primitive.ObjectID, request model.AuditLogRequest) (model.AuditLogsResponse, error) {
+	workspace, filter, err := ss.prepareAuditQuery(userId, request)
+	if err != nil {
+		return model.AuditLogsResponse{}, err
+	}
+
+	if request.Page < 1 {
+		request.Page = 1
+	}
+	if request.Limit <= 0 {
+		request.Limit = defaultAuditLimit
+	}
+	request.Limit = min(request.Limit, maxAuditLimit)
+
+	// one extra entry tells whether another page exists
+	auditLogs, err := ss.systemRepo.FindAuditLogs(workspace.Id, filter, (request.Page-1)*request.Limit, request.Limit+1)
+	if err != nil {
+		return model.AuditLogsResponse{}, err
+	}
+
+	response := model.AuditLogsResponse{
+		Logs:    make([]model.AuditLogResponse, 0, min(len(auditLogs), request.Limit)),
+		Page:    request.Page,
+		HasMore: len(auditLogs) > request.Limit,
+	}
+
+	emails := make(map[primitive.ObjectID]string)
+	for _, auditLog := range auditLogs[:min(len(auditLogs), request.Limit)] {
+		response.Logs = append(response.Logs, ss.createAuditLogResponse(auditLog, emails))
+	}
+
+	return response, nil
+}
+
+func (ss *SystemServiceImpl) ExportAuditLogs(userId primitive.ObjectID, request model.AuditLogRequest) ([]byte, error) {
+	workspace, filter, err := ss.prepareAuditQuery(userId, request)
+	if err != nil {
+		return nil, err
+	}
+
+	auditLogs, err := ss.systemRepo.FindAuditLogs(workspace.Id, filter, 0, auditExportLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if err = writer.Write([]string{"created_at", "actor", "action", "target_type", "target_id", "field", "before", "after", "ip", "user_agent"}); err != nil {
+		return nil, err
+	}
+
+	emails := make(map[primitive.ObjectID]string)
+	for _, auditLog := range auditLogs {
+		entry := ss.createAuditLogResponse(auditLog, emails)
+		changes := entry.Changes
+		if len(changes) == 0 {
+			changes = []model.AuditChangeResponse{{}}
+		}
+
+		// one row per changed field keeps the before and after values side by side
+		for _, change := range changes {
+			if err = writer.Write([]string{
+				entry.CreatedAt.Format(time.RFC3339),
+				csvCell(entry.Actor),
+				entry.Action,
+				entry.TargetType,
+				csvCell(entry.TargetId),
+				csvCell(change.Field),
+				csvCell(change.Before),
+				csvCell(change.After),
+				entry.IP,
+				csvCell(entry.UserAgent),
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (ss *SystemServiceImpl) GetRoles(userId primitive.ObjectID, workspaceId string) ([]model.RoleResponse, error) {
+	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ss.authorize(workspace, userId, utils.PermissionWorkspaceRead); err != nil {
+		return nil, err
+	}
+
+	authorizer := utils.NewAuthorizer(workspace.Roles)
+	response := make([]model.RoleResponse, 0, len(utils.BuiltInRoles())+len(workspace.Roles))
+	for name := range utils.BuiltInRoles() {
+		response = append(response, ss.createRoleResponse(name, true, authorizer.Permissions(name)))
+	}
+	for name := range workspace.Roles {
+		response = append(response, ss.createRoleResponse(name, false, authorizer.Permissions(name)))
+	}
+	sort.Slice(response, func(i, j int) bool {
+		if response[i].IsBuiltIn != response[j].IsBuiltIn {
+			return response[i].IsBuiltIn
+		}
+		return response[i].Name < response[j].Name
+	})
+
+	return response, nil
+}
+
+// GetInvitationDeliveries shows the delivery of the latest invitation sent to each address, so an admin can
+// tell whether it is still queued, was sent or failed and why
+func (ss *SystemServiceImpl) GetInvitationDeliveries(userId primitive.ObjectID, workspaceId string) ([]model.InvitationDeliveryResponse, error) {
+	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ss.authorize(workspace, userId, utils.PermissionMemberManage); err != nil {
+		return nil, err
+	}
+
+	emails, err := ss.systemRepo.FindWorkspaceEmails(workspace.Id, mailer.TemplateWorkspaceInvitation)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(emails))
+	response := make([]model.InvitationDeliveryResponse, 0, len(emails))
+	for _, email := range emails {
+		if seen[email.To] {
+			continue
+		}
+		seen[email.To] = true
+
+		_, isPending := workspace.PendingTeam[email.To]
+		delivery := model.InvitationDeliveryResponse{
+			Email:     email.To,
+			Status:    string(email.Status),
+			IsPending: isPending,
+			Attempts:  email.Attempts,
+			LastError: email.LastError,
+			CreatedAt: email.CreatedAt,
+		}
+		if email.Status == mailer.OutboxQueued {
+			delivery.NextAttemptAt = &email.NextAttemptAt
+		}
+		if email.Status == mailer.OutboxSent {
+			delivery.SentAt = &email.SentAt
+		}
+		response = append(response, delivery)
+	}
+
+	return response, nil
+}
+
+// ResendInvitation queues a new invitation with a fresh link for an address that has not joined yet
+func (ss *SystemServiceImpl) ResendInvitation(userId primitive.ObjectID, request model.ResendInvitationRequest) error {
+	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(request.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	if err = ss.authorize(workspace, userId, utils.PermissionMemberManage); err != nil {
+		return err
+	}
+
+	if _, isPending := workspace.PendingTeam[request.Email]; !isPending {
+		return errors.New("no pending invitation for this email")
+	}
+
+	return ss.sendWorkspaceInvitations(workspace, userId, []string{request.Email})
+}
+
+// SaveRole creates a custom role or replaces the permissions of an existing one
+func (ss *SystemServiceImpl) SaveRole(userId primitive.ObjectID, request model.SaveRoleRequest, meta entity.AuditMeta) error {
+	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(request.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	if err = ss.authorize(workspace, userId, utils.PermissionRoleManage); err != nil {
+		return err
+	}
+
+	if !roleNamePattern.MatchString(request.Name) {
+		return errors.New("role name must be 2 to 32 lowercase letters, digits, dashes or underscores")
+	}
+	if utils.IsBuiltInRole(request.Name) {
+		return errors.New("built-in roles cannot be changed")
+	}
+
+	permissions, err := ss.parseRolePermissions(workspace, userId, request.Permissions)
+	if err != nil {
+		return err
+	}
+
+	if workspace.Roles == nil {
+		workspace.Roles = make(map[string][]string)
+	}
+	previous := workspace.Roles[request.Name]
+	workspace.Roles[request.Name] = permissions
+	if err = ss.systemRepo.UpdateWorkspace(workspace); err != nil {
+		return err
+	}
+
+	ss.recordAudit(workspace.Id, userId, entity.AuditRoleSaved, "role", request.Name, meta,
+		entity.AuditChange{Field: "permissions", Before: strings.Join(previous, ", "), After: strings.Join(permissions, ", ")},
+	)
+	return nil
+}
+
+func (ss *SystemServiceImpl) DeleteRole(userId primitive.ObjectID, workspaceId, name string, meta entity.AuditMeta) error {
+	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(workspaceId)
+	if err != nil {
+		return err
+	}
+
+	if err = ss.authorize(workspace, userId, utils.PermissionRoleManage); err != nil {
+		return err
+	}
+
+	permissions, exists := workspace.Roles[name]
+	if !exists {
+		return errors.New("role not found")
+	}
+
+	for _, role := range workspace.Team {
+		if string(role) == name {
+			return errors.New("role is still assigned to a member")
+		}
+	}
+	for _, role := range workspace.PendingTeam {
+		if string(role) == name {
+			return errors.New("role is still assigned to an invited member")
+		}
+	}
+
+	delete(workspace.Roles, name)
+	if err = ss.systemRepo.UpdateWorkspace(workspace); err != nil {
+		return err
+	}
+
+	ss.recordAudit(workspace.Id, userId, entity.AuditRoleDeleted, "role", name, meta,
+		entity.AuditChange{Field: "permissions", Before: strings.Join(permissions, ", ")},
+	)
+	return nil
+}
+
+// UpdateTwoFactorRequirement turns the two-factor requirement of the workspace on or off. The caller has to use
+// two-factor authentication themselves before requiring it, so they cannot lock themselves out.
+func (ss *SystemServiceImpl) UpdateTwoFactorRequirement(userId primitive.ObjectID, request model.TwoFactorRequirementRequest, meta entity.AuditMeta) error {
+	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(request.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	if err = ss.authorize(workspace, userId, utils.PermissionSecurityManage); err != nil {
+		return err
+	}
+
+	if request.Required {
+		user, err := ss.systemRepo.FindUserById(userId)
+		if err != nil {
+			return err
+		}
+		if !user.TwoFactor.IsEnabled {
+			return errors.New("enable two-factor authentication before requiring it")
+		}
+	}
+
+	if workspace.RequireTwoFactor == request.Required {
+		return nil
+	}
+
+	workspace.RequireTwoFactor = request.Required
+	if err = ss.systemRepo.UpdateWorkspace(workspace); err != nil {
+		return err
+	}
+
+	ss.recordAudit(workspace.Id, userId, entity.AuditTwoFactorRequirement, "workspace", workspace.WorkspaceId, meta,
+		entity.AuditChange{Field: "require_two_factor", Before: strconv.FormatBool(!request.Required), After: strconv.FormatBool(request.Required)},
+	)
+	return nil
+}
+
 func (ss *SystemServiceImpl) GetAllTeams(userId primitive.ObjectID, workspaceId string) ([]model.TeamResponse, int, error) {
 	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(workspaceId)
 	if err != nil {
@@ -586,8 +1144,8 @@ func (ss *SystemServiceImpl) GetAllTeams(userId primitive.ObjectID, workspaceId
 		return nil, 204, err
 	}
 
-	if _, exists := workspace.Team[userId]; !exists {
-		return nil, 403, errors.New("unauthorised")
+	if err = ss.authorize(workspace, userId, utils.PermissionWorkspaceRead); err != nil {
+		return nil, 403, err
 	}
 
 	internalTeams, err := ss.systemRepo.FindTeamsByWorkspaceId(workspace.Id)
@@ -598,6 +1156,7 @@ func (ss *SystemServiceImpl) GetAllTeams(userId primitive.ObjectID, workspaceId
 		return nil, 204, nil
 	}
 
+	authorizer := utils.NewAuthorizer(workspace.Roles)
 	var teamsResponse []model.TeamResponse
 	for _, team := range internalTeams {
 		var memberCount int
@@ -605,7 +1164,7 @@ func (ss *SystemServiceImpl) GetAllTeams(userId primitive.ObjectID, workspaceId
 
 		for userId, _ := range team.Members {
 			memberCount++
-			if ss.isAdmin(workspace.Team[userId]) || ss.isOwner(workspace.Team[userId]) {
+			if authorizer.Can(string(workspace.Team[userId]), utils.PermissionTeamManage) {
 				user, _ := ss.systemRepo.FindUserById(userId)
 				admins = append(admins, user.FullName)
 			}
@@ -613,7 +1172,9 @@ func (ss *SystemServiceImpl) GetAllTeams(userId primitive.ObjectID, workspaceId
 		number, _ := ss.systemRepo.CountChatsByTeamId(team.Id)
 
 		logo, _ := ss.fileService.LoadFile("team." + team.TeamId)
-		teamsResponse = append(teamsResponse, *ss.createTeamResponse(team.TeamName, team.TeamId, memberCount, number, admins, logo))
+		teamResponse := ss.createTeamResponse(team.TeamName, team.TeamId, memberCount, number, admins, logo)
+		teamResponse.Assignment = ss.createTeamAssignmentResponse(team.Assignment)
+		teamsResponse = append(teamsResponse, *teamResponse)
 	}
 
 	return teamsResponse, 200, nil
@@ -625,13 +1186,222 @@ func (ss *SystemServiceImpl) GetAllFolders(userId primitive.ObjectID, workspaceI
 		return nil, err, 500
 	}
 
-	if _, exists := workspace.Team[userId]; !exists {
-		return nil, errors.New("unauthorised"), 403
+	if err = ss.authorize(workspace, userId, utils.PermissionWorkspaceRead); err != nil {
+		return nil, err, 403
 	}
 
 	return workspace.Folders, nil, 200
 }
 
+// csvCell stops spreadsheet apps from evaluating user supplied text such as deleted messages as a formula
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (ss *SystemServiceImpl) authorize(workspace *entity.Workspace, userId primitive.ObjectID, permissions ...utils.Permission) error {
+	return utils.NewAuthorizer(workspace.Roles).Authorize(string(workspace.Team[userId]), workspace.RequireTwoFactor, func() (bool, error) {
+		user, err := ss.systemRepo.FindUserById(userId)
+		if err != nil {
+			return false, err
+		}
+		return user.TwoFactor.IsEnabled, nil
+	}, permissions...)
+}
+
+// authorizeGrant stops a member from handing out, or taking away, a role with permissions they do not hold themselves
+func (ss *SystemServiceImpl) authorizeGrant(workspace *entity.Workspace, userId primitive.ObjectID, teamRoles map[primitive.ObjectID]entity.WorkspaceRole, pendingTeamRoles map[string]entity.WorkspaceRole) error {
+	roles := make([]entity.WorkspaceRole, 0, len(teamRoles)+len(pendingTeamRoles))
+	for _, role := range teamRoles {
+		roles = append(roles, role)
+	}
+	for _, role := range pendingTeamRoles {
+		roles = append(roles, role)
+	}
+
+	authorizer := utils.NewAuthorizer(workspace.Roles)
+	memberRole := string(workspace.Team[userId])
+	var permissions []utils.Permission
+	for _, role := range roles {
+		for _, permission := range authorizer.Permissions(string(role)) {
+			if !authorizer.Can(memberRole, permission) {
+				return fmt.Errorf("not allowed to grant the %s role", role)
+			}
+			permissions = append(permissions, permission)
+		}
+	}
+
+	return ss.authorize(workspace, userId, permissions...)
+}
+
+// authorizeNewMembers lets team managers add existing members freely, while bringing people into the workspace
+// also needs the member permission
+func (ss *SystemServiceImpl) authorizeNewMembers(workspace *entity.Workspace, userId primitive.ObjectID, teamRoles map[primitive.ObjectID]entity.WorkspaceRole, pendingTeamRoles map[string]entity.WorkspaceRole) error {
+	newRoles := make(map[primitive.ObjectID]entity.WorkspaceRole)
+	for id, role := range teamRoles {
+		if _, exists := workspace.Team[id]; !exists {
+			newRoles[id] = role
+		}
+	}
+	newPendingRoles := make(map[string]entity.WorkspaceRole)
+	for email, role := range pendingTeamRoles {
+		if _, exists := workspace.PendingTeam[email]; !exists {
+			newPendingRoles[email] = role
+		}
+	}
+	if len(newRoles) == 0 && len(newPendingRoles) == 0 {
+		return nil
+	}
+
+	if err := ss.authorize(workspace, userId, utils.PermissionMemberManage); err != nil {
+		return err
+	}
+	return ss.authorizeGrant(workspace, userId, newRoles, newPendingRoles)
+}
+
+func (ss *SystemServiceImpl) parseRolePermissions(workspace *entity.Workspace, userId primitive.ObjectID, values []string) ([]string, error) {
+	permissions, err := utils.ParsePermissions(values)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer := utils.NewAuthorizer(workspace.Roles)
+	granted := make([]utils.Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		if !authorizer.Can(string(workspace.Team[userId]), utils.Permission(permission)) {
+			return nil, fmt.Errorf("not allowed to grant the %s permission", permission)
+		}
+		granted = append(granted, utils.Permission(permission))
+	}
+
+	if err = ss.authorize(workspace, userId, granted...); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (ss *SystemServiceImpl) createRoleResponse(name string, isBuiltIn bool, permissions []utils.Permission) model.RoleResponse {
+	values := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		values = append(values, string(permission))
+	}
+
+	return model.RoleResponse{
+		Name:        name,
+		IsBuiltIn:   isBuiltIn,
+		Permissions: values,
+	}
+}
+
+func (ss *SystemServiceImpl) prepareAuditQuery(userId primitive.ObjectID, request model.AuditLogRequest) (*entity.Workspace, entity.AuditFilter, error) {
+	workspace, err := ss.systemRepo.FindWorkspaceByWorkspaceId(request.WorkspaceId)
+	if err != nil {
+		return nil, entity.AuditFilter{}, err
+	}
+
+	if err = ss.authorize(workspace, userId, utils.PermissionAuditRead); err != nil {
+		return nil, entity.AuditFilter{}, err
+	}
+
+	filter := entity.AuditFilter{Action: entity.AuditAction(request.Action)}
+	if request.Actor != "" {
+		if filter.ActorId, err = ss.systemRepo.FindUserByEmail(request.Actor); err != nil {
+			return nil, entity.AuditFilter{}, err
+		}
+	}
+	if filter.From, err = utils.ParseDate(request.From, false); err != nil {
+		return nil, entity.AuditFilter{}, err
+	}
+	if filter.To, err = utils.ParseDate(request.To, true); err != nil {
+		return nil, entity.AuditFilter{}, err
+	}
+
+	return workspace, filter, nil
+}
+
+func (ss *SystemServiceImpl) createAuditLogResponse(auditLog entity.AuditLog, emails map[primitive.ObjectID]string) model.AuditLogResponse {
+	actor, ok := emails[auditLog.ActorId]
+	if !ok {
+		actor = ss.findUserEmail(auditLog.ActorId)
+		emails[auditLog.ActorId] = actor
+	}
+
+	changes := make([]model.AuditChangeResponse, 0, len(auditLog.Changes))
+	for _, change := range auditLog.Changes {
+		changes = append(changes, model.AuditChangeResponse{
+			Field:  change.Field,
+			Before: change.Before,
+			After:  change.After,
+		})
+	}
+
+	return model.AuditLogResponse{
+		Actor:      actor,
+		Action:     string(auditLog.Action),
+		TargetType: auditLog.TargetType,
+		TargetId:   auditLog.TargetId,
+		Changes:    changes,
+		IP:         auditLog.IP,
+		UserAgent:  auditLog.UserAgent,
+		CreatedAt:  auditLog.CreatedAt,
+	}
+}
+
+// recordAudit writes the entry after the action has succeeded, so a failed write is logged instead of undoing it
+func (ss *SystemServiceImpl) recordAudit(workspaceId, actorId primitive.ObjectID, action entity.AuditAction, targetType, targetId string, meta entity.AuditMeta, changes ...entity.AuditChange) {
+	auditLog := &entity.AuditLog{
+		WorkspaceId: workspaceId,
+		ActorId:     actorId,
+		Action:      action,
+		TargetType:  targetType,
+		TargetId:    targetId,
+		Changes:     changes,
+		IP:          meta.IP,
+		UserAgent:   meta.UserAgent,
+		CreatedAt:   time.Now(),
+	}
+	if err := ss.systemRepo.InsertAuditLog(auditLog); err != nil {
+		log.Println("failed to write the audit log:", action, targetId, err)
+	}
+}
+
+// sendWorkspaceInvitations queues one invitation per email in the inviter's language, the invited people may not
+// have an account to take it from. The outbox delivers them and keeps their status for GetInvitationDeliveries.
+func (ss *SystemServiceImpl) sendWorkspaceInvitations(workspace *entity.Workspace, inviterId primitive.ObjectID, emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+
+	var language, inviterName string
+	if inviter, err := ss.systemRepo.FindUserById(inviterId); err == nil && inviter != nil {
+		language, inviterName = inviter.Language, inviter.FullName
+	}
+
+	for _, email := range emails {
+		emailHash, err := utils.GenerateInvitationJWTToken(ss.config.Auth.JWTSecretKey, email)
+		if err != nil {
+			return err
+		}
+
+		inviteLink := fmt.Sprintf("%s/signin/confirm?id=%s&email=%s", ss.config.Website.WebURL, workspace.WorkspaceId, emailHash)
+		if err := ss.emailService.SendWorkspaceInvitationEmail(email, language, inviteLink, workspace, inviterName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ss *SystemServiceImpl) findUserEmail(userId primitive.ObjectID) string {
+	email, err := ss.systemRepo.FindUserEmailById(userId)
+	if err != nil {
+		return userId.Hex()
+	}
+	return email
+}
+
 func (ss *SystemServiceImpl) createTeamResponse(teamName, teamId string, memberCount, chatCount int, adminNames []string, logo []byte) *model.TeamResponse {
 	return &model.TeamResponse{
 		TeamId:      teamId,
@@ -643,6 +1413,75 @@ func (ss *SystemServiceImpl) createTeamResponse(teamName, teamId string, memberC
 	}
 }
 
+func (ss *SystemServiceImpl) validateSavedReply(workspace *entity.Workspace, reply *entity.SavedReply, variants map[string]string) (map[entity.ChatLanguage]string, error) {
+	if !savedReplyShortcutPattern.MatchString(reply.Shortcut) {
+		return nil, errors.New("shortcut must start with / followed by letters, digits, - or _")
+	}
+
+	if reply.TeamId != "" {
+		if _, err := ss.systemRepo.FindTeamByTeamIdAndWorkspaceId(reply.TeamId, workspace.Id); err != nil {
+			return nil, errors.New("team not found")
+		}
+	}
+
+	exists, err := ss.systemRepo.CheckShortcutExists(workspace.Id, reply.TeamId, reply.Shortcut, reply.ReplyId)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("shortcut %s is already in use", reply.Shortcut)
+	}
+
+	if len(variants) == 0 {
+		return nil, errors.New("saved reply needs at least one language variant")
+	}
+
+	formatted := make(map[entity.ChatLanguage]string, len(variants))
+	for code, text := range variants {
+		language := entity.ChatLanguage(code)
+		switch language {
+		case entity.English, entity.Russian, entity.Uzbek:
+		default:
+			return nil, fmt.Errorf("invalid language: %s", code)
+		}
+
+		if strings.TrimSpace(text) == "" {
+			return nil, fmt.Errorf("%s variant is empty", code)
+		}
+		if err = utils.ValidatePlaceholders(text, utils.ReplyPlaceholders); err != nil {
+			return nil, err
+		}
+		formatted[language] = text
+	}
+
+	return formatted, nil
+}
+
+func (ss *SystemServiceImpl) createTeamAssignmentResponse(assignment entity.TeamAssignment) model.TeamAssignmentResponse {
+	strategy := assignment.Strategy
+	if strategy == "" {
+		strategy = entity.AssignmentLeastOpenTickets
+	}
+
+	languages := make(map[string][]string)
+	for memberId, codes := range assignment.Languages {
+		email, err := ss.systemRepo.FindUserEmailById(memberId)
+		if err != nil {
+			continue
+		}
+		for _, code := range codes {
+			languages[email] = append(languages[email], string(code))
+		}
+	}
+
+	return model.TeamAssignmentResponse{
+		Strategy:       string(strategy),
+		Sticky:         assignment.Sticky,
+		MaxOpenTickets: assignment.MaxOpenTickets,
+		Languages:      languages,
+	}
+}
+
 func (ss *SystemServiceImpl) createTeam(workspaceId primitive.ObjectID, teamName string, members map[primitive.ObjectID]bool, pendingMembers map[string]bool, isFirstTeam bool) *entity.Team {
 	return &entity.Team{
 		WorkspaceId:    workspaceId,
@@ -667,11 +1506,3 @@ func (ss *SystemServiceImpl) createUserResponse(email, fullName, role, status st
 		InTeam:               inTeam,
 	}
 }
-
-func (ss *SystemServiceImpl) isAdmin(userRole entity.WorkspaceRole) bool {
-	return userRole == entity.RoleAdmin
-}
-
-func (ss *SystemServiceImpl) isOwner(userRole entity.WorkspaceRole) bool {
-	return userRole == entity.RoleOwner
-}
@@ -1,13 +1,17 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"github.com/Point-AI/backend/config"
 	"github.com/Point-AI/backend/internal/user/delivery/model"
+	"github.com/Point-AI/backend/internal/user/domain/entity"
 	_interface "github.com/Point-AI/backend/internal/user/domain/interface"
+	"github.com/Point-AI/backend/mailer"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"net/http"
+	"strconv"
 )
 
 type UserController struct {
@@ -23,7 +27,7 @@ func NewUserController(userService _interface.UserService, cfg *config.Config) *
 }
 
 // RegisterUser registers a new user.
-// @Description Registers a new user with provided email and password.
+// @Description Registers an invited user when hash and workspace_id are given. Without them it signs up a new user, who has to confirm their email before signing in.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -38,7 +42,14 @@ func (uc *UserController) RegisterUser(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
 	}
 
-	if err := uc.userService.RegisterUser(request.Email, request.Password, request.WorkspaceId, request.Hash, request.Name, request.Logo); err != nil {
+	if request.Hash == "" && request.WorkspaceId == "" {
+		if err := uc.userService.SignUp(request.Email, request.Password, request.Name, preferredLanguage(c, request.Language), request.Logo); err != nil {
+			return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+		}
+		return c.JSON(http.StatusCreated, model.SuccessResponse{Message: "confirmation email sent"})
+	}
+
+	if err := uc.userService.RegisterUser(request.Email, request.Password, request.WorkspaceId, request.Hash, request.Name, preferredLanguage(c, request.Language), request.Logo); err != nil {
 		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 	}
 
@@ -46,26 +57,53 @@ func (uc *UserController) RegisterUser(c echo.Context) error {
 }
 
 // ConfirmUser confirms a user's registration.
-// @Description Confirms a user's registration using the confirmation token.
+// @Description Confirms a user's registration using the confirmation token and signs them in.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param token path string true "Confirmation token"
-// @Success 200 {object} model.SuccessResponse "User confirmed successfully"
+// @Success 200 {object} model.TokenResponse "User confirmed successfully"
 // @Failure 400 {object} model.ErrorResponse "Bad request"
-// @Failure 500 {object} model.ErrorResponse "Internal server error"
-// @Router /user/verify/{token} [get]
+// @Router /user/verify/{token} [post]
 func (uc *UserController) ConfirmUser(c echo.Context) error {
 	token := c.Param("token")
 	if token == "" {
 		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "confirmation token not provided"})
 	}
 
-	if err := uc.userService.ConfirmUser(token); err != nil {
-		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	tokens, err := uc.userService.ConfirmUser(token, sessionMeta(c, ""))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "user confirmed successfully"})
+	return c.JSON(http.StatusOK, model.TokenResponse{
+		AccessToken:    tokens.AccessToken,
+		RefreshToken:   tokens.RefreshToken,
+		ChallengeToken: tokens.ChallengeToken,
+	})
+}
+
+// ResendConfirmation sends a new confirmation email.
+// @Description Sends a new confirmation link to an unconfirmed user, at most once per cooldown.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body model.ResendConfirmationRequest true "Email to confirm"
+// @Success 200 {object} model.SuccessResponse "Confirmation email sent"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 429 {object} model.ErrorResponse "Too many attempts, see Retry-After"
+// @Router /user/verify/resend [post]
+func (uc *UserController) ResendConfirmation(c echo.Context) error {
+	var request model.ResendConfirmationRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := uc.userService.ResendConfirmation(request.Email, c.RealIP()); err != nil {
+		return errorResponse(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "if the account is waiting for confirmation, a new email was sent"})
 }
 
 // Login handles user login.
@@ -79,6 +117,7 @@ func (uc *UserController) ConfirmUser(c echo.Context) error {
 // @Failure 400 {object} model.ErrorResponse "Bad request"
 // @Failure 401 {object} model.ErrorResponse "Unauthorized"
 // @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 429 {object} model.ErrorResponse "Too many attempts, see Retry-After"
 // @Router /user/signin [post]
 func (uc *UserController) Login(c echo.Context) error {
 	var request model.UserRequest
@@ -86,14 +125,44 @@ func (uc *UserController) Login(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
 	}
 
-	accessToken, refreshToken, err := uc.userService.Login(request.Email, request.Password)
+	tokens, err := uc.userService.Login(request.Email, request.Password, sessionMeta(c, request.DeviceName))
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: err.Error()})
+		return errorResponse(c, http.StatusUnauthorized, err)
 	}
 
 	return c.JSON(http.StatusOK, model.TokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:    tokens.AccessToken,
+		RefreshToken:   tokens.RefreshToken,
+		ChallengeToken: tokens.ChallengeToken,
+	})
+}
+
+// VerifyTwoFactorLogin finishes a sign in that needs a second factor.
+// @Summary Two-factor sign in
+// @Description Exchanges the challenge token from sign in and a TOTP or recovery code for access and refresh tokens.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} model.TokenResponse "User logged in successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad request"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized"
+// @Failure 429 {object} model.ErrorResponse "Too many attempts, see Retry-After"
+// @Router /user/signin/2fa [post]
+func (uc *UserController) VerifyTwoFactorLogin(c echo.Context) error {
+	var request model.TwoFactorLoginRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	tokens, err := uc.userService.VerifyTwoFactorLogin(request.ChallengeToken, request.Code, sessionMeta(c, request.DeviceName))
+	if err != nil {
+		return errorResponse(c, http.StatusUnauthorized, err)
+	}
+
+	return c.JSON(http.StatusOK, model.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	})
 }
 
@@ -115,14 +184,15 @@ func (uc *UserController) GoogleTokens(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
 	}
 
-	accessToken, refreshToken, err := uc.userService.GoogleTokens(request.OAuth2Token)
+	tokens, err := uc.userService.GoogleTokens(request.OAuth2Token, sessionMeta(c, request.DeviceName))
 	if err != nil {
 		return c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: err.Error()})
 	}
 
 	return c.JSON(http.StatusOK, model.TokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:    tokens.AccessToken,
+		RefreshToken:   tokens.RefreshToken,
+		ChallengeToken: tokens.ChallengeToken,
 	})
 }
 
@@ -136,6 +206,7 @@ func (uc *UserController) GoogleTokens(c echo.Context) error {
 // @Success 200 {object} model.SuccessResponse "Password reset email sent successfully"
 // @Failure 400 {object} model.ErrorResponse "Bad request"
 // @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 429 {object} model.ErrorResponse "Too many attempts, see Retry-After"
 // @Router /user/recover [post]
 func (uc *UserController) ForgotPassword(c echo.Context) error {
 	var request model.ForgotPasswordRequest
@@ -143,11 +214,11 @@ func (uc *UserController) ForgotPassword(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
 	}
 
-	if err := uc.userService.ForgotPassword(request.Email); err != nil {
-		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	if err := uc.userService.ForgotPassword(request.Email, c.RealIP()); err != nil {
+		return errorResponse(c, http.StatusInternalServerError, err)
 	}
 
-	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "password reset email sent successfully"})
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "if the account exists, a password reset email was sent"})
 }
 
 // ResetPassword resets a user's password.
@@ -160,6 +231,7 @@ func (uc *UserController) ForgotPassword(c echo.Context) error {
 // @Success 200 {object} model.SuccessResponse "Password reset successfully"
 // @Failure 400 {object} model.ErrorResponse "Bad request"
 // @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 429 {object} model.ErrorResponse "Too many attempts, see Retry-After"
 // @Router /user/reset [post]
 func (uc *UserController) ResetPassword(c echo.Context) error {
 	var request model.PasswordResetRequest
@@ -167,8 +239,8 @@ func (uc *UserController) ResetPassword(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
 	}
 
-	if err := uc.userService.ResetPassword(request.Token, request.NewPassword); err != nil {
-		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	if err := uc.userService.ResetPassword(request.Token, request.NewPassword, c.RealIP()); err != nil {
+		return errorResponse(c, http.StatusInternalServerError, err)
 	}
 
 	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "password reset successfully"})
@@ -176,7 +248,7 @@ func (uc *UserController) ResetPassword(c echo.Context) error {
 
 // Logout logs out a user.
 // @Summary Logout
-// @Description Logs out a user by invalidating the access token.
+// @Description Logs out the current session by revoking its refresh token and access tokens.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -185,22 +257,186 @@ func (uc *UserController) ResetPassword(c echo.Context) error {
 // @Router /user/logout [post]
 func (uc *UserController) Logout(c echo.Context) error {
 	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
-	if err := uc.userService.Logout(userId); err != nil {
+	familyId := c.Request().Context().Value("familyId").(string)
+	if err := uc.userService.Logout(userId, familyId); err != nil {
 		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 	}
 	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "successfully logged out"})
 }
 
+// SignOutAll logs out every session of the user.
+// @Summary Sign out all sessions
+// @Description Revokes all refresh tokens of the user and denies every access token issued so far.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.SuccessResponse "Successfully signed out of all sessions"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /user/logout/all [post]
+func (uc *UserController) SignOutAll(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	if err := uc.userService.SignOutAll(userId); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "successfully signed out of all sessions"})
+}
+
+// EnrollTwoFactor starts two-factor enrollment.
+// @Summary Enroll in two-factor authentication
+// @Description Creates a TOTP secret and returns it as an otpauth URI and QR code. It is enabled once a code is confirmed.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} model.TwoFactorEnrollResponse "Secret to add to an authenticator app"
+// @Failure 400 {object} model.ErrorResponse "Two-factor authentication already enabled"
+// @Router /user/2fa/enroll [post]
+func (uc *UserController) EnrollTwoFactor(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	secret, uri, qrCode, err := uc.userService.EnrollTwoFactor(userId)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.TwoFactorEnrollResponse{Secret: secret, OTPAuthURI: uri, QRCode: qrCode})
+}
+
+// EnableTwoFactor confirms two-factor enrollment.
+// @Summary Enable two-factor authentication
+// @Description Confirms enrollment with a code from the authenticator app and returns one-time recovery codes. Other sessions are signed out.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} model.RecoveryCodesResponse "Recovery codes, shown only once"
+// @Failure 400 {object} model.ErrorResponse "Invalid code"
+// @Router /user/2fa/enable [post]
+func (uc *UserController) EnableTwoFactor(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	familyId := c.Request().Context().Value("familyId").(string)
+	var request model.TwoFactorCodeRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	recoveryCodes, err := uc.userService.EnableTwoFactor(userId, familyId, request.Code)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.RecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// DisableTwoFactor turns two-factor authentication off.
+// @Summary Disable two-factor authentication
+// @Description Disables two-factor authentication after checking a TOTP or recovery code. Refused while a workspace of the user requires it.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorCodeRequest true "TOTP or recovery code"
+// @Success 200 {object} model.SuccessResponse "Two-factor authentication disabled"
+// @Failure 400 {object} model.ErrorResponse "Invalid code"
+// @Router /user/2fa/disable [post]
+func (uc *UserController) DisableTwoFactor(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	var request model.TwoFactorCodeRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := uc.userService.DisableTwoFactor(userId, request.Code); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes.
+// @Summary Regenerate recovery codes
+// @Description Replaces all recovery codes after checking a TOTP or recovery code.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorCodeRequest true "TOTP or recovery code"
+// @Success 200 {object} model.RecoveryCodesResponse "New recovery codes, shown only once"
+// @Failure 400 {object} model.ErrorResponse "Invalid code"
+// @Router /user/2fa/recovery-codes [post]
+func (uc *UserController) RegenerateRecoveryCodes(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	var request model.TwoFactorCodeRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	recoveryCodes, err := uc.userService.RegenerateRecoveryCodes(userId, request.Code)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.RecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// GetSessions lists the devices the user is signed in on.
+// @Summary Get sessions
+// @Description Lists the active sessions of the user, the one making the request is marked as current.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Success 200 {array} model.SessionResponse "Sessions of the user"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /user/sessions [get]
+func (uc *UserController) GetSessions(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	familyId := c.Request().Context().Value("familyId").(string)
+
+	sessions, err := uc.userService.GetSessions(userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	response := make([]model.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, model.SessionResponse{
+			Id:         session.SessionId,
+			DeviceName: session.DeviceName,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			IsCurrent:  session.SessionId == familyId,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+		})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// RevokeSession signs the user out of one device.
+// @Summary Revoke session
+// @Description Ends a session of the user, its refresh token and access tokens stop working.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} model.SuccessResponse "Session revoked"
+// @Failure 404 {object} model.ErrorResponse "Session not found"
+// @Router /user/sessions/{id} [delete]
+func (uc *UserController) RevokeSession(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	if err := uc.userService.RevokeSession(userId, c.Param("id")); err != nil {
+		return c.JSON(http.StatusNotFound, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.SuccessResponse{Message: "session revoked"})
+}
+
 // RenewAccessToken renews a user's access token using a refresh token.
 // @Summary Renew access token
-// @Description Renews a user's access token using a refresh token.
+// @Description Exchanges a refresh token for a new access and refresh token. A refresh token can be used only once.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param request body model.RenewAccessTokenRequest true "Access token renewal request"
 // @Success 200 {object} model.TokenResponse "Access token renewed successfully"
 // @Failure 400 {object} model.ErrorResponse "Bad request"
-// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 401 {object} model.ErrorResponse "Refresh token invalid, revoked or reused"
 // @Router /user/renew [put]
 func (uc *UserController) RenewAccessToken(c echo.Context) error {
 	var request model.RenewAccessTokenRequest
@@ -208,12 +444,12 @@ func (uc *UserController) RenewAccessToken(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
 	}
 
-	accessToken, err := uc.userService.RenewAccessToken(request.RefreshToken)
+	accessToken, refreshToken, err := uc.userService.RenewAccessToken(request.RefreshToken, sessionMeta(c, ""))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+		return c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, model.TokenResponse{AccessToken: accessToken})
+	return c.JSON(http.StatusOK, model.TokenResponse{AccessToken: accessToken, RefreshToken: refreshToken})
 }
 
 // GoogleCallback handles the callback from Google OAuth2 login.
@@ -237,13 +473,40 @@ func (uc *UserController) GoogleCallback(c echo.Context) error {
 	return c.Redirect(http.StatusFound, fmt.Sprintf("%s/?oauth2token="+oAuth2Token, uc.config.Website.WebURL))
 }
 
+// FacebookAuthURL returns the page where a member connects the Facebook pages of a workspace.
+// @Summary Facebook OAuth2 url
+// @Description Returns the Facebook login url, its state ties the callback to the member and the workspace.
+// @Tags Auth
+// @Produce json
+// @Param id query string true "Workspace ID"
+// @Success 200 {object} model.URLResponse "Facebook login url"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /oauth2/facebook/url [get]
+func (uc *UserController) FacebookAuthURL(c echo.Context) error {
+	userId := c.Request().Context().Value("userId").(primitive.ObjectID)
+	url, err := uc.userService.FacebookAuthURL(userId, c.QueryParam("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, model.URLResponse{URL: url})
+}
+
+// FacebookCallback handles the callback from Facebook OAuth2 and connects the pages of the workspace in the state.
+// @Summary Facebook OAuth2 callback
+// @Tags Auth
+// @Param code query string true "Authorization code from Facebook"
+// @Param state query string true "State issued by /oauth2/facebook/url"
+// @Success 302 "Redirect to the integrations page"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /oauth2/facebook/callback [get]
 func (uc *UserController) FacebookCallback(c echo.Context) error {
-	code, workspaceId := c.QueryParam("code"), c.QueryParam("id")
-	if err := uc.userService.FacebookAuthCallback(code, workspaceId); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	code, state := c.QueryParam("code"), c.QueryParam("state")
+	if err := uc.userService.FacebookAuthCallback(code, state); err != nil {
+		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 	}
 
-	return c.Redirect(http.StatusFound, fmt.Sprintf(uc.config.Website.WebURL+"/integrations"))
+	return c.Redirect(http.StatusFound, uc.config.Website.WebURL+"/integrations")
 }
 
 // GetProfile returns the user profile.
@@ -267,6 +530,8 @@ func (uc *UserController) GetProfile(c echo.Context) error {
 		Logo:      logo,
 		Status:    string(user.Status),
 		Role:      string(user.Role),
+		Language:  string(mailer.ParseLocale(user.Language)),
+		TwoFactor: user.TwoFactor.IsEnabled,
 		CreatedAt: user.CreatedAt,
 	})
 }
@@ -287,7 +552,7 @@ func (uc *UserController) UpdateProfile(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
 	}
 
-	if err := uc.userService.UpdateUserProfile(userId, request.Logo, request.FullName); err != nil {
+	if err := uc.userService.UpdateUserProfile(userId, request.Logo, request.FullName, request.Language); err != nil {
 		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
 	}
 
@@ -304,3 +569,26 @@ func (uc *UserController) UpdateMemberStatus(c echo.Context) error {
 
 	return c.JSON(http.StatusCreated, model.SuccessResponse{Message: "status updated"})
 }
+
+func sessionMeta(c echo.Context, deviceName string) entity.SessionMeta {
+	return entity.SessionMeta{DeviceName: deviceName, IP: c.RealIP(), UserAgent: c.Request().UserAgent()}
+}
+
+// preferredLanguage falls back to the browser language when the request does not name one
+func preferredLanguage(c echo.Context, language string) string {
+	if language != "" {
+		return language
+	}
+	return c.Request().Header.Get("Accept-Language")
+}
+
+// errorResponse answers with the given status, or with 429 and Retry-After when the caller is throttled
+func errorResponse(c echo.Context, status int, err error) error {
+	var throttled *entity.ThrottledError
+	if errors.As(err, &throttled) {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(throttled.RetrySeconds()))
+		return c.JSON(http.StatusTooManyRequests, model.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(status, model.ErrorResponse{Error: err.Error()})
+}
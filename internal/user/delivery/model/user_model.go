@@ -13,6 +13,12 @@ type UserRequest struct {
 	Logo        []byte `json:"logo"`
 	Name        string `json:"name"`
 	Password    string `json:"password"`
+	Language    string `json:"language"`
+	DeviceName  string `json:"device_name"`
+}
+
+type ResendConfirmationRequest struct {
+	Email string `json:"email"`
 }
 
 type ForgotPasswordRequest struct {
@@ -30,11 +36,23 @@ type RenewAccessTokenRequest struct {
 
 type OAuth2TokenRequest struct {
 	OAuth2Token string `json:"oAuth2Token"`
+	DeviceName  string `json:"device_name"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	DeviceName     string `json:"device_name"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
 }
 
 type UserUpdateProfileRequest struct {
 	FullName string `json:"name"`
 	Logo     []byte `json:"logo"`
+	Language string `json:"language"`
 }
 
 // Responses
@@ -48,8 +66,19 @@ type SuccessResponse struct {
 }
 
 type TokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token,omitempty"` // Optional field for some responses
+	AccessToken    string `json:"access_token,omitempty"`
+	RefreshToken   string `json:"refresh_token,omitempty"`   // Optional field for some responses
+	ChallengeToken string `json:"challenge_token,omitempty"` // Set instead of the tokens when a second factor is needed
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCode     []byte `json:"qr_code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
 }
 
 type URLResponse struct {
@@ -62,5 +91,17 @@ type UserProfileResponse struct {
 	Logo      []byte    `json:"logo"`
 	Status    string    `json:"status"`
 	Role      string    `json:"role"`
+	Language  string    `json:"language"`
+	TwoFactor bool      `json:"two_factor_enabled"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+type SessionResponse struct {
+	Id         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	IsCurrent  bool      `json:"is_current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
@@ -3,9 +3,10 @@ package authDelivery
 import (
 	"github.com/Point-AI/backend/config"
 	"github.com/Point-AI/backend/internal/user/delivery/controller"
-	"github.com/Point-AI/backend/internal/user/infrastructure/client"
 	"github.com/Point-AI/backend/internal/user/infrastructure/repository"
 	"github.com/Point-AI/backend/internal/user/service"
+	infrastructureInterface "github.com/Point-AI/backend/internal/user/service/interface"
+	"github.com/Point-AI/backend/mailer"
 	"github.com/Point-AI/backend/middleware"
 	"github.com/labstack/echo/v4"
 	"github.com/minio/minio-go/v7"
@@ -13,29 +14,46 @@ import (
 	"sync"
 )
 
-func RegisterAuthRoutes(e *echo.Echo, cfg *config.Config, db *mongo.Database, str *minio.Client, repoMu *sync.RWMutex, storageMu *sync.RWMutex) {
+func RegisterAuthRoutes(e *echo.Echo, cfg *config.Config, db *mongo.Database, str *minio.Client, repoMu *sync.RWMutex, storageMu *sync.RWMutex, denylist middleware.TokenDenylist) {
 	ur := repository.NewUserRepositoryImpl(db, cfg, repoMu)
-	ec := client.NewEmailClientImpl(cfg.Email.SMTPUsername, cfg.Email.SMTPPassword, cfg.Email.SMTPHost, cfg.Email.SMTPPort)
+	ec := mailer.NewOutbox(db, cfg)
 	//sc := client.NewStorageClientImpl(str, storageMu)
-	es := service.NewEmailServiceImpl(ec)
+	es := service.NewEmailServiceImpl(ec, cfg)
 	fsi := service.NewFileServiceImpl("../../static")
-	us := service.NewUserServiceImpl(ur, fsi, es, cfg)
+	var as infrastructureInterface.AttemptStore
+	if cfg.Throttle.Store == "mongo" {
+		as = repository.NewMongoAttemptStoreImpl(db, cfg)
+	} else {
+		as = repository.NewMemoryAttemptStoreImpl()
+	}
+	ts := service.NewThrottleServiceImpl(as, cfg)
+	us := service.NewUserServiceImpl(ur, fsi, es, ts, cfg)
 	uc := controller.NewUserController(us, cfg)
 
 	userGroup := e.Group("/user")
 	userGroup.POST("/signup", uc.RegisterUser)
+	userGroup.POST("/verify/resend", uc.ResendConfirmation)
 	userGroup.POST("/verify/:token", uc.ConfirmUser)
 	userGroup.POST("/signin", uc.Login)
-	userGroup.POST("/logout", uc.Logout, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
+	userGroup.POST("/signin/2fa", uc.VerifyTwoFactorLogin)
+	userGroup.POST("/logout", uc.Logout, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	userGroup.POST("/logout/all", uc.SignOutAll, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	userGroup.POST("/2fa/enroll", uc.EnrollTwoFactor, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	userGroup.POST("/2fa/enable", uc.EnableTwoFactor, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	userGroup.POST("/2fa/disable", uc.DisableTwoFactor, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	userGroup.POST("/2fa/recovery-codes", uc.RegenerateRecoveryCodes, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	userGroup.GET("/sessions", uc.GetSessions, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	userGroup.DELETE("/sessions/:id", uc.RevokeSession, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
 	userGroup.POST("/recover", uc.ForgotPassword)
 	userGroup.POST("/reset", uc.ResetPassword)
 	userGroup.PUT("/renew", uc.RenewAccessToken)
-	userGroup.PUT("/update/:status", uc.UpdateMemberStatus, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	userGroup.GET("/profile", uc.GetProfile, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
-	userGroup.PUT("/profile", uc.UpdateProfile, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey))
+	userGroup.PUT("/update/:status", uc.UpdateMemberStatus, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	userGroup.GET("/profile", uc.GetProfile, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
+	userGroup.PUT("/profile", uc.UpdateProfile, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
 
 	oAuth2Group := e.Group("/oauth2")
 	oAuth2Group.GET("/google/callback", uc.GoogleCallback)
 	oAuth2Group.GET("/google/tokens", uc.GoogleTokens)
+	oAuth2Group.GET("/facebook/url", uc.FacebookAuthURL, middleware.ValidateAccessTokenMiddleware(cfg.Auth.JWTSecretKey, denylist))
 	oAuth2Group.GET("/facebook/callback", uc.FacebookCallback)
 }
@@ -1,7 +1,9 @@
 package entity
 
 import (
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
 	"time"
 )
 
@@ -14,26 +16,162 @@ type User struct {
 	FullName     string             `bson:"name"`
 	Role         UserRole           `bson:"role"`
 	Status       UserStatus         `bson:"status"`
+	Language     string             `bson:"language"`
 	Tokens       Tokens             `bson:"tokens"`
+	TwoFactor    TwoFactor          `bson:"two_factor"`
 	CreatedAt    time.Time          `bson:"created_at"`
 }
 
+// TwoFactor holds the TOTP enrollment of a user. Recovery codes are stored hashed, and ChallengeId is the
+// jti of the only sign in challenge that may still be answered.
+type TwoFactor struct {
+	IsEnabled      bool      `bson:"is_enabled"`
+	Secret         string    `bson:"secret"`
+	PendingSecret  string    `bson:"pending_secret"`
+	RecoveryCodes  []string  `bson:"recovery_codes"`
+	LastUsedStep   int64     `bson:"last_used_step"`
+	ChallengeId    string    `bson:"challenge_id"`
+	FailedAttempts int       `bson:"failed_attempts"`
+	EnabledAt      time.Time `bson:"enabled_at"`
+}
+
 type Tokens struct {
-	ConfirmToken string `bson:"confirm_token"`
-	OAuth2Token  string `bson:"oauth2_token"`
-	ResetToken   string `bson:"reset_token"`
-	RefreshToken string `bson:"refresh_token"`
+	ConfirmToken          string    `bson:"confirm_token"`
+	ConfirmTokenExpiresAt time.Time `bson:"confirm_token_expires_at"`
+	ConfirmSentAt         time.Time `bson:"confirm_sent_at"`
+	OAuth2Token           string    `bson:"oauth2_token"`
+	ResetToken            string    `bson:"reset_token"`
+}
+
+// Session is one signed in device. Its SessionId is the family id shared by the session's tokens.
+type Session struct {
+	Id         primitive.ObjectID `bson:"_id,omitempty"`
+	SessionId  string             `bson:"session_id"`
+	UserId     primitive.ObjectID `bson:"user_id"`
+	DeviceName string             `bson:"device_name"`
+	IP         string             `bson:"ip"`
+	UserAgent  string             `bson:"user_agent"`
+	CreatedAt  time.Time          `bson:"created_at"`
+	LastSeenAt time.Time          `bson:"last_seen_at"`
+	ExpiresAt  time.Time          `bson:"expires_at"`
+}
+
+// AuthTokens is the result of signing in. When a second factor is needed only ChallengeToken is set.
+type AuthTokens struct {
+	AccessToken    string
+	RefreshToken   string
+	ChallengeToken string
+}
+
+// SessionMeta describes the device a request came from
+type SessionMeta struct {
+	DeviceName string
+	IP         string
+	UserAgent  string
+}
+
+// LoginAttempt counts recent failures for one IP or account. It is forgotten at ExpiresAt,
+// which is never before BlockedUntil.
+type LoginAttempt struct {
+	Key          string    `bson:"key"`
+	Failures     int       `bson:"failures"`
+	BlockedUntil time.Time `bson:"blocked_until"`
+	ExpiresAt    time.Time `bson:"expires_at"`
+}
+
+// ThrottledError is returned while an IP or account has to wait before trying again
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("too many attempts, try again in %d seconds", e.RetrySeconds())
+}
+
+func (e *ThrottledError) RetrySeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// RefreshToken records one issued refresh token. Tokens of one login share a FamilyId and each renewal
+// replaces the token with its successor, so presenting a replaced token again means it was stolen.
+type RefreshToken struct {
+	Id         primitive.ObjectID `bson:"_id,omitempty"`
+	TokenId    string             `bson:"token_id"`
+	FamilyId   string             `bson:"family_id"`
+	UserId     primitive.ObjectID `bson:"user_id"`
+	ReplacedBy string             `bson:"replaced_by"`
+	IsRevoked  bool               `bson:"is_revoked"`
+	ExpiresAt  time.Time          `bson:"expires_at"`
+	CreatedAt  time.Time          `bson:"created_at"`
+}
+
+// RevokedToken denies access tokens until they would have expired anyway. It matches a single jti, a whole
+// family, or with both left empty every access token of the user issued before RevokedAt.
+type RevokedToken struct {
+	Id        primitive.ObjectID `bson:"_id,omitempty"`
+	TokenId   string             `bson:"token_id"`
+	FamilyId  string             `bson:"family_id"`
+	UserId    primitive.ObjectID `bson:"user_id"`
+	RevokedAt time.Time          `bson:"revoked_at"`
+	ExpiresAt time.Time          `bson:"expires_at"`
 }
 
 type Workspace struct {
-	Id          primitive.ObjectID                   `bson:"_id,omitempty"`
-	WorkspaceId string                               `bson:"workspace_id"`
-	Name        string                               `bson:"name"`
-	Team        map[primitive.ObjectID]WorkspaceRole `bson:"team"`
-	PendingTeam map[string]WorkspaceRole             `bson:"pending"`
-	Folders     map[string][]string                  `bson:"folders"`
-	Tags        []string                             `bson:"tags"`
-	CreatedAt   time.Time                            `bson:"created_at"`
+	Id               primitive.ObjectID                   `bson:"_id,omitempty"`
+	WorkspaceId      string                               `bson:"workspace_id"`
+	Name             string                               `bson:"name"`
+	Team             map[primitive.ObjectID]WorkspaceRole `bson:"team"`
+	PendingTeam      map[string]WorkspaceRole             `bson:"pending"`
+	Folders          map[string][]string                  `bson:"folders"`
+	Roles            map[string][]string                  `bson:"roles"`
+	RequireTwoFactor bool                                 `bson:"require_two_factor"`
+	Tags             []string                             `bson:"tags"`
+	Integrations     Integrations                         `bson:"integrations"`
+	CreatedAt        time.Time                            `bson:"created_at"`
+}
+
+type Integrations struct {
+	Telegram    *TelegramIntegration     `bson:"telegram"`
+	TelegramBot []TelegramBotIntegration `bson:"telegram_bot"`
+	WhatsApp    []WhatsAppIntegration    `bson:"whatsapp"`
+	Meta        *MetaIntegration         `bson:"meta"`
+}
+
+type TelegramIntegration struct {
+	PhoneNumber string `bson:"phone_number"`
+	IsActive    bool   `bson:"is_active"`
+}
+
+type TelegramBotIntegration struct {
+	BotToken      string    `bson:"bot_token"`
+	BotName       string    `bson:"bot_name"`
+	WebhookSecret string    `bson:"webhook_secret"`
+	IsActive      bool      `bson:"is_active"`
+	CreatedAt     time.Time `bson:"created_at"`
+}
+
+type WhatsAppIntegration struct {
+	PhoneNumberId string    `bson:"phone_number_id"`
+	PhoneNumber   string    `bson:"phone_number"`
+	AccessToken   string    `bson:"access_token"`
+	IsActive      bool      `bson:"is_active"`
+	CreatedAt     time.Time `bson:"created_at"`
+}
+
+type MetaIntegration struct {
+	AccessToken  string                `bson:"access_token"`
+	RefreshToken string                `bson:"refresh_token"`
+	Pages        []MetaPageIntegration `bson:"pages"`
+	IsActive     bool                  `bson:"is_active"`
+	CreatedAt    time.Time             `bson:"created_at"`
+}
+
+type MetaPageIntegration struct {
+	PageId             string `bson:"page_id"`
+	PageName           string `bson:"page_name"`
+	AccessToken        string `bson:"access_token"`
+	InstagramAccountId string `bson:"instagram_account_id"`
+	IsActive           bool   `bson:"is_active"`
 }
 
 type Team struct {
@@ -44,6 +182,15 @@ type Team struct {
 	Members        map[primitive.ObjectID]bool `bson:"members"`
 	PendingMembers map[string]bool             `bson:"pending_members"`
 	IsFirstTeam    bool                        `bson:"is_first_team"`
+	Assignment     TeamAssignment              `bson:"assignment"`
+}
+
+type TeamAssignment struct {
+	Strategy       AssignmentStrategy                    `bson:"strategy"`
+	Sticky         bool                                  `bson:"sticky"`
+	MaxOpenTickets int                                   `bson:"max_open_tickets"`
+	Languages      map[primitive.ObjectID][]ChatLanguage `bson:"languages"`
+	LastAssigneeId primitive.ObjectID                    `bson:"last_assignee_id"`
 }
 
 type Chat struct {
@@ -78,14 +225,19 @@ type Note struct {
 }
 
 type Ticket struct {
-	Id         primitive.ObjectID `bson:"_id,omitempty"`
-	TicketId   string             `bson:"ticket_id"`
-	Subject    string             `bson:"subject"`
-	Notes      []Note             `bson:"notes"`
-	Messages   []Message          `bson:"messages"`
-	Status     TicketStatus       `bson:"status"`
-	CreatedAt  time.Time          `bson:"created_at"`
-	ResolvedAt time.Time          `bson:"resolved_at"`
+	Id                 primitive.ObjectID `bson:"_id,omitempty"`
+	TicketId           string             `bson:"ticket_id"`
+	Subject            string             `bson:"subject"`
+	Notes              []Note             `bson:"notes"`
+	MessageCount       int                `bson:"message_count"`
+	Status             TicketStatus       `bson:"status"`
+	SLAPolicyId        string             `bson:"sla_policy_id"`
+	SLAStatus          SLAStatus          `bson:"sla_status"`
+	FirstResponseDueAt time.Time          `bson:"first_response_due_at"`
+	FirstResponseAt    time.Time          `bson:"first_response_at"`
+	SLADueAt           time.Time          `bson:"sla_due_at"`
+	CreatedAt          time.Time          `bson:"created_at"`
+	ResolvedAt         time.Time          `bson:"resolved_at"`
 }
 
 type Message struct {
@@ -104,8 +256,10 @@ type WorkspaceRole string
 type UserRole string
 type ChatSource string
 type TicketStatus string
+type SLAStatus string
 type UserStatus string
 type ChatLanguage string
+type AssignmentStrategy string
 
 const (
 	TypeChatNote   MessageType = "chat_note"
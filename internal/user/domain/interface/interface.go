@@ -3,27 +3,46 @@ package _interface
 import (
 	"github.com/Point-AI/backend/internal/user/domain/entity"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type UserService interface {
 	GoogleAuthCallback(code string) (string, error)
-	GoogleTokens(token string) (string, string, error)
-	Login(email, password string) (string, string, error)
-	RegisterUser(email, password, workspaceId, emailHash, name string, logo []byte) error
-	ConfirmUser(token string) error
-	ForgotPassword(email string) error
-	ResetPassword(token, newPassword string) error
-	RenewAccessToken(refreshToken string) (string, error)
-	Logout(userId primitive.ObjectID) error
+	GoogleTokens(token string, meta entity.SessionMeta) (entity.AuthTokens, error)
+	Login(email, password string, meta entity.SessionMeta) (entity.AuthTokens, error)
+	VerifyTwoFactorLogin(challengeToken, code string, meta entity.SessionMeta) (entity.AuthTokens, error)
+	EnrollTwoFactor(userId primitive.ObjectID) (string, string, []byte, error)
+	EnableTwoFactor(userId primitive.ObjectID, familyId, code string) ([]string, error)
+	DisableTwoFactor(userId primitive.ObjectID, code string) error
+	RegenerateRecoveryCodes(userId primitive.ObjectID, code string) ([]string, error)
+	RegisterUser(email, password, workspaceId, emailHash, name, language string, logo []byte) error
+	SignUp(email, password, name, language string, logo []byte) error
+	ResendConfirmation(email, ip string) error
+	ConfirmUser(token string, meta entity.SessionMeta) (entity.AuthTokens, error)
+	ForgotPassword(email, ip string) error
+	ResetPassword(token, newPassword, ip string) error
+	RenewAccessToken(refreshToken string, meta entity.SessionMeta) (string, string, error)
+	Logout(userId primitive.ObjectID, familyId string) error
+	SignOutAll(userId primitive.ObjectID) error
+	GetSessions(userId primitive.ObjectID) ([]entity.Session, error)
+	RevokeSession(userId primitive.ObjectID, sessionId string) error
 	GetUserProfile(userId primitive.ObjectID) (*entity.User, []byte, error)
-	UpdateUserProfile(userId primitive.ObjectID, logo []byte, name string) error
-	FacebookAuthCallback(code, workspaceId string) error
+	UpdateUserProfile(userId primitive.ObjectID, logo []byte, name, language string) error
+	FacebookAuthURL(userId primitive.ObjectID, workspaceId string) (string, error)
+	FacebookAuthCallback(code, state string) error
 	UpdateUserStatus(userId primitive.ObjectID, status string) error
 }
 
 type EmailService interface {
-	SendConfirmationEmail(recipientEmail, confirmationLink string) error
-	SendResetPasswordEmail(recipientEmail, resetLink string) error
+	SendConfirmationEmail(recipientEmail, language, confirmationLink string) error
+	SendResetPasswordEmail(recipientEmail, language, resetLink string) error
+	SendAccountLockedEmail(recipientEmail, language string, lockedUntil time.Time) error
+}
+
+type ThrottleService interface {
+	RetryAfter(action, ip, account string) (time.Duration, error)
+	RegisterFailure(action, ip, account string) (bool, time.Time, error)
+	Reset(action, account string) error
 }
 
 type FileService interface {
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/internal/user/domain/entity"
+	"github.com/Point-AI/backend/internal/user/service/interface"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sync"
+	"time"
+)
+
+// memoryPruneInterval bounds how often the in-memory store drops expired attempts
+const memoryPruneInterval = time.Minute
+
+type MemoryAttemptStoreImpl struct {
+	attempts  map[string]*entity.LoginAttempt
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+// NewMemoryAttemptStoreImpl keeps attempts in this process, which is enough for a single instance
+func NewMemoryAttemptStoreImpl() infrastructureInterface.AttemptStore {
+	return &MemoryAttemptStoreImpl{
+		attempts: make(map[string]*entity.LoginAttempt),
+	}
+}
+
+func (ms *MemoryAttemptStoreImpl) FindAttempt(key string) (*entity.LoginAttempt, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	attempt, exists := ms.attempts[key]
+	if !exists || attempt.ExpiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+
+	found := *attempt
+	return &found, nil
+}
+
+func (ms *MemoryAttemptStoreImpl) IncrementFailures(key string, expiresAt time.Time) (int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(ms.lastPrune) > memoryPruneInterval {
+		for attemptKey, attempt := range ms.attempts {
+			if attempt.ExpiresAt.Before(now) {
+				delete(ms.attempts, attemptKey)
+			}
+		}
+		ms.lastPrune = now
+	}
+
+	attempt, exists := ms.attempts[key]
+	if !exists || attempt.ExpiresAt.Before(now) {
+		attempt = &entity.LoginAttempt{Key: key}
+		ms.attempts[key] = attempt
+	}
+
+	attempt.Failures++
+	attempt.ExpiresAt = maxTime(attempt.ExpiresAt, expiresAt)
+	return attempt.Failures, nil
+}
+
+func (ms *MemoryAttemptStoreImpl) BlockAttempt(key string, until time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	attempt, exists := ms.attempts[key]
+	if !exists {
+		return nil
+	}
+
+	attempt.BlockedUntil = until
+	attempt.ExpiresAt = maxTime(attempt.ExpiresAt, until)
+	return nil
+}
+
+func (ms *MemoryAttemptStoreImpl) DeleteAttempt(key string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.attempts, key)
+	return nil
+}
+
+type MongoAttemptStoreImpl struct {
+	database *mongo.Database
+	config   *config.Config
+}
+
+// NewMongoAttemptStoreImpl shares attempts between instances. Every change is a single atomic update,
+// so it needs no lock.
+func NewMongoAttemptStoreImpl(db *mongo.Database, config *config.Config) infrastructureInterface.AttemptStore {
+	return &MongoAttemptStoreImpl{
+		database: db,
+		config:   config,
+	}
+}
+
+func (ms *MongoAttemptStoreImpl) FindAttempt(key string) (*entity.LoginAttempt, error) {
+	var attempt entity.LoginAttempt
+	err := ms.database.Collection(ms.config.MongoDB.LoginAttemptCollection).FindOne(
+		context.Background(),
+		bson.M{"key": key, "expires_at": bson.M{"$gt": time.Now()}},
+	).Decode(&attempt)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// IncrementFailures starts counting again once the previous attempts expired, the TTL index may not have removed them yet
+func (ms *MongoAttemptStoreImpl) IncrementFailures(key string, expiresAt time.Time) (int, error) {
+	now := time.Now()
+	collection := ms.database.Collection(ms.config.MongoDB.LoginAttemptCollection)
+
+	if _, err := collection.DeleteOne(
+		context.Background(),
+		bson.M{"key": key, "expires_at": bson.M{"$lte": now}},
+	); err != nil {
+		return 0, err
+	}
+
+	var attempt entity.LoginAttempt
+	err := collection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"key": key},
+		bson.M{"$inc": bson.M{"failures": 1}, "$max": bson.M{"expires_at": expiresAt}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempt)
+	if err != nil {
+		return 0, err
+	}
+
+	return attempt.Failures, nil
+}
+
+func (ms *MongoAttemptStoreImpl) BlockAttempt(key string, until time.Time) error {
+	_, err := ms.database.Collection(ms.config.MongoDB.LoginAttemptCollection).UpdateOne(
+		context.Background(),
+		bson.M{"key": key},
+		bson.M{"$set": bson.M{"blocked_until": until}, "$max": bson.M{"expires_at": until}},
+	)
+	return err
+}
+
+func (ms *MongoAttemptStoreImpl) DeleteAttempt(key string) error {
+	_, err := ms.database.Collection(ms.config.MongoDB.LoginAttemptCollection).DeleteOne(
+		context.Background(),
+		bson.M{"key": key},
+	)
+	return err
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -10,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
 	"sync"
 	"time"
@@ -29,7 +30,7 @@ func NewUserRepositoryImpl(db *mongo.Database, config *config.Config, mu *sync.R
 	}
 }
 
-func (ur *UserRepositoryImpl) CreateUser(userRole entity.UserRole, email, passwordHash, confirmToken string) error {
+func (ur *UserRepositoryImpl) CreateUser(userRole entity.UserRole, email, passwordHash, name, language, confirmToken string, confirmTokenExpiresAt time.Time) error {
 	ur.mu.Lock()
 	defer ur.mu.Unlock()
 
@@ -37,10 +38,15 @@ func (ur *UserRepositoryImpl) CreateUser(userRole entity.UserRole, email, passwo
 		Email:        email,
 		PasswordHash: passwordHash,
 		IsConfirmed:  false,
+		FullName:     name,
+		Language:     language,
 		Tokens: entity.Tokens{
-			ConfirmToken: confirmToken,
+			ConfirmToken:          confirmToken,
+			ConfirmTokenExpiresAt: confirmTokenExpiresAt,
+			ConfirmSentAt:         time.Now(),
 		},
 		Role:      userRole,
+		Status:    entity.StatusAvailable,
 		CreatedAt: time.Now(),
 	}
 
@@ -52,7 +58,7 @@ func (ur *UserRepositoryImpl) CreateUser(userRole entity.UserRole, email, passwo
 	return err
 }
 
-func (ur *UserRepositoryImpl) CreateReadyUser(userRole entity.UserRole, email, passwordHash, name string) error {
+func (ur *UserRepositoryImpl) CreateReadyUser(userRole entity.UserRole, email, passwordHash, name, language string) error {
 	ur.mu.Lock()
 	defer ur.mu.Unlock()
 
@@ -63,6 +69,7 @@ func (ur *UserRepositoryImpl) CreateReadyUser(userRole entity.UserRole, email, p
 		Role:         userRole,
 		Status:       entity.StatusAvailable,
 		FullName:     name,
+		Language:     language,
 		CreatedAt:    time.Now(),
 	}
 
@@ -262,44 +269,67 @@ func (ur *UserRepositoryImpl) GetUserByConfirmToken(token string) (*entity.User,
 		bson.M{"tokens.confirm_token": token},
 	).Decode(&user)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (ur *UserRepositoryImpl) SetResetToken(user *entity.User, token string) error {
-	user.Tokens.ResetToken = token
-	return ur.UpdateUser(user)
+func (ur *UserRepositoryImpl) SetConfirmToken(id primitive.ObjectID, token string, expiresAt time.Time) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	_, err := ur.database.Collection(ur.config.MongoDB.UserCollection).UpdateOne(
+		context.Background(),
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"tokens.confirm_token":            token,
+			"tokens.confirm_token_expires_at": expiresAt,
+			"tokens.confirm_sent_at":          time.Now(),
+		}},
+	)
+	return err
 }
 
-func (ur *UserRepositoryImpl) SetRefreshToken(user *entity.User, token string) error {
-	user.Tokens.RefreshToken = token
-	user.Tokens.OAuth2Token = ""
+func (ur *UserRepositoryImpl) SetResetToken(user *entity.User, token string) error {
+	user.Tokens.ResetToken = token
 	return ur.UpdateUser(user)
 }
 
-func (ur *UserRepositoryImpl) ClearResetToken(id primitive.ObjectID, password string) error {
+func (ur *UserRepositoryImpl) ClearOAuth2Token(id primitive.ObjectID) error {
 	ur.mu.Lock()
 	defer ur.mu.Unlock()
 
 	_, err := ur.database.Collection(ur.config.MongoDB.UserCollection).UpdateOne(
 		context.Background(),
 		bson.M{"_id": id},
-		bson.M{"$set": bson.M{"password": password, "tokens.reset_token": ""}},
+		bson.M{"$set": bson.M{"tokens.oauth2_token": ""}},
 	)
 	return err
 }
 
-func (ur *UserRepositoryImpl) ClearRefreshToken(id primitive.ObjectID) error {
+// ClearResetToken sets the new password only while the token is the one last sent to the user and clears it
+// in the same update, so a reset link works once. It reports false when the token was not the stored one.
+func (ur *UserRepositoryImpl) ClearResetToken(id primitive.ObjectID, token, password string) (bool, error) {
 	ur.mu.Lock()
 	defer ur.mu.Unlock()
 
-	_, err := ur.database.Collection(ur.config.MongoDB.UserCollection).UpdateOne(
+	if token == "" {
+		return false, nil
+	}
+
+	res, err := ur.database.Collection(ur.config.MongoDB.UserCollection).UpdateOne(
 		context.Background(),
-		bson.M{"_id": id},
-		bson.M{"$set": bson.M{"tokens.refresh_token": ""}},
+		bson.M{"_id": id, "tokens.reset_token": token},
+		bson.M{"$set": bson.M{"password": password, "tokens.reset_token": ""}},
 	)
-	return err
+	if err != nil {
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
 }
 
 func (ur *UserRepositoryImpl) ConfirmUser(userId primitive.ObjectID) error {
@@ -309,7 +339,7 @@ func (ur *UserRepositoryImpl) ConfirmUser(userId primitive.ObjectID) error {
 	_, err := ur.database.Collection(ur.config.MongoDB.UserCollection).UpdateOne(
 		context.Background(),
 		bson.M{"_id": userId},
-		bson.M{"$set": bson.M{"is_confirmed": true, "tokens.confirm_token": ""}},
+		bson.M{"$set": bson.M{"is_confirmed": true, "tokens.confirm_token": "", "tokens.confirm_token_expires_at": time.Time{}}},
 	)
 	return err
 }
@@ -326,6 +356,232 @@ func (ur *UserRepositoryImpl) UpdateUser(user *entity.User) error {
 	return err
 }
 
+func (ur *UserRepositoryImpl) UpdateTwoFactor(userId primitive.ObjectID, twoFactor entity.TwoFactor) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	_, err := ur.database.Collection(ur.config.MongoDB.UserCollection).UpdateOne(
+		context.Background(),
+		bson.M{"_id": userId},
+		bson.M{"$set": bson.M{"two_factor": twoFactor}},
+	)
+	return err
+}
+
+func (ur *UserRepositoryImpl) FindWorkspacesRequiringTwoFactor(userId primitive.ObjectID) ([]entity.Workspace, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	cursor, err := ur.database.Collection(ur.config.MongoDB.WorkspaceCollection).Find(
+		context.Background(),
+		bson.M{"team." + userId.Hex(): bson.M{"$exists": true}, "require_two_factor": true},
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var workspaces []entity.Workspace
+	if err = cursor.All(context.Background(), &workspaces); err != nil {
+		return nil, err
+	}
+
+	return workspaces, nil
+}
+
+func (ur *UserRepositoryImpl) InsertSession(session *entity.Session) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	_, err := ur.database.Collection(ur.config.MongoDB.SessionCollection).InsertOne(
+		context.Background(),
+		session,
+	)
+	return err
+}
+
+func (ur *UserRepositoryImpl) FindSessionBySessionId(sessionId string) (*entity.Session, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	var session entity.Session
+	err := ur.database.Collection(ur.config.MongoDB.SessionCollection).FindOne(
+		context.Background(),
+		bson.M{"session_id": sessionId},
+	).Decode(&session)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (ur *UserRepositoryImpl) FindSessionsByUserId(userId primitive.ObjectID) ([]entity.Session, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	cursor, err := ur.database.Collection(ur.config.MongoDB.SessionCollection).Find(
+		context.Background(),
+		bson.M{"user_id": userId},
+		options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	sessions := make([]entity.Session, 0)
+	if err = cursor.All(context.Background(), &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// TouchSession records a renewal: the device was seen again and the session lives as long as its newest refresh token
+func (ur *UserRepositoryImpl) TouchSession(sessionId, ip string, lastSeenAt, expiresAt time.Time) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	_, err := ur.database.Collection(ur.config.MongoDB.SessionCollection).UpdateOne(
+		context.Background(),
+		bson.M{"session_id": sessionId},
+		bson.M{"$set": bson.M{"ip": ip, "last_seen_at": lastSeenAt, "expires_at": expiresAt}},
+	)
+	return err
+}
+
+func (ur *UserRepositoryImpl) DeleteSession(sessionId string) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	_, err := ur.database.Collection(ur.config.MongoDB.SessionCollection).DeleteOne(
+		context.Background(),
+		bson.M{"session_id": sessionId},
+	)
+	return err
+}
+
+func (ur *UserRepositoryImpl) DeleteSessionsByUserId(userId primitive.ObjectID) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	_, err := ur.database.Collection(ur.config.MongoDB.SessionCollection).DeleteMany(
+		context.Background(),
+		bson.M{"user_id": userId},
+	)
+	return err
+}
+
+func (ur *UserRepositoryImpl) InsertRefreshToken(token *entity.RefreshToken) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	_, err := ur.database.Collection(ur.config.MongoDB.RefreshTokenCollection).InsertOne(
+		context.Background(),
+		token,
+	)
+	return err
+}
+
+func (ur *UserRepositoryImpl) FindRefreshTokenByTokenId(tokenId string) (*entity.RefreshToken, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	var token entity.RefreshToken
+	err := ur.database.Collection(ur.config.MongoDB.RefreshTokenCollection).FindOne(
+		context.Background(),
+		bson.M{"token_id": tokenId},
+	).Decode(&token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken marks the token as replaced. It reports false when the token was already replaced
+// or revoked, so two renewals racing with the same token cannot both succeed.
+func (ur *UserRepositoryImpl) RotateRefreshToken(tokenId, replacedBy string) (bool, error) {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	res, err := ur.database.Collection(ur.config.MongoDB.RefreshTokenCollection).UpdateOne(
+		context.Background(),
+		bson.M{"token_id": tokenId, "replaced_by": "", "is_revoked": false},
+		bson.M{"$set": bson.M{"replaced_by": replacedBy}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+func (ur *UserRepositoryImpl) RevokeRefreshTokenFamily(familyId string) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	_, err := ur.database.Collection(ur.config.MongoDB.RefreshTokenCollection).UpdateMany(
+		context.Background(),
+		bson.M{"family_id": familyId},
+		bson.M{"$set": bson.M{"is_revoked": true}},
+	)
+	return err
+}
+
+func (ur *UserRepositoryImpl) RevokeRefreshTokensByUserId(userId primitive.ObjectID) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	_, err := ur.database.Collection(ur.config.MongoDB.RefreshTokenCollection).UpdateMany(
+		context.Background(),
+		bson.M{"user_id": userId},
+		bson.M{"$set": bson.M{"is_revoked": true}},
+	)
+	return err
+}
+
+func (ur *UserRepositoryImpl) InsertRevokedToken(token *entity.RevokedToken) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	_, err := ur.database.Collection(ur.config.MongoDB.RevokedTokenCollection).InsertOne(
+		context.Background(),
+		token,
+	)
+	return err
+}
+
+// IsTokenRevoked looks the access token up by its jti, its family and any sign out of all sessions made after it was issued
+func (ur *UserRepositoryImpl) IsTokenRevoked(claims utils.TokenClaims) (bool, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	conditions := bson.A{
+		bson.M{"token_id": claims.TokenId},
+		bson.M{"user_id": claims.UserId, "token_id": "", "family_id": "", "revoked_at": bson.M{"$gt": claims.IssuedAt}},
+	}
+	if claims.FamilyId != "" {
+		conditions = append(conditions, bson.M{"family_id": claims.FamilyId})
+	}
+
+	count, err := ur.database.Collection(ur.config.MongoDB.RevokedTokenCollection).CountDocuments(
+		context.Background(),
+		bson.M{"$or": conditions},
+		options.Count().SetLimit(1),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
 func (ur *UserRepositoryImpl) FindWorkspaceByWorkspaceId(workspaceId string) (*entity.Workspace, error) {
 	ur.mu.RLock()
 	defer ur.mu.RUnlock()
@@ -361,6 +617,26 @@ func (ur *UserRepositoryImpl) UpdateWorkspace(workspace *entity.Workspace) error
 	return nil
 }
 
+// UpdateMetaIntegration only sets the meta integration, this module does not know every field of a workspace
+func (ur *UserRepositoryImpl) UpdateMetaIntegration(id primitive.ObjectID, meta *entity.MetaIntegration) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	res, err := ur.database.Collection(ur.config.MongoDB.WorkspaceCollection).UpdateOne(
+		context.Background(),
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"integrations.meta": meta}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("workspace not found")
+	}
+
+	return nil
+}
+
 func (ur *UserRepositoryImpl) printPoopRepo(poop string) {
 
 	log.Println(poop)
@@ -1,29 +1,45 @@
 package service
 
 import (
-	"fmt"
+	"github.com/Point-AI/backend/config"
 	_interface "github.com/Point-AI/backend/internal/user/domain/interface"
 	infrastructureInterface "github.com/Point-AI/backend/internal/user/service/interface"
+	"github.com/Point-AI/backend/mailer"
+	"time"
 )
 
 type EmailServiceImpl struct {
 	emailClient infrastructureInterface.EmailClient
+	config      *config.Config
 }
 
-func NewEmailServiceImpl(emailClient infrastructureInterface.EmailClient) _interface.EmailService {
+func NewEmailServiceImpl(emailClient infrastructureInterface.EmailClient, cfg *config.Config) _interface.EmailService {
 	return &EmailServiceImpl{
 		emailClient: emailClient,
+		config:      cfg,
 	}
 }
 
-func (es *EmailServiceImpl) SendConfirmationEmail(recipientEmail, confirmationLink string) error {
-	subject := "Confirm your email"
-	body := fmt.Sprintf("Click the following link to confirm your email: %s", confirmationLink)
-	return es.emailClient.SendEmail(recipientEmail, subject, body)
+func (es *EmailServiceImpl) SendConfirmationEmail(recipientEmail, language, confirmationLink string) error {
+	return es.send(mailer.TemplateConfirmation, recipientEmail, language, mailer.Data{Link: confirmationLink})
 }
 
-func (es *EmailServiceImpl) SendResetPasswordEmail(recipientEmail, resetLink string) error {
-	subject := "Reset your password"
-	body := fmt.Sprintf("Click the following link to reset your password: %s", resetLink)
-	return es.emailClient.SendEmail(recipientEmail, subject, body)
+func (es *EmailServiceImpl) SendResetPasswordEmail(recipientEmail, language, resetLink string) error {
+	return es.send(mailer.TemplateResetPassword, recipientEmail, language, mailer.Data{Link: resetLink})
+}
+
+func (es *EmailServiceImpl) SendAccountLockedEmail(recipientEmail, language string, lockedUntil time.Time) error {
+	return es.send(mailer.TemplateAccountLocked, recipientEmail, language, mailer.Data{LockedUntil: lockedUntil})
+}
+
+// send brands account emails with the product name, they do not belong to a workspace
+func (es *EmailServiceImpl) send(template mailer.Template, recipientEmail, language string, data mailer.Data) error {
+	data.Brand = mailer.Brand{Name: es.config.Email.FromName}
+
+	message, err := mailer.Render(template, mailer.ParseLocale(language), recipientEmail, data)
+	if err != nil {
+		return err
+	}
+
+	return es.emailClient.Send(message)
 }
@@ -2,7 +2,10 @@ package infrastructureInterface
 
 import (
 	"github.com/Point-AI/backend/internal/user/domain/entity"
+	"github.com/Point-AI/backend/mailer"
+	"github.com/Point-AI/backend/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type StorageClient interface {
@@ -13,25 +16,49 @@ type StorageClient interface {
 }
 
 type UserRepository interface {
-	CreateUser(userRole entity.UserRole, email, passwordHash, confirmToken string) error
-	CreateReadyUser(userRole entity.UserRole, email, passwordHash, name string) error
+	CreateUser(userRole entity.UserRole, email, passwordHash, name, language, confirmToken string, confirmTokenExpiresAt time.Time) error
+	CreateReadyUser(userRole entity.UserRole, email, passwordHash, name, language string) error
 	CreateOauth2User(email, authSource string) (string, error)
 	GetUserByEmail(email string) (*entity.User, error)
 	GetUserById(id primitive.ObjectID) (*entity.User, error)
 	GetUserByOAuth2Token(token string) (*entity.User, error)
 	GetUserByConfirmToken(token string) (*entity.User, error)
+	SetConfirmToken(id primitive.ObjectID, token string, expiresAt time.Time) error
 	SetResetToken(user *entity.User, token string) error
-	SetRefreshToken(user *entity.User, token string) error
-	ClearResetToken(id primitive.ObjectID, password string) error
-	ClearRefreshToken(id primitive.ObjectID) error
+	ClearOAuth2Token(id primitive.ObjectID) error
+	ClearResetToken(id primitive.ObjectID, token, password string) (bool, error)
 	ConfirmUser(userId primitive.ObjectID) error
 	UpdateUser(user *entity.User) error
 	UpdateAllPendingWorkspaceTeamInvites(userId primitive.ObjectID, email string) error
 	UpdateAllPendingWorkspaceInvites(userId primitive.ObjectID, email string) error
 	FindWorkspaceByWorkspaceId(workspaceId string) (*entity.Workspace, error)
 	UpdateWorkspace(workspace *entity.Workspace) error
+	UpdateMetaIntegration(id primitive.ObjectID, meta *entity.MetaIntegration) error
+	UpdateTwoFactor(userId primitive.ObjectID, twoFactor entity.TwoFactor) error
+	FindWorkspacesRequiringTwoFactor(userId primitive.ObjectID) ([]entity.Workspace, error)
+	InsertSession(session *entity.Session) error
+	FindSessionBySessionId(sessionId string) (*entity.Session, error)
+	FindSessionsByUserId(userId primitive.ObjectID) ([]entity.Session, error)
+	TouchSession(sessionId, ip string, lastSeenAt, expiresAt time.Time) error
+	DeleteSession(sessionId string) error
+	DeleteSessionsByUserId(userId primitive.ObjectID) error
+	InsertRefreshToken(token *entity.RefreshToken) error
+	FindRefreshTokenByTokenId(tokenId string) (*entity.RefreshToken, error)
+	RotateRefreshToken(tokenId, replacedBy string) (bool, error)
+	RevokeRefreshTokenFamily(familyId string) error
+	RevokeRefreshTokensByUserId(userId primitive.ObjectID) error
+	InsertRevokedToken(token *entity.RevokedToken) error
+	IsTokenRevoked(claims utils.TokenClaims) (bool, error)
+}
+
+// AttemptStore keeps failure counters for throttling. Expired attempts count as missing.
+type AttemptStore interface {
+	FindAttempt(key string) (*entity.LoginAttempt, error)
+	IncrementFailures(key string, expiresAt time.Time) (int, error)
+	BlockAttempt(key string, until time.Time) error
+	DeleteAttempt(key string) error
 }
 
 type EmailClient interface {
-	SendEmail(to, subject, body string) error
+	Send(message *mailer.Message) error
 }
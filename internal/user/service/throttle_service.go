@@ -0,0 +1,120 @@
+package service
+
+import (
+	"github.com/Point-AI/backend/config"
+	_interface "github.com/Point-AI/backend/internal/user/domain/interface"
+	infrastructureInterface "github.com/Point-AI/backend/internal/user/service/interface"
+	"strings"
+	"time"
+)
+
+// Throttled actions. Each keeps its own counters, so failing one does not block the others.
+const (
+	ThrottleSignIn  = "signin"
+	ThrottleRecover = "recover"
+	ThrottleReset   = "reset"
+	ThrottleResend  = "resend"
+)
+
+// maxBackoffShift keeps the doubling delay from overflowing before it is capped
+const maxBackoffShift = 30
+
+type ThrottleServiceImpl struct {
+	attemptStore infrastructureInterface.AttemptStore
+	config       *config.Config
+}
+
+func NewThrottleServiceImpl(attemptStore infrastructureInterface.AttemptStore, cfg *config.Config) _interface.ThrottleService {
+	return &ThrottleServiceImpl{
+		attemptStore: attemptStore,
+		config:       cfg,
+	}
+}
+
+// RetryAfter returns how long the IP or the account still has to wait, zero when both may try now
+func (ts *ThrottleServiceImpl) RetryAfter(action, ip, account string) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range ts.keys(action, ip, account) {
+		attempt, err := ts.attemptStore.FindAttempt(key)
+		if err != nil {
+			return 0, err
+		}
+		if attempt != nil {
+			wait = max(wait, time.Until(attempt.BlockedUntil))
+		}
+	}
+
+	return wait, nil
+}
+
+// RegisterFailure counts a failed attempt. The account waits twice as long after every failure and is locked
+// once it reaches the limit, the IP only starts waiting after its higher limit. It reports whether this failure
+// locked the account, so the owner is told only once.
+func (ts *ThrottleServiceImpl) RegisterFailure(action, ip, account string) (bool, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ts.config.Throttle.Window)
+
+	if ip != "" {
+		key := ts.ipKey(action, ip)
+		failures, err := ts.attemptStore.IncrementFailures(key, expiresAt)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		if failures > ts.config.Throttle.IPLimit {
+			if err = ts.attemptStore.BlockAttempt(key, now.Add(ts.backoff(failures-ts.config.Throttle.IPLimit))); err != nil {
+				return false, time.Time{}, err
+			}
+		}
+	}
+
+	if account == "" {
+		return false, time.Time{}, nil
+	}
+
+	key := ts.accountKey(action, account)
+	failures, err := ts.attemptStore.IncrementFailures(key, expiresAt)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	if failures < ts.config.Throttle.AccountLimit {
+		return false, time.Time{}, ts.attemptStore.BlockAttempt(key, now.Add(ts.backoff(failures)))
+	}
+
+	lockedUntil := now.Add(ts.config.Throttle.LockoutDuration)
+	if err = ts.attemptStore.BlockAttempt(key, lockedUntil); err != nil {
+		return false, time.Time{}, err
+	}
+
+	return failures == ts.config.Throttle.AccountLimit, lockedUntil, nil
+}
+
+// Reset forgets the failures of the account after a success. The IP keeps its count, otherwise signing in to
+// one account of their own would let an attacker keep guessing others.
+func (ts *ThrottleServiceImpl) Reset(action, account string) error {
+	return ts.attemptStore.DeleteAttempt(ts.accountKey(action, account))
+}
+
+func (ts *ThrottleServiceImpl) backoff(failures int) time.Duration {
+	shift := min(failures-1, maxBackoffShift)
+	return min(ts.config.Throttle.BaseDelay<<shift, ts.config.Throttle.MaxDelay)
+}
+
+func (ts *ThrottleServiceImpl) keys(action, ip, account string) []string {
+	var keys []string
+	if ip != "" {
+		keys = append(keys, ts.ipKey(action, ip))
+	}
+	if account != "" {
+		keys = append(keys, ts.accountKey(action, account))
+	}
+	return keys
+}
+
+func (ts *ThrottleServiceImpl) ipKey(action, ip string) string {
+	return action + ":ip:" + ip
+}
+
+func (ts *ThrottleServiceImpl) accountKey(action, account string) string {
+	return action + ":account:" + strings.ToLower(strings.TrimSpace(account))
+}
@@ -7,24 +7,49 @@ import (
 	"github.com/Point-AI/backend/internal/user/domain/entity"
 	_interface "github.com/Point-AI/backend/internal/user/domain/interface"
 	"github.com/Point-AI/backend/internal/user/service/interface"
+	"github.com/Point-AI/backend/mailer"
 	"github.com/Point-AI/backend/utils"
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
 	"net/mail"
+	"slices"
+	"strings"
+	"time"
 )
 
+const (
+	maxDeviceNameLength  = 128
+	maxTwoFactorAttempts = 5
+	recoveryCodeCount    = 10
+)
+
+// errInvalidCredentials is the only answer to a failed sign in, so it does not tell whether the account exists
+var errInvalidCredentials = errors.New("invalid email or password")
+
 type UserServiceImpl struct {
-	userRepo     infrastructureInterface.UserRepository
-	emailService _interface.EmailService
-	fileService  _interface.FileService
-	config       *config.Config
+	userRepo        infrastructureInterface.UserRepository
+	emailService    _interface.EmailService
+	fileService     _interface.FileService
+	throttleService _interface.ThrottleService
+	config          *config.Config
+	// dummyPasswordHash is checked for unknown emails so they take as long to refuse as a wrong password
+	dummyPasswordHash string
 }
 
-func NewUserServiceImpl(userRepo infrastructureInterface.UserRepository, fileService _interface.FileService, emailService _interface.EmailService, cfg *config.Config) _interface.UserService {
+func NewUserServiceImpl(userRepo infrastructureInterface.UserRepository, fileService _interface.FileService, emailService _interface.EmailService, throttleService _interface.ThrottleService, cfg *config.Config) _interface.UserService {
+	dummyPasswordHash, err := utils.HashPassword(uuid.New().String())
+	if err != nil {
+		log.Println("failed to hash the dummy password:", err)
+	}
+
 	return &UserServiceImpl{
-		userRepo:     userRepo,
-		emailService: emailService,
-		fileService:  fileService,
-		config:       cfg,
+		userRepo:          userRepo,
+		emailService:      emailService,
+		fileService:       fileService,
+		throttleService:   throttleService,
+		config:            cfg,
+		dummyPasswordHash: dummyPasswordHash,
 	}
 }
 
@@ -44,81 +69,331 @@ func (us *UserServiceImpl) GoogleAuthCallback(code string) (string, error) {
 	return oAuth2Token, nil
 }
 
-func (us *UserServiceImpl) FacebookAuthCallback(code, workspaceId string) error {
-	//accessToken, refreshToken, err := utils.ExchangeFacebookCodeForToken(us.config.OAuth2.MetaClientId, us.config.OAuth2.MetaClientSecret, code, us.config.Website.BaseURL+us.config.OAuth2.MetaRedirectURL)
-	//if err != nil {
-	//	return err
-	//}
+// FacebookAuthURL starts connecting the pages of a workspace. The state binds the callback to this member and workspace.
+func (us *UserServiceImpl) FacebookAuthURL(userId primitive.ObjectID, workspaceId string) (string, error) {
+	workspace, err := us.userRepo.FindWorkspaceByWorkspaceId(workspaceId)
+	if err != nil {
+		return "", err
+	}
+
+	if err = us.authorize(workspace, userId, utils.PermissionIntegrationManage); err != nil {
+		return "", err
+	}
+
+	state, err := utils.GenerateOAuth2StateToken(us.config.Auth.JWTSecretKey, userId, workspaceId)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.FacebookAuthCodeURL(us.config.OAuth2.MetaClientId, us.config.Website.BaseURL+us.config.OAuth2.MetaRedirectURL, state), nil
+}
+
+func (us *UserServiceImpl) FacebookAuthCallback(code, state string) error {
+	userId, workspaceId, err := utils.ValidateOAuth2StateToken(us.config.Auth.JWTSecretKey, state)
+	if err != nil {
+		return errors.New("invalid state")
+	}
 
 	workspace, err := us.userRepo.FindWorkspaceByWorkspaceId(workspaceId)
 	if err != nil {
 		return err
 	}
 
-	//facebookIntegration := entity.MetaIntegration{
-	//	AccessToken:  accessToken,
-	//	RefreshToken: refreshToken,
-	//	IsActive:     true,
-	//}
-	//
-	//*workspace.Integrations.Meta = facebookIntegration
-	if err = us.userRepo.UpdateWorkspace(workspace); err != nil {
+	// the member may have lost the permission while they were on the provider's page
+	if err = us.authorize(workspace, userId, utils.PermissionIntegrationManage); err != nil {
 		return err
 	}
 
-	return nil
+	accessToken, refreshToken, err := utils.ExchangeFacebookCodeForToken(us.config.OAuth2.MetaClientId, us.config.OAuth2.MetaClientSecret, code, us.config.Website.BaseURL+us.config.OAuth2.MetaRedirectURL)
+	if err != nil {
+		return err
+	}
+
+	accessToken, err = utils.ExchangeFacebookLongLivedToken(us.config.Integrations.MetaBaseURL, us.config.OAuth2.MetaClientId, us.config.OAuth2.MetaClientSecret, accessToken)
+	if err != nil {
+		return err
+	}
+
+	pages, err := utils.GetFacebookPages(us.config.Integrations.MetaBaseURL, accessToken)
+	if err != nil {
+		return err
+	}
+
+	facebookIntegration := entity.MetaIntegration{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Pages:        make([]entity.MetaPageIntegration, 0, len(pages)),
+		IsActive:     true,
+		CreatedAt:    time.Now(),
+	}
+
+	subscribed := 0
+	for _, page := range pages {
+		pageIntegration := entity.MetaPageIntegration{
+			PageId:      page.Id,
+			PageName:    page.Name,
+			AccessToken: page.AccessToken,
+		}
+		if page.InstagramBusinessAccount != nil {
+			pageIntegration.InstagramAccountId = page.InstagramBusinessAccount.Id
+		}
+
+		// a page that could not be subscribed is kept inactive, so it does not receive messages but the others still do
+		if err = utils.SubscribeFacebookPage(us.config.Integrations.MetaBaseURL, page.Id, page.AccessToken); err != nil {
+			log.Printf("failed to subscribe to the webhook of page %s: %v", page.Id, err)
+		} else {
+			pageIntegration.IsActive = true
+			subscribed++
+		}
+		facebookIntegration.Pages = append(facebookIntegration.Pages, pageIntegration)
+	}
+	if len(pages) > 0 && subscribed == 0 {
+		return errors.New("failed to subscribe to the webhook of any page")
+	}
+
+	return us.userRepo.UpdateMetaIntegration(workspace.Id, &facebookIntegration)
 }
 
-func (us *UserServiceImpl) GoogleTokens(token string) (string, string, error) {
+func (us *UserServiceImpl) GoogleTokens(token string, meta entity.SessionMeta) (entity.AuthTokens, error) {
 	user, err := us.userRepo.GetUserByOAuth2Token(token)
 	if err != nil {
-		return "", "", err
+		return entity.AuthTokens{}, err
 	}
 	if user == nil {
-		return "", "", errors.New("user not found")
+		return entity.AuthTokens{}, errors.New("user not found")
 	}
 
-	accessToken, refreshToken, err := us.setRefreshToken(user)
-	if err != nil {
-		return "", "", err
+	if err = us.userRepo.ClearOAuth2Token(user.Id); err != nil {
+		return entity.AuthTokens{}, err
 	}
 
 	go us.userRepo.UpdateAllPendingWorkspaceInvites(user.Id, user.Email)
 	go us.userRepo.UpdateAllPendingWorkspaceTeamInvites(user.Id, user.Email)
 
-	return accessToken, refreshToken, nil
+	return us.signIn(user, meta)
 }
 
-func (us *UserServiceImpl) Login(email, password string) (string, string, error) {
+func (us *UserServiceImpl) Login(email, password string, meta entity.SessionMeta) (entity.AuthTokens, error) {
+	if err := us.checkThrottle(ThrottleSignIn, meta.IP, email); err != nil {
+		return entity.AuthTokens{}, err
+	}
+
 	user, err := us.userRepo.GetUserByEmail(email)
 	if err != nil {
-		return "", "", err
-	}
-	if user == nil {
-		return "", "", errors.New("user not found")
+		return entity.AuthTokens{}, err
 	}
-
-	if user.PasswordHash == "" {
-		return "", "", errors.New("user does not yet has a password")
+	if user == nil || user.PasswordHash == "" {
+		utils.VerifyPassword(us.dummyPasswordHash, password)
+		if err = us.registerSignInFailure(meta.IP, email, nil); err != nil {
+			return entity.AuthTokens{}, err
+		}
+		return entity.AuthTokens{}, errInvalidCredentials
 	}
 
 	if !utils.VerifyPassword(user.PasswordHash, password) {
-		return "", "", errors.New("invalid password")
+		if err = us.registerSignInFailure(meta.IP, email, user); err != nil {
+			return entity.AuthTokens{}, err
+		}
+		return entity.AuthTokens{}, errInvalidCredentials
 	}
 
 	if !user.IsConfirmed {
-		return "", "", errors.New("email not confirmed")
+		return entity.AuthTokens{}, errors.New("email not confirmed")
+	}
+
+	if err = us.throttleService.Reset(ThrottleSignIn, email); err != nil {
+		return entity.AuthTokens{}, err
 	}
 
-	accessToken, refreshToken, err := us.setRefreshToken(user)
+	return us.signIn(user, meta)
+}
+
+// VerifyTwoFactorLogin finishes a sign in with a TOTP or recovery code. A challenge can be answered
+// until it succeeds or fails maxTwoFactorAttempts times.
+func (us *UserServiceImpl) VerifyTwoFactorLogin(challengeToken, code string, meta entity.SessionMeta) (entity.AuthTokens, error) {
+	claims, err := utils.ParseJWTToken(utils.TwoFactorToken, challengeToken, us.config.Auth.JWTSecretKey)
 	if err != nil {
-		return "", "", err
+		return entity.AuthTokens{}, err
 	}
 
-	return accessToken, refreshToken, nil
+	user, err := us.userRepo.GetUserById(claims.UserId)
+	if err != nil {
+		return entity.AuthTokens{}, err
+	}
+	if !user.TwoFactor.IsEnabled || user.TwoFactor.ChallengeId == "" || user.TwoFactor.ChallengeId != claims.TokenId {
+		return entity.AuthTokens{}, errors.New("invalid challenge token")
+	}
+
+	if err = us.checkThrottle(ThrottleSignIn, meta.IP, user.Email); err != nil {
+		return entity.AuthTokens{}, err
+	}
+
+	if !us.checkSecondFactor(user, code) {
+		if err = us.registerSignInFailure(meta.IP, user.Email, user); err != nil {
+			return entity.AuthTokens{}, err
+		}
+
+		user.TwoFactor.FailedAttempts++
+		if user.TwoFactor.FailedAttempts >= maxTwoFactorAttempts {
+			user.TwoFactor.ChallengeId = ""
+			user.TwoFactor.FailedAttempts = 0
+		}
+		if err = us.userRepo.UpdateTwoFactor(user.Id, user.TwoFactor); err != nil {
+			return entity.AuthTokens{}, err
+		}
+		return entity.AuthTokens{}, errors.New("invalid two-factor code")
+	}
+
+	user.TwoFactor.ChallengeId = ""
+	user.TwoFactor.FailedAttempts = 0
+	if err = us.userRepo.UpdateTwoFactor(user.Id, user.TwoFactor); err != nil {
+		return entity.AuthTokens{}, err
+	}
+	if err = us.throttleService.Reset(ThrottleSignIn, user.Email); err != nil {
+		return entity.AuthTokens{}, err
+	}
+
+	accessToken, refreshToken, err := us.startSession(user.Id, meta)
+	if err != nil {
+		return entity.AuthTokens{}, err
+	}
+
+	return entity.AuthTokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
 }
 
-func (us *UserServiceImpl) RegisterUser(email, password, workspaceId, emailHash, name string, logo []byte) error {
+// EnrollTwoFactor starts enrollment with a new secret. It only takes effect once EnableTwoFactor confirms a code from it.
+func (us *UserServiceImpl) EnrollTwoFactor(userId primitive.ObjectID) (string, string, []byte, error) {
+	user, err := us.userRepo.GetUserById(userId)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if user.TwoFactor.IsEnabled {
+		return "", "", nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	user.TwoFactor.PendingSecret = secret
+	if err = us.userRepo.UpdateTwoFactor(user.Id, user.TwoFactor); err != nil {
+		return "", "", nil, err
+	}
+
+	uri := utils.TOTPURI(us.config.Auth.TOTPIssuer, user.Email, secret)
+	qrCode, err := utils.GenerateQRCode(uri)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return secret, uri, qrCode, nil
+}
+
+// EnableTwoFactor confirms the enrollment and returns the recovery codes, which are shown only this once.
+// Other sessions were signed in without a second factor, so they are ended.
+func (us *UserServiceImpl) EnableTwoFactor(userId primitive.ObjectID, familyId, code string) ([]string, error) {
+	user, err := us.userRepo.GetUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactor.IsEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TwoFactor.PendingSecret == "" {
+		return nil, errors.New("two-factor enrollment not started")
+	}
+
+	step, ok := utils.ValidateTOTPCode(user.TwoFactor.PendingSecret, code, time.Now(), 0)
+	if !ok {
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	recoveryCodes, hashes, err := us.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	user.TwoFactor = entity.TwoFactor{
+		IsEnabled:     true,
+		Secret:        user.TwoFactor.PendingSecret,
+		RecoveryCodes: hashes,
+		LastUsedStep:  step,
+		EnabledAt:     time.Now(),
+	}
+	if err = us.userRepo.UpdateTwoFactor(user.Id, user.TwoFactor); err != nil {
+		return nil, err
+	}
+
+	sessions, err := us.userRepo.FindSessionsByUserId(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		if session.SessionId == familyId {
+			continue
+		}
+		if err = us.revokeFamily(user.Id, session.SessionId); err != nil {
+			return nil, err
+		}
+	}
+
+	return recoveryCodes, nil
+}
+
+func (us *UserServiceImpl) DisableTwoFactor(userId primitive.ObjectID, code string) error {
+	user, err := us.userRepo.GetUserById(userId)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactor.IsEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	workspaces, err := us.userRepo.FindWorkspacesRequiringTwoFactor(user.Id)
+	if err != nil {
+		return err
+	}
+	if len(workspaces) > 0 {
+		return fmt.Errorf("two-factor authentication is required by workspace %s", workspaces[0].Name)
+	}
+
+	if !us.checkSecondFactor(user, code) {
+		return errors.New("invalid two-factor code")
+	}
+
+	return us.userRepo.UpdateTwoFactor(user.Id, entity.TwoFactor{})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, the old ones stop working
+func (us *UserServiceImpl) RegenerateRecoveryCodes(userId primitive.ObjectID, code string) ([]string, error) {
+	user, err := us.userRepo.GetUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactor.IsEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	if !us.checkSecondFactor(user, code) {
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	recoveryCodes, hashes, err := us.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	user.TwoFactor.RecoveryCodes = hashes
+	if err = us.userRepo.UpdateTwoFactor(user.Id, user.TwoFactor); err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// RegisterUser creates the account of an invited member, the invitation already proved the email
+func (us *UserServiceImpl) RegisterUser(email, password, workspaceId, emailHash, name, language string, logo []byte) error {
 	existingUser, err := us.userRepo.GetUserByEmail(email)
 	if err != nil {
 		return err
@@ -127,18 +402,10 @@ func (us *UserServiceImpl) RegisterUser(email, password, workspaceId, emailHash,
 		return errors.New("user already exists")
 	}
 
-	//confirmToken, err := utils.GenerateToken()
-	//if err != nil {
-	//	return err
-	//}
 	passwordHash, err := utils.HashPassword(password)
 	if err != nil {
 		return err
 	}
-	//confirmationLink := fmt.Sprintf("%s/confirm?token=%s", us.config.Website.WebURL, confirmToken)
-	//if err := us.emailService.SendConfirmationEmail(email, confirmationLink); err != nil {
-	//	return err
-	//}
 
 	emailToken, err := utils.ValidateInvitationJWTToken(us.config.Auth.JWTSecretKey, emailHash)
 	if err != nil {
@@ -165,40 +432,120 @@ func (us *UserServiceImpl) RegisterUser(email, password, workspaceId, emailHash,
 		go us.fileService.SaveFile("user."+email, logo)
 	}
 
-	return us.userRepo.CreateReadyUser(entity.UserRoleMember, email, passwordHash, name)
+	return us.userRepo.CreateReadyUser(entity.UserRoleMember, email, passwordHash, name, string(mailer.ParseLocale(language)))
+}
+
+// SignUp creates an unconfirmed account for someone without an invitation and emails them a confirmation link
+func (us *UserServiceImpl) SignUp(email, password, name, language string, logo []byte) error {
+	if _, err := mail.ParseAddress(email); err != nil {
+		return errors.New("invalid email")
+	}
+	if err := utils.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	existingUser, err := us.userRepo.GetUserByEmail(email)
+	if err != nil {
+		return err
+	}
+	if existingUser != nil {
+		return errors.New("user already exists")
+	}
+
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	confirmToken, err := utils.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	language = string(mailer.ParseLocale(language))
+	if err = us.userRepo.CreateUser(entity.UserRoleMember, email, passwordHash, name, language, confirmToken, time.Now().Add(us.config.Auth.ConfirmTokenTTL)); err != nil {
+		return err
+	}
+	if logo != nil {
+		go us.fileService.SaveFile("user."+email, logo)
+	}
+
+	return us.emailService.SendConfirmationEmail(email, language, us.confirmationLink(confirmToken))
+}
+
+// ResendConfirmation sends a new confirmation link, the previous one stops working. It answers the same
+// for unknown and already confirmed emails.
+func (us *UserServiceImpl) ResendConfirmation(email, ip string) error {
+	if err := us.checkThrottle(ThrottleResend, ip, ""); err != nil {
+		return err
+	}
+	if _, _, err := us.throttleService.RegisterFailure(ThrottleResend, ip, ""); err != nil {
+		return err
+	}
+
+	user, err := us.userRepo.GetUserByEmail(email)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.IsConfirmed {
+		return nil
+	}
+
+	if wait := us.config.Auth.ConfirmResendCooldown - time.Since(user.Tokens.ConfirmSentAt); wait > 0 {
+		return &entity.ThrottledError{RetryAfter: wait}
+	}
+
+	confirmToken, err := utils.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	if err = us.userRepo.SetConfirmToken(user.Id, confirmToken, time.Now().Add(us.config.Auth.ConfirmTokenTTL)); err != nil {
+		return err
+	}
 
-	//err = us.userRepo.CreateUser(entity.UserRoleMember, email, passwordHash, confirmToken)
-	//if err != nil {
-	//	return err
-	//}
+	return us.emailService.SendConfirmationEmail(user.Email, user.Language, us.confirmationLink(confirmToken))
 }
 
-func (us *UserServiceImpl) ConfirmUser(token string) error {
+// ConfirmUser confirms the email and signs the user in, so a new user can go on to create their first workspace
+func (us *UserServiceImpl) ConfirmUser(token string, meta entity.SessionMeta) (entity.AuthTokens, error) {
 	user, err := us.userRepo.GetUserByConfirmToken(token)
 	if err != nil {
-		return err
+		return entity.AuthTokens{}, err
 	}
 	if user == nil {
-		return errors.New("invalid confirmation token")
+		return entity.AuthTokens{}, errors.New("invalid confirmation token")
+	}
+	if !user.Tokens.ConfirmTokenExpiresAt.IsZero() && time.Now().After(user.Tokens.ConfirmTokenExpiresAt) {
+		return entity.AuthTokens{}, errors.New("confirmation link expired, request a new one")
 	}
 
 	if err := us.userRepo.ConfirmUser(user.Id); err != nil {
-		return err
+		return entity.AuthTokens{}, err
 	}
 
 	go us.userRepo.UpdateAllPendingWorkspaceInvites(user.Id, user.Email)
 	go us.userRepo.UpdateAllPendingWorkspaceTeamInvites(user.Id, user.Email)
 
-	return nil
+	return us.signIn(user, meta)
 }
 
-func (us *UserServiceImpl) ForgotPassword(email string) error {
+// ForgotPassword answers the same whether the account exists or not. Every request counts against the IP and the
+// email, so the endpoint cannot be used to flood a mailbox.
+func (us *UserServiceImpl) ForgotPassword(email, ip string) error {
+	if err := us.checkThrottle(ThrottleRecover, ip, email); err != nil {
+		return err
+	}
+	if _, _, err := us.throttleService.RegisterFailure(ThrottleRecover, ip, email); err != nil {
+		return err
+	}
+
 	user, err := us.userRepo.GetUserByEmail(email)
 	if err != nil {
 		return err
 	}
 	if user == nil {
-		return errors.New("user not found")
+		return nil
 	}
 
 	resetToken, err := utils.GenerateJWTToken("reset_token", user.Id, us.config.Auth.JWTSecretKey)
@@ -211,16 +558,29 @@ func (us *UserServiceImpl) ForgotPassword(email string) error {
 	}
 
 	resetLink := fmt.Sprintf("%s/reset-password?token=%s", us.config.Website.WebURL, resetToken)
-	if err := us.emailService.SendResetPasswordEmail(email, resetLink); err != nil {
-		return err
-	}
+	go func() {
+		if err := us.emailService.SendResetPasswordEmail(user.Email, user.Language, resetLink); err != nil {
+			log.Println("failed to send the reset password email:", err)
+		}
+	}()
 
 	return nil
 }
 
-func (us *UserServiceImpl) ResetPassword(token, newPassword string) error {
+func (us *UserServiceImpl) ResetPassword(token, newPassword, ip string) error {
+	if err := us.checkThrottle(ThrottleReset, ip, ""); err != nil {
+		return err
+	}
+
 	userId, err := utils.ValidateJWTToken("reset_token", token, us.config.Auth.JWTSecretKey)
 	if err != nil {
+		if _, _, err = us.throttleService.RegisterFailure(ThrottleReset, ip, ""); err != nil {
+			return err
+		}
+		return errors.New("invalid or expired reset token")
+	}
+
+	if err = utils.ValidatePassword(newPassword); err != nil {
 		return err
 	}
 
@@ -229,55 +589,269 @@ func (us *UserServiceImpl) ResetPassword(token, newPassword string) error {
 		return errors.New("error hashing the password")
 	}
 
-	if err := us.userRepo.ClearResetToken(userId, passwordHash); err != nil {
+	// only the link sent last works, and only once
+	reset, err := us.userRepo.ClearResetToken(userId, token, passwordHash)
+	if err != nil {
 		return err
 	}
+	if !reset {
+		if _, _, err = us.throttleService.RegisterFailure(ThrottleReset, ip, ""); err != nil {
+			return err
+		}
+		return errors.New("invalid or expired reset token")
+	}
 
-	return nil
+	return us.SignOutAll(userId)
 }
 
-func (us *UserServiceImpl) RenewAccessToken(refreshToken string) (string, error) {
-	userId, err := utils.ValidateJWTToken(utils.RefreshToken, refreshToken, us.config.Auth.JWTSecretKey)
+// RenewAccessToken exchanges a refresh token for a new pair. Every refresh token can be used once,
+// presenting one again ends its session.
+func (us *UserServiceImpl) RenewAccessToken(refreshToken string, meta entity.SessionMeta) (string, string, error) {
+	claims, err := utils.ParseJWTToken(utils.RefreshToken, refreshToken, us.config.Auth.JWTSecretKey)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	user, err := us.userRepo.GetUserById(userId)
+	storedToken, err := us.userRepo.FindRefreshTokenByTokenId(claims.TokenId)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	if user == nil || user.Tokens.RefreshToken != refreshToken {
-		return "", errors.New("invalid refresh token")
+	if storedToken == nil || storedToken.UserId != claims.UserId {
+		return "", "", errors.New("invalid refresh token")
+	}
+	if storedToken.IsRevoked {
+		return "", "", errors.New("refresh token revoked")
+	}
+	if storedToken.ReplacedBy != "" {
+		if err = us.revokeFamily(storedToken.UserId, storedToken.FamilyId); err != nil {
+			return "", "", err
+		}
+		return "", "", errors.New("refresh token reuse detected")
 	}
 
-	accessToken, err := utils.GenerateJWTToken(utils.AccessToken, user.Id, us.config.Auth.JWTSecretKey)
+	session, err := us.userRepo.FindSessionBySessionId(storedToken.FamilyId)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+	if session == nil || session.UserId != storedToken.UserId {
+		return "", "", errors.New("session not found")
+	}
+
+	if _, err = us.userRepo.GetUserById(session.UserId); err != nil {
+		return "", "", err
+	}
+
+	accessToken, newRefreshToken, err := us.issueTokens(session.UserId, session.SessionId, storedToken.TokenId)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	if err = us.userRepo.TouchSession(session.SessionId, meta.IP, now, now.Add(utils.RefreshTokenTTL)); err != nil {
+		return "", "", err
 	}
 
-	return accessToken, nil
+	return accessToken, newRefreshToken, nil
 }
 
-func (us *UserServiceImpl) Logout(userId primitive.ObjectID) error {
-	return us.userRepo.ClearRefreshToken(userId)
+func (us *UserServiceImpl) Logout(userId primitive.ObjectID, familyId string) error {
+	return us.revokeFamily(userId, familyId)
 }
 
-func (us *UserServiceImpl) setRefreshToken(user *entity.User) (string, string, error) {
-	var refreshToken string
-	var err error
-	if user.Tokens.RefreshToken != "" {
-		refreshToken = user.Tokens.RefreshToken
-	} else {
-		if refreshToken, err = utils.GenerateJWTToken("refresh_token", user.Id, us.config.Auth.JWTSecretKey); err != nil {
-			return "", "", err
+// SignOutAll ends every session of the user and denies the access tokens issued until now
+func (us *UserServiceImpl) SignOutAll(userId primitive.ObjectID) error {
+	if err := us.userRepo.RevokeRefreshTokensByUserId(userId); err != nil {
+		return err
+	}
+	if err := us.userRepo.DeleteSessionsByUserId(userId); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return us.userRepo.InsertRevokedToken(&entity.RevokedToken{
+		UserId:    userId,
+		RevokedAt: utils.RevocationTime(now),
+		ExpiresAt: now.Add(utils.AccessTokenTTL),
+	})
+}
+
+func (us *UserServiceImpl) GetSessions(userId primitive.ObjectID) ([]entity.Session, error) {
+	return us.userRepo.FindSessionsByUserId(userId)
+}
+
+func (us *UserServiceImpl) RevokeSession(userId primitive.ObjectID, sessionId string) error {
+	session, err := us.userRepo.FindSessionBySessionId(sessionId)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserId != userId {
+		return errors.New("session not found")
+	}
+
+	return us.revokeFamily(userId, sessionId)
+}
+
+func (us *UserServiceImpl) authorize(workspace *entity.Workspace, userId primitive.ObjectID, permissions ...utils.Permission) error {
+	return utils.NewAuthorizer(workspace.Roles).Authorize(string(workspace.Team[userId]), workspace.RequireTwoFactor, func() (bool, error) {
+		user, err := us.userRepo.GetUserById(userId)
+		if err != nil {
+			return false, err
 		}
+		return user.TwoFactor.IsEnabled, nil
+	}, permissions...)
+}
+
+func (us *UserServiceImpl) confirmationLink(token string) string {
+	return fmt.Sprintf("%s/confirm?token=%s", us.config.Website.WebURL, token)
+}
+
+// checkThrottle refuses the request while the IP or the account has to wait
+func (us *UserServiceImpl) checkThrottle(action, ip, account string) error {
+	wait, err := us.throttleService.RetryAfter(action, ip, account)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &entity.ThrottledError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// registerSignInFailure counts a failed password or second factor and tells the owner when it locked their account
+func (us *UserServiceImpl) registerSignInFailure(ip, email string, user *entity.User) error {
+	locked, lockedUntil, err := us.throttleService.RegisterFailure(ThrottleSignIn, ip, email)
+	if err != nil {
+		return err
+	}
+
+	if locked && user != nil {
+		go func() {
+			if err := us.emailService.SendAccountLockedEmail(user.Email, user.Language, lockedUntil); err != nil {
+				log.Println("failed to send the account locked email:", err)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// signIn issues tokens once the password or OAuth2 check passed, or a challenge when the user has two-factor authentication
+func (us *UserServiceImpl) signIn(user *entity.User, meta entity.SessionMeta) (entity.AuthTokens, error) {
+	if user.TwoFactor.IsEnabled {
+		challengeToken, claims, err := utils.IssueJWTToken(utils.TwoFactorToken, user.Id, "", us.config.Auth.JWTSecretKey)
+		if err != nil {
+			return entity.AuthTokens{}, err
+		}
+
+		user.TwoFactor.ChallengeId = claims.TokenId
+		user.TwoFactor.FailedAttempts = 0
+		if err = us.userRepo.UpdateTwoFactor(user.Id, user.TwoFactor); err != nil {
+			return entity.AuthTokens{}, err
+		}
+
+		return entity.AuthTokens{ChallengeToken: challengeToken}, nil
+	}
+
+	accessToken, refreshToken, err := us.startSession(user.Id, meta)
+	if err != nil {
+		return entity.AuthTokens{}, err
+	}
 
-		if err := us.userRepo.SetRefreshToken(user, refreshToken); err != nil {
+	return entity.AuthTokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// checkSecondFactor accepts a TOTP code or consumes a recovery code. It updates user.TwoFactor,
+// which the caller has to save.
+func (us *UserServiceImpl) checkSecondFactor(user *entity.User, code string) bool {
+	if step, ok := utils.ValidateTOTPCode(user.TwoFactor.Secret, code, time.Now(), user.TwoFactor.LastUsedStep); ok {
+		user.TwoFactor.LastUsedStep = step
+		return true
+	}
+
+	index := slices.Index(user.TwoFactor.RecoveryCodes, utils.HashRecoveryCode(code))
+	if index == -1 {
+		return false
+	}
+
+	user.TwoFactor.RecoveryCodes = slices.Delete(user.TwoFactor.RecoveryCodes, index, index+1)
+	return true
+}
+
+func (us *UserServiceImpl) generateRecoveryCodes() ([]string, []string, error) {
+	recoveryCodes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, 0, len(recoveryCodes))
+	for _, recoveryCode := range recoveryCodes {
+		hashes = append(hashes, utils.HashRecoveryCode(recoveryCode))
+	}
+
+	return recoveryCodes, hashes, nil
+}
+
+// startSession records the device and issues the first tokens of a new session
+func (us *UserServiceImpl) startSession(userId primitive.ObjectID, meta entity.SessionMeta) (string, string, error) {
+	deviceName := strings.TrimSpace(meta.DeviceName)
+	if deviceName == "" {
+		deviceName = meta.UserAgent
+	}
+	if runes := []rune(deviceName); len(runes) > maxDeviceNameLength {
+		deviceName = string(runes[:maxDeviceNameLength])
+	}
+
+	now := time.Now()
+	session := &entity.Session{
+		SessionId:  uuid.New().String(),
+		UserId:     userId,
+		DeviceName: deviceName,
+		IP:         meta.IP,
+		UserAgent:  meta.UserAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenTTL),
+	}
+	if err := us.userRepo.InsertSession(session); err != nil {
+		return "", "", err
+	}
+
+	return us.issueTokens(userId, session.SessionId, "")
+}
+
+// issueTokens signs an access and refresh token pair for the login family. When replacedTokenId is set the
+// old refresh token is rotated first, and losing that race is treated like reuse.
+func (us *UserServiceImpl) issueTokens(userId primitive.ObjectID, familyId, replacedTokenId string) (string, string, error) {
+	refreshToken, refreshClaims, err := utils.IssueJWTToken(utils.RefreshToken, userId, familyId, us.config.Auth.JWTSecretKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	if replacedTokenId != "" {
+		rotated, err := us.userRepo.RotateRefreshToken(replacedTokenId, refreshClaims.TokenId)
+		if err != nil {
 			return "", "", err
 		}
+		if !rotated {
+			if err = us.revokeFamily(userId, familyId); err != nil {
+				return "", "", err
+			}
+			return "", "", errors.New("refresh token reuse detected")
+		}
+	}
+
+	if err = us.userRepo.InsertRefreshToken(&entity.RefreshToken{
+		TokenId:   refreshClaims.TokenId,
+		FamilyId:  familyId,
+		UserId:    userId,
+		ExpiresAt: refreshClaims.ExpiresAt,
+		CreatedAt: refreshClaims.IssuedAt,
+	}); err != nil {
+		return "", "", err
 	}
 
-	accessToken, err := utils.GenerateJWTToken("access_token", user.Id, us.config.Auth.JWTSecretKey)
+	accessToken, _, err := utils.IssueJWTToken(utils.AccessToken, userId, familyId, us.config.Auth.JWTSecretKey)
 	if err != nil {
 		return "", "", err
 	}
@@ -285,6 +859,24 @@ func (us *UserServiceImpl) setRefreshToken(user *entity.User) (string, string, e
 	return accessToken, refreshToken, nil
 }
 
+// revokeFamily ends one session: its refresh tokens stop working and its access tokens are denied until they expire
+func (us *UserServiceImpl) revokeFamily(userId primitive.ObjectID, familyId string) error {
+	if err := us.userRepo.RevokeRefreshTokenFamily(familyId); err != nil {
+		return err
+	}
+	if err := us.userRepo.DeleteSession(familyId); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return us.userRepo.InsertRevokedToken(&entity.RevokedToken{
+		FamilyId:  familyId,
+		UserId:    userId,
+		RevokedAt: now,
+		ExpiresAt: now.Add(utils.AccessTokenTTL),
+	})
+}
+
 func (us *UserServiceImpl) GetUserProfile(userId primitive.ObjectID) (*entity.User, []byte, error) {
 	user, err := us.userRepo.GetUserById(userId)
 	if err != nil {
@@ -296,7 +888,7 @@ func (us *UserServiceImpl) GetUserProfile(userId primitive.ObjectID) (*entity.Us
 	return user, logo, nil
 }
 
-func (us *UserServiceImpl) UpdateUserProfile(userId primitive.ObjectID, logo []byte, name string) error {
+func (us *UserServiceImpl) UpdateUserProfile(userId primitive.ObjectID, logo []byte, name, language string) error {
 	user, err := us.userRepo.GetUserById(userId)
 	if err != nil {
 		return err
@@ -310,8 +902,13 @@ func (us *UserServiceImpl) UpdateUserProfile(userId primitive.ObjectID, logo []b
 
 		go us.fileService.UpdateFile(logo, "user."+user.Email)
 	}
-	if name != "" {
-		user.FullName = name
+	if name != "" || language != "" {
+		if name != "" {
+			user.FullName = name
+		}
+		if language != "" {
+			user.Language = string(mailer.ParseLocale(language))
+		}
 		if err := us.userRepo.UpdateUser(user); err != nil {
 			return err
 		}
@@ -0,0 +1,144 @@
+package mailer
+
+// Template names one transactional email
+type Template string
+
+const (
+	TemplateConfirmation        Template = "confirmation"
+	TemplateResetPassword       Template = "reset_password"
+	TemplateAccountLocked       Template = "account_locked"
+	TemplateInvitation          Template = "invitation"
+	TemplateWorkspaceInvitation Template = "workspace_invitation"
+)
+
+// content holds the localized strings of an email. They are text templates executed with Data,
+// an empty Action leaves the button out.
+type content struct {
+	Subject string
+	Heading string
+	Body    string
+	Action  string
+	Note    string
+}
+
+// linkHints is shown under the button for mail clients that do not render it
+var linkHints = map[Locale]string{
+	LocaleEnglish: "If the button does not work, open this link:",
+	LocaleRussian: "Если кнопка не работает, откройте ссылку:",
+	LocaleUzbek:   "Agar tugma ishlamasa, ushbu havolani oching:",
+}
+
+var catalog = map[Template]map[Locale]content{
+	TemplateConfirmation: {
+		LocaleEnglish: {
+			Subject: "Confirm your email",
+			Heading: "Confirm your email",
+			Body:    "Thanks for signing up for {{.Brand.Name}}. Confirm your email address to finish creating your account.",
+			Action:  "Confirm email",
+			Note:    "If you did not sign up, you can ignore this email.",
+		},
+		LocaleRussian: {
+			Subject: "Подтвердите адрес электронной почты",
+			Heading: "Подтвердите адрес электронной почты",
+			Body:    "Спасибо за регистрацию в {{.Brand.Name}}. Подтвердите адрес электронной почты, чтобы завершить создание аккаунта.",
+			Action:  "Подтвердить почту",
+			Note:    "Если вы не регистрировались, просто проигнорируйте это письмо.",
+		},
+		LocaleUzbek: {
+			Subject: "Elektron pochtangizni tasdiqlang",
+			Heading: "Elektron pochtangizni tasdiqlang",
+			Body:    "{{.Brand.Name}} da roʻyxatdan oʻtganingiz uchun rahmat. Hisob yaratishni yakunlash uchun elektron pochta manzilingizni tasdiqlang.",
+			Action:  "Pochtani tasdiqlash",
+			Note:    "Agar siz roʻyxatdan oʻtmagan boʻlsangiz, bu xatni eʼtiborsiz qoldiring.",
+		},
+	},
+	TemplateResetPassword: {
+		LocaleEnglish: {
+			Subject: "Reset your password",
+			Heading: "Reset your password",
+			Body:    "We received a request to reset the password of your {{.Brand.Name}} account.",
+			Action:  "Reset password",
+			Note:    "If you did not ask for this, ignore this email. Your password stays the same.",
+		},
+		LocaleRussian: {
+			Subject: "Сброс пароля",
+			Heading: "Сброс пароля",
+			Body:    "Мы получили запрос на сброс пароля вашего аккаунта {{.Brand.Name}}.",
+			Action:  "Сбросить пароль",
+			Note:    "Если вы не запрашивали сброс, проигнорируйте это письмо. Ваш пароль останется прежним.",
+		},
+		LocaleUzbek: {
+			Subject: "Parolni tiklash",
+			Heading: "Parolni tiklash",
+			Body:    "{{.Brand.Name}} hisobingiz parolini tiklash soʻrovini oldik.",
+			Action:  "Parolni tiklash",
+			Note:    "Agar bu soʻrovni siz yubormagan boʻlsangiz, xatni eʼtiborsiz qoldiring. Parolingiz oʻzgarmaydi.",
+		},
+	},
+	TemplateAccountLocked: {
+		LocaleEnglish: {
+			Subject: "Your account was locked",
+			Heading: "Your account was locked",
+			Body:    "We blocked sign in to your {{.Brand.Name}} account until {{datetime .LockedUntil}} after several failed attempts.",
+			Note:    "If this was not you, reset your password.",
+		},
+		LocaleRussian: {
+			Subject: "Ваш аккаунт заблокирован",
+			Heading: "Ваш аккаунт заблокирован",
+			Body:    "После нескольких неудачных попыток мы заблокировали вход в ваш аккаунт {{.Brand.Name}} до {{datetime .LockedUntil}}.",
+			Note:    "Если это были не вы, смените пароль.",
+		},
+		LocaleUzbek: {
+			Subject: "Hisobingiz bloklandi",
+			Heading: "Hisobingiz bloklandi",
+			Body:    "Bir necha muvaffaqiyatsiz urinishdan soʻng {{.Brand.Name}} hisobingizga kirish {{datetime .LockedUntil}} gacha bloklandi.",
+			Note:    "Agar bu siz boʻlmasangiz, parolingizni tiklang.",
+		},
+	},
+	TemplateInvitation: {
+		LocaleEnglish: {
+			Subject: "You were invited to {{.Brand.Name}}",
+			Heading: "Join {{.Brand.Name}}",
+			Body:    "You were invited to join {{.Brand.Name}}. Confirm your email to create your account.",
+			Action:  "Accept invitation",
+			Note:    "If you were not expecting this invitation, you can ignore this email.",
+		},
+		LocaleRussian: {
+			Subject: "Приглашение в {{.Brand.Name}}",
+			Heading: "Присоединяйтесь к {{.Brand.Name}}",
+			Body:    "Вас пригласили в {{.Brand.Name}}. Подтвердите адрес электронной почты, чтобы создать аккаунт.",
+			Action:  "Принять приглашение",
+			Note:    "Если вы не ждали этого приглашения, просто проигнорируйте письмо.",
+		},
+		LocaleUzbek: {
+			Subject: "{{.Brand.Name}} ga taklif",
+			Heading: "{{.Brand.Name}} ga qoʻshiling",
+			Body:    "Sizni {{.Brand.Name}} ga taklif qilishdi. Hisob yaratish uchun elektron pochtangizni tasdiqlang.",
+			Action:  "Taklifni qabul qilish",
+			Note:    "Agar bu taklifni kutmagan boʻlsangiz, xatni eʼtiborsiz qoldiring.",
+		},
+	},
+	TemplateWorkspaceInvitation: {
+		LocaleEnglish: {
+			Subject: "You were invited to the {{.Brand.Name}} workspace",
+			Heading: "Join {{.Brand.Name}}",
+			Body:    "{{if .Inviter}}{{.Inviter}} invited you{{else}}You were invited{{end}} to the {{.Brand.Name}} workspace. Create your account to start working with your team.",
+			Action:  "Accept invitation",
+			Note:    "If you were not expecting this invitation, you can ignore this email.",
+		},
+		LocaleRussian: {
+			Subject: "Приглашение в рабочее пространство {{.Brand.Name}}",
+			Heading: "Присоединяйтесь к {{.Brand.Name}}",
+			Body:    "{{if .Inviter}}{{.Inviter}} приглашает вас{{else}}Вас пригласили{{end}} в рабочее пространство {{.Brand.Name}}. Создайте аккаунт, чтобы начать работу с командой.",
+			Action:  "Принять приглашение",
+			Note:    "Если вы не ждали этого приглашения, просто проигнорируйте письмо.",
+		},
+		LocaleUzbek: {
+			Subject: "{{.Brand.Name}} ish maydoniga taklif",
+			Heading: "{{.Brand.Name}} ga qoʻshiling",
+			Body:    "{{if .Inviter}}{{.Inviter}} sizni {{.Brand.Name}} ish maydoniga taklif qildi{{else}}Sizni {{.Brand.Name}} ish maydoniga taklif qilishdi{{end}}. Jamoangiz bilan ishlashni boshlash uchun hisob yarating.",
+			Action:  "Taklifni qabul qilish",
+			Note:    "Agar bu taklifni kutmagan boʻlsangiz, xatni eʼtiborsiz qoldiring.",
+		},
+	},
+}
@@ -0,0 +1,208 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Point-AI/backend/config"
+	"github.com/Point-AI/backend/mailer/smtptest"
+)
+
+// testLogo is the signature of a PNG file, enough for the content type to be detected
+var testLogo = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+const testLink = "https://app.pointai.test/confirm/token-123"
+
+func newTestClient(t *testing.T) (*SMTPClient, *smtptest.Server) {
+	t.Helper()
+
+	server, err := smtptest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	return NewSMTPClient(config.Email{
+		SMTPHost:    server.Host(),
+		SMTPPort:    server.Port(),
+		FromAddress: "noreply@pointai.test",
+		FromName:    "PointAI",
+	}), server
+}
+
+// sentPart is one leaf of a captured multipart body, decoded from its transfer encoding
+type sentPart struct {
+	contentType string
+	contentId   string
+	body        []byte
+}
+
+// readParts walks the nested multipart body and returns its leaves in order
+func readParts(t *testing.T, contentType string, body io.Reader) []sentPart {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		content, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []sentPart{{contentType: mediaType, body: content}}
+	}
+
+	var parts []sentPart
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var content io.Reader = part
+		if part.Header.Get("Content-Transfer-Encoding") == "base64" {
+			content = base64.NewDecoder(base64.StdEncoding, part)
+		}
+		leaves := readParts(t, part.Header.Get("Content-Type"), content)
+		if id := part.Header.Get("Content-Id"); id != "" {
+			leaves[0].contentId = id
+		}
+		parts = append(parts, leaves...)
+	}
+}
+
+func TestRenderAndSendEveryTemplateInEveryLocale(t *testing.T) {
+	client, server := newTestClient(t)
+	decoder := new(mime.WordDecoder)
+
+	for name, translations := range catalog {
+		for _, locale := range supportedLocales {
+			t.Run(string(name)+"/"+string(locale), func(t *testing.T) {
+				server.Reset()
+				data := Data{
+					Brand:       Brand{Name: "Acme Support", Logo: testLogo},
+					Link:        testLink,
+					Inviter:     "Aziza",
+					LockedUntil: time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC),
+				}
+
+				message, err := Render(name, locale, "customer@example.com", data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err = client.Send(message); err != nil {
+					t.Fatal(err)
+				}
+
+				sent := server.Messages()
+				if len(sent) != 1 || len(sent[0].To) != 1 || sent[0].To[0] != "customer@example.com" || sent[0].From != "noreply@pointai.test" {
+					t.Fatalf("unexpected envelope: %+v", sent)
+				}
+				parsed, err := sent[0].Parse()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				subject, err := decoder.DecodeHeader(parsed.Header.Get("Subject"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				expectedSubject, _ := executeString(translations[locale].Subject, data)
+				if subject != expectedSubject || subject != message.Subject {
+					t.Fatalf("expected subject %q, got %q", expectedSubject, subject)
+				}
+
+				parts := readParts(t, parsed.Header.Get("Content-Type"), parsed.Body)
+				if len(parts) != 3 || parts[0].contentType != "text/plain" || parts[1].contentType != "text/html" || parts[2].contentType != "image/png" {
+					t.Fatalf("expected text, html and the inline logo, got %+v", parts)
+				}
+
+				text, html := string(parts[0].body), string(parts[1].body)
+				heading, _ := executeString(translations[locale].Heading, data)
+				body, _ := executeString(translations[locale].Body, data)
+				if !strings.Contains(text, heading) || !strings.Contains(text, body) {
+					t.Fatalf("text part is missing the %s strings:\n%s", locale, text)
+				}
+				if translations[locale].Action != "" && (!strings.Contains(text, testLink) || !strings.Contains(html, `href="`+testLink+`"`)) {
+					t.Fatal("expected the link in both parts")
+				}
+				if !strings.Contains(html, `<html lang="`+string(locale)+`">`) || !strings.Contains(html, `src="cid:`+logoContentId+`"`) {
+					t.Fatalf("html part is missing the locale or the logo:\n%s", html)
+				}
+				for _, content := range []string{subject, text, html} {
+					if strings.Contains(content, "{{") || strings.Contains(content, "<no value>") {
+						t.Fatalf("unfilled placeholder in %q", content)
+					}
+				}
+
+				if parts[2].contentId != "<"+logoContentId+">" || !bytes.Equal(parts[2].body, testLogo) {
+					t.Fatalf("logo not attached inline: %+v", parts[2])
+				}
+			})
+		}
+	}
+}
+
+func TestSendWithoutLogoHasNoRelatedPart(t *testing.T) {
+	client, server := newTestClient(t)
+
+	message, err := Render(TemplateResetPassword, LocaleEnglish, "customer@example.com", Data{Brand: Brand{Name: "PointAI"}, Link: testLink})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Send(message); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := server.Messages()[0].Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := readParts(t, parsed.Header.Get("Content-Type"), parsed.Body)
+	if len(parts) != 2 || parts[0].contentType != "text/plain" || parts[1].contentType != "text/html" {
+		t.Fatalf("expected only the text and html parts, got %+v", parts)
+	}
+	if !strings.Contains(string(parts[1].body), "PointAI") {
+		t.Fatal("expected the brand name in place of the logo")
+	}
+}
+
+func TestRenderFallsBackToEnglish(t *testing.T) {
+	message, err := Render(TemplateConfirmation, Locale("de"), "customer@example.com", Data{Brand: Brand{Name: "PointAI"}, Link: testLink})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Subject != catalog[TemplateConfirmation][LocaleEnglish].Subject {
+		t.Fatalf("expected the English subject, got %q", message.Subject)
+	}
+
+	if _, err = Render(Template("unknown"), LocaleEnglish, "customer@example.com", Data{}); err == nil {
+		t.Fatal("expected an unknown template to be rejected")
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	for value, expected := range map[string]Locale{
+		"ru":                    LocaleRussian,
+		"uz-UZ,ru;q=0.8":        LocaleUzbek,
+		"de-DE, RU-ru;q=0.9":    LocaleRussian,
+		"fr":                    LocaleEnglish,
+		"":                      LocaleEnglish,
+		"en-GB,en;q=0.9,uz;q=1": LocaleEnglish,
+	} {
+		if locale := ParseLocale(value); locale != expected {
+			t.Fatalf("%q: expected %s, got %s", value, expected, locale)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+)
+
+// Message is a rendered email, ready to be sent or stored until it is.
+// Template and WorkspaceId only label the email in the outbox.
+type Message struct {
+	Template    Template           `bson:"template"`
+	WorkspaceId primitive.ObjectID `bson:"workspace_id,omitempty"`
+	To          string             `bson:"to"`
+	Subject     string             `bson:"subject"`
+	Text        string             `bson:"text"`
+	HTML        string             `bson:"html"`
+	Inline      []Attachment       `bson:"attachments"`
+}
+
+// Attachment is a file the HTML part refers to with cid:ContentId
+type Attachment struct {
+	ContentId   string `bson:"content_id"`
+	ContentType string `bson:"content_type"`
+	Data        []byte `bson:"data"`
+}
+
+type Locale string
+
+const (
+	LocaleEnglish Locale = "en"
+	LocaleRussian Locale = "ru"
+	LocaleUzbek   Locale = "uz"
+)
+
+var supportedLocales = []Locale{LocaleEnglish, LocaleRussian, LocaleUzbek}
+
+// ParseLocale picks the first supported language of a stored value or an Accept-Language header.
+// Region and quality suffixes are ignored and anything unknown falls back to English.
+func ParseLocale(value string) Locale {
+	for _, part := range strings.Split(value, ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		language, _, _ := strings.Cut(tag, "-")
+		language = strings.ToLower(language)
+
+		for _, locale := range supportedLocales {
+			if string(locale) == language {
+				return locale
+			}
+		}
+	}
+
+	return LocaleEnglish
+}
@@ -0,0 +1,182 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"github.com/Point-AI/backend/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxQueued  OutboxStatus = "queued"
+	OutboxSending OutboxStatus = "sending"
+	OutboxSent    OutboxStatus = "sent"
+	OutboxFailed  OutboxStatus = "failed"
+)
+
+// OutboxEmail is a queued message and its delivery state. A message stays "sending" only while a worker
+// holds it, if the worker dies the lock runs out and another one picks the message up again.
+type OutboxEmail struct {
+	Id            primitive.ObjectID `bson:"_id,omitempty"`
+	Message       `bson:",inline"`
+	Status        OutboxStatus `bson:"status"`
+	Attempts      int          `bson:"attempts"`
+	LastError     string       `bson:"last_error"`
+	NextAttemptAt time.Time    `bson:"next_attempt_at"`
+	LockedUntil   time.Time    `bson:"locked_until"`
+	CreatedAt     time.Time    `bson:"created_at"`
+	UpdatedAt     time.Time    `bson:"updated_at"`
+	SentAt        time.Time    `bson:"sent_at,omitempty"`
+	ExpiresAt     time.Time    `bson:"expires_at,omitempty"`
+}
+
+// Sender delivers a message right away
+type Sender interface {
+	Send(message *Message) error
+}
+
+type Outbox struct {
+	database *mongo.Database
+	config   *config.Config
+}
+
+func NewOutbox(db *mongo.Database, cfg *config.Config) *Outbox {
+	return &Outbox{
+		database: db,
+		config:   cfg,
+	}
+}
+
+// Send only queues the message, OutboxWorker delivers it
+func (o *Outbox) Send(message *Message) error {
+	now := time.Now()
+	_, err := o.database.Collection(o.config.MongoDB.OutboxCollection).InsertOne(context.Background(), &OutboxEmail{
+		Message:       *message,
+		Status:        OutboxQueued,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+	return err
+}
+
+type OutboxWorker struct {
+	database *mongo.Database
+	config   *config.Config
+	sender   Sender
+}
+
+// NewOutboxWorker sends queued email through sender. Several workers can share the outbox,
+// each message is claimed by one of them at a time.
+func NewOutboxWorker(db *mongo.Database, cfg *config.Config, sender Sender) *OutboxWorker {
+	return &OutboxWorker{
+		database: db,
+		config:   cfg,
+		sender:   sender,
+	}
+}
+
+func (w *OutboxWorker) Run() {
+	ticker := time.NewTicker(w.config.Outbox.PollInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if err := w.ProcessDue(now); err != nil {
+			log.Println("outbox delivery failed:", err)
+		}
+	}
+}
+
+// ProcessDue sends every message that is due, one at a time, until none is left
+func (w *OutboxWorker) ProcessDue(now time.Time) error {
+	for {
+		email, err := w.claim(now)
+		if err != nil {
+			return err
+		}
+		if email == nil {
+			return nil
+		}
+
+		if err := w.deliver(email); err != nil {
+			return err
+		}
+	}
+}
+
+func (w *OutboxWorker) claim(now time.Time) (*OutboxEmail, error) {
+	var email OutboxEmail
+	err := w.database.Collection(w.config.MongoDB.OutboxCollection).FindOneAndUpdate(
+		context.Background(),
+		bson.M{"$or": []bson.M{
+			{"status": OutboxQueued, "next_attempt_at": bson.M{"$lte": now}},
+			{"status": OutboxSending, "locked_until": bson.M{"$lte": now}},
+		}},
+		bson.M{"$set": bson.M{
+			"status":       OutboxSending,
+			"locked_until": now.Add(w.config.Outbox.LockTimeout),
+			"updated_at":   now,
+		}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).SetReturnDocument(options.After),
+	).Decode(&email)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &email, nil
+}
+
+// deliver records the outcome of one attempt. Only a failure to record it is returned,
+// a failed send is kept on the message for the next attempt.
+func (w *OutboxWorker) deliver(email *OutboxEmail) error {
+	sendErr := w.sender.Send(&email.Message)
+	now := time.Now()
+	attempts := email.Attempts + 1
+
+	update := bson.M{
+		"attempts":     attempts,
+		"locked_until": time.Time{},
+		"updated_at":   now,
+	}
+	switch {
+	case sendErr == nil:
+		update["status"] = OutboxSent
+		update["last_error"] = ""
+		update["sent_at"] = now
+		update["expires_at"] = now.Add(w.config.Outbox.SentRetention)
+	case attempts >= w.config.Outbox.MaxAttempts:
+		update["status"] = OutboxFailed
+		update["last_error"] = sendErr.Error()
+		log.Printf("giving up on email %s to %s after %d attempts: %v", email.Id.Hex(), email.To, attempts, sendErr)
+	default:
+		update["status"] = OutboxQueued
+		update["last_error"] = sendErr.Error()
+		update["next_attempt_at"] = now.Add(w.retryDelay(attempts))
+	}
+
+	_, err := w.database.Collection(w.config.MongoDB.OutboxCollection).UpdateOne(
+		context.Background(),
+		bson.M{"_id": email.Id},
+		bson.M{"$set": update},
+	)
+	return err
+}
+
+// retryDelay doubles the base delay with every failed attempt, up to the maximum
+func (w *OutboxWorker) retryDelay(attempts int) time.Duration {
+	delay := w.config.Outbox.BaseDelay
+	for i := 1; i < attempts && delay < w.config.Outbox.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, w.config.Outbox.MaxDelay)
+}
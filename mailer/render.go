@@ -0,0 +1,138 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmlTemplate "html/template"
+	"net/http"
+	"strings"
+	textTemplate "text/template"
+	"time"
+)
+
+const logoContentId = "logo"
+
+//go:embed templates
+var templateFiles embed.FS
+
+var (
+	htmlLayout = htmlTemplate.Must(htmlTemplate.ParseFS(templateFiles, "templates/layout.html"))
+	textLayout = textTemplate.Must(textTemplate.ParseFS(templateFiles, "templates/layout.txt"))
+
+	stringFuncs = textTemplate.FuncMap{
+		"datetime": func(t time.Time) string {
+			return t.UTC().Format("02.01.2006 15:04 UTC")
+		},
+	}
+)
+
+// Brand is shown in the header of an email. Invitations carry the workspace, the rest the product itself.
+type Brand struct {
+	Name string
+	Logo []byte
+}
+
+// Data fills the placeholders of the localized strings
+type Data struct {
+	Brand       Brand
+	Link        string
+	Inviter     string
+	LockedUntil time.Time
+}
+
+type layoutData struct {
+	Locale    Locale
+	Subject   string
+	BrandName string
+	HasLogo   bool
+	LogoId    string
+	Heading   string
+	Body      string
+	Action    string
+	Link      string
+	LinkHint  string
+	Note      string
+}
+
+// Render builds the HTML and plain text parts of an email in the given locale,
+// a locale without translations falls back to English
+func Render(name Template, locale Locale, to string, data Data) (*Message, error) {
+	translations, ok := catalog[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template: %s", name)
+	}
+	strs, ok := translations[locale]
+	if !ok {
+		locale = LocaleEnglish
+		strs = translations[locale]
+	}
+
+	layout := layoutData{
+		Locale:    locale,
+		BrandName: data.Brand.Name,
+		HasLogo:   len(data.Brand.Logo) > 0,
+		LogoId:    logoContentId,
+		Link:      data.Link,
+		LinkHint:  linkHints[locale],
+	}
+
+	for _, field := range []struct {
+		value  string
+		target *string
+	}{
+		{strs.Subject, &layout.Subject},
+		{strs.Heading, &layout.Heading},
+		{strs.Body, &layout.Body},
+		{strs.Action, &layout.Action},
+		{strs.Note, &layout.Note},
+	} {
+		value, err := executeString(field.value, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s email: %w", name, err)
+		}
+		*field.target = value
+	}
+
+	var html, text bytes.Buffer
+	if err := htmlLayout.Execute(&html, layout); err != nil {
+		return nil, err
+	}
+	if err := textLayout.Execute(&text, layout); err != nil {
+		return nil, err
+	}
+
+	message := &Message{
+		Template: name,
+		To:       to,
+		Subject:  layout.Subject,
+		Text:     text.String(),
+		HTML:     html.String(),
+	}
+	if layout.HasLogo {
+		message.Inline = append(message.Inline, Attachment{
+			ContentId:   logoContentId,
+			ContentType: http.DetectContentType(data.Brand.Logo),
+			Data:        data.Brand.Logo,
+		})
+	}
+
+	return message, nil
+}
+
+func executeString(value string, data Data) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+
+	tmpl, err := textTemplate.New("").Funcs(stringFuncs).Parse(value)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, data); err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,181 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Point-AI/backend/config"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+type SMTPClient struct {
+	username string
+	password string
+	host     string
+	port     string
+	from     mail.Address
+}
+
+// NewSMTPClient sends from SMTP_FROM, or the SMTP user when it is not set.
+// Without a username it does not authenticate, which is what a local relay or smtptest expects.
+func NewSMTPClient(cfg config.Email) *SMTPClient {
+	from := cfg.FromAddress
+	if from == "" {
+		from = cfg.SMTPUsername
+	}
+
+	return &SMTPClient{
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+		host:     cfg.SMTPHost,
+		port:     cfg.SMTPPort,
+		from:     mail.Address{Name: cfg.FromName, Address: from},
+	}
+}
+
+func (c *SMTPClient) Send(message *Message) error {
+	body, err := c.build(message)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if c.username != "" {
+		auth = smtp.PlainAuth("", c.username, c.password, c.host)
+	}
+
+	if err := smtp.SendMail(c.host+":"+c.port, auth, c.from.Address, []string{message.To}, body); err != nil {
+		return errors.New("failed to send email: " + err.Error())
+	}
+
+	return nil
+}
+
+// build writes a multipart/alternative message. With inline images the HTML part becomes
+// multipart/related so the images travel with it.
+func (c *SMTPClient) build(message *Message) ([]byte, error) {
+	messageId, err := c.messageId()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	alternative := multipart.NewWriter(&buf)
+
+	header := []string{
+		"From: " + c.from.String(),
+		"To: " + (&mail.Address{Address: message.To}).String(),
+		"Subject: " + mime.QEncoding.Encode("utf-8", message.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: " + messageId,
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + alternative.Boundary(),
+	}
+	buf.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	if err := writeQuotedPrintable(alternative, "text/plain; charset=utf-8", message.Text); err != nil {
+		return nil, err
+	}
+
+	if len(message.Inline) == 0 {
+		if err := writeQuotedPrintable(alternative, "text/html; charset=utf-8", message.HTML); err != nil {
+			return nil, err
+		}
+		if err := alternative.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var relatedBody bytes.Buffer
+	related := multipart.NewWriter(&relatedBody)
+	if err := writeQuotedPrintable(related, "text/html; charset=utf-8", message.HTML); err != nil {
+		return nil, err
+	}
+	for _, attachment := range message.Inline {
+		part, err := related.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Id":                {"<" + attachment.ContentId + ">"},
+			"Content-Disposition":       {"inline"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, attachment.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := related.Close(); err != nil {
+		return nil, err
+	}
+
+	part, err := alternative.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/related; type=\"text/html\"; boundary=" + related.Boundary()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(relatedBody.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := alternative.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *SMTPClient) messageId() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if _, host, found := strings.Cut(c.from.Address, "@"); found {
+		domain = host
+	}
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(random), domain), nil
+}
+
+func writeQuotedPrintable(writer *multipart.Writer, contentType, content string) error {
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := quotedprintable.NewWriter(part)
+	if _, err := encoder.Write([]byte(content)); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// writeBase64 wraps the encoded data at 76 characters as RFC 2045 requires
+func writeBase64(part io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := part.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := part.Write([]byte(encoded + "\r\n"))
+	return err
+}
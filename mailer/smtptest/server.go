@@ -0,0 +1,188 @@
+// Package smtptest runs a local SMTP server that keeps every message it receives, so tests and local runs
+// can check outgoing mail without a real relay. It accepts any credentials and never delivers anything.
+package smtptest
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+)
+
+// Message is one mail captured from the DATA command
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// Parse reads the headers and body of the captured mail
+func (m Message) Parse() (*mail.Message, error) {
+	return mail.ReadMessage(bytes.NewReader(m.Data))
+}
+
+type Server struct {
+	listener net.Listener
+	messages []Message
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+}
+
+// NewServer listens on a random local port until Close is called
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.listener.Addr().String())
+	return host
+}
+
+func (s *Server) Port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+// Messages returns a copy of everything received so far
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = nil
+}
+
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(lines ...string) bool {
+		_, err := conn.Write([]byte(strings.Join(lines, "\r\n") + "\r\n"))
+		return err == nil
+	}
+
+	if !reply("220 smtptest ready") {
+		return
+	}
+
+	var current Message
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, argument, _ := strings.Cut(line, " ")
+
+		ok := true
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ok = reply("250-smtptest", "250-8BITMIME", "250 AUTH PLAIN LOGIN")
+		case "HELO":
+			ok = reply("250 smtptest")
+		case "AUTH":
+			ok = reply("235 authenticated")
+		case "MAIL":
+			current = Message{From: address(argument)}
+			ok = reply("250 ok")
+		case "RCPT":
+			current.To = append(current.To, address(argument))
+			ok = reply("250 ok")
+		case "DATA":
+			if !reply("354 end data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := readData(reader)
+			if err != nil {
+				return
+			}
+			current.Data = data
+
+			s.mu.Lock()
+			s.messages = append(s.messages, current)
+			s.mu.Unlock()
+
+			current = Message{}
+			ok = reply("250 queued")
+		case "RSET":
+			current = Message{}
+			ok = reply("250 ok")
+		case "NOOP":
+			ok = reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			ok = reply("502 command not implemented")
+		}
+
+		if !ok {
+			return
+		}
+	}
+}
+
+// readData reads up to the lone dot line and undoes the dot stuffing
+func readData(reader *bufio.Reader) ([]byte, error) {
+	var data bytes.Buffer
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return data.Bytes(), nil
+		}
+		data.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+// address strips the FROM:/TO: prefix and angle brackets from a MAIL or RCPT argument
+func address(argument string) string {
+	_, value, found := strings.Cut(argument, ":")
+	if !found {
+		value = argument
+	}
+	value, _, _ = strings.Cut(strings.TrimSpace(value), " ")
+	return strings.Trim(value, "<>")
+}
@@ -2,13 +2,19 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"github.com/Point-AI/backend/utils"
 	"github.com/labstack/echo/v4"
 	"net/http"
 	"strings"
 )
 
-func ValidateAccessTokenMiddleware(secretKey string) echo.MiddlewareFunc {
+// TokenDenylist reports whether an access token was revoked before it expired
+type TokenDenylist interface {
+	IsTokenRevoked(claims utils.TokenClaims) (bool, error)
+}
+
+func ValidateAccessTokenMiddleware(secretKey string, denylist TokenDenylist) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			authHeader := c.Request().Header.Get("Authorization")
@@ -23,12 +29,14 @@ func ValidateAccessTokenMiddleware(secretKey string) echo.MiddlewareFunc {
 
 			token := parts[1]
 
-			userId, err := utils.ValidateJWTToken(utils.AccessToken, token, secretKey)
+			claims, err := validateAccessToken(token, secretKey, denylist)
 			if err != nil {
 				return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
 			}
 
-			ctx := context.WithValue(c.Request().Context(), "userId", userId)
+			ctx := context.WithValue(c.Request().Context(), "userId", claims.UserId)
+			ctx = context.WithValue(ctx, "tokenId", claims.TokenId)
+			ctx = context.WithValue(ctx, "familyId", claims.FamilyId)
 			c.SetRequest(c.Request().WithContext(ctx))
 			return next(c)
 		}
@@ -59,7 +67,7 @@ func ValidateServerMiddleware(secretKey string) echo.MiddlewareFunc {
 	}
 }
 
-func ValidateAccessTokenForWebsocketMiddleware(secretKey string) echo.MiddlewareFunc {
+func ValidateAccessTokenForWebsocketMiddleware(secretKey string, denylist TokenDenylist) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			token := c.QueryParam("token")
@@ -67,13 +75,32 @@ func ValidateAccessTokenForWebsocketMiddleware(secretKey string) echo.Middleware
 				return c.JSON(http.StatusForbidden, map[string]string{"error": "Token query parameter required"})
 			}
 
-			userId, err := utils.ValidateJWTToken(utils.AccessToken, token, secretKey)
+			claims, err := validateAccessToken(token, secretKey, denylist)
 			if err != nil {
 				return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
 			}
 
-			c.Set("userId", userId)
+			c.Set("userId", claims.UserId)
+			c.Set("tokenId", claims.TokenId)
+			c.Set("familyId", claims.FamilyId)
 			return next(c)
 		}
 	}
 }
+
+func validateAccessToken(token, secretKey string, denylist TokenDenylist) (utils.TokenClaims, error) {
+	claims, err := utils.ParseJWTToken(utils.AccessToken, token, secretKey)
+	if err != nil {
+		return utils.TokenClaims{}, err
+	}
+
+	revoked, err := denylist.IsTokenRevoked(claims)
+	if err != nil {
+		return utils.TokenClaims{}, err
+	}
+	if revoked {
+		return utils.TokenClaims{}, errors.New("token revoked")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type cursor struct {
+	CreatedAt time.Time `json:"t"`
+	Id        string    `json:"id"`
+}
+
+// EncodeCursor builds an opaque pagination cursor from the position of the last returned item
+func EncodeCursor(createdAt time.Time, id string) string {
+	data, _ := json.Marshal(cursor{CreatedAt: createdAt, Id: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (time.Time, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return time.Time{}, "", errors.New("invalid cursor")
+	}
+
+	var c cursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return time.Time{}, "", errors.New("invalid cursor")
+	}
+
+	return c.CreatedAt, c.Id, nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"time"
 )
@@ -14,70 +15,145 @@ const (
 	AccessToken  TokenType = "access_token"
 	RefreshToken TokenType = "refresh_token"
 	ResetToken   TokenType = "reset_token"
+	// TwoFactorToken proves the password was checked and waits for the second factor
+	TwoFactorToken TokenType = "two_factor_token"
+	// OAuth2StateToken is sent to a provider as the state and ties its callback to the member who started the flow
+	OAuth2StateToken TokenType = "oauth2_state_token"
 )
 
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 90 * 24 * time.Hour
+	ResetTokenTTL   = 60 * time.Minute
+	TwoFactorTTL    = 5 * time.Minute
+	OAuth2StateTTL  = 10 * time.Minute
+)
+
+// TokenClaims are the claims this service puts in its tokens. FamilyId ties access and refresh tokens
+// to the login that started them, so the whole login can be revoked at once.
+type TokenClaims struct {
+	UserId    primitive.ObjectID
+	TokenId   string
+	FamilyId  string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// RevocationTime cuts the time of a sign out of all sessions to whole seconds like the iat claim. Only tokens issued
+// in an earlier second are denied, so a login in the same second right after the sign out keeps working.
+func RevocationTime(t time.Time) time.Time {
+	return t.Truncate(time.Second)
+}
+
 func GenerateJWTToken(tokenType TokenType, id primitive.ObjectID, secretKey string) (string, error) {
-	claims := jwt.MapClaims{
-		"id":   id,
-		"type": tokenType,
+	signedToken, _, err := IssueJWTToken(tokenType, id, "", secretKey)
+	return signedToken, err
+}
+
+// IssueJWTToken signs a token with a fresh jti and returns its claims next to it
+func IssueJWTToken(tokenType TokenType, id primitive.ObjectID, familyId, secretKey string) (string, TokenClaims, error) {
+	now := time.Now()
+	tokenClaims := TokenClaims{
+		UserId:   id,
+		TokenId:  uuid.New().String(),
+		FamilyId: familyId,
+		IssuedAt: now,
 	}
 
 	switch tokenType {
 	case AccessToken:
-		claims["exp"] = time.Now().Add(time.Hour * 24 * 365).Unix()
+		tokenClaims.ExpiresAt = now.Add(AccessTokenTTL)
 	case RefreshToken:
-		claims["exp"] = time.Now().Add(time.Hour * 24 * 90).Unix()
+		tokenClaims.ExpiresAt = now.Add(RefreshTokenTTL)
 	case ResetToken:
-		claims["exp"] = time.Now().Add(time.Minute * 60).Unix()
+		tokenClaims.ExpiresAt = now.Add(ResetTokenTTL)
+	case TwoFactorToken:
+		tokenClaims.ExpiresAt = now.Add(TwoFactorTTL)
 	default:
-		return "", errors.New("wrong token type")
+		return "", TokenClaims{}, errors.New("wrong token type")
+	}
+
+	claims := jwt.MapClaims{
+		"id":   id,
+		"type": tokenType,
+		"jti":  tokenClaims.TokenId,
+		"iat":  tokenClaims.IssuedAt.Unix(),
+		"exp":  tokenClaims.ExpiresAt.Unix(),
+	}
+	if familyId != "" {
+		claims["fid"] = familyId
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	signedToken, err := token.SignedString([]byte(secretKey))
 	if err != nil {
-		return "", err
+		return "", TokenClaims{}, err
 	}
-	return signedToken, nil
+	return signedToken, tokenClaims, nil
 }
 
 func ValidateJWTToken(expectedTokenType TokenType, signedToken, secretKey string) (primitive.ObjectID, error) {
+	claims, err := ParseJWTToken(expectedTokenType, signedToken, secretKey)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+	return claims.UserId, nil
+}
+
+// ParseJWTToken validates the token and returns its claims. Access and refresh tokens without a jti were issued
+// before tokens could be revoked and are rejected.
+func ParseJWTToken(expectedTokenType TokenType, signedToken, secretKey string) (TokenClaims, error) {
 	parsedToken, err := jwt.Parse(signedToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
 		return []byte(secretKey), nil
 	})
 	if err != nil {
-		return primitive.ObjectID{}, err
+		return TokenClaims{}, err
 	}
 
-	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok && parsedToken.Valid {
-		tokenType, typeExists := claims["type"].(string)
-		if !typeExists || TokenType(tokenType) != expectedTokenType {
-			return primitive.ObjectID{}, errors.New("invalid token type")
-		}
-		expFloat, exists := claims["exp"].(float64)
-		if !exists {
-			return primitive.ObjectID{}, errors.New("expiration claim missing or invalid")
-		}
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok || !parsedToken.Valid {
+		return TokenClaims{}, errors.New("invalid token")
+	}
 
-		expTime := time.Unix(int64(expFloat), 0)
-		if expTime.Before(time.Now()) {
-			return primitive.ObjectID{}, errors.New("token expired")
-		}
+	tokenType, typeExists := claims["type"].(string)
+	if !typeExists || TokenType(tokenType) != expectedTokenType {
+		return TokenClaims{}, errors.New("invalid token type")
+	}
+	expFloat, exists := claims["exp"].(float64)
+	if !exists {
+		return TokenClaims{}, errors.New("expiration claim missing or invalid")
+	}
 
-		idStr, idExists := claims["id"].(string)
-		if !idExists {
-			return primitive.ObjectID{}, errors.New("id field missing or invalid")
-		}
+	expTime := time.Unix(int64(expFloat), 0)
+	if expTime.Before(time.Now()) {
+		return TokenClaims{}, errors.New("token expired")
+	}
 
-		objectID, err := primitive.ObjectIDFromHex(idStr)
-		if err != nil {
-			return primitive.ObjectID{}, errors.New("invalid id format")
-		}
+	idStr, idExists := claims["id"].(string)
+	if !idExists {
+		return TokenClaims{}, errors.New("id field missing or invalid")
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return TokenClaims{}, errors.New("invalid id format")
+	}
+
+	tokenClaims := TokenClaims{UserId: objectID, ExpiresAt: expTime}
+	tokenClaims.TokenId, _ = claims["jti"].(string)
+	tokenClaims.FamilyId, _ = claims["fid"].(string)
+	if iatFloat, ok := claims["iat"].(float64); ok {
+		tokenClaims.IssuedAt = time.Unix(int64(iatFloat), 0)
+	}
 
-		return objectID, nil
+	if expectedTokenType != ResetToken && tokenClaims.TokenId == "" {
+		return TokenClaims{}, errors.New("token is no longer supported, please sign in again")
 	}
 
-	return primitive.ObjectID{}, errors.New("invalid token")
+	return tokenClaims, nil
 }
 
 func GenerateInvitationJWTToken(secretKey, email string) (string, error) {
@@ -116,3 +192,51 @@ func ValidateInvitationJWTToken(secretKey, tokenString string) (string, error) {
 
 	return "", errors.New("invalid token")
 }
+
+// GenerateOAuth2StateToken signs the member and the workspace an integration is being connected for
+func GenerateOAuth2StateToken(secretKey string, userId primitive.ObjectID, workspaceId string) (string, error) {
+	claims := jwt.MapClaims{
+		"id":           userId,
+		"workspace_id": workspaceId,
+		"type":         OAuth2StateToken,
+		"exp":          time.Now().Add(OAuth2StateTTL).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(secretKey))
+}
+
+func ValidateOAuth2StateToken(secretKey, state string) (primitive.ObjectID, string, error) {
+	token, err := jwt.Parse(state, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(secretKey), nil
+	})
+	if err != nil {
+		return primitive.ObjectID{}, "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return primitive.ObjectID{}, "", errors.New("invalid state")
+	}
+	if tokenType, _ := claims["type"].(string); TokenType(tokenType) != OAuth2StateToken {
+		return primitive.ObjectID{}, "", errors.New("invalid state")
+	}
+	if _, exists := claims["exp"].(float64); !exists {
+		return primitive.ObjectID{}, "", errors.New("invalid state")
+	}
+
+	idStr, _ := claims["id"].(string)
+	userId, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return primitive.ObjectID{}, "", errors.New("invalid state")
+	}
+	workspaceId, _ := claims["workspace_id"].(string)
+	if workspaceId == "" {
+		return primitive.ObjectID{}, "", errors.New("invalid state")
+	}
+
+	return userId, workspaceId, nil
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// isRevoked mirrors the revoked_at > iat condition the revoked token lookup runs
+func isRevoked(revokedAt time.Time, claims TokenClaims) bool {
+	return revokedAt.After(claims.IssuedAt)
+}
+
+func issueAccessToken(t *testing.T, userId primitive.ObjectID) TokenClaims {
+	t.Helper()
+
+	signedToken, _, err := IssueJWTToken(AccessToken, userId, "family", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseJWTToken(AccessToken, signedToken, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func TestTokenIssuedRightAfterRevocationIsValid(t *testing.T) {
+	userId := primitive.NewObjectID()
+
+	// the sign out lands late in a second and the new login in the same one, the iat claim loses the fraction
+	for i := 0; i < 20; i++ {
+		revokedAt := RevocationTime(time.Now())
+		claims := issueAccessToken(t, userId)
+
+		if isRevoked(revokedAt, claims) {
+			t.Fatalf("a token issued at %v was denied by the revocation at %v", claims.IssuedAt, revokedAt)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestTokenIssuedBeforeRevocationIsDenied(t *testing.T) {
+	claims := issueAccessToken(t, primitive.NewObjectID())
+
+	revokedAt := RevocationTime(claims.IssuedAt.Add(time.Second))
+	if !isRevoked(revokedAt, claims) {
+		t.Fatal("a sign out in a later second must deny the token")
+	}
+}
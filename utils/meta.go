@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/go-resty/resty/v2"
+	"strings"
+)
+
+type FacebookPage struct {
+	Id                       string `json:"id"`
+	Name                     string `json:"name"`
+	AccessToken              string `json:"access_token"`
+	InstagramBusinessAccount *struct {
+		Id string `json:"id"`
+	} `json:"instagram_business_account"`
+}
+
+type facebookError struct {
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// VerifyMetaWebhook answers the hub.challenge handshake Meta sends when a webhook is subscribed
+func VerifyMetaWebhook(mode, verifyToken, expectedToken, challenge string) (string, error) {
+	if mode != "subscribe" || expectedToken == "" {
+		return "", errors.New("invalid webhook verification request")
+	}
+
+	if !hmac.Equal([]byte(verifyToken), []byte(expectedToken)) {
+		return "", errors.New("invalid verify token")
+	}
+
+	return challenge, nil
+}
+
+// ValidateMetaSignature checks the X-Hub-Signature-256 header against the app secret.
+// Without a secret nothing can be verified, so every webhook is rejected.
+func ValidateMetaSignature(appSecret string, payload []byte, signature string) bool {
+	if appSecret == "" {
+		return false
+	}
+
+	digest, found := strings.CutPrefix(signature, "sha256=")
+	if !found {
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write(payload)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// ExchangeFacebookLongLivedToken trades a short-lived user token for a long-lived one, so page tokens derived from it do not expire
+func ExchangeFacebookLongLivedToken(baseURL, clientID, clientSecret, accessToken string) (string, error) {
+	var result struct {
+		AccessToken string `json:"access_token"`
+		facebookError
+	}
+
+	resp, err := resty.New().R().
+		SetQueryParams(map[string]string{
+			"grant_type":        "fb_exchange_token",
+			"client_id":         clientID,
+			"client_secret":     clientSecret,
+			"fb_exchange_token": accessToken,
+		}).
+		SetResult(&result).
+		SetError(&result).
+		Get(strings.TrimSuffix(baseURL, "/") + "/oauth/access_token")
+	if err != nil {
+		return "", err
+	}
+
+	if resp.IsError() {
+		return "", result.err()
+	}
+
+	return result.AccessToken, nil
+}
+
+func GetFacebookPages(baseURL, accessToken string) ([]FacebookPage, error) {
+	var result struct {
+		Data []FacebookPage `json:"data"`
+		facebookError
+	}
+
+	resp, err := resty.New().R().
+		SetQueryParams(map[string]string{
+			"fields":       "id,name,access_token,instagram_business_account",
+			"access_token": accessToken,
+		}).
+		SetResult(&result).
+		SetError(&result).
+		Get(strings.TrimSuffix(baseURL, "/") + "/me/accounts")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, result.err()
+	}
+
+	return result.Data, nil
+}
+
+func SubscribeFacebookPage(baseURL, pageId, pageAccessToken string) error {
+	var result facebookError
+
+	resp, err := resty.New().R().
+		SetQueryParams(map[string]string{
+			"subscribed_fields": "messages",
+			"access_token":      pageAccessToken,
+		}).
+		SetError(&result).
+		Post(strings.TrimSuffix(baseURL, "/") + "/" + pageId + "/subscribed_apps")
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return result.err()
+	}
+
+	return nil
+}
+
+func (fe facebookError) err() error {
+	if fe.Error == nil || fe.Error.Message == "" {
+		return errors.New("facebook api request failed")
+	}
+
+	return errors.New("facebook api: " + fe.Error.Message)
+}
@@ -58,6 +58,18 @@ func ExtractGoogleData(clientID, clientSecret, code, redirectURL string) (string
 	return profile.Email, pictureData, nil
 }
 
+// FacebookAuthCodeURL is where a member approves access to their pages and the Instagram accounts linked to them
+func FacebookAuthCodeURL(clientID, redirectURL, state string) string {
+	config := &oauth2.Config{
+		ClientID:    clientID,
+		RedirectURL: redirectURL,
+		Endpoint:    facebook.Endpoint,
+		Scopes:      []string{"pages_show_list", "pages_messaging", "pages_manage_metadata", "instagram_basic", "instagram_manage_messages"},
+	}
+
+	return config.AuthCodeURL(state)
+}
+
 func ExchangeFacebookCodeForToken(clientID, clientSecret, code, redirectURL string) (string, string, error) {
 	config := &oauth2.Config{
 		ClientID:     clientID,
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+type Permission string
+
+const (
+	PermissionWorkspaceRead     Permission = "workspace.read"
+	PermissionWorkspaceManage   Permission = "workspace.manage"
+	PermissionWorkspaceDelete   Permission = "workspace.delete"
+	PermissionMemberManage      Permission = "member.manage"
+	PermissionRoleManage        Permission = "role.manage"
+	PermissionTeamManage        Permission = "team.manage"
+	PermissionFolderManage      Permission = "folder.manage"
+	PermissionChatRead          Permission = "chat.read"
+	PermissionChatReadAll       Permission = "chat.read_all"
+	PermissionChatReply         Permission = "chat.reply"
+	PermissionChatUpdate        Permission = "chat.update"
+	PermissionTagRead           Permission = "tag.read"
+	PermissionTicketReassign    Permission = "ticket.reassign"
+	PermissionTicketStatus      Permission = "ticket.update_status"
+	PermissionMessageDelete     Permission = "message.delete"
+	PermissionIntegrationManage Permission = "integration.manage"
+	PermissionSavedReplyManage  Permission = "saved_reply.manage"
+	PermissionSLAManage         Permission = "sla.manage"
+	PermissionAuditRead         Permission = "audit.read"
+	PermissionSecurityManage    Permission = "security.manage"
+)
+
+const (
+	roleOwner = "owner"
+	roleAdmin = "admin"
+	roleAgent = "agent"
+)
+
+var allPermissions = []Permission{
+	PermissionWorkspaceRead,
+	PermissionWorkspaceManage,
+	PermissionWorkspaceDelete,
+	PermissionMemberManage,
+	PermissionRoleManage,
+	PermissionTeamManage,
+	PermissionFolderManage,
+	PermissionChatRead,
+	PermissionChatReadAll,
+	PermissionChatReply,
+	PermissionChatUpdate,
+	PermissionTagRead,
+	PermissionTicketReassign,
+	PermissionTicketStatus,
+	PermissionMessageDelete,
+	PermissionIntegrationManage,
+	PermissionSavedReplyManage,
+	PermissionSLAManage,
+	PermissionAuditRead,
+	PermissionSecurityManage,
+}
+
+var agentPermissions = []Permission{
+	PermissionWorkspaceRead,
+	PermissionFolderManage,
+	PermissionChatRead,
+	PermissionChatReadAll,
+	PermissionChatReply,
+	PermissionChatUpdate,
+	PermissionTicketReassign,
+	PermissionTicketStatus,
+	PermissionMessageDelete,
+}
+
+// builtInRoles keeps the permissions the owner, admin and agent roles had before custom roles existed.
+// Admins get everything except deleting the workspace, editing roles and security settings, so they cannot grant themselves more.
+var builtInRoles = map[string][]Permission{
+	roleOwner: allPermissions,
+	roleAdmin: slices.DeleteFunc(slices.Clone(allPermissions), func(permission Permission) bool {
+		return permission == PermissionWorkspaceDelete || permission == PermissionRoleManage || permission == PermissionSecurityManage
+	}),
+	roleAgent: agentPermissions,
+}
+
+// Authorizer resolves the built-in and custom roles of a workspace to permissions.
+// It only looks at the data it is given, so it needs no database.
+type Authorizer struct {
+	customRoles map[string][]string
+}
+
+func NewAuthorizer(customRoles map[string][]string) *Authorizer {
+	return &Authorizer{customRoles: customRoles}
+}
+
+// Permissions lists what the role may do. Every known role can at least read its workspace,
+// and an unknown role can do nothing.
+func (a *Authorizer) Permissions(role string) []Permission {
+	if permissions, ok := builtInRoles[role]; ok {
+		return permissions
+	}
+
+	values, ok := a.customRoles[role]
+	if !ok {
+		return nil
+	}
+
+	permissions := []Permission{PermissionWorkspaceRead}
+	for _, value := range values {
+		if permission := Permission(value); slices.Contains(allPermissions, permission) && !slices.Contains(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions
+}
+
+func (a *Authorizer) Can(role string, permission Permission) bool {
+	return slices.Contains(a.Permissions(role), permission)
+}
+
+// Authorize is the one permission check every workspace action goes through. An empty role means the user is not
+// a member. When the workspace requires two-factor authentication, twoFactorEnabled is asked once after the
+// permissions passed, so services only load the user when they have to.
+func (a *Authorizer) Authorize(role string, requireTwoFactor bool, twoFactorEnabled func() (bool, error), permissions ...Permission) error {
+	if role == "" {
+		return errors.New("unauthorised")
+	}
+	for _, permission := range permissions {
+		if !a.Can(role, permission) {
+			return errors.New("unauthorised")
+		}
+	}
+
+	if !requireTwoFactor {
+		return nil
+	}
+	enabled, err := twoFactorEnabled()
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errors.New("two-factor authentication is required by this workspace")
+	}
+
+	return nil
+}
+
+// HasRole reports whether the role is built in or defined for the workspace
+func (a *Authorizer) HasRole(role string) bool {
+	if IsBuiltInRole(role) {
+		return true
+	}
+	_, ok := a.customRoles[role]
+	return ok
+}
+
+func IsBuiltInRole(role string) bool {
+	_, ok := builtInRoles[role]
+	return ok
+}
+
+func BuiltInRoles() map[string][]Permission {
+	return builtInRoles
+}
+
+// ParsePermissions checks permission names sent by a client and drops duplicates
+func ParsePermissions(values []string) ([]string, error) {
+	var permissions []string
+	for _, value := range values {
+		if !slices.Contains(allPermissions, Permission(value)) {
+			return nil, fmt.Errorf("unknown permission: %s", value)
+		}
+		if !slices.Contains(permissions, value) {
+			permissions = append(permissions, value)
+		}
+	}
+	return permissions, nil
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestBuiltInRolePermissions(t *testing.T) {
+	authorizer := NewAuthorizer(nil)
+
+	if !slices.Equal(authorizer.Permissions(roleOwner), allPermissions) {
+		t.Fatal("the owner should hold every permission")
+	}
+	for _, permission := range []Permission{PermissionWorkspaceDelete, PermissionRoleManage, PermissionSecurityManage} {
+		if authorizer.Can(roleAdmin, permission) {
+			t.Fatalf("admins must not hold %s", permission)
+		}
+	}
+	if !authorizer.Can(roleAdmin, PermissionMemberManage) {
+		t.Fatal("admins should manage members")
+	}
+	if authorizer.Can(roleAgent, PermissionIntegrationManage) || !authorizer.Can(roleAgent, PermissionChatReply) {
+		t.Fatal("agents should reply to chats but not manage integrations")
+	}
+}
+
+func TestCustomRolePermissions(t *testing.T) {
+	authorizer := NewAuthorizer(map[string][]string{
+		"supervisor": {"sla.manage", "chat.read_all", "sla.manage", "made.up"},
+	})
+
+	permissions := authorizer.Permissions("supervisor")
+	if !slices.Equal(permissions, []Permission{PermissionWorkspaceRead, PermissionSLAManage, PermissionChatReadAll}) {
+		t.Fatalf("expected workspace.read plus the known permissions once, got %v", permissions)
+	}
+	if authorizer.Permissions("unknown") != nil || authorizer.Can("unknown", PermissionWorkspaceRead) {
+		t.Fatal("an unknown role should hold nothing")
+	}
+	if !authorizer.HasRole("supervisor") || !authorizer.HasRole(roleAgent) || authorizer.HasRole("unknown") {
+		t.Fatal("HasRole should know built-in and custom roles only")
+	}
+}
+
+func TestAuthorizeRefusesMissingPermission(t *testing.T) {
+	authorizer := NewAuthorizer(nil)
+
+	if err := authorizer.Authorize(roleAgent, false, nil, PermissionChatReply, PermissionSLAManage); err == nil {
+		t.Fatal("expected every permission to be required")
+	}
+	if err := authorizer.Authorize("", false, nil); err == nil {
+		t.Fatal("expected a non-member to be refused")
+	}
+	if err := authorizer.Authorize(roleAgent, false, nil, PermissionChatReply, PermissionChatReadAll); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthorizeChecksTwoFactorOnce(t *testing.T) {
+	authorizer := NewAuthorizer(nil)
+
+	calls := 0
+	enabled := func() (bool, error) {
+		calls++
+		return true, nil
+	}
+	if err := authorizer.Authorize(roleAdmin, true, enabled, PermissionMemberManage, PermissionTeamManage, PermissionChatRead); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected the user to be looked up once, got %d", calls)
+	}
+
+	if err := authorizer.Authorize(roleAgent, true, enabled, PermissionMemberManage); err == nil || calls != 1 {
+		t.Fatal("a missing permission should be refused before the user is looked up")
+	}
+	if err := authorizer.Authorize(roleAdmin, false, enabled, PermissionMemberManage); err != nil || calls != 1 {
+		t.Fatal("the user should not be looked up when the workspace does not require two-factor authentication")
+	}
+}
+
+func TestAuthorizeRequiresTwoFactor(t *testing.T) {
+	authorizer := NewAuthorizer(nil)
+
+	err := authorizer.Authorize(roleOwner, true, func() (bool, error) { return false, nil }, PermissionWorkspaceRead)
+	if err == nil {
+		t.Fatal("expected a member without two-factor authentication to be refused")
+	}
+
+	lookupErr := errors.New("user not found")
+	if err = authorizer.Authorize(roleOwner, true, func() (bool, error) { return false, lookupErr }, PermissionWorkspaceRead); !errors.Is(err, lookupErr) {
+		t.Fatalf("expected the lookup error, got %v", err)
+	}
+}
+
+func TestParsePermissions(t *testing.T) {
+	permissions, err := ParsePermissions([]string{"chat.read", "chat.reply", "chat.read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(permissions, []string{"chat.read", "chat.reply"}) {
+		t.Fatalf("expected duplicates dropped, got %v", permissions)
+	}
+
+	if _, err = ParsePermissions([]string{"chat.read", "root"}); err == nil {
+		t.Fatal("expected an unknown permission to be rejected")
+	}
+}
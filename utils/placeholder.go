@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ReplyPlaceholders are the keys a saved reply can use as {{key}}
+var ReplyPlaceholders = []string{"name", "company", "client_email", "agent_name"}
+
+var placeholderPattern = regexp.MustCompile(`{{\s*([a-zA-Z_]+)\s*}}`)
+
+// FillPlaceholders replaces every {{key}} in text with values[key]; keys without a value become empty
+func FillPlaceholders(text string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		key := strings.ToLower(placeholderPattern.FindStringSubmatch(match)[1])
+		return values[key]
+	})
+}
+
+// ValidatePlaceholders rejects placeholders that are not in allowed
+func ValidatePlaceholders(text string, allowed []string) error {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		key := strings.ToLower(match[1])
+		found := false
+		for _, name := range allowed {
+			if name == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown placeholder: %s", match[0])
+		}
+	}
+
+	return nil
+}
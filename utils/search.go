@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// SearchTerms splits a text search query into lowercase terms, dropping negations and quotes
+func SearchTerms(query string) []string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+
+		term := strings.ToLower(strings.Trim(field, `"'`))
+		if term != "" {
+			terms = append(terms, term)
+		}
+	}
+
+	return terms
+}
+
+// HighlightSnippet cuts a window of text around the first matching term and wraps every match in <em> tags.
+// The rest of the snippet is HTML-escaped. The boolean reports whether any term matched.
+func HighlightSnippet(text string, terms []string, radius int) (string, bool) {
+	runes := []rune(text)
+	lowered := make([]rune, len(runes))
+	for i, r := range runes {
+		lowered[i] = unicode.ToLower(r)
+	}
+
+	var matches [][2]int
+	for i := 0; i < len(lowered); i++ {
+		for _, term := range terms {
+			termRunes := []rune(term)
+			if len(termRunes) == 0 || i+len(termRunes) > len(lowered) || string(lowered[i:i+len(termRunes)]) != term {
+				continue
+			}
+			matches = append(matches, [2]int{i, i + len(termRunes)})
+			i += len(termRunes) - 1
+			break
+		}
+	}
+
+	if len(matches) == 0 {
+		if len(runes) > 2*radius {
+			return html.EscapeString(string(runes[:2*radius])) + "…", false
+		}
+		return html.EscapeString(text), false
+	}
+
+	start, end := max(matches[0][0]-radius, 0), min(matches[0][1]+radius, len(runes))
+
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString("…")
+	}
+
+	position := start
+	for _, match := range matches {
+		if match[0] < start || match[1] > end {
+			continue
+		}
+		snippet.WriteString(html.EscapeString(string(runes[position:match[0]])))
+		snippet.WriteString("<em>" + html.EscapeString(string(runes[match[0]:match[1]])) + "</em>")
+		position = match[1]
+	}
+	snippet.WriteString(html.EscapeString(string(runes[position:end])))
+
+	if end < len(runes) {
+		snippet.WriteString("…")
+	}
+
+	return snippet.String(), true
+}
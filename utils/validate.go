@@ -21,6 +21,15 @@ func ValidateWorkspaceId(projectId string) error {
 	return nil
 }
 
+// ValidatePassword keeps passwords within what bcrypt hashes, it ignores everything after 72 bytes
+func ValidatePassword(password string) error {
+	if len(password) < 8 || len(password) > 72 {
+		return errors.New("password must be between 8 and 72 characters")
+	}
+
+	return nil
+}
+
 func isValidCharacter(char rune) bool {
 	return (char >= 'a' && char <= 'z') ||
 		(char >= '0' && char <= '9') ||